@@ -67,39 +67,14 @@ func WriteProtein(inputFile, outputDir string) {
 	// Create a buffered writer for the output file
 	writer := bufio.NewWriter(outFile)
 
-	// Flags to track whether we're in Model 1 or in a single-model PDB file
-	inModel1 := false
-	multiModel := false // Detect if the file contains multiple models
-
 	// Process the input file line by line
 	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
 		line := scanner.Text()
 
-		// Detect MODEL start (multi-model case)
-		if strings.HasPrefix(line, "MODEL") {
-			if strings.TrimSpace(line) == "MODEL        1" {
-				inModel1 = true
-			} else if multiModel && inModel1 {
-				// Stop processing after ENDMDL of Model 1 in multi-model PDB
-				break
-			}
-			multiModel = true
-			continue
-		}
-
-		// Stop processing after ENDMDL (multi-model case)
-		if strings.HasPrefix(line, "ENDMDL") && inModel1 {
-			break
-		}
-
-		// If no MODEL keyword is present, assume a single-model PDB
-		if !multiModel {
-			inModel1 = true
-		}
-
-		// Write ATOM records only if in Model 1 or single-model case
-		if inModel1 && strings.HasPrefix(line, "ATOM") {
+		// Keep the ATOM records of every model, with the MODEL and ENDMDL records around them, so the MD
+		// snapshots can be docked as an ensemble; single-model files have no MODEL records to keep
+		if strings.HasPrefix(line, "ATOM") || strings.HasPrefix(line, "MODEL") || strings.HasPrefix(line, "ENDMDL") {
 			_, err := writer.WriteString(line + "\n")
 			if err != nil {
 				fmt.Println("Failed to write to output file:", err)
@@ -121,7 +96,7 @@ func WriteProtein(inputFile, outputDir string) {
 		return
 	}
 
-	fmt.Println("Filtered ATOM records written to:", outputFilePath)
+	fmt.Println("Filtered ATOM records of every model written to:", outputFilePath)
 }
 
 func WriteLigand(inputFile, outputDir string) {
@@ -0,0 +1,63 @@
+package analysis
+
+import "math"
+
+// EuclideanDistances computes the distance between every pair of rows, such as the energies of two ligands
+// against the same receptors. Coordinates that are NaN in either row are left out.
+// Input: a slice of rows, each a slice of float64 of the same length
+// Output: a square slice of slices of float64 distances
+func EuclideanDistances(rows [][]float64) [][]float64 {
+	distances := make([][]float64, len(rows))
+	for i := range distances {
+		distances[i] = make([]float64, len(rows))
+	}
+	for i := range rows {
+		for j := i + 1; j < len(rows); j++ {
+			sum := 0.0
+			for k := range rows[i] {
+				if d := rows[i][k] - rows[j][k]; !math.IsNaN(d) {
+					sum += d * d
+				}
+			}
+			distances[i][j], distances[j][i] = math.Sqrt(sum), math.Sqrt(sum)
+		}
+	}
+	return distances
+}
+
+// ClusterOrder orders items by average-linkage hierarchical clustering: the two closest clusters are merged until
+// one is left, and the items are returned in the leaf order of the tree, so similar items end up next to each
+// other, as along the axes of a clustered heatmap. Ties merge the clusters that come first.
+// Input: a square slice of slices of float64 distances between the items
+// Output: a slice of int item indices
+func ClusterOrder(distances [][]float64) []int {
+	clusters := make([][]int, len(distances))
+	for i := range clusters {
+		clusters[i] = []int{i}
+	}
+	linkage := func(a, b []int) float64 {
+		sum := 0.0
+		for _, i := range a {
+			for _, j := range b {
+				sum += distances[i][j]
+			}
+		}
+		return sum / float64(len(a)*len(b))
+	}
+	for len(clusters) > 1 {
+		first, second, closest := 0, 1, math.Inf(1)
+		for i := range clusters {
+			for j := i + 1; j < len(clusters); j++ {
+				if d := linkage(clusters[i], clusters[j]); d < closest {
+					first, second, closest = i, j, d
+				}
+			}
+		}
+		clusters[first] = append(clusters[first], clusters[second]...)
+		clusters = append(clusters[:second], clusters[second+1:]...)
+	}
+	if len(clusters) == 0 {
+		return nil
+	}
+	return clusters[0]
+}
@@ -0,0 +1,20 @@
+package analysis
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestClusterOrder(t *testing.T) {
+	rows := [][]float64{{0, 0}, {10, 10}, {0.5, 0}, {10, 11}, {0, math.NaN()}}
+	distances := EuclideanDistances(rows)
+	if distances[0][2] != 0.5 || distances[0][4] != 0 || distances[1][3] != 1 {
+		t.Errorf("Unexpected distances %v", distances)
+	}
+	order := ClusterOrder(distances)
+	// 0 and 4 merge first, then 2 joins them, then 1 and 3 merge
+	if want := []int{0, 4, 2, 1, 3}; !slices.Equal(order, want) {
+		t.Errorf("Expected the leaf order %v, got %v", want, order)
+	}
+}
@@ -0,0 +1,4 @@
+// Package analysis evaluates docked poses and results: RMSD with superposition and symmetry correction, detection
+// of protein–ligand interactions with fingerprints, correlation statistics with bootstrap confidence intervals,
+// and hierarchical clustering to order heatmaps.
+package analysis
@@ -0,0 +1,599 @@
+package analysis
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/prepare"
+)
+
+// InteractionType is a kind of non-covalent protein–ligand contact
+type InteractionType int
+
+const (
+	HydrogenBond InteractionType = iota
+	SaltBridge
+	PiStacking
+	CationPi
+	HydrophobicContact
+	MetalCoordination
+	numInteractionTypes
+)
+
+// Geometric criteria of the interaction detection (distances in Å, angles in degrees)
+const (
+	HBONDDISTANCE       = 3.5  // donor–acceptor heavy atom distance
+	HBONDMINANGLE       = 120  // donor–H···acceptor angle when hydrogens are present
+	SALTBRIDGEDISTANCE  = 4.0  // between opposite charges
+	PISTACKINGDISTANCE  = 5.5  // between ring centroids
+	PISTACKINGOFFSET    = 2.0  // lateral offset of parallel rings
+	CATIONPIDISTANCE    = 6.0  // charge to ring centroid
+	CATIONPIOFFSET      = 2.0  // lateral offset of the charge from the ring axis
+	HYDROPHOBICDISTANCE = 4.0  // between hydrophobic atoms
+	METALDISTANCE       = 2.8  // metal to coordinating N, O or S
+	PARALLELANGLE       = 30.0 // ring normals closer than this are stacked face to face
+	TSHAPEDANGLE        = 60.0 // ring normals further apart than this are stacked edge to face
+)
+
+// String returns the name of an interaction type.
+// Input: an InteractionType
+// Output: a string
+func (t InteractionType) String() string {
+	switch t {
+	case HydrogenBond:
+		return "hbond"
+	case SaltBridge:
+		return "salt_bridge"
+	case PiStacking:
+		return "pi_stacking"
+	case CationPi:
+		return "cation_pi"
+	case HydrophobicContact:
+		return "hydrophobic"
+	case MetalCoordination:
+		return "metal"
+	}
+	return "unknown"
+}
+
+// Interaction is one contact between a receptor residue and a ligand atom or ring
+type Interaction struct {
+	Type        InteractionType
+	Residue     string // residue identifier such as "A:ASP25"
+	ProteinAtom string
+	LigandAtom  string
+	Distance    float64
+	Detail      string // direction of H-bonds and cation-π, geometry of π-stacking
+	residue     int    // index of the residue in SplitResidues order
+}
+
+// Receptor atom roles by residue and atom name, used because receptor files rarely carry hydrogens or bond orders
+var (
+	proteinDonors = map[string][]string{
+		"ARG": {"NE", "NH1", "NH2"}, "ASN": {"ND2"}, "GLN": {"NE2"}, "HIS": {"ND1", "NE2"}, "HID": {"ND1"},
+		"HIE": {"NE2"}, "HIP": {"ND1", "NE2"}, "LYS": {"NZ"}, "LYN": {"NZ"}, "SER": {"OG"}, "THR": {"OG1"},
+		"TYR": {"OH"}, "TRP": {"NE1"}, "CYS": {"SG"}, "ASH": {"OD2"}, "GLH": {"OE2"}, "HOH": {"O"},
+	}
+	proteinAcceptors = map[string][]string{
+		"ASP": {"OD1", "OD2"}, "GLU": {"OE1", "OE2"}, "ASH": {"OD1", "OD2"}, "GLH": {"OE1", "OE2"},
+		"ASN": {"OD1"}, "GLN": {"OE1"}, "HIS": {"ND1", "NE2"}, "HID": {"NE2"}, "HIE": {"ND1"},
+		"SER": {"OG"}, "THR": {"OG1"}, "TYR": {"OH"}, "MET": {"SD"}, "HOH": {"O"},
+	}
+	proteinPositive = map[string][]string{
+		"LYS": {"NZ"}, "ARG": {"NE", "NH1", "NH2"}, "HIP": {"ND1", "NE2"},
+	}
+	proteinNegative = map[string][]string{
+		"ASP": {"OD1", "OD2"}, "GLU": {"OE1", "OE2"},
+	}
+	// carbons bonded to N or O, which are not hydrophobic
+	proteinPolarCarbons = map[string][]string{
+		"ARG": {"CD", "CZ"}, "ASN": {"CG"}, "ASP": {"CG"}, "GLN": {"CD"}, "GLU": {"CD"}, "HIS": {"CD2", "CE1"},
+		"HID": {"CD2", "CE1"}, "HIE": {"CD2", "CE1"}, "HIP": {"CG", "CD2", "CE1"}, "LYS": {"CE"}, "SER": {"CB"},
+		"THR": {"CB"}, "TYR": {"CZ"}, "TRP": {"CD1", "CE2"}, "PRO": {"CD"},
+	}
+	proteinAromaticRings = map[string][][]string{
+		"PHE": {{"CG", "CD1", "CE1", "CZ", "CE2", "CD2"}},
+		"TYR": {{"CG", "CD1", "CE1", "CZ", "CE2", "CD2"}},
+		"TRP": {{"CG", "CD1", "NE1", "CE2", "CD2"}, {"CD2", "CE2", "CZ2", "CH2", "CZ3", "CE3"}},
+		"HIS": {{"CG", "ND1", "CE1", "NE2", "CD2"}}, "HID": {{"CG", "ND1", "CE1", "NE2", "CD2"}},
+		"HIE": {{"CG", "ND1", "CE1", "NE2", "CD2"}}, "HIP": {{"CG", "ND1", "CE1", "NE2", "CD2"}},
+	}
+	metalElements = map[string]bool{
+		"Zn": true, "Mg": true, "Ca": true, "Mn": true, "Fe": true, "Cu": true, "Co": true, "Ni": true,
+		"Na": true, "K": true, "Cd": true, "Hg": true,
+	}
+)
+
+// hasName reports whether a name is listed for the residue in a role table.
+// Input: a role table, a string residue name, a string atom name
+// Output: a bool
+func hasName(table map[string][]string, residue, name string) bool {
+	for _, candidate := range table[residue] {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
+
+// interactionSite holds the per-atom roles of one molecule
+type interactionSite struct {
+	molecule    molecule.Molecule
+	neighbors   [][]int
+	donors      map[int]bool
+	acceptors   map[int]bool
+	positive    map[int]bool
+	negative    map[int]bool
+	hydrophobic map[int]bool
+	metals      map[int]bool
+	rings       []molecule.Ring
+}
+
+// receptorSite assigns interaction roles to receptor atoms from residue and atom names.
+// Input: a Molecule receptor
+// Output: an interactionSite
+func receptorSite(receptor molecule.Molecule) interactionSite {
+	site := newInteractionSite(receptor)
+	residues := molecule.SplitResidues(receptor)
+	for _, residue := range residues {
+		for _, i := range residue.Atoms {
+			atom := receptor.Atoms[i]
+			switch {
+			case metalElements[atom.Element]:
+				site.metals[i] = true
+				continue
+			case atom.Name == "N" && residue.Name != "PRO":
+				site.donors[i] = true
+			case atom.Name == "O" || atom.Name == "OXT":
+				site.acceptors[i] = true
+			}
+			if atom.Name == "OXT" {
+				site.negative[i] = true
+			}
+			if hasName(proteinDonors, residue.Name, atom.Name) {
+				site.donors[i] = true
+			}
+			if hasName(proteinAcceptors, residue.Name, atom.Name) {
+				site.acceptors[i] = true
+			}
+			if hasName(proteinPositive, residue.Name, atom.Name) {
+				site.positive[i] = true
+			}
+			if hasName(proteinNegative, residue.Name, atom.Name) {
+				site.negative[i] = true
+			}
+			isCarbon := atom.Element == "C" && atom.Name != "C" && atom.Name != "CA"
+			if (isCarbon && !hasName(proteinPolarCarbons, residue.Name, atom.Name)) || (atom.Element == "S" && residue.Name == "MET") {
+				site.hydrophobic[i] = true
+			}
+		}
+		for _, names := range proteinAromaticRings[residue.Name] {
+			var atoms []int
+			for _, name := range names {
+				if i := residue.AtomIndex(receptor, name); i >= 0 {
+					atoms = append(atoms, i)
+				}
+			}
+			if len(atoms) == len(names) {
+				site.rings = append(site.rings, molecule.NewRing(receptor, atoms))
+			}
+		}
+	}
+	return site
+}
+
+// ligandSite assigns interaction roles to ligand atoms from elements, SYBYL types, formal charges and bonds.
+// Amines and carboxylic acids are treated as charged, as they are at physiological pH.
+// Input: a Molecule ligand
+// Output: an interactionSite
+func ligandSite(ligand molecule.Molecule) interactionSite {
+	molecule.EnsureBonds(&ligand)
+	site := newInteractionSite(ligand)
+	neighbors := site.neighbors
+	for i, atom := range ligand.Atoms {
+		heavyNeighbors, polarNeighbor := 0, false
+		for _, n := range neighbors[i] {
+			if ligand.Atoms[n].Element != "H" {
+				heavyNeighbors++
+			}
+			if e := ligand.Atoms[n].Element; e != "C" && e != "H" && e != "F" && e != "Cl" && e != "Br" && e != "I" && e != "S" {
+				polarNeighbor = true
+			}
+		}
+		hydrogens := prepare.ImplicitHydrogenCount(ligand, neighbors, i)
+		if prepare.HasHydrogenNeighbor(ligand, neighbors, i) {
+			hydrogens++
+		}
+		switch atom.Element {
+		case "N":
+			if hydrogens > 0 {
+				site.donors[i] = true
+			}
+			hybridization := molecule.AtomHybridization(ligand, neighbors, i)
+			if hydrogens == 0 && heavyNeighbors <= 2 && atom.FormalCharge <= 0 && hybridization != molecule.HybridSP3 &&
+				atom.Type != "N.am" && atom.Type != "N.pl3" {
+				site.acceptors[i] = true
+			}
+			if atom.FormalCharge > 0 || atom.Type == "N.4" || prepare.IsAliphaticAmine(ligand, neighbors, i) {
+				site.positive[i] = true
+			}
+		case "O":
+			if hydrogens > 0 {
+				site.donors[i] = true
+			}
+			if atom.FormalCharge <= 0 {
+				site.acceptors[i] = true
+			}
+			if atom.FormalCharge < 0 || atom.Type == "O.co2" {
+				site.negative[i] = true
+			}
+		case "S":
+			if heavyNeighbors > 0 && !polarNeighbor {
+				site.hydrophobic[i] = true
+			}
+		case "C":
+			if !polarNeighbor {
+				site.hydrophobic[i] = true
+			}
+			if hydroxyl, carbonyl, ok := prepare.CarboxylOxygens(ligand, neighbors, i); ok {
+				site.negative[hydroxyl], site.negative[carbonyl] = true, true
+			}
+			if atom.Type == "C.cat" {
+				for _, n := range neighbors[i] {
+					if ligand.Atoms[n].Element == "N" {
+						site.positive[n] = true
+					}
+				}
+			}
+		case "Cl", "Br", "I":
+			site.hydrophobic[i] = true
+		}
+	}
+	site.rings = molecule.AromaticRings(ligand, neighbors)
+	return site
+}
+
+// newInteractionSite creates an empty interactionSite for a molecule with bonds.
+// Input: a Molecule m
+// Output: an interactionSite
+func newInteractionSite(m molecule.Molecule) interactionSite {
+	molecule.EnsureBonds(&m)
+	return interactionSite{
+		molecule: m, neighbors: molecule.Neighbors(m), donors: map[int]bool{}, acceptors: map[int]bool{},
+		positive: map[int]bool{}, negative: map[int]bool{}, hydrophobic: map[int]bool{}, metals: map[int]bool{},
+	}
+}
+
+// DetectInteractions finds the hydrogen bonds, salt bridges, π-stacking, cation-π, hydrophobic contacts and
+// metal coordination between a receptor and a ligand pose. Hydrophobic contacts are reduced to the closest
+// receptor atom per residue and ligand atom, and salt bridges to the closest pair per residue and ligand group.
+// Input: a Molecule receptor, a Molecule ligand
+// Output: a slice of Interaction sorted by residue and type
+func DetectInteractions(receptor, ligand molecule.Molecule) []Interaction {
+	protein := receptorSite(receptor)
+	small := ligandSite(ligand)
+	residueOf := molecule.ResidueIndexOf(receptor)
+	residues := molecule.SplitResidues(receptor)
+	cutoff := CATIONPIDISTANCE + 1
+	var interactions []Interaction
+	add := func(t InteractionType, p int, ligandAtom string, distance float64, detail string) {
+		atom := receptor.Atoms[p]
+		interactions = append(interactions, Interaction{
+			Type: t, Residue: residues[residueOf[p]].ID(), ProteinAtom: atom.Name, LigandAtom: ligandAtom,
+			Distance: distance, Detail: detail, residue: residueOf[p],
+		})
+	}
+
+	// receptor atoms near the ligand, so the pair loops stay small for large proteins
+	var nearby []int
+	for p, atom := range receptor.Atoms {
+		for _, l := range ligand.Atoms {
+			if math.Abs(atom.Position.X-l.Position.X) < cutoff && molecule.Distance(atom.Position, l.Position) < cutoff {
+				nearby = append(nearby, p)
+				break
+			}
+		}
+	}
+
+	closestHydrophobic := make(map[string]Interaction)
+	closestSaltBridge := make(map[string]Interaction)
+	for _, p := range nearby {
+		pAtom := receptor.Atoms[p]
+		for l, lAtom := range ligand.Atoms {
+			d := molecule.Distance(pAtom.Position, lAtom.Position)
+			if d > cutoff {
+				continue
+			}
+			if d <= HBONDDISTANCE {
+				if protein.donors[p] && small.acceptors[l] && hydrogenBondAngleOK(protein, p, lAtom.Position) {
+					add(HydrogenBond, p, lAtom.Name, d, "protein donor")
+				}
+				if small.donors[l] && protein.acceptors[p] && hydrogenBondAngleOK(small, l, pAtom.Position) {
+					add(HydrogenBond, p, lAtom.Name, d, "ligand donor")
+				}
+			}
+			if d <= SALTBRIDGEDISTANCE && ((protein.positive[p] && small.negative[l]) || (protein.negative[p] && small.positive[l])) {
+				key := fmt.Sprintf("%d/%s", residueOf[p], ligandGroupKey(small, l))
+				if previous, ok := closestSaltBridge[key]; !ok || d < previous.Distance {
+					closestSaltBridge[key] = Interaction{Type: SaltBridge, Residue: residues[residueOf[p]].ID(), ProteinAtom: pAtom.Name, LigandAtom: lAtom.Name, Distance: d, residue: residueOf[p]}
+				}
+			}
+			if d <= HYDROPHOBICDISTANCE && protein.hydrophobic[p] && small.hydrophobic[l] {
+				key := fmt.Sprintf("%d/%d", residueOf[p], l)
+				if previous, ok := closestHydrophobic[key]; !ok || d < previous.Distance {
+					closestHydrophobic[key] = Interaction{Type: HydrophobicContact, Residue: residues[residueOf[p]].ID(), ProteinAtom: pAtom.Name, LigandAtom: lAtom.Name, Distance: d, residue: residueOf[p]}
+				}
+			}
+			if d <= METALDISTANCE && protein.metals[p] && (lAtom.Element == "N" || lAtom.Element == "O" || lAtom.Element == "S") {
+				add(MetalCoordination, p, lAtom.Name, d, "")
+			}
+		}
+	}
+	for _, interaction := range closestSaltBridge {
+		interactions = append(interactions, interaction)
+	}
+	for _, interaction := range closestHydrophobic {
+		interactions = append(interactions, interaction)
+	}
+
+	// ring interactions
+	for _, pRing := range protein.rings {
+		p := pRing.Atoms[0]
+		for _, lRing := range small.rings {
+			if detail, d, ok := piStacking(pRing, lRing); ok {
+				add(PiStacking, p, ringName(ligand, lRing), d, detail)
+			}
+		}
+		for l := range small.positive {
+			if d, ok := cationPi(pRing, ligand.Atoms[l].Position); ok {
+				add(CationPi, p, ligand.Atoms[l].Name, d, "ligand cation")
+			}
+		}
+	}
+	for _, lRing := range small.rings {
+		for p := range protein.positive {
+			if d, ok := cationPi(lRing, receptor.Atoms[p].Position); ok {
+				add(CationPi, p, ringName(ligand, lRing), d, "protein cation")
+			}
+		}
+	}
+
+	sort.SliceStable(interactions, func(i, j int) bool {
+		a, b := interactions[i], interactions[j]
+		if a.residue != b.residue {
+			return a.residue < b.residue
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.LigandAtom != b.LigandAtom {
+			return a.LigandAtom < b.LigandAtom
+		}
+		return a.Distance < b.Distance
+	})
+	return interactions
+}
+
+// ligandGroupKey identifies the charged group of a ligand atom, so that both oxygens of a carboxylate
+// count as one salt bridge.
+// Input: an interactionSite, an int atom index
+// Output: a string key
+func ligandGroupKey(site interactionSite, i int) string {
+	for _, n := range site.neighbors[i] {
+		if site.molecule.Atoms[i].Element == "O" && site.molecule.Atoms[n].Element != "H" {
+			return "group" + strconv.Itoa(n)
+		}
+	}
+	return "atom" + strconv.Itoa(i)
+}
+
+// hydrogenBondAngleOK checks the donor–H···acceptor angle when the donor carries explicit hydrogens. Without
+// hydrogens the distance criterion alone is used.
+// Input: the interactionSite of the donor, an int donor index, a Position3d acceptor position
+// Output: a bool
+func hydrogenBondAngleOK(site interactionSite, donor int, acceptor molecule.Position3d) bool {
+	hasHydrogen := false
+	for _, n := range site.neighbors[donor] {
+		if site.molecule.Atoms[n].Element != "H" {
+			continue
+		}
+		hasHydrogen = true
+		angle := molecule.BondAngle(site.molecule.Atoms[donor].Position, site.molecule.Atoms[n].Position, acceptor) * 180 / math.Pi
+		if angle >= HBONDMINANGLE {
+			return true
+		}
+	}
+	return !hasHydrogen
+}
+
+// piStacking tests two aromatic rings for face-to-face or edge-to-face stacking.
+// Input: two Rings
+// Output: a string geometry ("parallel" or "t-shaped"), the centroid distance, and a bool
+func piStacking(a, b molecule.Ring) (string, float64, bool) {
+	d := molecule.Distance(a.Centroid, b.Centroid)
+	if d > PISTACKINGDISTANCE {
+		return "", d, false
+	}
+	angle := math.Acos(math.Min(1, math.Abs(a.Normal.Dot(b.Normal)))) * 180 / math.Pi
+	offset := math.Min(ringOffset(a, b.Centroid), ringOffset(b, a.Centroid))
+	switch {
+	case angle <= PARALLELANGLE && offset <= PISTACKINGOFFSET:
+		return "parallel", d, true
+	case angle >= TSHAPEDANGLE && offset <= PISTACKINGOFFSET:
+		return "t-shaped", d, true
+	}
+	return "", d, false
+}
+
+// cationPi tests a charged atom for a cation-π interaction with an aromatic ring.
+// Input: a Ring, a Position3d charge position
+// Output: the centroid distance and a bool
+func cationPi(ring molecule.Ring, charge molecule.Position3d) (float64, bool) {
+	d := molecule.Distance(ring.Centroid, charge)
+	return d, d <= CATIONPIDISTANCE && ringOffset(ring, charge) <= CATIONPIOFFSET
+}
+
+// ringOffset returns the distance of a point's projection onto the ring plane from the ring centroid.
+// Input: a Ring, a Position3d point
+// Output: a float64 offset
+func ringOffset(ring molecule.Ring, point molecule.Position3d) float64 {
+	v := point.Add(ring.Centroid.Scale(-1))
+	height := v.Dot(ring.Normal)
+	return math.Sqrt(math.Max(0, v.Dot(v)-height*height))
+}
+
+// ringName labels a ligand ring by its atom names.
+// Input: a Molecule ligand, a Ring
+// Output: a string such as "ring(C1,C2,C3,C4,C5,C6)"
+func ringName(ligand molecule.Molecule, ring molecule.Ring) string {
+	names := make([]string, len(ring.Atoms))
+	for k, i := range ring.Atoms {
+		names[k] = ligand.Atoms[i].Name
+	}
+	return "ring(" + strings.Join(names, ",") + ")"
+}
+
+// InteractionFingerprint is a bit vector with one bit per receptor residue and interaction type, set when the
+// residue makes that kind of contact with the ligand. Fingerprints against the same receptor are comparable.
+type InteractionFingerprint struct {
+	Residues []string
+	Bits     []bool
+}
+
+// NewInteractionFingerprint builds the fingerprint of a pose from its interactions.
+// Input: a Molecule receptor, a slice of Interaction
+// Output: an InteractionFingerprint
+func NewInteractionFingerprint(receptor molecule.Molecule, interactions []Interaction) InteractionFingerprint {
+	residues := molecule.SplitResidues(receptor)
+	fingerprint := InteractionFingerprint{Residues: make([]string, len(residues)), Bits: make([]bool, len(residues)*int(numInteractionTypes))}
+	position := make(map[string]int, len(residues))
+	for r, residue := range residues {
+		fingerprint.Residues[r] = residue.ID()
+		position[residue.ID()] = r
+	}
+	for _, interaction := range interactions {
+		if r, ok := position[interaction.Residue]; ok {
+			fingerprint.Bits[r*int(numInteractionTypes)+int(interaction.Type)] = true
+		}
+	}
+	return fingerprint
+}
+
+// String returns the fingerprint as a string of 0s and 1s.
+// Input: an InteractionFingerprint
+// Output: a string
+func (f InteractionFingerprint) String() string {
+	var b strings.Builder
+	for _, bit := range f.Bits {
+		if bit {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+// SetBits names the set bits of a fingerprint, such as "A:ASP25:hbond".
+// Input: an InteractionFingerprint
+// Output: a slice of strings
+func (f InteractionFingerprint) SetBits() []string {
+	var names []string
+	for k, bit := range f.Bits {
+		if bit {
+			names = append(names, f.Residues[k/int(numInteractionTypes)]+":"+InteractionType(k%int(numInteractionTypes)).String())
+		}
+	}
+	return names
+}
+
+// Tanimoto returns the Tanimoto similarity of two fingerprints of the same receptor.
+// Input: two InteractionFingerprints a and b
+// Output: a float64 between 0 and 1 (1 when neither has any bit set)
+func Tanimoto(a, b InteractionFingerprint) float64 {
+	both, either := 0, 0
+	for k := range a.Bits {
+		if k >= len(b.Bits) {
+			break
+		}
+		if a.Bits[k] && b.Bits[k] {
+			both++
+		}
+		if a.Bits[k] || b.Bits[k] {
+			either++
+		}
+	}
+	if either == 0 {
+		return 1
+	}
+	return float64(both) / float64(either)
+}
+
+// WriteInteractionTable writes one row per interaction.
+// Input: a string fileName, a slice of Interaction
+// Output: an error or nil
+func WriteInteractionTable(fileName string, interactions []Interaction) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"residue", "type", "protein_atom", "ligand_atom", "distance", "detail"}); err != nil {
+		return err
+	}
+	for _, interaction := range interactions {
+		row := []string{interaction.Residue, interaction.Type.String(), interaction.ProteinAtom, interaction.LigandAtom,
+			fmt.Sprintf("%.2f", interaction.Distance), interaction.Detail}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteResidueInteractionTable writes one row per interacting residue with the number of contacts of each type.
+// Input: a string fileName, a slice of Interaction sorted by residue
+// Output: an error or nil
+func WriteResidueInteractionTable(fileName string, interactions []Interaction) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	header := []string{"residue"}
+	for t := InteractionType(0); t < numInteractionTypes; t++ {
+		header = append(header, t.String())
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	var order []string
+	counts := make(map[string][]int)
+	for _, interaction := range interactions {
+		if _, ok := counts[interaction.Residue]; !ok {
+			counts[interaction.Residue] = make([]int, numInteractionTypes)
+			order = append(order, interaction.Residue)
+		}
+		counts[interaction.Residue][interaction.Type]++
+	}
+	for _, residue := range order {
+		row := []string{residue}
+		for _, count := range counts[residue] {
+			row = append(row, strconv.Itoa(count))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,31 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+)
+
+func TestDetectInteractions(t *testing.T) {
+	receptor := moltest.Pocket()
+	interactions := DetectInteractions(receptor, moltest.Benzoate())
+
+	found := make(map[string]bool)
+	for _, interaction := range interactions {
+		found[interaction.Residue+":"+interaction.Type.String()] = true
+	}
+	for _, expected := range []string{"A:LYS10:salt_bridge", "A:LYS10:hbond", "A:PHE20:pi_stacking", "A:PHE20:hydrophobic"} {
+		if !found[expected] {
+			t.Errorf("Expected interaction %s, got %v", expected, interactions)
+		}
+	}
+
+	fingerprint := NewInteractionFingerprint(receptor, interactions)
+	if len(fingerprint.Bits) != 2*int(numInteractionTypes) || Tanimoto(fingerprint, fingerprint) != 1 {
+		t.Errorf("Unexpected fingerprint %s", fingerprint)
+	}
+	empty := NewInteractionFingerprint(receptor, nil)
+	if Tanimoto(fingerprint, empty) != 0 {
+		t.Errorf("Expected zero similarity to an empty fingerprint")
+	}
+}
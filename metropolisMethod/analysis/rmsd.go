@@ -0,0 +1,402 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"gonum.org/v1/gonum/mat"
+)
+
+// RMSDMode selects how two poses of the same molecule are compared
+type RMSDMode int
+
+const (
+	// RMSDInPlace compares raw coordinates atom by atom, as needed for docking poses in the receptor frame
+	RMSDInPlace RMSDMode = iota
+	// RMSDKabsch superposes the poses with the Kabsch algorithm first, for comparing conformers in a common frame
+	RMSDKabsch
+	// RMSDSymmetry compares heavy atoms in place under the best graph automorphism of the bond graph
+	RMSDSymmetry
+	// RMSDSymmetryKabsch combines the automorphism search with Kabsch superposition
+	RMSDSymmetryKabsch
+)
+
+// maxAutomorphisms caps the automorphism search for highly symmetric molecules
+const maxAutomorphisms = 10000
+
+// String returns the command-line name of an RMSD mode.
+// Input: an RMSDMode
+// Output: a string
+func (mode RMSDMode) String() string {
+	switch mode {
+	case RMSDKabsch:
+		return "kabsch"
+	case RMSDSymmetry:
+		return "symmetry"
+	case RMSDSymmetryKabsch:
+		return "symmetry-kabsch"
+	default:
+		return "inplace"
+	}
+}
+
+// ParseRMSDMode converts a mode name ("inplace", "kabsch", "symmetry" or "symmetry-kabsch") to an RMSDMode.
+// Input: a string name
+// Output: the RMSDMode and an error or nil
+func ParseRMSDMode(name string) (RMSDMode, error) {
+	for _, mode := range []RMSDMode{RMSDInPlace, RMSDKabsch, RMSDSymmetry, RMSDSymmetryKabsch} {
+		if strings.EqualFold(name, mode.String()) {
+			return mode, nil
+		}
+	}
+	return RMSDInPlace, fmt.Errorf("unknown RMSD mode %q", name)
+}
+
+// CalculateRMSDMode calculates the RMSD between two poses of the same molecule using the given mode.
+// Input: Molecules simulated and reference with the same atom order, an RMSDMode mode
+// Output: a float64 RMSD value
+func CalculateRMSDMode(simulated, reference molecule.Molecule, mode RMSDMode) float64 {
+	switch mode {
+	case RMSDKabsch:
+		return KabschRMSD(molecule.Positions(simulated), molecule.Positions(reference))
+	case RMSDSymmetry:
+		return SymmetryRMSD(simulated, reference, false)
+	case RMSDSymmetryKabsch:
+		return SymmetryRMSD(simulated, reference, true)
+	default:
+		return CalculateRMSD(simulated, reference)
+	}
+}
+
+// KabschRotation finds the rotation that best superposes mobile onto target after both are centered,
+// so that target ≈ R·(mobile - mobileCentroid) + targetCentroid.
+// Input: slices of Position3d mobile and target of equal length
+// Output: the 3x3 rotation matrix, the two centroids
+func KabschRotation(mobile, target []molecule.Position3d) ([3][3]float64, molecule.Position3d, molecule.Position3d) {
+	cm, ct := molecule.Centroid(mobile), molecule.Centroid(target)
+	h := mat.NewDense(3, 3, nil)
+	for i := range mobile {
+		p, q := mobile[i].Add(cm.Scale(-1)), target[i].Add(ct.Scale(-1))
+		pv, qv := [3]float64{p.X, p.Y, p.Z}, [3]float64{q.X, q.Y, q.Z}
+		for r := 0; r < 3; r++ {
+			for c := 0; c < 3; c++ {
+				h.Set(r, c, h.At(r, c)+pv[r]*qv[c])
+			}
+		}
+	}
+	var svd mat.SVD
+	var rotation [3][3]float64
+	if !svd.Factorize(h, mat.SVDFull) {
+		rotation[0][0], rotation[1][1], rotation[2][2] = 1, 1, 1
+		return rotation, cm, ct
+	}
+	var u, v mat.Dense
+	svd.UTo(&u)
+	svd.VTo(&v)
+	// correct for reflections so that R is a proper rotation
+	d := 1.0
+	if mat.Det(&v)*mat.Det(&u) < 0 {
+		d = -1
+	}
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			rotation[r][c] = v.At(r, 0)*u.At(c, 0) + v.At(r, 1)*u.At(c, 1) + d*v.At(r, 2)*u.At(c, 2)
+		}
+	}
+	return rotation, cm, ct
+}
+
+// applyRotation applies a 3x3 rotation matrix to a vector.
+// Input: a rotation matrix r, a Position3d p
+// Output: the rotated Position3d
+func applyRotation(r [3][3]float64, p molecule.Position3d) molecule.Position3d {
+	return molecule.Position3d{
+		X: r[0][0]*p.X + r[0][1]*p.Y + r[0][2]*p.Z,
+		Y: r[1][0]*p.X + r[1][1]*p.Y + r[1][2]*p.Z,
+		Z: r[2][0]*p.X + r[2][1]*p.Y + r[2][2]*p.Z,
+	}
+}
+
+// Superpose returns a copy of mobile moved onto target by the Kabsch superposition of corresponding atoms.
+// Input: Molecules mobile and target with the same atom order
+// Output: the superposed Molecule
+func Superpose(mobile, target molecule.Molecule) molecule.Molecule {
+	rotation, cm, ct := KabschRotation(molecule.Positions(mobile), molecule.Positions(target))
+	result := molecule.CopyLigand(mobile)
+	for i := range result.Atoms {
+		result.Atoms[i].Position = applyRotation(rotation, result.Atoms[i].Position.Add(cm.Scale(-1))).Add(ct)
+	}
+	return result
+}
+
+// KabschRMSD calculates the RMSD between two point sets after optimal superposition.
+// Input: slices of Position3d mobile and target of equal length
+// Output: a float64 RMSD value
+func KabschRMSD(mobile, target []molecule.Position3d) float64 {
+	if len(mobile) == 0 {
+		return 0
+	}
+	rotation, cm, ct := KabschRotation(mobile, target)
+	var sum float64
+	for i := range mobile {
+		moved := applyRotation(rotation, mobile[i].Add(cm.Scale(-1))).Add(ct)
+		sum += squaredDistance(moved, target[i])
+	}
+	return math.Sqrt(sum / float64(len(mobile)))
+}
+
+// squaredDistance returns the squared Euclidean distance between two points.
+// Input: two Position3d a and b
+// Output: a float64
+func squaredDistance(a, b molecule.Position3d) float64 {
+	dx, dy, dz := a.X-b.X, a.Y-b.Y, a.Z-b.Z
+	return dx*dx + dy*dy + dz*dz
+}
+
+// SymmetryRMSD calculates the heavy-atom RMSD between two poses of the same molecule, minimised over the
+// automorphisms of the heavy-atom bond graph so that equivalent atoms (carboxylate oxygens, ring flips) are
+// not counted as displaced.
+// Input: Molecules simulated and reference with the same atom order, a bool superpose to Kabsch-align each mapping
+// Output: a float64 RMSD value
+func SymmetryRMSD(simulated, reference molecule.Molecule, superpose bool) float64 {
+	graph := molecule.CopyLigand(reference)
+	molecule.EnsureBonds(&graph)
+	var heavy []int
+	for i, atom := range graph.Atoms {
+		if atom.Element != "H" {
+			heavy = append(heavy, i)
+		}
+	}
+	if len(heavy) == 0 {
+		return 0
+	}
+	refPositions := make([]molecule.Position3d, len(heavy))
+	for k, i := range heavy {
+		refPositions[k] = reference.Atoms[i].Position
+	}
+
+	best := math.Inf(1)
+	simPositions := make([]molecule.Position3d, len(heavy))
+	for _, mapping := range Automorphisms(graph, heavy, maxAutomorphisms) {
+		for k, j := range mapping {
+			simPositions[k] = simulated.Atoms[heavy[j]].Position
+		}
+		var value float64
+		if superpose {
+			value = KabschRMSD(simPositions, refPositions)
+		} else {
+			var sum float64
+			for k := range heavy {
+				sum += squaredDistance(simPositions[k], refPositions[k])
+			}
+			value = math.Sqrt(sum / float64(len(heavy)))
+		}
+		best = math.Min(best, value)
+	}
+	return best
+}
+
+// Automorphisms enumerates the automorphisms of the bond graph restricted to the given atoms. Each automorphism
+// maps position k of atoms to position mapping[k]; atoms must agree in element and refined neighbourhood
+// invariants. Bond orders are ignored because input files disagree on how they write carboxylates and
+// aromatic rings. The identity is always returned first.
+// Input: a Molecule m with bonds, a slice of atom indices atoms, an int limit on the number of mappings
+// Output: a slice of mappings
+func Automorphisms(m molecule.Molecule, atoms []int, limit int) [][]int {
+	n := len(atoms)
+	local := make(map[int]int, n)
+	for k, i := range atoms {
+		local[i] = k
+	}
+	adjacency := make([]map[int]bool, n)
+	for k := range adjacency {
+		adjacency[k] = make(map[int]bool)
+	}
+	for _, bond := range m.Bonds {
+		a, okA := local[bond.A]
+		b, okB := local[bond.B]
+		if okA && okB && a != b {
+			adjacency[a][b], adjacency[b][a] = true, true
+		}
+	}
+	classes := refineAtomClasses(m, atoms, adjacency)
+
+	identity := make([]int, n)
+	for k := range identity {
+		identity[k] = k
+	}
+	result := [][]int{identity}
+
+	// visit atoms in breadth-first order so that each new atom is constrained by already mapped neighbours
+	order := bfsOrder(adjacency)
+	mapping := make([]int, n)
+	used := make([]bool, n)
+	for k := range mapping {
+		mapping[k] = -1
+	}
+	var search func(depth int) bool
+	search = func(depth int) bool {
+		if depth == n {
+			if !isIdentity(mapping) {
+				result = append(result, append([]int(nil), mapping...))
+			}
+			return len(result) < limit
+		}
+		k := order[depth]
+		for candidate := 0; candidate < n; candidate++ {
+			if used[candidate] || classes[candidate] != classes[k] {
+				continue
+			}
+			if !consistentMapping(adjacency, mapping, k, candidate) {
+				continue
+			}
+			mapping[k], used[candidate] = candidate, true
+			more := search(depth + 1)
+			mapping[k], used[candidate] = -1, false
+			if !more {
+				return false
+			}
+		}
+		return true
+	}
+	search(0)
+	return result
+}
+
+// refineAtomClasses assigns each atom an integer class from its element and degree, refined by the classes of
+// its neighbours until stable (Morgan-style), so that only equivalent atoms share a class.
+// Input: a Molecule m, the atom indices, the local adjacency
+// Output: a slice of class labels
+func refineAtomClasses(m molecule.Molecule, atoms []int, adjacency []map[int]bool) []int {
+	labels := make([]string, len(atoms))
+	for k, i := range atoms {
+		labels[k] = fmt.Sprintf("%s/%d", m.Atoms[i].Element, len(adjacency[k]))
+	}
+	classes := classesFromLabels(labels)
+	for round := 0; round < len(atoms); round++ {
+		for k := range atoms {
+			var neighbors []string
+			for j := range adjacency[k] {
+				neighbors = append(neighbors, strconv.Itoa(classes[j]))
+			}
+			sort.Strings(neighbors)
+			labels[k] = fmt.Sprintf("%d(%s)", classes[k], strings.Join(neighbors, ","))
+		}
+		refined := classesFromLabels(labels)
+		if countDistinct(refined) == countDistinct(classes) {
+			break
+		}
+		classes = refined
+	}
+	return classes
+}
+
+// classesFromLabels numbers distinct labels in sorted order.
+// Input: a slice of strings labels
+// Output: a slice of int classes
+func classesFromLabels(labels []string) []int {
+	distinct := append([]string(nil), labels...)
+	sort.Strings(distinct)
+	index := make(map[string]int)
+	for _, label := range distinct {
+		if _, ok := index[label]; !ok {
+			index[label] = len(index)
+		}
+	}
+	classes := make([]int, len(labels))
+	for k, label := range labels {
+		classes[k] = index[label]
+	}
+	return classes
+}
+
+// countDistinct returns the number of distinct values in a slice.
+// Input: a slice of ints
+// Output: an int
+func countDistinct(values []int) int {
+	seen := make(map[int]bool)
+	for _, v := range values {
+		seen[v] = true
+	}
+	return len(seen)
+}
+
+// bfsOrder returns the vertices of a graph in breadth-first order, covering every connected component.
+// Input: an adjacency list
+// Output: a slice of vertex indices
+func bfsOrder(adjacency []map[int]bool) []int {
+	visited := make([]bool, len(adjacency))
+	var order []int
+	for start := range adjacency {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+		queue := []int{start}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			order = append(order, v)
+			neighbors := make([]int, 0, len(adjacency[v]))
+			for j := range adjacency[v] {
+				neighbors = append(neighbors, j)
+			}
+			sort.Ints(neighbors)
+			for _, j := range neighbors {
+				if !visited[j] {
+					visited[j] = true
+					queue = append(queue, j)
+				}
+			}
+		}
+	}
+	return order
+}
+
+// consistentMapping checks that mapping k to candidate preserves bonding to every already mapped atom.
+// Input: the adjacency list, the partial mapping, the atom k and its candidate image
+// Output: a bool
+func consistentMapping(adjacency []map[int]bool, mapping []int, k, candidate int) bool {
+	for j, image := range mapping {
+		if image >= 0 && adjacency[k][j] != adjacency[candidate][image] {
+			return false
+		}
+	}
+	return true
+}
+
+// isIdentity reports whether a mapping leaves every atom in place.
+// Input: a slice of ints mapping
+// Output: a bool
+func isIdentity(mapping []int) bool {
+	for k, v := range mapping {
+		if k != v {
+			return false
+		}
+	}
+	return true
+}
+
+// CalculateRMSD  calculates the root-mean-square deviation (RMSD) between corresponding atoms in the two given molecules
+// Input: Molecules simulated and reference
+// Output: a float64 RMSD value
+func CalculateRMSD(simulated, reference molecule.Molecule) float64 {
+	var sumSquaredDist float64
+	numAtoms := float64(len(simulated.Atoms))
+
+	for i := 0; i < len(simulated.Atoms); i++ {
+		simPos := simulated.Atoms[i].Position
+		refPos := reference.Atoms[i].Position
+
+		dx := simPos.X - refPos.X
+		dy := simPos.Y - refPos.Y
+		dz := simPos.Z - refPos.Z
+
+		sumSquaredDist += dx*dx + dy*dy + dz*dz
+	}
+	return math.Sqrt(sumSquaredDist / numAtoms)
+}
@@ -0,0 +1,39 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+func TestSymmetryRMSDCarboxylate(t *testing.T) {
+	reference := moltest.Acetate()
+	swapped := molecule.CopyLigand(reference)
+	swapped.Atoms[2].Position, swapped.Atoms[3].Position = reference.Atoms[3].Position, reference.Atoms[2].Position
+
+	if CalculateRMSDMode(swapped, reference, RMSDInPlace) < 1 {
+		t.Errorf("Expected a large in-place RMSD for swapped oxygens")
+	}
+	if rmsd := CalculateRMSDMode(swapped, reference, RMSDSymmetry); rmsd > 1e-9 {
+		t.Errorf("Expected zero symmetry-corrected RMSD, got %f", rmsd)
+	}
+}
+
+func TestKabschRMSDRigidMotion(t *testing.T) {
+	reference := moltest.Acetate()
+	moved := molecule.CopyLigand(reference)
+	axis := molecule.Position3d{X: 1, Y: 2, Z: 3}
+	axis.Normalize()
+	for i := range moved.Atoms {
+		moved.Atoms[i].Position = molecule.RotateAtom(moved.Atoms[i].Position, axis, 1.1).Add(molecule.Position3d{X: 4, Y: -2, Z: 7})
+	}
+	if rmsd := CalculateRMSDMode(moved, reference, RMSDKabsch); rmsd > 1e-9 {
+		t.Errorf("Expected zero RMSD after superposition, got %f", rmsd)
+	}
+	superposed := Superpose(moved, reference)
+	if rmsd := CalculateRMSD(superposed, reference); math.Abs(rmsd) > 1e-9 {
+		t.Errorf("Expected Superpose to restore the reference pose, got RMSD %f", rmsd)
+	}
+}
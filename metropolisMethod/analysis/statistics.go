@@ -0,0 +1,218 @@
+package analysis
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+)
+
+// ConfidenceInterval is a bootstrap percentile interval around a point estimate
+type ConfidenceInterval struct {
+	Estimate float64 `json:"estimate"`
+	Lower    float64 `json:"lower"`
+	Upper    float64 `json:"upper"`
+}
+
+// MarshalJSON writes undefined bounds (NaN) as null, which encoding/json cannot represent otherwise.
+// Input: a ConfidenceInterval
+// Output: the JSON encoding and an error or nil
+func (interval ConfidenceInterval) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"estimate": FiniteOrNil(interval.Estimate),
+		"lower":    FiniteOrNil(interval.Lower),
+		"upper":    FiniteOrNil(interval.Upper),
+	})
+}
+
+// FiniteOrNil returns v, or nil when v is NaN or infinite, so it can be encoded as JSON.
+// Input: a float64 v
+// Output: v or nil
+func FiniteOrNil(v float64) interface{} {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return v
+}
+
+// PearsonCorrelation computes Pearson's linear correlation coefficient of two equally long samples.
+// Input: slices of float64 x and y
+// Output: a float64 r (NaN when either sample has no variance)
+func PearsonCorrelation(x, y []float64) float64 {
+	n := len(x)
+	if n < 2 {
+		return math.NaN()
+	}
+	mx, my := Mean(x), Mean(y)
+	var sxy, sxx, syy float64
+	for i := 0; i < n; i++ {
+		dx, dy := x[i]-mx, y[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	if sxx == 0 || syy == 0 {
+		return math.NaN()
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// SpearmanCorrelation computes Spearman's rank correlation, the Pearson correlation of the ranks with ties
+// given their average rank.
+// Input: slices of float64 x and y
+// Output: a float64 rho
+func SpearmanCorrelation(x, y []float64) float64 {
+	return PearsonCorrelation(Ranks(x), Ranks(y))
+}
+
+// Ranks returns the 1-based ranks of the values, giving tied values the average of their ranks.
+// Input: a slice of float64 values
+// Output: a slice of float64 ranks
+func Ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return values[order[a]] < values[order[b]] })
+	ranks := make([]float64, len(values))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && values[order[end]] == values[order[start]] {
+			end++
+		}
+		rank := float64(start+end+1) / 2
+		for k := start; k < end; k++ {
+			ranks[order[k]] = rank
+		}
+		start = end
+	}
+	return ranks
+}
+
+// KendallTau computes Kendall's tau-b rank correlation, which corrects for ties in either sample.
+// Input: slices of float64 x and y
+// Output: a float64 tau
+func KendallTau(x, y []float64) float64 {
+	var concordant, discordant, tiesX, tiesY float64
+	for i := 0; i < len(x); i++ {
+		for j := i + 1; j < len(x); j++ {
+			dx, dy := x[i]-x[j], y[i]-y[j]
+			switch {
+			case dx == 0 && dy == 0:
+			case dx == 0:
+				tiesX++
+			case dy == 0:
+				tiesY++
+			case (dx > 0) == (dy > 0):
+				concordant++
+			default:
+				discordant++
+			}
+		}
+	}
+	denominator := math.Sqrt((concordant + discordant + tiesX) * (concordant + discordant + tiesY))
+	if denominator == 0 {
+		return math.NaN()
+	}
+	return (concordant - discordant) / denominator
+}
+
+// LinearRegression fits y = slope*x + intercept by ordinary least squares.
+// Input: slices of float64 x and y
+// Output: the float64 slope and intercept
+func LinearRegression(x, y []float64) (float64, float64) {
+	mx, my := Mean(x), Mean(y)
+	var sxy, sxx float64
+	for i := range x {
+		sxy += (x[i] - mx) * (y[i] - my)
+		sxx += (x[i] - mx) * (x[i] - mx)
+	}
+	if sxx == 0 {
+		return 0, my
+	}
+	slope := sxy / sxx
+	return slope, my - slope*mx
+}
+
+// BootstrapInterval estimates a statistic of paired samples and its percentile confidence interval by
+// resampling the pairs with replacement. Resamples where the statistic is undefined are skipped.
+// Input: slices of float64 x and y, a statistic function, an int number of resamples, a float64 confidence
+// level (such as 0.95), a *rand.Rand source
+// Output: a ConfidenceInterval
+func BootstrapInterval(x, y []float64, statistic func(x, y []float64) float64, resamples int, level float64, source *rand.Rand) ConfidenceInterval {
+	interval := ConfidenceInterval{Estimate: statistic(x, y), Lower: math.NaN(), Upper: math.NaN()}
+	n := len(x)
+	if n < 2 || resamples < 1 {
+		return interval
+	}
+	values := make([]float64, 0, resamples)
+	sampleX, sampleY := make([]float64, n), make([]float64, n)
+	for b := 0; b < resamples; b++ {
+		for i := 0; i < n; i++ {
+			k := source.Intn(n)
+			sampleX[i], sampleY[i] = x[k], y[k]
+		}
+		if value := statistic(sampleX, sampleY); !math.IsNaN(value) {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return interval
+	}
+	sort.Float64s(values)
+	alpha := (1 - level) / 2
+	interval.Lower = Percentile(values, alpha)
+	interval.Upper = Percentile(values, 1-alpha)
+	return interval
+}
+
+// Percentile returns the q-th quantile (0 <= q <= 1) of sorted values by linear interpolation.
+// Input: a sorted slice of float64 values, a float64 q
+// Output: a float64
+func Percentile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	position := q * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	upper := int(math.Ceil(position))
+	fraction := position - float64(lower)
+	return sorted[lower]*(1-fraction) + sorted[upper]*fraction
+}
+
+// Mean calculates and returns the average of a slice of float64 values
+// Input: a slice of float64 values
+// Output: a float64 average value
+func Mean(arr []float64) float64 {
+	if len(arr) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, value := range arr {
+		sum += value
+	}
+	return sum / float64(len(arr))
+}
+
+// BoltzmannAverage is the mean of energies weighted by their Boltzmann factors exp(-energy / temperature), with
+// the temperature in the energy units of the Metropolis criterion. Weights are taken relative to the lowest energy
+// so they do not underflow; a temperature of 0 gives the lowest energy.
+// Input: a slice of float64 energies, a float64 temperature
+// Output: a float64 average, NaN without energies
+func BoltzmannAverage(energies []float64, temperature float64) float64 {
+	if len(energies) == 0 {
+		return math.NaN()
+	}
+	lowest := slices.Min(energies)
+	if temperature <= 0 {
+		return lowest
+	}
+	sum, weights := 0.0, 0.0
+	for _, energy := range energies {
+		weight := math.Exp(-(energy - lowest) / temperature)
+		sum += weight * energy
+		weights += weight
+	}
+	return sum / weights
+}
@@ -0,0 +1,54 @@
+package analysis
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestCorrelations(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5}
+	y := []float64{2, 1, 4, 3, 5}
+
+	if r := PearsonCorrelation(x, y); math.Abs(r-0.8) > 1e-9 {
+		t.Errorf("Expected Pearson r 0.8, got %f", r)
+	}
+	if rho := SpearmanCorrelation(x, y); math.Abs(rho-0.8) > 1e-9 {
+		t.Errorf("Expected Spearman rho 0.8, got %f", rho)
+	}
+	// 8 concordant and 2 discordant pairs
+	if tau := KendallTau(x, y); math.Abs(tau-0.6) > 1e-9 {
+		t.Errorf("Expected Kendall tau 0.6, got %f", tau)
+	}
+	ranks := Ranks([]float64{3, 1, 3, 2})
+	if ranks[0] != 3.5 || ranks[1] != 1 || ranks[2] != 3.5 || ranks[3] != 2 {
+		t.Errorf("Expected tied ranks [3.5 1 3.5 2], got %v", ranks)
+	}
+}
+
+func TestBootstrapInterval(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5, 6, 7, 8}
+	y := []float64{1.2, 1.9, 3.4, 3.8, 5.1, 6.3, 6.8, 8.1}
+	interval := BootstrapInterval(x, y, PearsonCorrelation, 500, 0.95, rand.New(rand.NewSource(1)))
+	if !(interval.Lower <= interval.Estimate && interval.Estimate <= interval.Upper) || interval.Upper > 1 {
+		t.Errorf("Expected the estimate inside its interval, got %+v", interval)
+	}
+	slope, intercept := LinearRegression(x, []float64{3, 5, 7, 9, 11, 13, 15, 17})
+	if math.Abs(slope-2) > 1e-9 || math.Abs(intercept-1) > 1e-9 {
+		t.Errorf("Expected y = 2x + 1, got y = %fx + %f", slope, intercept)
+	}
+}
+
+func TestBoltzmannAverage(t *testing.T) {
+	energies := []float64{-10, -10 + math.Log(3)}
+	// weights 3:1 at temperature 1
+	if average := BoltzmannAverage(energies, 1); math.Abs(average-(-10+math.Log(3)/4)) > 1e-12 {
+		t.Errorf("Expected the weighted mean %g, got %g", -10+math.Log(3)/4, average)
+	}
+	if BoltzmannAverage([]float64{-1e9, 5}, 310) != -1e9 || BoltzmannAverage(energies, 0) != -10 {
+		t.Errorf("Expected the lowest energy to dominate at low temperature")
+	}
+	if !math.IsNaN(BoltzmannAverage(nil, 1)) {
+		t.Errorf("Expected NaN without energies")
+	}
+}
@@ -0,0 +1,491 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+)
+
+// BENCHMARKTHRESHOLDS are the RMSD cut-offs (Å) at which redocking success rates are reported
+var BENCHMARKTHRESHOLDS = []float64{1, 2, 3}
+
+// BenchmarkEntry is one complex of a benchmark manifest
+type BenchmarkEntry struct {
+	ID      string
+	Protein string
+	Ligand  string
+}
+
+// BenchmarkSettings records how a benchmark was run
+type BenchmarkSettings struct {
+	Manifest string    `json:"manifest"`
+	DataDir  string    `json:"data_dir"`
+	Runs     int       `json:"runs"`
+	RMSDMode string    `json:"rmsd_mode"`
+	Config   RunConfig `json:"config"` // iterations, seed, moves, temperature schedule and energy model of every run
+}
+
+// BenchmarkResult is the redocking outcome of one complex
+type BenchmarkResult struct {
+	ID       string  `json:"id"`
+	Status   string  `json:"status"`
+	Error    string  `json:"error,omitempty"`
+	Atoms    int     `json:"atoms"`
+	RMSD     float64 `json:"rmsd"`
+	BestRMSD float64 `json:"best_rmsd"`
+	Energy   float64 `json:"energy"`
+	Seconds  float64 `json:"seconds"`
+}
+
+// BenchmarkSummary aggregates the results of a benchmark run
+type BenchmarkSummary struct {
+	Complexes    int                `json:"complexes"`
+	Completed    int                `json:"completed"`
+	Failed       int                `json:"failed"`
+	SuccessRates map[string]float64 `json:"success_rates"`
+	MedianRMSD   float64            `json:"median_rmsd"`
+	MeanRMSD     float64            `json:"mean_rmsd"`
+	TotalSeconds float64            `json:"total_seconds"`
+}
+
+// BenchmarkReport is the JSON document written by a benchmark run and read back for regression checks
+type BenchmarkReport struct {
+	Date        string            `json:"date"`
+	Settings    BenchmarkSettings `json:"settings"`
+	Summary     BenchmarkSummary  `json:"summary"`
+	Results     []BenchmarkResult `json:"results"`
+	Regressions []string          `json:"regressions,omitempty"`
+}
+
+// ReadBenchmarkManifest reads the complexes of a benchmark. The manifest is either a CSV file with a PDB_ID
+// column (such as extended_PLAS20K.csv) and optional protein and ligand columns, or a plain list of PDB ids
+// separated by commas or newlines (such as PLAS20K_pdb_ids.txt). Missing paths are resolved in dataDir as
+// <id>_protein and <id>_ligand with a .mol2, .pqr or .pdb extension.
+// Input: a string manifest path, a string dataDir
+// Output: a slice of BenchmarkEntry and an error or nil
+func ReadBenchmarkManifest(manifest, dataDir string) ([]BenchmarkEntry, error) {
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return nil, err
+	}
+	var entries []BenchmarkEntry
+	firstLine := strings.ToLower(strings.SplitN(string(data), "\n", 2)[0])
+	if strings.Contains(firstLine, "pdb_id") || strings.Contains(firstLine, "protein") {
+		records, err := csv.NewReader(strings.NewReader(string(data))).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("reading manifest %s: %w", manifest, err)
+		}
+		columns := make(map[string]int)
+		for i, name := range records[0] {
+			columns[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		idColumn, ok := columns["pdb_id"]
+		if !ok {
+			idColumn, ok = columns["id"]
+		}
+		if !ok {
+			return nil, fmt.Errorf("manifest %s has no PDB_ID column", manifest)
+		}
+		for _, record := range records[1:] {
+			entry := BenchmarkEntry{ID: strings.TrimSpace(record[idColumn])}
+			if i, ok := columns["protein"]; ok && i < len(record) {
+				entry.Protein = strings.TrimSpace(record[i])
+			}
+			if i, ok := columns["ligand"]; ok && i < len(record) {
+				entry.Ligand = strings.TrimSpace(record[i])
+			}
+			entries = append(entries, entry)
+		}
+	} else {
+		for _, id := range strings.FieldsFunc(string(data), func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+			if id = strings.TrimSpace(id); id != "" {
+				entries = append(entries, BenchmarkEntry{ID: id})
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	unique := entries[:0]
+	for _, entry := range entries {
+		if entry.ID == "" || seen[entry.ID] {
+			continue
+		}
+		seen[entry.ID] = true
+		entry.Protein = resolveBenchmarkFile(entry.Protein, dataDir, entry.ID, "protein")
+		entry.Ligand = resolveBenchmarkFile(entry.Ligand, dataDir, entry.ID, "ligand")
+		unique = append(unique, entry)
+	}
+	return unique, nil
+}
+
+// resolveBenchmarkFile returns the path of a complex component, looking for <id>_<part>.mol2/.pqr/.pdb in
+// dataDir when the manifest does not give one. Relative manifest paths are taken relative to dataDir.
+// Input: a string path from the manifest, a string dataDir, a string id, a string part ("protein" or "ligand")
+// Output: a string path, which may not exist
+func resolveBenchmarkFile(path, dataDir, id, part string) string {
+	if path != "" {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dataDir, path)
+	}
+	for _, ext := range []string{".mol2", ".pqr", ".pdb"} {
+		candidate := filepath.Join(dataDir, id+"_"+part+ext)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return filepath.Join(dataDir, id+"_"+part+".mol2")
+}
+
+// RunBenchmark redocks the native ligand of every complex from randomized starting poses. Each complex is
+// docked settings.Runs times; the lowest-energy pose gives the reported RMSD and the best RMSD over all runs
+// is kept alongside it. Run k of complex i starts from the pose and walker sources of ligand index i*Runs+k, so a
+// seeded benchmark is reproducible. Complexes whose files cannot be read are recorded as failed rather than aborting
+// the run.
+// Input: a slice of BenchmarkEntry, a BenchmarkSettings
+// Output: a BenchmarkReport
+func RunBenchmark(entries []BenchmarkEntry, settings BenchmarkSettings) BenchmarkReport {
+	mode, err := analysis.ParseRMSDMode(settings.RMSDMode)
+	Check(err)
+	report := BenchmarkReport{Date: time.Now().Format(time.RFC3339), Settings: settings}
+	start := time.Now()
+	for i, entry := range entries {
+		result := redockComplex(entry, i*settings.Runs, settings.Runs, settings.Config, mode)
+		if result.Status == "ok" {
+			fmt.Printf("[%d/%d] %s: RMSD %.2f Å (best %.2f Å) in %.1fs\n", i+1, len(entries), entry.ID, result.RMSD, result.BestRMSD, result.Seconds)
+		} else {
+			fmt.Printf("[%d/%d] %s: failed: %s\n", i+1, len(entries), entry.ID, result.Error)
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Summary = SummarizeBenchmark(report.Results)
+	report.Summary.TotalSeconds = time.Since(start).Seconds()
+	return report
+}
+
+// redockComplex runs the redocking of a single complex.
+// Input: a BenchmarkEntry, an int first ligand index of its runs, an int runs, a RunConfig config whose
+// DockingSimulation redocks the complex, an RMSDMode
+// Output: a BenchmarkResult
+func redockComplex(entry BenchmarkEntry, first, runs int, config RunConfig, mode analysis.RMSDMode) (result BenchmarkResult) {
+	result = BenchmarkResult{ID: entry.ID, Status: "failed"}
+	start := time.Now()
+	defer func() {
+		result.Seconds = time.Since(start).Seconds()
+	}()
+	protein, err := molio.LoadReceptor(entry.Protein)
+	if err != nil && !molio.IsWarning(err) {
+		result.Error = err.Error()
+		return result
+	}
+	reference, err := molio.LoadLigand(entry.Ligand)
+	if err != nil && !molio.IsWarning(err) {
+		result.Error = err.Error()
+		return result
+	}
+	if len(protein.Atoms) == 0 || len(reference.Atoms) == 0 {
+		result.Error = "no atoms read"
+		return result
+	}
+	result.Atoms = len(reference.Atoms)
+	sim, err := config.DockingSimulation(protein, []molecule.Molecule{reference})
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	bestEnergy := math.Inf(1)
+	result.BestRMSD = math.Inf(1)
+	for run := 0; run < runs; run++ {
+		start := RandomizeLigandPose(molecule.CopyLigand(reference), sim.StartSource(first+run))
+		docked, receptor, _, err := sampling.SimulateFlexibleLigand(context.Background(), protein, start, first+run, sim, false)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		dockedEnergy := sim.Energy.Energy(receptor, docked)
+		rmsd := analysis.CalculateRMSDMode(docked, reference, mode)
+		if dockedEnergy < bestEnergy {
+			bestEnergy, result.Energy, result.RMSD = dockedEnergy, dockedEnergy, rmsd
+		}
+		result.BestRMSD = math.Min(result.BestRMSD, rmsd)
+	}
+	result.Status = "ok"
+	return result
+}
+
+// SummarizeBenchmark computes success rates at BENCHMARKTHRESHOLDS and the median and mean RMSD of the
+// completed complexes.
+// Input: a slice of BenchmarkResult
+// Output: a BenchmarkSummary
+func SummarizeBenchmark(results []BenchmarkResult) BenchmarkSummary {
+	summary := BenchmarkSummary{Complexes: len(results), SuccessRates: make(map[string]float64)}
+	var rmsds []float64
+	for _, result := range results {
+		if result.Status == "ok" {
+			rmsds = append(rmsds, result.RMSD)
+		} else {
+			summary.Failed++
+		}
+		summary.TotalSeconds += result.Seconds
+	}
+	summary.Completed = len(rmsds)
+	for _, threshold := range BENCHMARKTHRESHOLDS {
+		successes := 0
+		for _, rmsd := range rmsds {
+			if rmsd <= threshold {
+				successes++
+			}
+		}
+		rate := 0.0
+		if len(rmsds) > 0 {
+			rate = float64(successes) / float64(len(rmsds))
+		}
+		summary.SuccessRates[thresholdKey(threshold)] = rate
+	}
+	summary.MedianRMSD = median(rmsds)
+	summary.MeanRMSD = analysis.Mean(rmsds)
+	return summary
+}
+
+// thresholdKey formats an RMSD threshold as a map key such as "2A".
+// Input: a float64 threshold
+// Output: a string
+func thresholdKey(threshold float64) string {
+	return strconv.FormatFloat(threshold, 'f', -1, 64) + "A"
+}
+
+// median returns the median of a slice of float64 values without modifying it.
+// Input: a slice of float64 values
+// Output: a float64 median value (0 for an empty slice)
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// CompareBenchmarks flags regressions of a benchmark run against a previous one: a drop of more than
+// rateTolerance in any success rate, complexes that no longer complete, complexes that fall out of the
+// 2 Å success window, and complexes whose RMSD grew by more than rmsdTolerance.
+// Input: the current and previous BenchmarkReport, a float64 rmsdTolerance (Å), a float64 rateTolerance (fraction)
+// Output: a slice of strings describing each regression
+func CompareBenchmarks(current, previous BenchmarkReport, rmsdTolerance, rateTolerance float64) []string {
+	var regressions []string
+	for _, threshold := range BENCHMARKTHRESHOLDS {
+		key := thresholdKey(threshold)
+		before, ok := previous.Summary.SuccessRates[key]
+		if after := current.Summary.SuccessRates[key]; ok && before-after > rateTolerance {
+			regressions = append(regressions, fmt.Sprintf("success rate at %s dropped from %.1f%% to %.1f%%", key, 100*before, 100*after))
+		}
+	}
+	previousResults := make(map[string]BenchmarkResult)
+	for _, result := range previous.Results {
+		previousResults[result.ID] = result
+	}
+	for _, result := range current.Results {
+		before, ok := previousResults[result.ID]
+		if !ok || before.Status != "ok" {
+			continue
+		}
+		switch {
+		case result.Status != "ok":
+			regressions = append(regressions, fmt.Sprintf("%s: previously completed, now failed (%s)", result.ID, result.Error))
+		case before.RMSD <= 2 && result.RMSD > 2:
+			regressions = append(regressions, fmt.Sprintf("%s: no longer docked within 2 Å (%.2f -> %.2f Å)", result.ID, before.RMSD, result.RMSD))
+		case result.RMSD-before.RMSD > rmsdTolerance:
+			regressions = append(regressions, fmt.Sprintf("%s: RMSD grew from %.2f to %.2f Å", result.ID, before.RMSD, result.RMSD))
+		}
+	}
+	return regressions
+}
+
+// ReadBenchmarkReport loads a benchmark report written by WriteBenchmarkJSON.
+// Input: a string filename
+// Output: a BenchmarkReport and an error or nil
+func ReadBenchmarkReport(filename string) (BenchmarkReport, error) {
+	var report BenchmarkReport
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return report, err
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return report, fmt.Errorf("reading benchmark report %s: %w", filename, err)
+	}
+	return report, nil
+}
+
+// WriteBenchmarkJSON writes the full benchmark report as indented JSON.
+// Input: an io.Writer w, a BenchmarkReport
+// Output: an error or nil
+func WriteBenchmarkJSON(w io.Writer, report BenchmarkReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// WriteBenchmarkCSV writes one row per complex with its status, RMSD, energy and timing.
+// Input: an io.Writer w, a slice of BenchmarkResult
+// Output: an error or nil
+func WriteBenchmarkCSV(w io.Writer, results []BenchmarkResult) error {
+	writer := csv.NewWriter(w)
+	header := []string{"pdb_id", "status", "atoms", "rmsd", "best_rmsd", "energy", "seconds"}
+	for _, threshold := range BENCHMARKTHRESHOLDS {
+		header = append(header, "success_"+thresholdKey(threshold))
+	}
+	header = append(header, "error")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, result := range results {
+		row := []string{result.ID, result.Status, strconv.Itoa(result.Atoms)}
+		if result.Status == "ok" {
+			row = append(row, fmt.Sprintf("%.4f", result.RMSD), fmt.Sprintf("%.4f", result.BestRMSD), fmt.Sprintf("%.6g", result.Energy))
+		} else {
+			row = append(row, "", "", "")
+		}
+		row = append(row, fmt.Sprintf("%.3f", result.Seconds))
+		for _, threshold := range BENCHMARKTHRESHOLDS {
+			row = append(row, strconv.FormatBool(result.Status == "ok" && result.RMSD <= threshold))
+		}
+		row = append(row, result.Error)
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// PrintBenchmarkSummary prints the success rates, RMSD statistics and timing of a benchmark run.
+// Input: a BenchmarkSummary
+// Output: none (prints to standard output)
+func PrintBenchmarkSummary(summary BenchmarkSummary) {
+	fmt.Printf("Complexes: %d (completed %d, failed %d)\n", summary.Complexes, summary.Completed, summary.Failed)
+	for _, threshold := range BENCHMARKTHRESHOLDS {
+		fmt.Printf("Success rate at %.0f Å: %.1f%%\n", threshold, 100*summary.SuccessRates[thresholdKey(threshold)])
+	}
+	fmt.Printf("Median RMSD: %.2f Å, mean RMSD: %.2f Å\n", summary.MedianRMSD, summary.MeanRMSD)
+	fmt.Printf("Total time: %.1fs\n", summary.TotalSeconds)
+}
+
+// BenchmarkMain is the entry point of the "benchmark" command. It redocks every complex of the manifest and
+// writes benchmark.csv and benchmark.json into the output directory, comparing against a previous
+// benchmark.json when -compare is given.
+// Usage: benchmark [flags] manifest dataDir outputDir
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the reports; exits with status 1 when regressions are found)
+func BenchmarkMain(args []string) {
+	flags := commandFlags("benchmark", "manifest dataDir outputDir",
+		"Redocks every complex of the manifest from randomized starting poses and writes benchmark.csv and\n"+
+			"benchmark.json to the output directory. With -compare, exits with status 1 when a complex or the success\n"+
+			"rate regresses against a previous benchmark.json. The resolved run-config.json is written with the results.")
+	defaults := DefaultRunConfig()
+	defaults.Iterations = 1000
+	configFile := flags.String("config", "", "run configuration (.json or .toml); flags override its values")
+	defaults.AddSimulationFlags(flags)
+	runs := flags.Int("runs", 3, "randomized redocking runs per complex")
+	mode := flags.String("rmsd", analysis.RMSDSymmetry.String(), "RMSD mode: inplace, kabsch, symmetry or symmetry-kabsch")
+	limit := flags.Int("limit", 0, "only run the first n complexes (0 runs all)")
+	compare := flags.String("compare", "", "previous benchmark.json to check for regressions")
+	rmsdTolerance := flags.Float64("rmsd-tolerance", 1.0, "per-complex RMSD increase (Å) flagged as a regression")
+	rateTolerance := flags.Float64("rate-tolerance", 0.05, "success-rate drop (fraction) flagged as a regression")
+	flags.Parse(args)
+	if flags.NArg() != 3 {
+		flags.Usage()
+		return
+	}
+	if _, err := analysis.ParseRMSDMode(*mode); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if *runs < 1 {
+		fmt.Println("runs must be positive")
+		return
+	}
+	config, err := ResolveRunConfig(flags, *configFile, defaults)
+	exitOnConfigError(err)
+
+	manifest, dataDir, outputDir := flags.Arg(0), flags.Arg(1), flags.Arg(2)
+	config.Outputs.Dir = outputDir
+	provenance := NewProvenance("benchmark")
+	provenance.SetConfig(config)
+	provenance.Parameter("runs", strconv.Itoa(*runs))
+	provenance.Parameter("rmsd", *mode)
+	provenance.Parameter("limit", strconv.Itoa(*limit))
+	provenance.Stage("load inputs")
+	Check(provenance.Input("manifest", manifest))
+	entries, err := ReadBenchmarkManifest(manifest, dataDir)
+	Check(err)
+	if *limit > 0 && *limit < len(entries) {
+		entries = entries[:*limit]
+	}
+	settings := BenchmarkSettings{
+		Manifest: manifest,
+		DataDir:  dataDir,
+		Runs:     *runs,
+		RMSDMode: *mode,
+		Config:   config,
+	}
+	provenance.Stage("redocking")
+	report := RunBenchmark(entries, settings)
+	for i, result := range report.Results {
+		if result.Status == "ok" {
+			Check(provenance.Input("protein", entries[i].Protein))
+			Check(provenance.Input("ligand", entries[i].Ligand))
+		}
+	}
+	PrintBenchmarkSummary(report.Summary)
+
+	if *compare != "" {
+		previous, err := ReadBenchmarkReport(*compare)
+		Check(err)
+		Check(provenance.Input("previous", *compare))
+		report.Regressions = CompareBenchmarks(report, previous, *rmsdTolerance, *rateTolerance)
+		if len(report.Regressions) == 0 {
+			fmt.Println("No regressions against", *compare)
+		}
+		for _, regression := range report.Regressions {
+			fmt.Println("REGRESSION:", regression)
+		}
+	}
+
+	provenance.Stage("write results")
+	Check(os.MkdirAll(outputDir, 0755))
+	Check(SaveRunConfig(filepath.Join(outputDir, runConfigName(*configFile)), config))
+	csvFile, err := os.Create(filepath.Join(outputDir, "benchmark.csv"))
+	Check(err)
+	Check(WriteBenchmarkCSV(csvFile, report.Results))
+	csvFile.Close()
+	jsonFile, err := os.Create(filepath.Join(outputDir, "benchmark.json"))
+	Check(err)
+	Check(WriteBenchmarkJSON(jsonFile, report))
+	jsonFile.Close()
+	Check(provenance.Save(outputDir))
+	fmt.Println("Benchmark results written to", outputDir)
+
+	if len(report.Regressions) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSummarizeBenchmark(t *testing.T) {
+	results := []BenchmarkResult{
+		{ID: "a", Status: "ok", RMSD: 0.5},
+		{ID: "b", Status: "ok", RMSD: 1.5},
+		{ID: "c", Status: "ok", RMSD: 2.5},
+		{ID: "d", Status: "ok", RMSD: 4.0},
+		{ID: "e", Status: "failed"},
+	}
+	summary := SummarizeBenchmark(results)
+	if summary.Completed != 4 || summary.Failed != 1 {
+		t.Errorf("Expected 4 completed and 1 failed, got %d and %d", summary.Completed, summary.Failed)
+	}
+	if summary.SuccessRates["1A"] != 0.25 || summary.SuccessRates["2A"] != 0.5 || summary.SuccessRates["3A"] != 0.75 {
+		t.Errorf("Unexpected success rates %v", summary.SuccessRates)
+	}
+	if summary.MedianRMSD != 2.0 {
+		t.Errorf("Expected median RMSD 2.0, got %f", summary.MedianRMSD)
+	}
+
+	previous := BenchmarkReport{Results: []BenchmarkResult{{ID: "b", Status: "ok", RMSD: 1.5}, {ID: "c", Status: "ok", RMSD: 1.8}}}
+	previous.Summary = SummarizeBenchmark(previous.Results)
+	current := BenchmarkReport{Results: results, Summary: summary}
+	regressions := CompareBenchmarks(current, previous, 1.0, 0.05)
+	// c left the 2 Å window and the 2 Å and 3 Å success rates dropped from 100%
+	if len(regressions) != 3 {
+		t.Errorf("Expected 3 regressions, got %v", regressions)
+	}
+}
+
+func TestReadBenchmarkManifest(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "ids.txt")
+	if err := os.WriteFile(manifest, []byte("1abc,2xyz\n1abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "2xyz_protein.pdb"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadBenchmarkManifest(manifest, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[1].Protein != filepath.Join(dir, "2xyz_protein.pdb") || entries[0].Ligand != filepath.Join(dir, "1abc_ligand.mol2") {
+		t.Errorf("Unexpected manifest entries %v", entries)
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+)
+
+// BOXCOLUMNS are the optional manifest columns of a search box; a row gives all of them or none
+var BOXCOLUMNS = []string{"center_x", "center_y", "center_z", "size_x", "size_y", "size_z"}
+
+// CampaignEntry is one receptor/ligand pair of a screening campaign
+type CampaignEntry struct {
+	LigandID     string
+	Receptor     string        // as written in the manifest
+	Ligand       string        // as written in the manifest
+	ReceptorPath string        // resolved against the folder of the manifest
+	LigandPath   string        // resolved against the folder of the manifest
+	Box          *sampling.Box // nil to search everywhere
+}
+
+// CampaignResult is the docking result of one pair of a campaign
+type CampaignResult struct {
+	Rank        int
+	Entry       CampaignEntry
+	Energy      float64
+	HeavyAtoms  int
+	Efficiency  float64      // energy per heavy atom
+	Diagnostics ReportLigand // RMSD from the input pose, acceptance rate, displacement, pocket distance and interactions
+	Pose        string       // MOL2 file of the final pose, relative to the output folder
+	Notes       string
+}
+
+// ReadCampaignManifest reads a CSV manifest with a header row and one receptor/ligand pair per row. The receptor
+// and ligand columns are required; ligand_id defaults to the label of the ligand file, and center_x, center_y,
+// center_z, size_x, size_y and size_z define an optional search box in Å. Relative paths are resolved against the
+// folder of the manifest. Every problem is reported with its line number.
+// Input: a string fileName
+// Output: a slice of CampaignEntry and an error or nil
+func ReadCampaignManifest(fileName string) ([]CampaignEntry, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", fileName, err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("%s has no pairs", fileName)
+	}
+	columns := make(map[string]int)
+	for i, header := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(header))] = i
+	}
+	for _, required := range []string{"receptor", "ligand"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%s has no %s column", fileName, required)
+		}
+	}
+	cell := func(record []string, name string) string {
+		if column, ok := columns[name]; ok && column < len(record) {
+			return strings.TrimSpace(record[column])
+		}
+		return ""
+	}
+	resolve := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(filepath.Dir(fileName), path)
+	}
+
+	var entries []CampaignEntry
+	var problems []string
+	seen := make(map[[2]string]int)
+	type receptorName struct {
+		path, receptor string
+		line           int
+	}
+	stems := make(map[string]receptorName)
+	for i, record := range records[1:] {
+		line := i + 2
+		entry := CampaignEntry{LigandID: cell(record, "ligand_id"), Receptor: cell(record, "receptor"), Ligand: cell(record, "ligand")}
+		if entry.Receptor == "" || entry.Ligand == "" {
+			problems = append(problems, fmt.Sprintf("line %d: receptor and ligand must not be empty", line))
+			continue
+		}
+		entry.ReceptorPath, entry.LigandPath = resolve(entry.Receptor), resolve(entry.Ligand)
+		if entry.LigandID == "" {
+			entry.LigandID = molio.ExtractFileLabel(entry.Ligand)
+		}
+		for _, path := range []string{entry.ReceptorPath, entry.LigandPath} {
+			if _, err := os.Stat(path); err != nil {
+				problems = append(problems, fmt.Sprintf("line %d: %v", line, err))
+			}
+		}
+		box, err := parseCampaignBox(record, cell)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", line, err))
+		}
+		entry.Box = box
+		// Poses are written to poses/<receptor stem>/, so two receptor files may not share a stem
+		stem := fileStem(entry.ReceptorPath)
+		if other, ok := stems[stem]; ok && other.path != entry.ReceptorPath {
+			problems = append(problems, fmt.Sprintf("line %d: receptor %s has the same name as %s on line %d, and their poses would share poses/%s",
+				line, entry.Receptor, other.receptor, other.line, stem))
+		} else if !ok {
+			stems[stem] = receptorName{path: entry.ReceptorPath, receptor: entry.Receptor, line: line}
+		}
+		key := [2]string{entry.LigandID, entry.ReceptorPath}
+		if first, ok := seen[key]; ok {
+			problems = append(problems, fmt.Sprintf("line %d: ligand %s against %s is already on line %d", line, entry.LigandID, entry.Receptor, first))
+		}
+		seen[key] = line
+		entries = append(entries, entry)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s:\n  %s", fileName, strings.Join(problems, "\n  "))
+	}
+	return entries, nil
+}
+
+// parseCampaignBox reads the search box of a manifest row.
+// Input: a slice of strings record, a function cell returning the value of a column of the record
+// Output: a *Box (nil when the row has no box) and an error or nil
+func parseCampaignBox(record []string, cell func([]string, string) string) (*sampling.Box, error) {
+	values := make([]float64, len(BOXCOLUMNS))
+	given := 0
+	for i, column := range BOXCOLUMNS {
+		if cell(record, column) == "" {
+			continue
+		}
+		given++
+		value, err := strconv.ParseFloat(cell(record, column), 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, fmt.Errorf("%s must be a number, got %q", column, cell(record, column))
+		}
+		values[i] = value
+	}
+	switch {
+	case given == 0:
+		return nil, nil
+	case given < len(BOXCOLUMNS):
+		return nil, fmt.Errorf("a box needs all of %s", strings.Join(BOXCOLUMNS, ", "))
+	case values[3] <= 0 || values[4] <= 0 || values[5] <= 0:
+		return nil, fmt.Errorf("box sizes must be positive")
+	}
+	return &sampling.Box{
+		Center: molecule.Position3d{X: values[0], Y: values[1], Z: values[2]},
+		Size:   molecule.Position3d{X: values[3], Y: values[4], Z: values[5]},
+	}, nil
+}
+
+// heavyAtomCount counts the atoms of a molecule that are not hydrogens.
+// Input: a Molecule
+// Output: an int
+func heavyAtomCount(m molecule.Molecule) int {
+	count := 0
+	for _, atom := range m.Atoms {
+		if atom.Element != "H" {
+			count++
+		}
+	}
+	return count
+}
+
+// fileStem returns the base name of a file without its extension, used to name output folders.
+// Input: a string path
+// Output: a string
+func fileStem(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// RunCampaign docks every pair of a campaign, loading each receptor once, and writes each final pose to
+// poses/<receptor>/<ligand id>.mol2 in the output folder. Pairs with a box search inside it only.
+// Input: a context.Context ctx, a slice of CampaignEntry, a RunConfig config, a string outputDir, a *Provenance
+// that records the structures used (may be nil)
+// Output: the CampaignResults in manifest order, and ctx.Err() when the run was cancelled
+func RunCampaign(ctx context.Context, entries []CampaignEntry, config RunConfig, outputDir string, provenance *Provenance) ([]CampaignResult, error) {
+	receptors := make(map[string]molecule.Molecule)
+	var results []CampaignResult
+	for i, entry := range entries {
+		receptor, ok := receptors[entry.ReceptorPath]
+		if !ok {
+			var err error
+			receptor, err = molio.LoadReceptor(entry.ReceptorPath)
+			warnOrCheck(err)
+			receptors[entry.ReceptorPath] = receptor
+			if provenance != nil {
+				Check(provenance.Input("receptor", entry.ReceptorPath))
+			}
+		}
+		ligand, err := molio.ParseMol2(entry.LigandPath)
+		warnOrCheck(err)
+		if provenance != nil {
+			Check(provenance.Input("ligand", entry.LigandPath))
+		}
+		sim, err := config.DockingSimulation(receptor, []molecule.Molecule{ligand})
+		if err != nil {
+			return nil, fmt.Errorf("%s against %s: %w", entry.LigandID, entry.Receptor, err)
+		}
+		sim.Box = entry.Box
+		result := CampaignResult{Entry: entry, HeavyAtoms: heavyAtomCount(ligand)}
+		if entry.Box != nil {
+			if _, moved := entry.Box.PlaceInBox(ligand); moved {
+				result.Notes = "ligand started outside the box and was moved to its centre"
+			}
+		}
+		pose, docked, traces, err := sampling.SimulateFlexibleLigand(ctx, receptor, ligand, i, sim, true)
+		if err != nil {
+			return nil, err
+		}
+		result.Energy = sim.Energy.Energy(docked, pose)
+		result.Efficiency = result.Energy / float64(max(result.HeavyAtoms, 1))
+		result.Diagnostics = NewReportLigand(entry.LigandID, docked, pose, result.Energy, ligand, traces)
+		result.Diagnostics.Pose = nil
+		result.Pose = filepath.ToSlash(filepath.Join("poses", fileStem(entry.Receptor), entry.LigandID+".mol2"))
+		poseFile := filepath.Join(outputDir, filepath.FromSlash(result.Pose))
+		Check(os.MkdirAll(filepath.Dir(poseFile), 0755))
+		Check(UpdateMol2Coordinates(entry.LigandPath, poseFile, pose))
+		fmt.Printf("%s against %s: %.4g\n", entry.LigandID, entry.Receptor, result.Energy)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// RankCampaignResults sorts results from the lowest (best) energy up, with non-finite energies last, and numbers
+// their ranks from 1.
+// Input: a slice of CampaignResult (sorted in place)
+// Output: none
+func RankCampaignResults(results []CampaignResult) {
+	slices.SortStableFunc(results, func(a, b CampaignResult) int {
+		aFinite, bFinite := !math.IsNaN(a.Energy) && !math.IsInf(a.Energy, 0), !math.IsNaN(b.Energy) && !math.IsInf(b.Energy, 0)
+		switch {
+		case aFinite != bFinite:
+			if aFinite {
+				return -1
+			}
+			return 1
+		case a.Energy < b.Energy:
+			return -1
+		case a.Energy > b.Energy:
+			return 1
+		}
+		return 0
+	})
+	for i := range results {
+		results[i].Rank = i + 1
+	}
+}
+
+// WriteCampaignTable writes the ranked results of a campaign as CSV.
+// Input: a string fileName, a slice of ranked CampaignResult
+// Output: an error or nil
+func WriteCampaignTable(fileName string, results []CampaignResult) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"rank", "ligand_id", "receptor", "energy", "heavy_atoms", "ligand_efficiency", "rmsd_from_input",
+		"acceptance_rate", "displacement", "pocket_distance", "interactions", "pose", "notes"})
+	format := func(value float64) string {
+		if math.IsNaN(value) {
+			return ""
+		}
+		return strconv.FormatFloat(value, 'g', 6, 64)
+	}
+	for _, result := range results {
+		writer.Write([]string{
+			strconv.Itoa(result.Rank), result.Entry.LigandID, result.Entry.Receptor, format(result.Energy),
+			strconv.Itoa(result.HeavyAtoms), format(result.Efficiency), format(result.Diagnostics.RMSD),
+			format(result.Diagnostics.AcceptanceRate), format(result.Diagnostics.Displacement),
+			format(result.Diagnostics.PocketDistance), strconv.Itoa(result.Diagnostics.Interactions), result.Pose, result.Notes,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// SaveTopPoses writes the poses of the best ranked results into one multi-molecule MOL2 file. Each molecule is
+// named "<ligand id> <receptor> rank <rank> energy <energy>".
+// Input: a string fileName, a slice of ranked CampaignResult, a string outputDir holding the poses, an int top (0 for all)
+// Output: an error or nil
+func SaveTopPoses(fileName string, results []CampaignResult, outputDir string, top int) error {
+	if top <= 0 || top > len(results) {
+		top = len(results)
+	}
+	var poses strings.Builder
+	for _, result := range results[:top] {
+		data, err := os.ReadFile(filepath.Join(outputDir, filepath.FromSlash(result.Pose)))
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+		for i := range lines {
+			if strings.HasPrefix(lines[i], "@<TRIPOS>MOLECULE") && i+1 < len(lines) {
+				lines[i+1] = fmt.Sprintf("%s %s rank %d energy %.4f", result.Entry.LigandID, result.Entry.Receptor, result.Rank, result.Energy)
+				break
+			}
+		}
+		poses.WriteString(strings.Join(lines, "\n") + "\n")
+	}
+	return os.WriteFile(fileName, []byte(poses.String()), 0644)
+}
+
+// CampaignMain is the entry point of the "campaign" command, which docks the receptor/ligand pairs of a CSV
+// manifest and ranks them.
+// Usage: campaign [flags] manifest.csv
+// Input: a slice of strings args (without the command name)
+// Output: none (writes to <output>/<manifest name>/)
+func CampaignMain(args []string) {
+	defaults := DefaultRunConfig()
+	defaults.Inputs = InputConfig{}
+	flags := commandFlags("campaign", "manifest.csv",
+		"Docks every receptor/ligand pair of a CSV manifest and writes to <output>/<manifest name>/:\n"+
+			"results.csv ranked by energy (ligand id, receptor, energy, ligand efficiency per heavy atom, diagnostics and\n"+
+			"pose path), the pose of every pair in poses/<receptor>/, the top poses in top_poses.mol2, a ranked energy plot,\n"+
+			"the resolved run configuration and the provenance manifest. The manifest has receptor and ligand columns\n"+
+			"(paths relative to the manifest) and optional ligand_id and center_x, center_y, center_z, size_x, size_y, size_z\n"+
+			"columns that confine the ligand centroid of that pair to a search box in Å.")
+	configFile := flags.String("config", "", "run configuration (.json or .toml); flags override its values")
+	top := flags.Int("top", 10, "number of best poses in top_poses.mol2 (0 for all)")
+	defaults.AddSimulationFlags(flags)
+	defaults.AddOutputFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return
+	}
+	config, err := ResolveRunConfig(flags, *configFile, defaults)
+	exitOnConfigError(err)
+	provenance := NewProvenance("campaign")
+	provenance.SetConfig(config)
+	provenance.Parameter("top", strconv.Itoa(*top))
+	provenance.Stage("load inputs")
+	entries, err := ReadCampaignManifest(flags.Arg(0))
+	exitOnConfigError(err)
+	Check(provenance.Input("campaign", flags.Arg(0)))
+	outputDir := filepath.Join(config.Outputs.Dir, fileStem(flags.Arg(0)))
+	Check(os.MkdirAll(outputDir, 0755))
+	Check(SaveRunConfig(filepath.Join(outputDir, runConfigName(*configFile)), config))
+
+	provenance.Stage("docking")
+	results, err := RunCampaign(context.Background(), entries, config, outputDir, provenance)
+	exitOnConfigError(err)
+	provenance.Stage("results")
+	RankCampaignResults(results)
+	Check(WriteCampaignTable(filepath.Join(outputDir, "results.csv"), results))
+	Check(SaveTopPoses(filepath.Join(outputDir, "top_poses.mol2"), results, outputDir, *top))
+	labels := make([]string, len(results))
+	energies := make([]float64, len(results))
+	for i, result := range results {
+		labels[i], energies[i] = result.Entry.LigandID+"@"+fileStem(result.Entry.Receptor), result.Energy
+	}
+	plotEnergy(labels, energies, filepath.Join(outputDir, "energies"), config.Outputs.Plot)
+	Check(provenance.Save(outputDir))
+	fmt.Printf("Ranked %d pairs in %s\n", len(results), filepath.Join(outputDir, "results.csv"))
+}
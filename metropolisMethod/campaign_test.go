@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadCampaignManifest(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"receptor.pdb", "a.mol2", "b.mol2"} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+	manifest := filepath.Join(dir, "pairs.csv")
+	os.WriteFile(manifest, []byte("Ligand_ID,receptor,ligand,center_x,center_y,center_z,size_x,size_y,size_z\n"+
+		",receptor.pdb,a.mol2,,,,,,\n"+
+		"second,receptor.pdb,b.mol2,1,2,3,10,10,10\n"), 0644)
+	entries, err := ReadCampaignManifest(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].LigandID != "a" || entries[0].Box != nil || entries[1].LigandID != "second" {
+		t.Fatalf("Unexpected entries %+v", entries)
+	}
+	if entries[1].Box == nil || entries[1].Box.Center.Z != 3 || entries[1].LigandPath != filepath.Join(dir, "b.mol2") {
+		t.Errorf("Expected a box and a path resolved against the manifest, got %+v", entries[1])
+	}
+
+	os.WriteFile(manifest, []byte("receptor,ligand,ligand_id,center_x\n"+
+		"receptor.pdb,a.mol2,x,1\n"+
+		"receptor.pdb,missing.mol2,x,\n"), 0644)
+	_, err = ReadCampaignManifest(manifest)
+	if err == nil {
+		t.Fatal("Expected errors for an incomplete box, a missing file and a duplicate pair")
+	}
+	for _, want := range []string{"line 2: a box needs", "line 3:", "already on line 2"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+
+	os.Mkdir(filepath.Join(dir, "other"), 0755)
+	os.WriteFile(filepath.Join(dir, "other", "receptor.pdb"), nil, 0644)
+	os.WriteFile(manifest, []byte("receptor,ligand\n"+
+		"receptor.pdb,a.mol2\n"+
+		"./receptor.pdb,b.mol2\n"+
+		"other/receptor.pdb,a.mol2\n"), 0644)
+	if _, err = ReadCampaignManifest(manifest); err == nil || !strings.Contains(err.Error(), "line 4: receptor other/receptor.pdb has the same name as receptor.pdb on line 2") {
+		t.Errorf("Expected two receptors sharing a pose folder to be reported, got %v", err)
+	}
+}
+
+func TestRankCampaignResults(t *testing.T) {
+	results := []CampaignResult{{Energy: math.NaN()}, {Energy: -2}, {Energy: 1}, {Energy: -5}}
+	RankCampaignResults(results)
+	for i, want := range []float64{-5, -2, 1} {
+		if results[i].Energy != want || results[i].Rank != i+1 {
+			t.Errorf("Expected rank %d to have energy %g, got %+v", i+1, want, results[i])
+		}
+	}
+	if !math.IsNaN(results[3].Energy) {
+		t.Errorf("Expected the failed pair last, got %+v", results[3])
+	}
+}
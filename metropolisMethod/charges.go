@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/prepare"
+)
+
+// AssignChargesMain is the entry point of the "charges" command, which rewrites MOL2 files with Gasteiger-Marsili charges.
+// Usage: charges input.mol2 output.mol2
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the output file)
+func AssignChargesMain(args []string) {
+	flags := commandFlags("charges", "input.mol2 output.mol2",
+		"Rewrites a MOL2 file with Gasteiger-Marsili charges, keeping every other record.")
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) != 2 {
+		flags.Usage()
+		return
+	}
+	provenance := NewProvenance("charges")
+	provenance.Stage("charges")
+	molecule, _, err := molio.ReadMol2(args[0])
+	Check(err)
+	Check(provenance.Input("ligand", args[0]))
+	prepare.AssignGasteigerCharges(&molecule)
+	total := 0.0
+	for _, atom := range molecule.Atoms {
+		total += atom.Charge
+	}
+	Check(prepare.UpdateMol2Charges(args[0], args[1], molecule, "GASTEIGER"))
+	Check(provenance.SaveFiles(args[1]))
+	fmt.Printf("Wrote Gasteiger charges for %d atoms (total charge %.4f) to %s\n", len(molecule.Atoms), total, args[1])
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// PROGRAMNAME is the name of the binary shown in the help pages
+const PROGRAMNAME = "metropolis"
+
+// Command is one subcommand of the metropolis tool
+type Command struct {
+	Name    string
+	Summary string
+	Run     func(args []string) // args without the command name; "-h" prints the command's help page
+}
+
+// Commands returns every subcommand in the order of the help page.
+// Input: none
+// Output: a slice of Command
+func Commands() []Command {
+	return []Command{
+		{"simulate", "dock ligand files against one protein and write the energies (used by the R Shiny app)", SimulateMain},
+		{"screen", "screen the ligands of a data directory against a protein with plots, traces and an HTML report", ScreenMain},
+		{"campaign", "dock the receptor/ligand pairs of a CSV manifest and rank them with ligand efficiency and diagnostics", CampaignMain},
+		{"crossdock", "dock every ligand against every receptor and score the selectivity of each ligand", CrossDockMain},
+		{"ensemble", "dock ligands into every snapshot of a receptor ensemble and aggregate their energies", EnsembleMain},
+		{"redock", "redock the complexes of a data directory from random poses and plot the RMSD", RedockMain},
+		{"config", "validate a JSON or TOML run configuration and print it fully resolved", ConfigMain},
+		{"verify", "check the input files of a run against the checksums of its manifest", VerifyMain},
+		{"serve", "run a local HTTP/JSON service for uploads, docking jobs, results and predictions", ServeMain},
+		{"rmsd", "compute the RMSD between two poses of a ligand", RMSDMain},
+		{"split", "split PDB complexes into protein and ligand files", SplitMain},
+		{"train", "train a random forest on a table of energy terms and save the model", TrainMain},
+		{"predict", "predict binding affinities with a trained random forest", PredictMain},
+		{"prepare", "protonate, complete and charge split complexes", PrepareMain},
+		{"charges", "assign Gasteiger-Marsili charges to a mol2 ligand", AssignChargesMain},
+		{"receptor", "assign AMBER ff14SB charges and types to a receptor", PrepareReceptorMain},
+		{"benchmark", "benchmark redocking success rates on PLAS20K", BenchmarkMain},
+		{"correlate", "correlate simulated energies with experimental affinities", CorrelateMain},
+		{"enrichment", "evaluate actives against decoys with ROC and enrichment factors", EnrichmentMain},
+		{"interactions", "analyse the interactions of docked poses", InteractionsMain},
+		{"decompose", "split the binding energy of a pose by residue, ligand atom and term", DecomposeMain},
+		{"landscape", "plot energy distributions and landscapes from trace files", LandscapeMain},
+	}
+}
+
+// FindCommand looks up a subcommand by name.
+// Input: a string name
+// Output: the Command and a bool reporting whether it exists
+func FindCommand(name string) (Command, bool) {
+	for _, command := range Commands() {
+		if command.Name == name {
+			return command, true
+		}
+	}
+	return Command{}, false
+}
+
+// PrintUsage prints the list of subcommands.
+// Input: none
+// Output: none (prints to standard output)
+func PrintUsage() {
+	fmt.Printf("Usage: %s <command> [flags] [arguments]\n\nCommands:\n", PROGRAMNAME)
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, command := range Commands() {
+		fmt.Fprintf(writer, "  %s\t%s\n", command.Name, command.Summary)
+	}
+	fmt.Fprintf(writer, "  help\tshow this list or the help page of a command\n")
+	writer.Flush()
+	fmt.Printf("\nRun \"%s help <command>\" or \"%s <command> -h\" for the flags and arguments of a command.\n", PROGRAMNAME, PROGRAMNAME)
+}
+
+// RunCommand dispatches the command line to a subcommand.
+// Input: a slice of strings args (without the program name)
+// Output: an int exit status
+func RunCommand(args []string) int {
+	if len(args) == 0 {
+		PrintUsage()
+		return 2
+	}
+	name := args[0]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if len(args) < 2 {
+			PrintUsage()
+			return 0
+		}
+		command, ok := FindCommand(args[1])
+		if !ok {
+			fmt.Printf("Unknown command %q\n\n", args[1])
+			PrintUsage()
+			return 2
+		}
+		fmt.Printf("%s %s: %s\n\n", PROGRAMNAME, command.Name, command.Summary)
+		command.Run([]string{"-h"})
+		return 0
+	}
+	command, ok := FindCommand(name)
+	if !ok {
+		fmt.Printf("Unknown command %q\n\n", name)
+		PrintUsage()
+		return 2
+	}
+	command.Run(args[1:])
+	return 0
+}
+
+// commandFlags creates the flag set of a command whose help page shows the usage line, a description and the flags.
+// Input: a string name, a string usage (the arguments after the flags), a string description
+// Output: a *flag.FlagSet
+func commandFlags(name, usage, description string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.SetOutput(os.Stdout)
+	flags.Usage = func() {
+		fmt.Printf("Usage: %s\n\n%s\n\nFlags:\n", strings.TrimSpace(PROGRAMNAME+" "+name+" [flags] "+usage), description)
+		flags.PrintDefaults()
+	}
+	return flags
+}
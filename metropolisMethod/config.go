@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/docking"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/energy"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+)
+
+// CONFIGVERSION is the version of the run configuration format read and written by this build
+const CONFIGVERSION = 1
+
+// InputConfig selects the structures of a run
+type InputConfig struct {
+	Dir            string   `json:"dir" toml:"dir"`
+	Protein        string   `json:"protein" toml:"protein"`                 // file in Dir or a path
+	Ligands        []string `json:"ligands" toml:"ligands"`                 // ligand files; empty selects the files in Dir whose name contains "ligand"
+	Limit          int      `json:"limit" toml:"limit"`                     // number of ligands to use, 0 for all
+	ShiftThreshold float64  `json:"shift_threshold" toml:"shift_threshold"` // move each ligand within this distance in Å of the protein first, 0 keeps the input pose
+}
+
+// OutputConfig selects where results go and which of them are written
+type OutputConfig struct {
+	Dir           string      `json:"dir" toml:"dir"`
+	Traces        bool        `json:"traces" toml:"traces"`               // walker trace CSV files and plots
+	Distributions bool        `json:"distributions" toml:"distributions"` // energy histograms and landscapes of the sampled ensembles
+	Interactions  bool        `json:"interactions" toml:"interactions"`   // interaction reports of the final poses
+	Report        bool        `json:"report" toml:"report"`               // the HTML report
+	Plot          PlotOptions `json:"plot" toml:"plot"`
+}
+
+// RunConfig describes a run: its inputs, the engine settings of package docking (iterations, seed, energy model,
+// move set, temperature schedule, parallelism, flexible side chains and restraints) and its outputs. It is read
+// from versioned JSON or TOML files, where the engine settings are top-level keys, and written next to the results
+// fully resolved.
+type RunConfig struct {
+	Version int `json:"version" toml:"version"`
+	docking.Settings
+	Inputs  InputConfig  `json:"inputs" toml:"inputs"`
+	Outputs OutputConfig `json:"outputs" toml:"outputs"`
+}
+
+// DefaultRunConfig returns the screen of the original main(): the first 5 ligands of Data/mol2_files against
+// 223l_protein.mol2 for 3000 iterations with rotations at body temperature on every CPU, with every output.
+// Input: none
+// Output: a RunConfig
+func DefaultRunConfig() RunConfig {
+	return RunConfig{
+		Version:  CONFIGVERSION,
+		Settings: docking.DefaultSettings(),
+		Inputs:   InputConfig{Dir: "Data/mol2_files", Protein: "223l_protein.mol2", Limit: 5},
+		Outputs: OutputConfig{
+			Dir:           "Output",
+			Traces:        true,
+			Distributions: true,
+			Interactions:  true,
+			Report:        true,
+			Plot:          RankedPlotOptions(),
+		},
+	}
+}
+
+// ConfigErrors collects every problem found in a run configuration, so they can all be fixed at once
+type ConfigErrors []string
+
+// Error lists the problems, one per line.
+func (errs ConfigErrors) Error() string {
+	return "invalid run configuration:\n  " + strings.Join(errs, "\n  ")
+}
+
+// Validate checks every field of the run configuration and reports all problems with their keys.
+// Input: a RunConfig
+// Output: a ConfigErrors or nil
+func (config RunConfig) Validate() error {
+	var errs ConfigErrors
+	add := func(key string, err error) {
+		if err != nil {
+			errs = append(errs, key+": "+err.Error())
+		}
+	}
+	check := func(key string, ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, key+": "+fmt.Sprintf(format, args...))
+		}
+	}
+	switch {
+	case config.Version == 0:
+		errs = append(errs, fmt.Sprintf("version: missing, this build reads version %d", CONFIGVERSION))
+	case config.Version != CONFIGVERSION:
+		errs = append(errs, fmt.Sprintf("version: unsupported version %d, this build reads version %d", config.Version, CONFIGVERSION))
+	}
+	errs = append(errs, config.Settings.Problems()...)
+	check("inputs.limit", config.Inputs.Limit >= 0, "must not be negative, got %d", config.Inputs.Limit)
+	check("inputs.shift_threshold", config.Inputs.ShiftThreshold >= 0, "must not be negative, got %v", config.Inputs.ShiftThreshold)
+	check("outputs.dir", config.Outputs.Dir != "", "must not be empty")
+	add("outputs.plot", config.Outputs.Plot.Validate())
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateInputs checks that the protein and ligand files of a screen exist.
+// Input: a RunConfig
+// Output: a ConfigErrors or nil
+func (config RunConfig) ValidateInputs() error {
+	var errs ConfigErrors
+	if config.Inputs.Protein == "" {
+		errs = append(errs, "inputs.protein: must not be empty")
+	} else if _, err := os.Stat(config.ProteinPath()); err != nil {
+		errs = append(errs, fmt.Sprintf("inputs.protein: %v", err))
+	}
+	if len(config.Inputs.Ligands) == 0 {
+		if info, err := os.Stat(config.Inputs.Dir); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Sprintf("inputs.dir: %q is not a directory", config.Inputs.Dir))
+		}
+	}
+	for i, ligand := range config.Inputs.Ligands {
+		if _, err := os.Stat(ligand); err != nil {
+			errs = append(errs, fmt.Sprintf("inputs.ligands[%d]: %v", i, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ProteinPath returns the protein file: Inputs.Protein itself when it exists, otherwise that name in Inputs.Dir.
+// Input: a RunConfig
+// Output: a string path
+func (config RunConfig) ProteinPath() string {
+	if _, err := os.Stat(config.Inputs.Protein); err == nil {
+		return config.Inputs.Protein
+	}
+	return filepath.Join(config.Inputs.Dir, config.Inputs.Protein)
+}
+
+// LigandFiles returns the ligand files of the run: Inputs.Ligands, or the files in Inputs.Dir whose name contains
+// "ligand", cut to Inputs.Limit.
+// Input: a RunConfig
+// Output: a slice of file names and an error or nil
+func (config RunConfig) LigandFiles() ([]string, error) {
+	files := config.Inputs.Ligands
+	if len(files) == 0 {
+		var err error
+		if files, err = findFilesWithSubstring(config.Inputs.Dir, "ligand"); err != nil {
+			return nil, err
+		}
+	}
+	if config.Inputs.Limit > 0 && config.Inputs.Limit < len(files) {
+		files = files[:config.Inputs.Limit]
+	}
+	return files, nil
+}
+
+// configFormat returns "json" or "toml" from the extension of a config file name.
+// Input: a string fileName
+// Output: a string format and an error or nil
+func configFormat(fileName string) (string, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json":
+		return "json", nil
+	case ".toml":
+		return "toml", nil
+	}
+	return "", fmt.Errorf("%s: run configurations must be .json or .toml files", fileName)
+}
+
+// ReadRunConfig decodes a JSON or TOML run configuration over the given defaults, so keys that are left out keep
+// their default. Unknown keys are errors, to catch typos. The version must be given.
+// Input: an io.Reader r, a string format ("json" or "toml"), a RunConfig defaults
+// Output: the RunConfig and an error or nil
+func ReadRunConfig(r io.Reader, format string, defaults RunConfig) (RunConfig, error) {
+	config := defaults
+	config.Version = 0
+	switch format {
+	case "json":
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			return config, err
+		}
+	case "toml":
+		metadata, err := toml.NewDecoder(r).Decode(&config)
+		if err != nil {
+			return config, err
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, key := range undecoded {
+				keys[i] = key.String()
+			}
+			return config, fmt.Errorf("unknown keys %s", strings.Join(keys, ", "))
+		}
+	default:
+		return config, fmt.Errorf("unknown config format %q", format)
+	}
+	return config, nil
+}
+
+// LoadRunConfig reads a run configuration file; the format follows the extension (.json or .toml).
+// Input: a string fileName, a RunConfig defaults
+// Output: the RunConfig and an error or nil
+func LoadRunConfig(fileName string, defaults RunConfig) (RunConfig, error) {
+	format, err := configFormat(fileName)
+	if err != nil {
+		return defaults, err
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		return defaults, err
+	}
+	defer file.Close()
+	config, err := ReadRunConfig(file, format, defaults)
+	if err != nil {
+		return config, fmt.Errorf("reading %s: %w", fileName, err)
+	}
+	return config, nil
+}
+
+// WriteRunConfig writes a run configuration as indented JSON or as TOML.
+// Input: an io.Writer w, a RunConfig, a string format ("json" or "toml")
+// Output: an error or nil
+func WriteRunConfig(w io.Writer, config RunConfig, format string) error {
+	if format == "toml" {
+		return toml.NewEncoder(w).Encode(config)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(config)
+}
+
+// SaveRunConfig writes a run configuration to a .json or .toml file.
+// Input: a string fileName, a RunConfig
+// Output: an error or nil
+func SaveRunConfig(fileName string, config RunConfig) error {
+	format, err := configFormat(fileName)
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	if err := WriteRunConfig(&buffer, config, format); err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, buffer.Bytes(), 0644)
+}
+
+// AddInputFlags registers the input settings as flags, with the current values as defaults.
+// Input: a *RunConfig, a *flag.FlagSet
+// Output: none (the flags write into the config when parsed)
+func (config *RunConfig) AddInputFlags(flags *flag.FlagSet) {
+	flags.StringVar(&config.Inputs.Dir, "dir", config.Inputs.Dir, "data directory")
+	flags.StringVar(&config.Inputs.Protein, "protein", config.Inputs.Protein, "protein file, in the data directory or as a path")
+	flags.IntVar(&config.Inputs.Limit, "limit", config.Inputs.Limit, "number of ligands to use (0 for all)")
+	flags.Float64Var(&config.Inputs.ShiftThreshold, "shift", config.Inputs.ShiftThreshold, "move each ligand within this distance (Å) of the protein first (0 keeps the input pose)")
+}
+
+// AddSimulationFlags registers the iterations, seed, energy model, moves, temperature schedule and walkers as flags,
+// with the current values as defaults.
+// Input: a *RunConfig, a *flag.FlagSet
+// Output: none (the flags write into the config when parsed)
+func (config *RunConfig) AddSimulationFlags(flags *flag.FlagSet) {
+	flags.IntVar(&config.Iterations, "iterations", config.Iterations, "Metropolis iterations per ligand, split over the walkers")
+	flags.Int64Var(&config.Seed, "seed", config.Seed, "random seed (0 draws one and records it in the resolved config)")
+	config.AddEnergyFlags(flags)
+	flags.BoolVar(&config.Moves.Rotate, "rotate", config.Moves.Rotate, "allow rotational moves")
+	flags.Float64Var(&config.Moves.StepSize, "step-size", config.Moves.StepSize, "width of the random displacement of each atom (Å)")
+	flags.Float64Var(&config.Moves.MaxAngle, "max-angle", config.Moves.MaxAngle, "largest rotation per move (radians)")
+	flags.Float64Var(&config.Moves.MinDistance, "min-distance", config.Moves.MinDistance, "smallest distance between ligand atoms (Å)")
+	flags.StringVar(&config.Temperature.Kind, "schedule", config.Temperature.Kind, "temperature schedule: "+strings.Join(sampling.TemperatureSchedules, ", "))
+	flags.Float64Var(&config.Temperature.Start, "temperature", config.Temperature.Start, "Metropolis temperature (start of the schedule)")
+	flags.Float64Var(&config.Temperature.End, "end-temperature", config.Temperature.End, "final temperature of the linear and exponential schedules")
+	flags.IntVar(&config.Parallel.Walkers, "procs", config.Parallel.Walkers, "parallel walkers")
+}
+
+// AddEnergyFlags registers the energy model, Coulomb constant, dielectric and cutoff as flags, with the current
+// values as defaults.
+// Input: a *RunConfig, a *flag.FlagSet
+// Output: none (the flags write into the config when parsed)
+func (config *RunConfig) AddEnergyFlags(flags *flag.FlagSet) {
+	flags.StringVar(&config.Energy.Model, "energy", config.Energy.Model, "energy model: "+strings.Join(energy.EnergyModels, ", "))
+	flags.Float64Var(&config.Energy.Constant, "energy-constant", config.Energy.Constant, "Coulomb constant")
+	flags.StringVar(&config.Energy.Dielectric, "dielectric", config.Energy.Dielectric, "dielectric: "+strings.Join(energy.DielectricModels, ", "))
+	flags.Float64Var(&config.Energy.Cutoff, "cutoff", config.Energy.Cutoff, "ignore atom pairs farther apart than this (Å, 0 for no cutoff)")
+}
+
+// AddFlexibleFlags registers the flexible side-chain settings as flags, with the current values as defaults.
+// Input: a *RunConfig, a *flag.FlagSet
+// Output: none (the flags write into the config when parsed)
+func (config *RunConfig) AddFlexibleFlags(flags *flag.FlagSet) {
+	flags.Var(listValue{&config.Flexible.Residues}, "flexible", "comma-separated receptor residues whose side chains turn, e.g. A:TYR45,A:88")
+	flags.Float64Var(&config.Flexible.Distance, "flexible-distance", config.Flexible.Distance, "also turn the side chains within this distance (Å) of a ligand (0 for none)")
+	flags.Float64Var(&config.Flexible.Fraction, "flexible-fraction", config.Flexible.Fraction, "share of the moves that turn a side chain")
+}
+
+// listValue is a flag holding a comma-separated list of strings
+type listValue struct {
+	target *[]string
+}
+
+// String returns the list joined by commas.
+// Input: a listValue
+// Output: a string
+func (value listValue) String() string {
+	if value.target == nil {
+		return ""
+	}
+	return strings.Join(*value.target, ",")
+}
+
+// Set replaces the list by the comma-separated items of text, leaving out empty ones.
+// Input: a listValue, a string flag value
+// Output: an error or nil
+func (value listValue) Set(text string) error {
+	*value.target = nil
+	for _, item := range strings.Split(text, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*value.target = append(*value.target, item)
+		}
+	}
+	return nil
+}
+
+// AddOutputFlags registers the output directory and the plot options as flags, with the current values as defaults.
+// Input: a *RunConfig, a *flag.FlagSet
+// Output: none (the flags write into the config when parsed)
+func (config *RunConfig) AddOutputFlags(flags *flag.FlagSet) {
+	flags.StringVar(&config.Outputs.Dir, "output", config.Outputs.Dir, "output directory")
+	config.Outputs.Plot.AddFlags(flags)
+}
+
+// ResolveRunConfig builds the configuration of a command: the defaults, then the config file when one is given,
+// then every flag set on the command line. A zero seed is replaced by a drawn one so the run can be repeated.
+// Input: a parsed *flag.FlagSet of a command, a string fileName (empty for none), a RunConfig defaults
+// Output: the validated RunConfig and an error or nil
+func ResolveRunConfig(flags *flag.FlagSet, fileName string, defaults RunConfig) (RunConfig, error) {
+	config := defaults
+	if fileName != "" {
+		var err error
+		if config, err = LoadRunConfig(fileName, defaults); err != nil {
+			return config, err
+		}
+	}
+	overrides := flag.NewFlagSet("overrides", flag.ContinueOnError)
+	config.AddInputFlags(overrides)
+	config.AddSimulationFlags(overrides)
+	config.AddFlexibleFlags(overrides)
+	config.AddOutputFlags(overrides)
+	var err error
+	flags.Visit(func(f *flag.Flag) {
+		if overrides.Lookup(f.Name) != nil && err == nil {
+			err = overrides.Set(f.Name, f.Value.String())
+		}
+	})
+	if err != nil {
+		return config, err
+	}
+	config = completeRunConfig(config)
+	return config, config.Validate()
+}
+
+// completeRunConfig fills the values derived from the others: the end temperature of a constant schedule, and a
+// drawn seed in place of 0 so the run can be repeated.
+// Input: a RunConfig
+// Output: the completed RunConfig
+func completeRunConfig(config RunConfig) RunConfig {
+	if config.Temperature.Kind == "constant" {
+		config.Temperature.End = config.Temperature.Start
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	return config
+}
+
+// ConfigMain is the entry point of the "config" command, which validates a run configuration and prints or writes
+// it fully resolved.
+// Usage: config [flags] [resolved.json|resolved.toml]
+// Input: a slice of strings args (without the command name)
+// Output: none (prints or writes the resolved configuration)
+func ConfigMain(args []string) {
+	defaults := DefaultRunConfig()
+	flags := commandFlags("config", "[resolved.json|resolved.toml]",
+		"Validates a run configuration and prints it fully resolved as TOML, or writes it to the given .json or .toml file.\n"+
+			"Without -config the defaults are resolved, which is a starting point for a new configuration file.\n"+
+			"The flags of screen, simulate and redock override the file here as they do there.")
+	configFile := flags.String("config", "", "run configuration (.json or .toml)")
+	defaults.AddInputFlags(flags)
+	defaults.AddSimulationFlags(flags)
+	defaults.AddFlexibleFlags(flags)
+	defaults.AddOutputFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return
+	}
+	config, err := ResolveRunConfig(flags, *configFile, DefaultRunConfig())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if flags.NArg() == 1 {
+		Check(SaveRunConfig(flags.Arg(0), config))
+		fmt.Println("Resolved configuration written to", flags.Arg(0))
+		return
+	}
+	Check(WriteRunConfig(os.Stdout, config, "toml"))
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/energy"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+)
+
+func TestReadRunConfig(t *testing.T) {
+	toml := strings.Join([]string{
+		"version = 1",
+		"iterations = 500",
+		"seed = 7",
+		"[moves]",
+		"step_size = 0.2",
+		"[temperature]",
+		`schedule = "linear"`,
+		"start = 600.0",
+		"end = 300.0",
+		"[[restraints]]",
+		`kind = "distance"`,
+		`form = "flat-bottom"`,
+		`atoms = ["ligand@O1", "receptor:A:LYS10@NZ"]`,
+		"target = 3.0",
+		"width = 0.5",
+		"weight = 10.0",
+	}, "\n")
+	config, err := ReadRunConfig(strings.NewReader(toml), "toml", DefaultRunConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Iterations != 500 || config.Seed != 7 || config.Moves.StepSize != 0.2 || config.Temperature.End != 300 {
+		t.Errorf("Expected the values of the file, got %+v", config)
+	}
+	if !config.Moves.Rotate || config.Moves.MaxAngle != sampling.MAXANGLE || config.Energy != energy.DefaultEnergyModel() {
+		t.Errorf("Expected the defaults for keys left out, got %+v", config)
+	}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Expected a valid configuration, got %v", err)
+	}
+	restraints, err := config.LoadRestraints(moltest.Pocket(), []molecule.Molecule{moltest.Benzoate()})
+	if err != nil || len(restraints) != 1 || restraints[0].Width != 0.5 {
+		t.Errorf("Expected the restraint of the file to bind to the pocket and benzoate, got %+v and %v", restraints, err)
+	}
+	if _, err := config.LoadRestraints(moltest.Pocket(), []molecule.Molecule{moltest.Ligand()}); err == nil || !strings.Contains(err.Error(), "ligand 1") {
+		t.Errorf("Expected a ligand without atom O1 to be reported, got %v", err)
+	}
+
+	if _, err := ReadRunConfig(strings.NewReader("version = 1\niteratons = 5"), "toml", DefaultRunConfig()); err == nil {
+		t.Errorf("Expected an error for an unknown TOML key")
+	}
+	if _, err := ReadRunConfig(strings.NewReader(`{"version": 1, "moves": {"stepsize": 1}}`), "json", DefaultRunConfig()); err == nil {
+		t.Errorf("Expected an error for an unknown JSON key")
+	}
+
+	config, _ = ReadRunConfig(strings.NewReader(`{"iterations": 0, "moves": {"step_size": -1}, "restraints": [{"kind": "torsion"}]}`), "json", DefaultRunConfig())
+	err = config.Validate()
+	for _, key := range []string{"version:", "iterations:", "moves.step_size:", "restraints[0]:"} {
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("Expected an error for %s, got %v", key, err)
+		}
+	}
+}
+
+func TestResolveRunConfig(t *testing.T) {
+	defaults := DefaultRunConfig()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	defaults.AddInputFlags(flags)
+	defaults.AddSimulationFlags(flags)
+	defaults.AddFlexibleFlags(flags)
+	defaults.AddOutputFlags(flags)
+	if err := flags.Parse([]string{"-iterations", "200", "-width", "5", "-temperature", "400", "-flexible", "A:45, A:SER45"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config, err := ResolveRunConfig(flags, "", DefaultRunConfig())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Iterations != 200 || config.Outputs.Plot.Width != 5*72 || config.Temperature.End != 400 {
+		t.Errorf("Expected the flags to override the defaults, got %+v", config)
+	}
+	if config.Seed == 0 {
+		t.Errorf("Expected a drawn seed to be recorded")
+	}
+	if sim, err := config.DockingSimulation(moltest.Serine(), nil); err != nil || sim.Iterations != 200 || sim.Flexible == nil {
+		t.Errorf("Expected the docking simulation to make serine 45 flexible, got %+v and %v", sim, err)
+	}
+
+	var buffer bytes.Buffer
+	for _, format := range []string{"json", "toml"} {
+		buffer.Reset()
+		if err := WriteRunConfig(&buffer, config, format); err != nil {
+			t.Fatalf("Unexpected error writing %s: %v", format, err)
+		}
+		read, err := ReadRunConfig(&buffer, format, RunConfig{})
+		if err != nil {
+			t.Fatalf("Unexpected error reading %s: %v", format, err)
+		}
+		if read.Iterations != config.Iterations || read.Seed != config.Seed || read.Temperature != config.Temperature ||
+			read.Outputs.Plot.Width != config.Outputs.Plot.Width || len(read.Flexible.Residues) != 2 {
+			t.Errorf("Expected the %s round trip to keep the configuration, got %+v", format, read)
+		}
+	}
+}
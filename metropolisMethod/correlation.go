@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// AffinityPair is a simulated energy joined to the experimental value of the same complex
+type AffinityPair struct {
+	ID           string
+	Energy       float64
+	Experimental float64
+}
+
+// CorrelationReport summarises how well simulated energies track experimental affinities
+type CorrelationReport struct {
+	Simulation string                      `json:"simulation"`
+	Reference  string                      `json:"reference"`
+	Column     string                      `json:"column"`
+	Matched    int                         `json:"matched"`
+	Unmatched  []string                    `json:"unmatched,omitempty"`
+	Pearson    analysis.ConfidenceInterval `json:"pearson"`
+	Spearman   analysis.ConfidenceInterval `json:"spearman"`
+	Kendall    analysis.ConfidenceInterval `json:"kendall"`
+	Slope      float64                     `json:"slope"`
+	Intercept  float64                     `json:"intercept"`
+	Resamples  int                         `json:"resamples"`
+	Confidence float64                     `json:"confidence"`
+	Seed       int64                       `json:"seed"`
+}
+
+// simulationIDColumns and simulationEnergyColumns are the headers recognised in simulation output tables
+var (
+	simulationIDColumns     = []string{"pdb_id", "id", "label", "ligand", "file name"}
+	simulationEnergyColumns = []string{"energy", "binding_energy", "min_energy"}
+)
+
+// ReadColumnTable reads a CSV file with a header row into a map from the normalised PDB id of idColumns
+// to the numeric value of valueColumns (the first header of each list that is present). Rows whose value is
+// empty or not a number, or whose status column is not "ok", are skipped.
+// Input: a string filename, slices of candidate id and value column names (case-insensitive)
+// Output: a map of id to value, the ids in file order, and an error or nil
+func ReadColumnTable(filename string, idColumns, valueColumns []string) (map[string]float64, []string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%s is empty", filename)
+	}
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	idColumn, ok := firstColumn(columns, idColumns)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s has none of the id columns %v", filename, idColumns)
+	}
+	valueColumn, ok := firstColumn(columns, valueColumns)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s has none of the value columns %v", filename, valueColumns)
+	}
+	statusColumn, hasStatus := columns["status"]
+
+	values := make(map[string]float64)
+	var order []string
+	for _, record := range records[1:] {
+		if idColumn >= len(record) || valueColumn >= len(record) {
+			continue
+		}
+		if hasStatus && statusColumn < len(record) && record[statusColumn] != "ok" {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(record[valueColumn]), 64)
+		if err != nil {
+			continue
+		}
+		id := normalizePDBID(record[idColumn])
+		if _, seen := values[id]; !seen {
+			order = append(order, id)
+		}
+		values[id] = value
+	}
+	return values, order, nil
+}
+
+// firstColumn returns the index of the first candidate header present in columns.
+// Input: a map of lower-case header to index, a slice of candidate names
+// Output: the int index and a bool reporting whether one was found
+func firstColumn(columns map[string]int, candidates []string) (int, bool) {
+	for _, name := range candidates {
+		if i, ok := columns[strings.ToLower(name)]; ok {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// normalizePDBID turns a PDB id or a file name such as 223l_ligand.mol2 into the lower-case PDB id.
+// Input: a string value
+// Output: a string id
+func normalizePDBID(value string) string {
+	base := filepath.Base(strings.TrimSpace(value))
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	return strings.ToLower(strings.Split(base, "_")[0])
+}
+
+// JoinAffinities pairs simulated energies with experimental values by PDB id.
+// Input: maps of id to energy and to experimental value, the simulation ids in order
+// Output: a slice of AffinityPair and the simulation ids without an experimental value
+func JoinAffinities(energies, experimental map[string]float64, order []string) ([]AffinityPair, []string) {
+	var pairs []AffinityPair
+	var unmatched []string
+	for _, id := range order {
+		if value, ok := experimental[id]; ok {
+			pairs = append(pairs, AffinityPair{ID: id, Energy: energies[id], Experimental: value})
+		} else {
+			unmatched = append(unmatched, id)
+		}
+	}
+	return pairs, unmatched
+}
+
+// CorrelateAffinities computes the Pearson, Spearman and Kendall correlations of the pairs with bootstrap
+// confidence intervals and the least-squares line of experimental value against energy.
+// Input: a slice of AffinityPair, an int number of resamples, a float64 confidence level, an int64 seed
+// Output: a CorrelationReport
+func CorrelateAffinities(pairs []AffinityPair, resamples int, level float64, seed int64) CorrelationReport {
+	x, y := affinityColumns(pairs)
+	source := rand.New(rand.NewSource(seed))
+	report := CorrelationReport{Matched: len(pairs), Resamples: resamples, Confidence: level, Seed: seed}
+	report.Pearson = analysis.BootstrapInterval(x, y, analysis.PearsonCorrelation, resamples, level, source)
+	report.Spearman = analysis.BootstrapInterval(x, y, analysis.SpearmanCorrelation, resamples, level, source)
+	report.Kendall = analysis.BootstrapInterval(x, y, analysis.KendallTau, resamples, level, source)
+	report.Slope, report.Intercept = analysis.LinearRegression(x, y)
+	return report
+}
+
+// affinityColumns splits pairs into the energy and experimental samples.
+// Input: a slice of AffinityPair
+// Output: slices of float64 energies and experimental values
+func affinityColumns(pairs []AffinityPair) ([]float64, []float64) {
+	x, y := make([]float64, len(pairs)), make([]float64, len(pairs))
+	for i, pair := range pairs {
+		x[i], y[i] = pair.Energy, pair.Experimental
+	}
+	return x, y
+}
+
+// plotAffinityScatter draws the simulated energies against the experimental values with the regression line.
+// Input: a slice of AffinityPair, a CorrelationReport, a string fileName (without extension), a PlotOptions
+// Output: none (saves a plot)
+func plotAffinityScatter(pairs []AffinityPair, report CorrelationReport, fileName string, options PlotOptions) {
+	points := make(plotter.XYs, len(pairs))
+	for i, pair := range pairs {
+		points[i].X, points[i].Y = pair.Energy, pair.Experimental
+	}
+	p := plot.New()
+	p.Title.Text = fmt.Sprintf("Simulated energy vs %s (Pearson r = %.3f, n = %d)", report.Column, report.Pearson.Estimate, report.Matched)
+	p.X.Label.Text = "Protein Ligand Binding Energy"
+	p.Y.Label.Text = report.Column
+
+	scatter, err := plotter.NewScatter(points)
+	Check(err)
+	scatter.GlyphStyle.Shape = draw.CircleGlyph{}
+	scatter.GlyphStyle.Radius = vg.Points(2.5)
+	scatter.GlyphStyle.Color = options.ink()
+	p.Add(scatter)
+
+	line := plotter.NewFunction(func(x float64) float64 { return report.Slope*x + report.Intercept })
+	line.Color = color.RGBA{R: 200, A: 255}
+	line.Width = vg.Points(1.5)
+	p.Add(line)
+	p.Legend.Add(fmt.Sprintf("y = %.3gx + %.3g", report.Slope, report.Intercept), line)
+
+	savePlot(p, 6*vg.Inch, 4*vg.Inch, fileName, options)
+}
+
+// writeAffinityPairs writes the joined table used for the correlation.
+// Input: a string fileName, a slice of AffinityPair, a string column name of the experimental value
+// Output: an error or nil
+func writeAffinityPairs(fileName string, pairs []AffinityPair, column string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"pdb_id", "energy", column}); err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		if err := writer.Write([]string{pair.ID, strconv.FormatFloat(pair.Energy, 'g', -1, 64), strconv.FormatFloat(pair.Experimental, 'g', -1, 64)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// CorrelateMain is the entry point of the "correlate" command. It joins a simulation table (any CSV with a
+// pdb_id/label column and an energy column, such as benchmark.csv) to extended_PLAS20K.csv by PDB id and
+// writes correlation.json, the joined correlation_pairs.csv and a correlation scatter plot.
+// Usage: correlate [flags] simulation.csv extended_PLAS20K.csv outputDir
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the reports and prints the correlations)
+func CorrelateMain(args []string) {
+	flags := commandFlags("correlate", "simulation.csv extended_PLAS20K.csv outputDir",
+		"Joins a simulation table (a CSV with a pdb_id or label column and an energy column, such as benchmark.csv) to\n"+
+			"extended_PLAS20K.csv by PDB id and writes correlation.json, correlation_pairs.csv and a scatter plot.")
+	column := flags.String("column", "Experimental", "PLAS20K column to correlate against, e.g. Experimental or DELTA_TOTAL")
+	resamples := flags.Int("bootstrap", 1000, "bootstrap resamples for the confidence intervals")
+	level := flags.Float64("confidence", 0.95, "confidence level of the intervals")
+	seed := flags.Int64("seed", 1, "random seed of the bootstrap")
+	plotOptions := DefaultPlotOptions()
+	plotOptions.AddFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() != 3 {
+		flags.Usage()
+		return
+	}
+	if *level <= 0 || *level >= 1 {
+		fmt.Println("confidence must be between 0 and 1")
+		return
+	}
+	Check(plotOptions.Validate())
+	simulationFile, referenceFile, outputDir := flags.Arg(0), flags.Arg(1), flags.Arg(2)
+	provenance := NewProvenance("correlate")
+	provenance.Parameter("column", *column)
+	provenance.Parameter("bootstrap", strconv.Itoa(*resamples))
+	provenance.Parameter("confidence", strconv.FormatFloat(*level, 'g', -1, 64))
+	provenance.Parameter("seed", strconv.FormatInt(*seed, 10))
+	provenance.Stage("load inputs")
+	Check(provenance.Input("simulation", simulationFile))
+	Check(provenance.Input("reference", referenceFile))
+
+	energies, order, err := ReadColumnTable(simulationFile, simulationIDColumns, simulationEnergyColumns)
+	Check(err)
+	experimental, _, err := ReadColumnTable(referenceFile, []string{"pdb_id"}, []string{*column})
+	Check(err)
+	pairs, unmatched := JoinAffinities(energies, experimental, order)
+	if len(pairs) < 3 {
+		fmt.Printf("Only %d complexes matched between %s and %s, at least 3 are needed\n", len(pairs), simulationFile, referenceFile)
+		return
+	}
+
+	provenance.Stage("correlation")
+	report := CorrelateAffinities(pairs, *resamples, *level, *seed)
+	report.Simulation, report.Reference, report.Column, report.Unmatched = simulationFile, referenceFile, *column, unmatched
+
+	fmt.Printf("Matched %d complexes (%d without a %s value)\n", report.Matched, len(unmatched), *column)
+	for _, named := range []struct {
+		name     string
+		interval analysis.ConfidenceInterval
+	}{{"Pearson r", report.Pearson}, {"Spearman rho", report.Spearman}, {"Kendall tau", report.Kendall}} {
+		fmt.Printf("%-13s %6.3f  [%.3f, %.3f] (%.0f%% CI)\n", named.name, named.interval.Estimate, named.interval.Lower, named.interval.Upper, 100**level)
+	}
+
+	provenance.Stage("write results")
+	Check(os.MkdirAll(outputDir, 0755))
+	Check(writeAffinityPairs(filepath.Join(outputDir, "correlation_pairs.csv"), pairs, *column))
+	data, err := json.MarshalIndent(report, "", "  ")
+	Check(err)
+	Check(os.WriteFile(filepath.Join(outputDir, "correlation.json"), append(data, '\n'), 0644))
+	plotAffinityScatter(pairs, report, filepath.Join(outputDir, "correlation"), plotOptions)
+	Check(provenance.Save(outputDir))
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/energy"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// CrossDockMatrix holds the energy of every ligand docked against every receptor
+type CrossDockMatrix struct {
+	Ligands   []string              // ligand labels, one per row
+	Receptors []string              // receptor labels, one per column
+	Energies  [][]float64           // Energies[ligand][receptor], NaN when the pair was not docked
+	Poses     [][]molecule.Molecule // Poses[ligand][receptor], the final pose of each docked pair
+}
+
+// LigandSelectivity summarises how strongly a ligand prefers one receptor over the others
+type LigandSelectivity struct {
+	Ligand       string
+	Best         string // receptor with the lowest energy
+	BestEnergy   float64
+	RunnerUp     string  // receptor with the second lowest energy
+	Gap          float64 // runner-up energy minus the best energy, NaN with fewer than two receptors
+	Score        float64 // Gap in standard deviations of the ligand's energies over the receptors
+	ZScore       float64 // best energy minus the mean energy, in standard deviations
+	Target       string  // receptor the selectivity is measured for, empty for none
+	TargetEnergy float64
+	TargetGap    float64 // lowest energy at any other receptor minus the target energy; positive when the target is preferred
+	TargetScore  float64 // TargetGap in standard deviations
+}
+
+// uniqueLabels returns the label of every file and an error when two files have the same label.
+// Input: a slice of strings files
+// Output: a slice of strings labels and an error or nil
+func uniqueLabels(files []string) ([]string, error) {
+	labels := make([]string, len(files))
+	seen := make(map[string]string)
+	for i, file := range files {
+		labels[i] = molio.ExtractFileLabel(file)
+		if other, ok := seen[labels[i]]; ok {
+			return nil, fmt.Errorf("%s and %s have the same label %q", other, file, labels[i])
+		}
+		seen[labels[i]] = file
+	}
+	return labels, nil
+}
+
+// RunCrossDock docks every ligand against every receptor file with DockMatrix; receptors are named by the label
+// of their file.
+// Input: a context.Context ctx, slices of strings receptorFiles and ligandFiles, a RunConfig config, a string
+// outputDir, a *Provenance that records the structures used (may be nil)
+// Output: the CrossDockMatrix, and ctx.Err() when the run was cancelled (the matrix is then incomplete) or the
+// error of a receptor file or of DockMatrix
+func RunCrossDock(ctx context.Context, receptorFiles, ligandFiles []string, config RunConfig, outputDir string, provenance *Provenance) (CrossDockMatrix, error) {
+	receptorLabels, err := uniqueLabels(receptorFiles)
+	if err != nil {
+		return CrossDockMatrix{}, err
+	}
+	receptors := make([]molio.NamedMolecule, len(receptorFiles))
+	for i, file := range receptorFiles {
+		receptor, err := molio.LoadReceptor(file)
+		if err := warn(err); err != nil {
+			return CrossDockMatrix{}, err
+		}
+		receptors[i] = molio.NamedMolecule{Name: receptorLabels[i], Molecule: receptor}
+		if provenance != nil {
+			if err := provenance.Input("receptor", file); err != nil {
+				return CrossDockMatrix{}, err
+			}
+		}
+	}
+	return DockMatrix(ctx, receptors, ligandFiles, config, outputDir, provenance)
+}
+
+// DockMatrix docks every ligand against every receptor, running config.Parallel.Walkers pairs at a time with one
+// walker each, so the run uses as many goroutines as a screen. Each receptor gets its own DockingSimulation and,
+// unless it has flexible side chains or restraints that need its other atoms, is reduced to its charged atoms for
+// scoring once; each ligand is parsed once. A pair is seeded by the index of its ligand, so each column of the
+// matrix repeats the same seeded search against its receptor. The final pose of every pair is written to
+// poses/<receptor>/<ligand>.mol2 in the output folder. The first pair that fails stops the run.
+// Input: a context.Context ctx, a slice of NamedMolecule receptors, a slice of strings ligandFiles, a RunConfig
+// config, a string outputDir, a *Provenance that records the ligand files (may be nil)
+// Output: the CrossDockMatrix, and ctx.Err() when the run was cancelled (the matrix is then incomplete), the
+// error of a flexible residue or a restraint that cannot be used with a receptor, or the first error of a pair
+func DockMatrix(ctx context.Context, receptors []molio.NamedMolecule, ligandFiles []string, config RunConfig, outputDir string, provenance *Provenance) (CrossDockMatrix, error) {
+	ligandLabels, err := uniqueLabels(ligandFiles)
+	if err != nil {
+		return CrossDockMatrix{}, err
+	}
+	ligands := make([]molecule.Molecule, len(ligandFiles))
+	for i, file := range ligandFiles {
+		ligand, err := molio.ParseMol2(file)
+		if err := warn(err); err != nil {
+			return CrossDockMatrix{}, err
+		}
+		ligands[i] = ligand
+		if provenance != nil {
+			if err := provenance.Input("ligand", file); err != nil {
+				return CrossDockMatrix{}, err
+			}
+		}
+	}
+	receptorLabels := make([]string, len(receptors))
+	scoring := make([]molecule.Molecule, len(receptors))
+	sims := make([]sampling.Simulation, len(receptors))
+	for i, receptor := range receptors {
+		receptorLabels[i], scoring[i] = receptor.Name, receptor.Molecule
+		if sims[i], err = config.DockingSimulation(receptor.Molecule, ligands); err != nil {
+			return CrossDockMatrix{}, fmt.Errorf("%s: %w", receptor.Name, err)
+		}
+		sims[i].Walkers = 1
+		if sims[i].Flexible == nil && len(sims[i].Restraints) == 0 {
+			scoring[i] = energy.ChargedAtoms(receptor.Molecule)
+		}
+	}
+
+	matrix := CrossDockMatrix{Ligands: ligandLabels, Receptors: receptorLabels, Energies: make([][]float64, len(ligands)), Poses: make([][]molecule.Molecule, len(ligands))}
+	for i := range matrix.Energies {
+		matrix.Energies[i] = make([]float64, len(receptors))
+		matrix.Poses[i] = make([]molecule.Molecule, len(receptors))
+		for j := range matrix.Energies[i] {
+			matrix.Energies[i][j] = math.NaN()
+		}
+	}
+	run, stop := context.WithCancel(ctx)
+	defer stop()
+	failures := make(chan error, 1)
+	// fail keeps the first error of a pair and stops the other workers
+	fail := func(err error) {
+		select {
+		case failures <- err:
+		default:
+		}
+		stop()
+	}
+	pairs := make(chan [2]int)
+	var workers sync.WaitGroup
+	for w := 0; w < config.Parallel.Walkers; w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for pair := range pairs {
+				l, r := pair[0], pair[1]
+				start := ligands[l]
+				if config.Inputs.ShiftThreshold > 0 {
+					start = sampling.ShiftLigandCloserByThreshold(start, receptors[r].Molecule, config.Inputs.ShiftThreshold)
+				}
+				pose, docked, _, err := sampling.SimulateFlexibleLigand(run, scoring[r], start, l, sims[r], false)
+				if err != nil {
+					if ctx.Err() == nil && run.Err() == nil {
+						fail(fmt.Errorf("%s against %s: %w", ligandLabels[l], receptorLabels[r], err))
+					}
+					continue
+				}
+				// each pair writes its own cells and pose file
+				matrix.Energies[l][r], matrix.Poses[l][r] = sims[r].Energy.Energy(docked, pose), pose
+				poseFile := filepath.Join(outputDir, "poses", receptorLabels[r], ligandLabels[l]+".mol2")
+				if err := os.MkdirAll(filepath.Dir(poseFile), 0755); err != nil {
+					fail(err)
+					continue
+				}
+				if err := UpdateMol2Coordinates(ligandFiles[l], poseFile, pose); err != nil {
+					fail(err)
+					continue
+				}
+				fmt.Printf("%s against %s: %.4g\n", ligandLabels[l], receptorLabels[r], matrix.Energies[l][r])
+			}
+		}()
+	}
+	for r := range receptors {
+		for l := range ligands {
+			if run.Err() == nil {
+				pairs <- [2]int{l, r}
+			}
+		}
+	}
+	close(pairs)
+	workers.Wait()
+	select {
+	case err := <-failures:
+		return matrix, err
+	default:
+		return matrix, ctx.Err()
+	}
+}
+
+// finiteStatistics returns the mean and population standard deviation of the finite values.
+// Input: a slice of float64 values
+// Output: float64 mean and standard deviation, NaN without finite values
+func finiteStatistics(values []float64) (float64, float64) {
+	var finite []float64
+	for _, value := range values {
+		if !math.IsNaN(value) && !math.IsInf(value, 0) {
+			finite = append(finite, value)
+		}
+	}
+	if len(finite) == 0 {
+		return math.NaN(), math.NaN()
+	}
+	mean := analysis.Mean(finite)
+	variance := 0.0
+	for _, value := range finite {
+		variance += (value - mean) * (value - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(finite)))
+}
+
+// inDeviations divides a difference by a standard deviation, NaN when the deviation is 0 or unknown.
+// Input: float64 difference and deviation
+// Output: a float64
+func inDeviations(difference, deviation float64) float64 {
+	if deviation == 0 || math.IsNaN(deviation) {
+		return math.NaN()
+	}
+	return difference / deviation
+}
+
+// Selectivity scores every ligand of the matrix: its best and runner-up receptors and the energy gap between
+// them, also in standard deviations of the ligand's energies so ligands of different sizes compare. With a target
+// receptor it also gives how much lower the energy at the target is than at the best of the other receptors.
+// Input: a CrossDockMatrix, a string target receptor label (empty for none)
+// Output: a slice of LigandSelectivity in ligand order and an error or nil when the target is not a receptor
+func (matrix CrossDockMatrix) Selectivity(target string) ([]LigandSelectivity, error) {
+	targetIndex := -1
+	for j, receptor := range matrix.Receptors {
+		if receptor == target {
+			targetIndex = j
+		}
+	}
+	if target != "" && targetIndex < 0 {
+		return nil, fmt.Errorf("target %q is not one of the receptors %v", target, matrix.Receptors)
+	}
+	scores := make([]LigandSelectivity, len(matrix.Ligands))
+	for i, energies := range matrix.Energies {
+		score := LigandSelectivity{Ligand: matrix.Ligands[i], BestEnergy: math.NaN(), Gap: math.NaN(), Score: math.NaN(), ZScore: math.NaN(),
+			Target: target, TargetEnergy: math.NaN(), TargetGap: math.NaN(), TargetScore: math.NaN()}
+		mean, deviation := finiteStatistics(energies)
+		best, second, bestOther := -1, -1, math.NaN()
+		for j, value := range energies {
+			if math.IsNaN(value) {
+				continue
+			}
+			switch {
+			case best < 0 || value < energies[best]:
+				best, second = j, best
+			case second < 0 || value < energies[second]:
+				second = j
+			}
+			if j != targetIndex && (math.IsNaN(bestOther) || value < bestOther) {
+				bestOther = value
+			}
+		}
+		if best >= 0 {
+			score.Best, score.BestEnergy = matrix.Receptors[best], energies[best]
+			score.ZScore = inDeviations(energies[best]-mean, deviation)
+		}
+		if second >= 0 {
+			score.RunnerUp, score.Gap = matrix.Receptors[second], energies[second]-energies[best]
+			score.Score = inDeviations(score.Gap, deviation)
+		}
+		if targetIndex >= 0 {
+			score.TargetEnergy = energies[targetIndex]
+			score.TargetGap = bestOther - score.TargetEnergy
+			score.TargetScore = inDeviations(score.TargetGap, deviation)
+		}
+		scores[i] = score
+	}
+	return scores, nil
+}
+
+// ZScores standardises the energies of each ligand over the receptors, so rows of ligands of different sizes
+// share one colour scale in a heatmap.
+// Input: a CrossDockMatrix
+// Output: a slice of rows of float64, NaN where the energy is unknown or a ligand has the same energy everywhere
+func (matrix CrossDockMatrix) ZScores() [][]float64 {
+	scores := make([][]float64, len(matrix.Energies))
+	for i, energies := range matrix.Energies {
+		mean, deviation := finiteStatistics(energies)
+		scores[i] = make([]float64, len(energies))
+		for j, value := range energies {
+			scores[i][j] = inDeviations(value-mean, deviation)
+		}
+	}
+	return scores
+}
+
+// WriteCrossDockMatrix writes the energies as CSV with one row per ligand and one column per receptor.
+// Input: a string fileName, a CrossDockMatrix
+// Output: an error or nil
+func WriteCrossDockMatrix(fileName string, matrix CrossDockMatrix) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	writer.Write(append([]string{"ligand"}, matrix.Receptors...))
+	for i, energies := range matrix.Energies {
+		row := []string{matrix.Ligands[i]}
+		for _, value := range energies {
+			row = append(row, formatOptional(value))
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatOptional formats a value for a CSV cell, leaving NaN empty.
+// Input: a float64 value
+// Output: a string
+func formatOptional(value float64) string {
+	if math.IsNaN(value) {
+		return ""
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// WriteSelectivityTable writes the selectivity of every ligand as CSV; the target columns are left out without a
+// target.
+// Input: a string fileName, a slice of LigandSelectivity
+// Output: an error or nil
+func WriteSelectivityTable(fileName string, scores []LigandSelectivity) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	targeted := len(scores) > 0 && scores[0].Target != ""
+	header := []string{"ligand", "best_receptor", "best_energy", "runner_up", "gap", "selectivity", "best_zscore"}
+	if targeted {
+		header = append(header, "target", "target_energy", "target_gap", "target_selectivity")
+	}
+	writer.Write(header)
+	for _, score := range scores {
+		row := []string{score.Ligand, score.Best, formatOptional(score.BestEnergy), score.RunnerUp, formatOptional(score.Gap),
+			formatOptional(score.Score), formatOptional(score.ZScore)}
+		if targeted {
+			row = append(row, score.Target, formatOptional(score.TargetEnergy), formatOptional(score.TargetGap), formatOptional(score.TargetScore))
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// plotCrossDockHeatMap draws the per-ligand z-scores of the matrix as a heatmap with ligands and receptors
+// reordered by hierarchical clustering, so ligands with similar selectivity profiles and receptors that bind the
+// same ligands sit together.
+// Input: a CrossDockMatrix, a string fileName (without extension), a string title, a string receptorLabel for the
+// x axis, a PlotOptions
+// Output: none (saves a plot)
+func plotCrossDockHeatMap(matrix CrossDockMatrix, fileName, title, receptorLabel string, options PlotOptions) {
+	scores := matrix.ZScores()
+	columns := make([][]float64, len(matrix.Receptors))
+	for j := range columns {
+		columns[j] = make([]float64, len(scores))
+		for i := range scores {
+			columns[j][i] = scores[i][j]
+		}
+	}
+	rowOrder := analysis.ClusterOrder(analysis.EuclideanDistances(scores))
+	columnOrder := analysis.ClusterOrder(analysis.EuclideanDistances(columns))
+	grid := landscapeGrid{xLower: -0.5, xWidth: 1, yLower: -0.5, yWidth: 1, values: make([][]float64, len(columnOrder))}
+	receptors := make([]string, len(columnOrder))
+	ligands := make([]string, len(rowOrder))
+	for c, j := range columnOrder {
+		receptors[c] = matrix.Receptors[j]
+		grid.values[c] = make([]float64, len(rowOrder))
+		for r, i := range rowOrder {
+			grid.values[c][r] = scores[i][j]
+		}
+	}
+	for r, i := range rowOrder {
+		ligands[r] = matrix.Ligands[i]
+	}
+	p, colorMap := newHeatMap(grid, title, receptorLabel, "Ligand", options)
+	p.NominalX(receptors...)
+	p.NominalY(ligands...)
+	p.X.Tick.Label.Rotation = options.LabelAngle * math.Pi / 180
+	if options.LabelAngle != 0 {
+		p.X.Tick.Label.XAlign, p.X.Tick.Label.YAlign = draw.XRight, draw.YCenter
+	}
+	width := max(7*vg.Inch, 2*vg.Inch+vg.Length(len(receptors))*0.35*vg.Inch)
+	height := max(4*vg.Inch, 1.5*vg.Inch+vg.Length(len(ligands))*0.2*vg.Inch)
+	saveHeatMap(p, colorMap, width, height, fileName, options)
+}
+
+// CrossDockMain is the entry point of the "crossdock" command, which docks every ligand of the data directory
+// against every receptor and scores the selectivity of each ligand.
+// Usage: crossdock [flags] [receptor...]
+// Input: a slice of strings args (without the command name)
+// Output: none (writes to <output>/crossdock/)
+func CrossDockMain(args []string) {
+	defaults := DefaultRunConfig()
+	defaults.Inputs.Protein = ""
+	flags := commandFlags("crossdock", "[receptor...]",
+		"Docks every ligand of the data directory against every receptor given, or against every file of the data\n"+
+			"directory whose name contains \"protein\", and writes to <output>/crossdock/: energies.csv with one row per\n"+
+			"ligand and one column per receptor, selectivity.csv with the best and runner-up receptor of each ligand and\n"+
+			"the energy gap between them (also in standard deviations of the ligand's energies), a heatmap of per-ligand\n"+
+			"z-scores with ligands and receptors clustered, the pose of every pair in poses/<receptor>/, the resolved run\n"+
+			"configuration and the provenance manifest. -procs pairs are docked at a time.")
+	configFile := flags.String("config", "", "run configuration (.json or .toml); flags override its values")
+	target := flags.String("target", "", "receptor label to score the selectivity of each ligand for, such as 223l")
+	flags.StringVar(&defaults.Inputs.Dir, "dir", defaults.Inputs.Dir, "data directory")
+	flags.IntVar(&defaults.Inputs.Limit, "limit", defaults.Inputs.Limit, "number of ligands to use (0 for all)")
+	flags.Float64Var(&defaults.Inputs.ShiftThreshold, "shift", defaults.Inputs.ShiftThreshold, "move each ligand within this distance (Å) of each receptor first (0 keeps the input pose)")
+	defaults.AddSimulationFlags(flags)
+	defaults.AddOutputFlags(flags)
+	flags.Parse(args)
+	config, err := ResolveRunConfig(flags, *configFile, defaults)
+	exitOnConfigError(err)
+	receptorFiles := flags.Args()
+	if len(receptorFiles) == 0 {
+		receptorFiles, err = findFilesWithSubstring(config.Inputs.Dir, "protein")
+		exitOnConfigError(err)
+	}
+	ligandFiles, err := config.LigandFiles()
+	exitOnConfigError(err)
+	var problems ConfigErrors
+	for _, file := range receptorFiles {
+		if _, err := os.Stat(file); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if labels, err := uniqueLabels(receptorFiles); err != nil {
+		problems = append(problems, err.Error())
+	} else if *target != "" && !slices.Contains(labels, *target) {
+		problems = append(problems, fmt.Sprintf("target %q is not one of the receptors %v", *target, labels))
+	}
+	if len(receptorFiles) == 0 || len(ligandFiles) == 0 {
+		problems = append(problems, fmt.Sprintf("cross-docking needs receptors and ligands, found %d and %d", len(receptorFiles), len(ligandFiles)))
+	}
+	if len(problems) > 0 {
+		exitOnConfigError(problems)
+	}
+
+	provenance := NewProvenance("crossdock")
+	provenance.SetConfig(config)
+	provenance.Parameter("target", *target)
+	outputDir := filepath.Join(config.Outputs.Dir, "crossdock")
+	Check(os.MkdirAll(outputDir, 0755))
+	Check(SaveRunConfig(filepath.Join(outputDir, runConfigName(*configFile)), config))
+	provenance.Stage("docking")
+	matrix, err := RunCrossDock(context.Background(), receptorFiles, ligandFiles, config, outputDir, provenance)
+	exitOnConfigError(err)
+	provenance.Stage("results")
+	scores, err := matrix.Selectivity(*target)
+	exitOnConfigError(err)
+	Check(WriteCrossDockMatrix(filepath.Join(outputDir, "energies.csv"), matrix))
+	Check(WriteSelectivityTable(filepath.Join(outputDir, "selectivity.csv"), scores))
+	plotCrossDockHeatMap(matrix, filepath.Join(outputDir, "heatmap"), "Cross-docking energy (z-score per ligand)", "Receptor", config.Outputs.Plot)
+	Check(provenance.Save(outputDir))
+	fmt.Printf("Docked %d ligands against %d receptors into %s\n", len(matrix.Ligands), len(matrix.Receptors), outputDir)
+}
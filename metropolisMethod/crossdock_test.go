@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+)
+
+func TestCrossDockSelectivity(t *testing.T) {
+	matrix := CrossDockMatrix{
+		Ligands:   []string{"a", "b", "c"},
+		Receptors: []string{"r1", "r2", "r3"},
+		Energies:  [][]float64{{-10, -4, -4}, {-3, -3, -3}, {math.NaN(), -2, -6}},
+	}
+	scores, err := matrix.Selectivity("r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := scores[0]
+	if a.Best != "r1" || a.RunnerUp != "r2" || a.Gap != 6 || math.Abs(a.Score-6/math.Sqrt(8)) > 1e-9 || a.TargetGap != 6 {
+		t.Errorf("Unexpected selectivity of a %+v", a)
+	}
+	if b := scores[1]; b.Gap != 0 || !math.IsNaN(b.Score) || !math.IsNaN(b.ZScore) {
+		t.Errorf("Expected no selectivity in standard deviations for equal energies, got %+v", b)
+	}
+	if c := scores[2]; c.Best != "r3" || c.Gap != 4 || !math.IsNaN(c.TargetEnergy) {
+		t.Errorf("Expected the undocked pair to be skipped, got %+v", c)
+	}
+	if _, err := matrix.Selectivity("r4"); err == nil {
+		t.Errorf("Expected an error for an unknown target")
+	}
+	if z := matrix.ZScores(); math.Abs(z[0][0]+math.Sqrt(2)) > 1e-9 || !math.IsNaN(z[2][0]) {
+		t.Errorf("Unexpected z-scores %v", z)
+	}
+}
+
+func TestDockMatrixReturnsPairErrors(t *testing.T) {
+	dir := t.TempDir()
+	ligandFile := filepath.Join(dir, "lig1_ligand.mol2")
+	if err := molio.SaveMol2(ligandFile, moltest.Ligand()); err != nil {
+		t.Fatal(err)
+	}
+	// a file in place of the poses folder makes every pose fail to save
+	os.WriteFile(filepath.Join(dir, "poses"), nil, 0644)
+	config := DefaultRunConfig()
+	config.Iterations, config.Seed, config.Parallel.Walkers = 20, 1, 2
+	receptors := []molio.NamedMolecule{{Name: "r1", Molecule: moltest.Protein(0.5, -0.5)}, {Name: "r2", Molecule: moltest.Protein(0.5, -0.5)}}
+	if _, err := DockMatrix(context.Background(), receptors, []string{ligandFile}, config, dir, nil); err == nil {
+		t.Errorf("Expected the error of a pose that cannot be saved")
+	}
+}
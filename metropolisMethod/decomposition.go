@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/energy"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// plotTopResidues draws the interaction energy of the most favourable residues as a bar chart.
+// Input: a slice of ResidueEnergy, a string fileName (without extension), a PlotOptions
+// Output: none (saves a plot)
+func plotTopResidues(residues []energy.ResidueEnergy, fileName string, options PlotOptions) {
+	values := make(plotter.Values, len(residues))
+	labels := make([]string, len(residues))
+	for i, residue := range residues {
+		values[i] = residue.Total
+		labels[i] = residue.Residue
+	}
+	p := plot.New()
+	p.Title.Text = fmt.Sprintf("Top %d contributing residues", len(residues))
+	p.Y.Label.Text = "Protein Ligand Binding Energy"
+	bars, err := plotter.NewBarChart(values, vg.Points(12))
+	Check(err)
+	bars.LineStyle.Color = options.ink()
+	p.Add(bars)
+	p.NominalX(labels...)
+	p.X.Tick.Label.Rotation = options.LabelAngle * math.Pi / 180
+	if options.LabelAngle != 0 {
+		p.X.Tick.Label.XAlign, p.X.Tick.Label.YAlign = draw.XRight, draw.YCenter
+	}
+	savePlot(p, 8*vg.Inch, 4*vg.Inch, fileName, options)
+}
+
+// SaveEnergyDecomposition writes the residue and ligand atom tables, the top-residue bar plot, and PDB files
+// of the receptor (each atom coloured by its residue's energy) and of the ligand (each atom by its own energy).
+// Input: a Molecule protein, a Molecule ligand, an EnergyModel, a string label for the file names, a string outputDir,
+// an int top, a PlotOptions
+// Output: the EnergyDecomposition and an error or nil
+func SaveEnergyDecomposition(protein, ligand molecule.Molecule, model energy.EnergyModel, label, outputDir string, top int, options PlotOptions) (energy.EnergyDecomposition, error) {
+	decomposition := energy.DecomposeEnergy(protein, ligand, model)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return decomposition, err
+	}
+	base := filepath.Join(outputDir, label)
+	write := func(fileName string, fn func(w io.Writer) error) error {
+		file, err := os.Create(fileName)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		writer := bufio.NewWriter(file)
+		if err := fn(writer); err != nil {
+			return err
+		}
+		return writer.Flush()
+	}
+	steps := []struct {
+		fileName string
+		fn       func(w io.Writer) error
+	}{
+		{base + "_residue_energy.csv", func(w io.Writer) error { return energy.WriteResidueEnergies(w, decomposition) }},
+		{base + "_ligand_atom_energy.csv", func(w io.Writer) error { return energy.WriteLigandAtomEnergies(w, ligand, decomposition) }},
+		{base + "_protein_energy.pdb", func(w io.Writer) error {
+			molio.WriteBFactors(w, protein, decomposition.ResidueAtomValues(protein))
+			return nil
+		}},
+		{base + "_ligand_energy.pdb", func(w io.Writer) error {
+			molio.WriteBFactors(w, ligand, decomposition.LigandAtoms)
+			return nil
+		}},
+	}
+	for _, step := range steps {
+		if err := write(step.fileName, step.fn); err != nil {
+			return decomposition, err
+		}
+	}
+	plotTopResidues(decomposition.TopResidues(top), base+"_residue_energy", options)
+	return decomposition, nil
+}
+
+// DecomposeMain is the entry point of the "decompose" command, which splits the energy of a pose by residue,
+// ligand atom and term.
+// Usage: decompose [flags] protein ligand outputDir [top]
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the decomposition files and prints the top residues)
+func DecomposeMain(args []string) {
+	flags := commandFlags("decompose", "protein ligand outputDir [top]",
+		"Splits the binding energy of a pose by receptor residue, ligand atom and energy term, and plots the top\n"+
+			"residues (15 by default). The energy model is read from -config or set by the energy flags of screen.")
+	defaults := DefaultRunConfig()
+	configFile := flags.String("config", "", "run configuration (.json or .toml) whose energy model scores the pose")
+	defaults.AddEnergyFlags(flags)
+	plotOptions := DefaultPlotOptions()
+	plotOptions.AddFlags(flags)
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) < 3 || len(args) > 4 {
+		flags.Usage()
+		return
+	}
+	Check(plotOptions.Validate())
+	config, err := ResolveRunConfig(flags, *configFile, defaults)
+	exitOnConfigError(err)
+	top := 15
+	if len(args) == 4 {
+		top, err = strconv.Atoi(args[3])
+		Check(err)
+	}
+	provenance := NewProvenance("decompose")
+	provenance.SetConfig(config)
+	provenance.Parameter("top", strconv.Itoa(top))
+	provenance.Stage("load inputs")
+	protein, err := molio.LoadReceptor(args[0])
+	warnOrCheck(err)
+	ligand, err := molio.LoadLigand(args[1])
+	warnOrCheck(err)
+	Check(provenance.Input("protein", args[0]))
+	Check(provenance.Input("ligand", args[1]))
+	provenance.Stage("decomposition")
+	label := molio.ExtractFileLabel(args[1])
+	decomposition, err := SaveEnergyDecomposition(protein, ligand, config.Energy, label, args[2], top, plotOptions)
+	Check(err)
+	Check(provenance.Save(args[2]))
+
+	fmt.Printf("Total energy: %.6g\n", decomposition.Total)
+	for t, name := range decomposition.Terms {
+		fmt.Printf("  %s: %.6g\n", name, decomposition.ByTerm[t])
+	}
+	for _, residue := range decomposition.TopResidues(top) {
+		fmt.Printf("%-12s %12.6g\n", residue.Residue, residue.Total)
+	}
+	fmt.Println("Energy decomposition written to", args[2])
+}
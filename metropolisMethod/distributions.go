@@ -0,0 +1,422 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/palette/moreland"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// AxisRange is the lower and upper bound of a plot axis; a range with Min >= Max is fitted to the data
+type AxisRange struct {
+	Min, Max float64
+}
+
+// Auto reports whether the range should be fitted to the data.
+// Input: an AxisRange
+// Output: a bool
+func (r AxisRange) Auto() bool {
+	return r.Min >= r.Max
+}
+
+// String formats the range as "min,max", or "auto".
+// Input: an AxisRange
+// Output: a string
+func (r AxisRange) String() string {
+	if r.Auto() {
+		return "auto"
+	}
+	return fmt.Sprintf("%g,%g", r.Min, r.Max)
+}
+
+// ParseAxisRange parses a range written as "min,max"; an empty string or "auto" fits the range to the data.
+// Input: a string value
+// Output: an AxisRange and an error or nil
+func ParseAxisRange(value string) (AxisRange, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "auto" {
+		return AxisRange{}, nil
+	}
+	parts := strings.Split(value, ",")
+	if len(parts) != 2 {
+		return AxisRange{}, fmt.Errorf("range %q is not min,max", value)
+	}
+	lower, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return AxisRange{}, fmt.Errorf("range %q: %w", value, err)
+	}
+	upper, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return AxisRange{}, fmt.Errorf("range %q: %w", value, err)
+	}
+	if lower >= upper {
+		return AxisRange{}, fmt.Errorf("range %q has min >= max", value)
+	}
+	return AxisRange{Min: lower, Max: upper}, nil
+}
+
+// resolve returns the range itself, or the span of the values when the range is automatic.
+// Input: an AxisRange, a slice of float64 values
+// Output: the float64 lower and upper bound
+func (r AxisRange) resolve(values []float64) (float64, float64) {
+	if !r.Auto() {
+		return r.Min, r.Max
+	}
+	lower, upper := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lower, upper = math.Min(lower, v), math.Max(upper, v)
+	}
+	if len(values) == 0 {
+		return 0, 1
+	}
+	if lower == upper {
+		pad := math.Max(math.Abs(lower)*0.05, 0.5)
+		return lower - pad, upper + pad
+	}
+	return lower, upper
+}
+
+// DistributionOptions configures the distribution and landscape plots of a sampled ensemble
+type DistributionOptions struct {
+	Bins          int     // bins of the energy histogram
+	GridBins      int     // bins per axis of the landscape heatmaps
+	Bandwidth     float64 // bandwidth of the kernel density estimate, 0 for Silverman's rule
+	EnergyRange   AxisRange
+	DistanceRange AxisRange // ligand centroid distance to the pocket centre, in Å
+	AngleRange    AxisRange // orientation angle relative to the start, in degrees
+}
+
+// DefaultDistributionOptions returns 40 histogram bins, a 30x30 landscape grid, Silverman's bandwidth, fitted
+// energy and distance ranges and an orientation range of 0 to 180 degrees.
+// Input: none
+// Output: a DistributionOptions
+func DefaultDistributionOptions() DistributionOptions {
+	return DistributionOptions{Bins: 40, GridBins: 30, AngleRange: AxisRange{Min: 0, Max: 180}}
+}
+
+// binIndex returns the bin of value in [lower, upper) split into n bins, or -1 when it is outside;
+// the upper bound itself falls in the last bin.
+// Input: a float64 value, float64 lower and upper bounds, an int n
+// Output: an int bin index
+func binIndex(value, lower, upper float64, n int) int {
+	if value < lower || value > upper || math.IsNaN(value) {
+		return -1
+	}
+	i := int((value - lower) / (upper - lower) * float64(n))
+	if i == n {
+		i--
+	}
+	return i
+}
+
+// HistogramDensity bins values into n bins between lower and upper, normalised so the bars integrate to 1
+// over the samples inside the range.
+// Input: a slice of float64 values, an int n, float64 lower and upper bounds
+// Output: a slice of plotter.HistogramBin
+func HistogramDensity(values []float64, n int, lower, upper float64) []plotter.HistogramBin {
+	width := (upper - lower) / float64(n)
+	bins := make([]plotter.HistogramBin, n)
+	for i := range bins {
+		bins[i].Min = lower + float64(i)*width
+		bins[i].Max = lower + float64(i+1)*width
+	}
+	inside := 0
+	for _, v := range values {
+		if i := binIndex(v, lower, upper, n); i >= 0 {
+			bins[i].Weight++
+			inside++
+		}
+	}
+	if inside > 0 {
+		for i := range bins {
+			bins[i].Weight /= float64(inside) * width
+		}
+	}
+	return bins
+}
+
+// SilvermanBandwidth returns Silverman's rule-of-thumb bandwidth for a Gaussian kernel density estimate.
+// Input: a slice of float64 values
+// Output: a float64 bandwidth (0 when the values have no spread)
+func SilvermanBandwidth(values []float64) float64 {
+	n := len(values)
+	if n < 2 {
+		return 0
+	}
+	mean := analysis.Mean(values)
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	spread := math.Sqrt(variance / float64(n-1))
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	if iqr := (analysis.Percentile(sorted, 0.75) - analysis.Percentile(sorted, 0.25)) / 1.34; iqr > 0 {
+		spread = math.Min(spread, iqr)
+	}
+	return 0.9 * spread * math.Pow(float64(n), -0.2)
+}
+
+// KernelDensity evaluates the Gaussian kernel density estimate of the values at x.
+// Input: a slice of float64 values, a float64 bandwidth, a float64 x
+// Output: a float64 density
+func KernelDensity(values []float64, bandwidth, x float64) float64 {
+	if len(values) == 0 || bandwidth <= 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		u := (x - v) / bandwidth
+		sum += math.Exp(-u * u / 2)
+	}
+	return sum / (float64(len(values)) * bandwidth * math.Sqrt(2*math.Pi))
+}
+
+// landscapeGrid is a 2D histogram of samples implementing plotter.GridXYZ
+type landscapeGrid struct {
+	xLower, xWidth float64
+	yLower, yWidth float64
+	values         [][]float64 // values[column][row]
+}
+
+// Dims returns the number of columns and rows of the grid
+func (g landscapeGrid) Dims() (int, int) { return len(g.values), len(g.values[0]) }
+
+// Z returns the value of the cell in column c and row r
+func (g landscapeGrid) Z(c, r int) float64 { return g.values[c][r] }
+
+// X returns the centre of column c
+func (g landscapeGrid) X(c int) float64 { return g.xLower + (float64(c)+0.5)*g.xWidth }
+
+// Y returns the centre of row r
+func (g landscapeGrid) Y(r int) float64 { return g.yLower + (float64(r)+0.5)*g.yWidth }
+
+// Landscape bins the samples (x[i], y[i]) on an n by n grid and returns the number of samples in each cell and
+// the mean of weights over the samples of each cell. Empty cells are NaN in both, so they stay blank in a heatmap.
+// Input: slices of float64 x, y and weights, an int n, AxisRange xRange and yRange
+// Output: two landscapeGrid values, counts and mean weights
+func Landscape(x, y, weights []float64, n int, xRange, yRange AxisRange) (landscapeGrid, landscapeGrid) {
+	xLower, xUpper := xRange.resolve(x)
+	yLower, yUpper := yRange.resolve(y)
+	counts := landscapeGrid{xLower: xLower, xWidth: (xUpper - xLower) / float64(n), yLower: yLower, yWidth: (yUpper - yLower) / float64(n)}
+	means := counts
+	counts.values, means.values = make([][]float64, n), make([][]float64, n)
+	for c := 0; c < n; c++ {
+		counts.values[c], means.values[c] = make([]float64, n), make([]float64, n)
+	}
+	for i := range x {
+		c, r := binIndex(x[i], xLower, xUpper, n), binIndex(y[i], yLower, yUpper, n)
+		if c < 0 || r < 0 {
+			continue
+		}
+		counts.values[c][r]++
+		means.values[c][r] += weights[i]
+	}
+	for c := range means.values {
+		for r := range means.values[c] {
+			if counts.values[c][r] == 0 {
+				counts.values[c][r], means.values[c][r] = math.NaN(), math.NaN()
+			} else {
+				means.values[c][r] /= counts.values[c][r]
+			}
+		}
+	}
+	return counts, means
+}
+
+// plotHistogram draws the normalised histogram of values.
+// Input: a slice of float64 values, an int number of bins, an AxisRange, a string fileName (without extension),
+// a string title, a string xLabel, a PlotOptions
+// Output: none (saves a plot)
+func plotHistogram(values []float64, bins int, axis AxisRange, fileName, title, xLabel string, options PlotOptions) {
+	lower, upper := axis.resolve(values)
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = xLabel
+	p.Y.Label.Text = "Density"
+	p.Add(&plotter.Histogram{
+		Bins:      HistogramDensity(values, bins, lower, upper),
+		Width:     (upper - lower) / float64(bins),
+		FillColor: color.Gray{Y: 128},
+		LineStyle: draw.LineStyle{Color: options.ink(), Width: plotter.DefaultLineStyle.Width},
+	})
+	p.X.Min, p.X.Max = lower, upper
+	savePlot(p, 6*vg.Inch, 4*vg.Inch, fileName, options)
+}
+
+// plotDensity draws the Gaussian kernel density estimate of values.
+// Input: a slice of float64 values, a float64 bandwidth (0 for Silverman's rule), an AxisRange,
+// a string fileName (without extension), a string title, a string xLabel, a PlotOptions
+// Output: none (saves a plot)
+func plotDensity(values []float64, bandwidth float64, axis AxisRange, fileName, title, xLabel string, options PlotOptions) {
+	if bandwidth <= 0 {
+		bandwidth = SilvermanBandwidth(values)
+	}
+	lower, upper := axis.resolve(values)
+	if bandwidth <= 0 {
+		bandwidth = (upper - lower) / 20
+	}
+	const points = 200
+	curve := make(plotter.XYs, points)
+	for i := range curve {
+		curve[i].X = lower + (upper-lower)*float64(i)/(points-1)
+		curve[i].Y = KernelDensity(values, bandwidth, curve[i].X)
+	}
+	p := plot.New()
+	p.Title.Text = fmt.Sprintf("%s (bandwidth %.3g)", title, bandwidth)
+	p.X.Label.Text = xLabel
+	p.Y.Label.Text = "Density"
+	line, err := plotter.NewLine(curve)
+	Check(err)
+	line.Width = vg.Points(1.5)
+	line.Color = options.ink()
+	p.Add(line)
+	savePlot(p, 6*vg.Inch, 4*vg.Inch, fileName, options)
+}
+
+// plotHeatMap draws a landscape grid with a colour bar of its values to the right.
+// Input: a landscapeGrid, a string fileName (without extension), a string title, strings xLabel and yLabel, a PlotOptions
+// Output: none (saves a plot)
+func plotHeatMap(grid landscapeGrid, fileName, title, xLabel, yLabel string, options PlotOptions) {
+	p, colorMap := newHeatMap(grid, title, xLabel, yLabel, options)
+	saveHeatMap(p, colorMap, 7*vg.Inch, 4*vg.Inch, fileName, options)
+}
+
+// newHeatMap returns the plot of a grid as a heatmap, and its colour map spanning the values of the grid.
+// Input: a plotter.GridXYZ grid, a string title, strings xLabel and yLabel, a PlotOptions
+// Output: a *plot.Plot and a palette.ColorMap
+func newHeatMap(grid plotter.GridXYZ, title, xLabel, yLabel string, options PlotOptions) (*plot.Plot, palette.ColorMap) {
+	colorMap := moreland.ExtendedBlackBody()
+	heat := plotter.NewHeatMap(grid, colorMap.Palette(64))
+	heat.NaN = color.Transparent
+	if heat.Min >= heat.Max {
+		heat.Max = heat.Min + 1
+	}
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = xLabel
+	p.Y.Label.Text = yLabel
+	p.X.Padding, p.Y.Padding = 0, 0
+	p.Add(heat)
+	options.apply(p)
+	colorMap.SetMin(heat.Min)
+	colorMap.SetMax(heat.Max)
+	return p, colorMap
+}
+
+// saveHeatMap saves a heatmap plot with a colour bar of its colour map to the right.
+// Input: a *plot.Plot p, a palette.ColorMap, the default vg.Length width and height, a string fileName (without
+// extension), a PlotOptions
+// Output: none (saves a plot)
+func saveHeatMap(p *plot.Plot, colorMap palette.ColorMap, width, height vg.Length, fileName string, options PlotOptions) {
+	bar := plot.New()
+	bar.Add(&plotter.ColorBar{ColorMap: colorMap, Vertical: true})
+	bar.HideX()
+	bar.Y.Padding = 0
+	bar.Title.Text = " "
+	// the colour bar only takes the theme and font size of the options
+	barOptions := DefaultPlotOptions()
+	barOptions.Theme, barOptions.FontSize = options.Theme, options.FontSize
+	barOptions.apply(bar)
+
+	Check(saveDrawing(func(canvas draw.Canvas) {
+		canvasWidth := canvas.Max.X - canvas.Min.X
+		canvas.SetColor(p.BackgroundColor)
+		canvas.Fill(canvas.Rectangle.Path())
+		p.Draw(draw.Crop(canvas, 0, -vg.Inch, 0, 0))
+		bar.Draw(draw.Crop(canvas, canvasWidth-0.9*vg.Inch, -0.2*vg.Inch, p.X.Label.TextStyle.Height(p.X.Label.Text)+0.3*vg.Inch, 0))
+	}, width, height, fileName, options))
+}
+
+// ensembleSamples pools the samples of all walkers.
+// Input: a slice of WalkerTrace
+// Output: slices of float64 energies, pocket distances and orientation angles
+func ensembleSamples(walkers []sampling.WalkerTrace) ([]float64, []float64, []float64) {
+	var energies, distances, angles []float64
+	for _, walker := range walkers {
+		energies = append(energies, walker.Energy...)
+		distances = append(distances, walker.PocketDistance...)
+		angles = append(angles, walker.Orientation...)
+	}
+	return energies, distances, angles
+}
+
+// SaveSampleDistributions plots the sampled ensemble of a simulation: a histogram and a kernel density estimate
+// of the energies, and heatmaps of the sample count and mean energy over the ligand centroid distance to the
+// pocket centre and the orientation angle relative to the start.
+// Input: a slice of WalkerTrace, a string baseName (the plots are baseName-<plot>), a DistributionOptions, a PlotOptions
+// Output: an error or nil
+func SaveSampleDistributions(walkers []sampling.WalkerTrace, baseName string, options DistributionOptions, plotOptions PlotOptions) error {
+	if options.Bins < 1 || options.GridBins < 1 {
+		return fmt.Errorf("bins and grid bins must be at least 1, got %d and %d", options.Bins, options.GridBins)
+	}
+	energies, distances, angles := ensembleSamples(walkers)
+	if len(energies) == 0 {
+		return fmt.Errorf("no samples for %s", baseName)
+	}
+	energyLabel := "Protein Ligand Binding Energy"
+	distanceLabel := "Centroid distance to pocket centre (Å)"
+	angleLabel := "Orientation angle from start (°)"
+	plotHistogram(energies, options.Bins, options.EnergyRange, baseName+"-energy-histogram", "Sampled energies", energyLabel, plotOptions)
+	plotDensity(energies, options.Bandwidth, options.EnergyRange, baseName+"-energy-density", "Sampled energy density", energyLabel, plotOptions)
+	counts, means := Landscape(distances, angles, energies, options.GridBins, options.DistanceRange, options.AngleRange)
+	plotHeatMap(counts, baseName+"-landscape-samples", "Samples per cell", distanceLabel, angleLabel, plotOptions)
+	plotHeatMap(means, baseName+"-landscape-energy", "Mean energy per cell", distanceLabel, angleLabel, plotOptions)
+	return nil
+}
+
+// LandscapeMain is the entry point of the "landscape" command, which plots the sampled ensemble of trace CSV
+// files written by a simulation.
+// Usage: landscape [flags] trace.csv [more traces] outputDir
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the plots of each trace)
+func LandscapeMain(args []string) {
+	options := DefaultDistributionOptions()
+	flags := commandFlags("landscape", "trace.csv [more traces] outputDir",
+		"Plots the sampled ensemble of each trace CSV written by screen: the energy histogram and density, and the\n"+
+			"samples and mean energy over pocket distance and orientation.")
+	flags.IntVar(&options.Bins, "bins", options.Bins, "bins of the energy histogram")
+	flags.IntVar(&options.GridBins, "grid", options.GridBins, "bins per axis of the landscape heatmaps")
+	flags.Float64Var(&options.Bandwidth, "bandwidth", options.Bandwidth, "kernel density bandwidth, 0 for Silverman's rule")
+	energyRange := flags.String("energy-range", options.EnergyRange.String(), "energy axis as min,max or auto")
+	distanceRange := flags.String("distance-range", options.DistanceRange.String(), "pocket distance axis in Å as min,max or auto")
+	angleRange := flags.String("angle-range", options.AngleRange.String(), "orientation axis in degrees as min,max or auto")
+	plotOptions := DefaultPlotOptions()
+	plotOptions.AddFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return
+	}
+	Check(plotOptions.Validate())
+	var err error
+	for _, axis := range []struct {
+		value  string
+		target *AxisRange
+	}{{*energyRange, &options.EnergyRange}, {*distanceRange, &options.DistanceRange}, {*angleRange, &options.AngleRange}} {
+		*axis.target, err = ParseAxisRange(axis.value)
+		Check(err)
+	}
+
+	outputDir := flags.Arg(flags.NArg() - 1)
+	Check(os.MkdirAll(outputDir, 0755))
+	for _, traceFile := range flags.Args()[:flags.NArg()-1] {
+		walkers, err := sampling.ReadTraceCSV(traceFile)
+		Check(err)
+		baseName := filepath.Join(outputDir, strings.TrimSuffix(filepath.Base(traceFile), filepath.Ext(traceFile)))
+		Check(SaveSampleDistributions(walkers, baseName, options, plotOptions))
+	}
+}
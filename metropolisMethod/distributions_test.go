@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+)
+
+func TestHistogramDensity(t *testing.T) {
+	values := []float64{0, 0.5, 1, 1.5, 2, 2, 7}
+	bins := HistogramDensity(values, 4, 0, 2)
+	area := 0.0
+	for _, bin := range bins {
+		area += bin.Weight * (bin.Max - bin.Min)
+	}
+	if math.Abs(area-1) > 1e-12 || bins[3].Weight != 3*bins[0].Weight {
+		t.Errorf("Unexpected histogram %v with area %g", bins, area)
+	}
+	if _, err := ParseAxisRange("3,1"); err == nil {
+		t.Errorf("Expected an error for a reversed range")
+	}
+}
+
+func TestKernelDensity(t *testing.T) {
+	values := []float64{-1, 0, 0, 1, 2}
+	bandwidth := SilvermanBandwidth(values)
+	area := 0.0
+	for x := -10.0; x < 10; x += 0.01 {
+		area += KernelDensity(values, bandwidth, x) * 0.01
+	}
+	if bandwidth <= 0 || math.Abs(area-1) > 1e-3 {
+		t.Errorf("Expected a density integrating to 1, got %g with bandwidth %g", area, bandwidth)
+	}
+}
+
+func TestLandscape(t *testing.T) {
+	x := []float64{0.5, 0.6, 1.5, 5}
+	y := []float64{10, 20, 100, 100}
+	energies := []float64{-2, -4, 1, 3}
+	counts, means := Landscape(x, y, energies, 2, AxisRange{Min: 0, Max: 2}, AxisRange{Min: 0, Max: 180})
+	if counts.Z(0, 0) != 2 || means.Z(0, 0) != -3 || counts.Z(1, 1) != 1 || !math.IsNaN(counts.Z(0, 1)) {
+		t.Errorf("Unexpected landscape counts %v and means %v", counts.values, means.values)
+	}
+}
+
+func TestOrientationAngle(t *testing.T) {
+	start := molecule.Positions(moltest.Acetate())
+	rotated := make([]molecule.Position3d, len(start))
+	for i, p := range start {
+		rotated[i] = molecule.RotateAtom(p, molecule.Position3d{Z: 1}, math.Pi/2).Add(molecule.Position3d{X: 3})
+	}
+	if angle := sampling.OrientationAngle(start, rotated); math.Abs(angle-90) > 1e-6 {
+		t.Errorf("Expected 90 degrees, got %g", angle)
+	}
+}
@@ -0,0 +1,5 @@
+// Package docking holds the engine settings of a docking run: iterations, seed, energy model, move set,
+// temperature schedule, walkers, flexible side chains and restraints. Settings.DockingSimulation turns them into
+// the sampling.Simulation that docks ligands against one receptor, so programs embedding the engine build the same
+// simulation as the commands of metropolisMethod.
+package docking
@@ -0,0 +1,172 @@
+package docking
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"slices"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/energy"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+)
+
+// ParallelConfig sets how a run is spread over goroutines
+type ParallelConfig struct {
+	Walkers int `json:"walkers" toml:"walkers"`
+}
+
+// FlexibleConfig selects the receptor residues whose side chains turn during a screen
+type FlexibleConfig struct {
+	Residues []string `json:"residues" toml:"residues"` // residues such as "A:TYR45", "A:45" or "45"
+	Distance float64  `json:"distance" toml:"distance"` // also every residue with a side-chain atom this close in Å to a ligand, 0 for none
+	Fraction float64  `json:"fraction" toml:"fraction"` // share of the moves that turn a side chain
+}
+
+// Enabled reports whether any residue is made flexible.
+// Input: a FlexibleConfig
+// Output: a bool
+func (flexible FlexibleConfig) Enabled() bool {
+	return len(flexible.Residues) > 0 || flexible.Distance > 0
+}
+
+// Settings are the engine parameters of a docking run, with the keys they have in a run configuration file
+type Settings struct {
+	Iterations  int                          `json:"iterations" toml:"iterations"` // iterations per ligand, split over the walkers
+	Seed        int64                        `json:"seed" toml:"seed"`             // 0 draws a seed, which the resolved config records
+	Energy      energy.EnergyModel           `json:"energy" toml:"energy"`
+	Moves       sampling.MoveSet             `json:"moves" toml:"moves"`
+	Temperature sampling.TemperatureSchedule `json:"temperature" toml:"temperature"`
+	Parallel    ParallelConfig               `json:"parallel" toml:"parallel"`
+	Flexible    FlexibleConfig               `json:"flexible" toml:"flexible"`
+	Restraints  []sampling.Restraint         `json:"restraints" toml:"restraints"` // penalties guiding the screen, reported apart from the energy
+}
+
+// DefaultSettings returns 3000 iterations with rotations at body temperature on every CPU, with a rigid receptor
+// and no restraints.
+// Input: none
+// Output: a Settings
+func DefaultSettings() Settings {
+	return Settings{
+		Iterations:  3000,
+		Energy:      energy.DefaultEnergyModel(),
+		Moves:       sampling.DefaultMoveSet(true),
+		Temperature: sampling.ConstantTemperature(sampling.TEMPERATURE),
+		Parallel:    ParallelConfig{Walkers: runtime.NumCPU()},
+		Flexible:    FlexibleConfig{Fraction: sampling.SIDECHAINFRACTION},
+	}
+}
+
+// Problems checks every setting and lists what is wrong with each, prefixed by its configuration key.
+// Input: a Settings
+// Output: a slice of strings, empty when the settings are valid
+func (settings Settings) Problems() []string {
+	var problems []string
+	add := func(key string, err error) {
+		if err != nil {
+			problems = append(problems, key+": "+err.Error())
+		}
+	}
+	check := func(key string, ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, key+": "+fmt.Sprintf(format, args...))
+		}
+	}
+	check("iterations", settings.Iterations >= 1, "must be at least 1, got %d", settings.Iterations)
+	add("energy", settings.Energy.Validate())
+	check("moves.step_size", settings.Moves.StepSize > 0, "must be positive, got %v", settings.Moves.StepSize)
+	check("moves.max_angle", !settings.Moves.Rotate || (settings.Moves.MaxAngle > 0 && settings.Moves.MaxAngle <= math.Pi),
+		"must be between 0 and π radians when rotating, got %v", settings.Moves.MaxAngle)
+	check("moves.min_distance", settings.Moves.MinDistance >= 0, "must not be negative, got %v", settings.Moves.MinDistance)
+	add("temperature", settings.Temperature.Validate())
+	check("parallel.walkers", settings.Parallel.Walkers >= 1, "must be at least 1, got %d", settings.Parallel.Walkers)
+	check("parallel.walkers", settings.Iterations < 1 || settings.Parallel.Walkers <= settings.Iterations, "must not exceed the %d iterations", settings.Iterations)
+	check("flexible.distance", settings.Flexible.Distance >= 0, "must not be negative, got %v", settings.Flexible.Distance)
+	check("flexible.fraction", settings.Flexible.Fraction > 0 && settings.Flexible.Fraction < 1, "must be between 0 and 1, got %v", settings.Flexible.Fraction)
+	for i, restraint := range settings.Restraints {
+		add(fmt.Sprintf("restraints[%d]", i), restraint.Validate())
+	}
+	return problems
+}
+
+// Simulation returns the engine parameters of the run, with a rigid receptor and no restraints.
+// Input: a Settings
+// Output: a Simulation
+func (settings Settings) Simulation() sampling.Simulation {
+	return sampling.Simulation{
+		Iterations: settings.Iterations,
+		Walkers:    settings.Parallel.Walkers,
+		Moves:      settings.Moves,
+		Schedule:   settings.Temperature,
+		Energy:     settings.Energy,
+		Seed:       settings.Seed,
+	}
+}
+
+// SideChains returns the flexible side chains of the run: the residues of Flexible.Residues and those of the
+// binding site within Flexible.Distance of the ligands, or nil when the receptor is rigid.
+// Input: a Settings, a Molecule receptor, a slice of Molecule ligands
+// Output: a *SideChains and an error when a residue is not found or cannot turn
+func (settings Settings) SideChains(receptor molecule.Molecule, ligands []molecule.Molecule) (*sampling.SideChains, error) {
+	if !settings.Flexible.Enabled() {
+		return nil, nil
+	}
+	all := molecule.SplitResidues(receptor)
+	var residues []molecule.Residue
+	for _, spec := range settings.Flexible.Residues {
+		residue, err := molecule.FindResidue(all, spec)
+		if err != nil {
+			return nil, fmt.Errorf("flexible.residues: %w", err)
+		}
+		residues = append(residues, residue)
+	}
+	if settings.Flexible.Distance > 0 {
+		residues = append(residues, sampling.BindingSiteResidues(receptor, ligands, settings.Flexible.Distance)...)
+	}
+	sideChains, err := sampling.NewSideChains(receptor, residues, settings.Flexible.Fraction)
+	if err != nil {
+		return nil, fmt.Errorf("flexible: %w", err)
+	}
+	return sideChains, nil
+}
+
+// DockingSimulation returns the engine parameters of the run for docking ligands against one receptor: Simulation
+// with the flexible side chains of the receptor and the restraints checked against it and every ligand. Every
+// docking command builds its simulation here, so none of them drops the flexible residues or restraints of a
+// configuration.
+// Input: a Settings, a Molecule receptor, a slice of Molecule ligands
+// Output: a Simulation and the error of SideChains or LoadRestraints
+func (settings Settings) DockingSimulation(receptor molecule.Molecule, ligands []molecule.Molecule) (sampling.Simulation, error) {
+	sim := settings.Simulation()
+	var err error
+	if sim.Flexible, err = settings.SideChains(receptor, ligands); err != nil {
+		return sim, err
+	}
+	sim.Restraints, err = settings.LoadRestraints(receptor, ligands)
+	return sim, err
+}
+
+// LoadRestraints returns the restraints of the run with the reference files of position restraints read, and
+// checks that the atoms of every restraint are found in the receptor and each ligand before the run starts.
+// Input: a Settings, a Molecule receptor, a slice of Molecule ligands
+// Output: a slice of Restraints and an error naming the ligand, the restraint and the file or atom at fault
+func (settings Settings) LoadRestraints(receptor molecule.Molecule, ligands []molecule.Molecule) ([]sampling.Restraint, error) {
+	restraints := slices.Clone(settings.Restraints)
+	for i := range restraints {
+		if restraints[i].Reference == "" {
+			continue
+		}
+		reference, err := molio.LoadMolecule(restraints[i].Reference)
+		if err != nil && !molio.IsWarning(err) {
+			return nil, fmt.Errorf("restraints[%d].reference: %w", i, err)
+		}
+		restraints[i].ReferenceMolecule = reference
+	}
+	for i, ligand := range ligands {
+		if _, err := sampling.NewRestraintSet(restraints, receptor, ligand); err != nil {
+			return nil, fmt.Errorf("restraints: ligand %d: %w", i+1, err)
+		}
+	}
+	return restraints, nil
+}
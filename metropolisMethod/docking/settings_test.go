@@ -0,0 +1,42 @@
+package docking
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+)
+
+func TestDockingSimulation(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Iterations = 200
+	settings.Flexible.Residues = []string{"A:45", "A:SER45"}
+	sideChains, err := settings.SideChains(moltest.Serine(), nil)
+	if err != nil || len(sideChains.Residues) != 1 || sideChains.Fraction != sampling.SIDECHAINFRACTION {
+		t.Errorf("Expected residues %v to make serine 45 flexible once, got %v and %v", settings.Flexible.Residues, sideChains, err)
+	}
+	settings.Flexible.Residues = []string{"A:46"}
+	if _, err := settings.SideChains(moltest.Serine(), nil); err == nil || !strings.Contains(err.Error(), "flexible.residues") {
+		t.Errorf("Expected a missing residue to be reported, got %v", err)
+	}
+	settings.Flexible.Residues = []string{"A:45"}
+	settings.Restraints = []sampling.Restraint{{Kind: "position", Form: "harmonic", Atoms: []string{"ligand"}, Point: []float64{0, 0, 0}, Weight: 1}}
+	sim, err := settings.DockingSimulation(moltest.Serine(), []molecule.Molecule{moltest.Ligand()})
+	if err != nil || sim.Iterations != 200 || sim.Flexible == nil || len(sim.Restraints) != 1 {
+		t.Errorf("Expected the docking simulation to carry the side chains and restraints, got %+v and %v", sim, err)
+	}
+
+	settings.Iterations = 0
+	settings.Flexible.Fraction = 1
+	problems := strings.Join(settings.Problems(), "\n")
+	for _, key := range []string{"iterations:", "flexible.fraction:"} {
+		if !strings.Contains(problems, key) {
+			t.Errorf("Expected a problem with %s, got %q", key, problems)
+		}
+	}
+	if problems := DefaultSettings().Problems(); len(problems) != 0 {
+		t.Errorf("Expected the default settings to be valid, got %v", problems)
+	}
+}
@@ -0,0 +1,142 @@
+package energy
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// EnergyTerm is one pairwise term of the scoring function
+type EnergyTerm struct {
+	Name string
+	Pair func(protein, ligand molecule.Atom, distance float64) float64
+}
+
+// Terms lists the terms that the model sums; the decomposition reports each one separately.
+// Input: an EnergyModel
+// Output: a slice of EnergyTerm
+func (model EnergyModel) Terms() []EnergyTerm {
+	return []EnergyTerm{{Name: model.Model, Pair: model.PairEnergy}}
+}
+
+// ResidueEnergy is the interaction energy of one receptor residue with the ligand
+type ResidueEnergy struct {
+	Residue string
+	Total   float64
+	ByTerm  []float64 // in the order of the model's Terms
+}
+
+// EnergyDecomposition splits a protein–ligand energy by residue, receptor atom, ligand atom and term
+type EnergyDecomposition struct {
+	Terms        []string
+	Total        float64
+	ByTerm       []float64
+	Residues     []ResidueEnergy // in receptor order
+	ProteinAtoms []float64
+	LigandAtoms  []float64
+}
+
+// DecomposeEnergy computes every pairwise term between the protein and ligand and accumulates it per
+// receptor residue, receptor atom, ligand atom and term. The totals add up to the model's Energy.
+// Input: a Molecule protein, a Molecule ligand, an EnergyModel
+// Output: an EnergyDecomposition
+func DecomposeEnergy(protein, ligand molecule.Molecule, model EnergyModel) EnergyDecomposition {
+	terms := model.Terms()
+	residues := molecule.SplitResidues(protein)
+	residueOf := molecule.ResidueIndexOf(protein)
+	decomposition := EnergyDecomposition{
+		Terms:        make([]string, len(terms)),
+		ByTerm:       make([]float64, len(terms)),
+		Residues:     make([]ResidueEnergy, len(residues)),
+		ProteinAtoms: make([]float64, len(protein.Atoms)),
+		LigandAtoms:  make([]float64, len(ligand.Atoms)),
+	}
+	for t, term := range terms {
+		decomposition.Terms[t] = term.Name
+	}
+	for r, residue := range residues {
+		decomposition.Residues[r] = ResidueEnergy{Residue: residue.ID(), ByTerm: make([]float64, len(terms))}
+	}
+	for p, atomP := range protein.Atoms {
+		residue := &decomposition.Residues[residueOf[p]]
+		for l, atomL := range ligand.Atoms {
+			d := molecule.Distance(atomP.Position, atomL.Position)
+			for t, term := range terms {
+				e := term.Pair(atomP, atomL, d)
+				decomposition.Total += e
+				decomposition.ByTerm[t] += e
+				residue.Total += e
+				residue.ByTerm[t] += e
+				decomposition.ProteinAtoms[p] += e
+				decomposition.LigandAtoms[l] += e
+			}
+		}
+	}
+	return decomposition
+}
+
+// TopResidues returns the n residues with the most favourable (lowest) interaction energy.
+// Input: an EnergyDecomposition, an int n
+// Output: a slice of ResidueEnergy sorted from most to least favourable
+func (d EnergyDecomposition) TopResidues(n int) []ResidueEnergy {
+	sorted := append([]ResidueEnergy(nil), d.Residues...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Total < sorted[j].Total })
+	if n > 0 && n < len(sorted) {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// ResidueAtomValues spreads each residue's total over its atoms, for colouring whole residues by B-factor.
+// Input: a Molecule protein, an EnergyDecomposition of it
+// Output: a slice of float64 values, one per protein atom
+func (d EnergyDecomposition) ResidueAtomValues(protein molecule.Molecule) []float64 {
+	values := make([]float64, len(protein.Atoms))
+	for i, r := range molecule.ResidueIndexOf(protein) {
+		values[i] = d.Residues[r].Total
+	}
+	return values
+}
+
+// WriteResidueEnergies writes the per-residue energies, one column per term, sorted from most favourable.
+// Input: an io.Writer w, an EnergyDecomposition
+// Output: an error or nil
+func WriteResidueEnergies(w io.Writer, d EnergyDecomposition) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append([]string{"residue", "total"}, d.Terms...)); err != nil {
+		return err
+	}
+	for _, residue := range d.TopResidues(0) {
+		row := []string{residue.Residue, strconv.FormatFloat(residue.Total, 'g', 8, 64)}
+		for _, value := range residue.ByTerm {
+			row = append(row, strconv.FormatFloat(value, 'g', 8, 64))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteLigandAtomEnergies writes the energy of every ligand atom with the whole receptor.
+// Input: an io.Writer w, a Molecule ligand, an EnergyDecomposition
+// Output: an error or nil
+func WriteLigandAtomEnergies(w io.Writer, ligand molecule.Molecule, d EnergyDecomposition) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"index", "atom", "element", "charge", "energy"}); err != nil {
+		return err
+	}
+	for i, atom := range ligand.Atoms {
+		row := []string{strconv.Itoa(i + 1), atom.Name, atom.Element, strconv.FormatFloat(atom.Charge, 'f', 4, 64),
+			strconv.FormatFloat(d.LigandAtoms[i], 'g', 8, 64)}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,38 @@
+package energy
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+)
+
+func TestDecomposeEnergy(t *testing.T) {
+	receptor := moltest.Pocket()
+	receptor.Atoms[1].Charge = 0.8
+	receptor.Atoms[3].Charge = -0.1
+	ligand := moltest.Benzoate()
+	ligand.Atoms[7].Charge, ligand.Atoms[8].Charge = -0.5, -0.5
+
+	decomposition := DecomposeEnergy(receptor, ligand, DefaultEnergyModel())
+	expected := CalculateEnergy(receptor, ligand)
+	if math.Abs(decomposition.Total-expected) > 1e-9*math.Abs(expected) {
+		t.Errorf("Expected total %g, got %g", expected, decomposition.Total)
+	}
+	sum := 0.0
+	for _, residue := range decomposition.Residues {
+		sum += residue.Total
+	}
+	if math.Abs(sum-expected) > 1e-9*math.Abs(expected) {
+		t.Errorf("Residue energies add up to %g, expected %g", sum, expected)
+	}
+	if top := decomposition.TopResidues(1); len(top) != 1 || top[0].Residue != "A:LYS10" {
+		t.Errorf("Expected A:LYS10 as the top residue, got %v", top)
+	}
+
+	model := EnergyModel{Model: "coulomb", Constant: 332, Dielectric: "distance", Cutoff: 4}
+	expected = model.Energy(receptor, ligand)
+	if total := DecomposeEnergy(receptor, ligand, model).Total; math.Abs(total-expected) > 1e-9*math.Abs(expected) {
+		t.Errorf("Expected the distance-dependent model with a cutoff to give %g, got %g", expected, total)
+	}
+}
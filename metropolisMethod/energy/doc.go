@@ -0,0 +1,3 @@
+// Package energy scores protein–ligand poses: the Coulomb energy of the original engine, configurable energy models
+// with a dielectric and cutoff, and the decomposition of a binding energy by residue, ligand atom and term.
+package energy
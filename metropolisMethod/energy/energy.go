@@ -0,0 +1,121 @@
+package energy
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// universal constant
+const K = 9e9
+
+// CalculateEnergy computes the electrostatic potential energy between the protein and ligand atoms using Coulomb's law.
+// Input: a Molecule protein, a Molecule ligand
+// Output: a float64 energy value
+func CalculateEnergy(protein, ligand molecule.Molecule) float64 {
+	energy := 0.0
+	for _, atomP := range protein.Atoms {
+		for _, atomL := range ligand.Atoms {
+			atomPPos := atomP.Position
+			atomLPos := atomL.Position
+			distance := math.Sqrt(math.Pow(atomPPos.X-atomLPos.X, 2) + math.Pow(atomPPos.Y-atomLPos.Y, 2) + math.Pow(atomPPos.Z-atomLPos.Z, 2))
+			// to ensure non-zero
+			if distance < 1e-6 {
+				distance = 1e-6
+			}
+			energy += K * (atomP.Charge * atomL.Charge) / distance //kQ1,Q2/d^2
+		}
+	}
+	return energy
+}
+
+// EnergyModels and DielectricModels are the supported energy functions and dielectric models
+var (
+	EnergyModels     = []string{"coulomb"}
+	DielectricModels = []string{"constant", "distance"}
+)
+
+// EnergyModel is the protein–ligand scoring function of a simulation
+type EnergyModel struct {
+	Model      string  `json:"model" toml:"model"`           // "coulomb"
+	Constant   float64 `json:"constant" toml:"constant"`     // Coulomb constant
+	Dielectric string  `json:"dielectric" toml:"dielectric"` // "constant", or "distance" to divide by r once more
+	Cutoff     float64 `json:"cutoff" toml:"cutoff"`         // pairs farther apart in Å are ignored, 0 for no cutoff
+}
+
+// DefaultEnergyModel returns the scoring of CalculateEnergy: Coulomb's law with constant K and no cutoff.
+// Input: none
+// Output: an EnergyModel
+func DefaultEnergyModel() EnergyModel {
+	return EnergyModel{Model: "coulomb", Constant: K, Dielectric: "constant"}
+}
+
+// Energy computes the protein–ligand energy with the model; the default model is CalculateEnergy.
+// Input: an EnergyModel, a Molecule protein, a Molecule ligand
+// Output: a float64 energy
+func (model EnergyModel) Energy(protein, ligand molecule.Molecule) float64 {
+	if model == DefaultEnergyModel() {
+		return CalculateEnergy(protein, ligand)
+	}
+	energy := 0.0
+	for _, atomP := range protein.Atoms {
+		for _, atomL := range ligand.Atoms {
+			energy += model.PairEnergy(atomP, atomL, molecule.Distance(atomP.Position, atomL.Position))
+		}
+	}
+	return energy
+}
+
+// PairEnergy computes the energy of two atoms a given distance apart with the model, 0 beyond the cutoff.
+// Input: an EnergyModel, two Atoms a and b, a float64 distance in Å
+// Output: a float64 energy
+func (model EnergyModel) PairEnergy(a, b molecule.Atom, distance float64) float64 {
+	if model.Cutoff > 0 && distance > model.Cutoff {
+		return 0
+	}
+	// to ensure non-zero
+	if distance < 1e-6 {
+		distance = 1e-6
+	}
+	pair := model.Constant * a.Charge * b.Charge / distance
+	if model.Dielectric == "distance" {
+		pair /= distance
+	}
+	return pair
+}
+
+// ChargedAtoms returns the atoms of a receptor that carry a charge. Uncharged atoms add nothing to the energy of
+// any model, so scoring against the result gives the same energy with fewer pairs; it has no bonds, so it is only
+// meant for scoring.
+// Input: a Molecule receptor
+// Output: a Molecule
+func ChargedAtoms(receptor molecule.Molecule) molecule.Molecule {
+	var charged molecule.Molecule
+	for _, atom := range receptor.Atoms {
+		if atom.Charge != 0 {
+			charged.Atoms = append(charged.Atoms, atom)
+		}
+	}
+	return charged
+}
+
+// Validate checks the model and dielectric names and that the constant is positive and the cutoff is not negative.
+// Input: an EnergyModel
+// Output: an error or nil
+func (model EnergyModel) Validate() error {
+	if !slices.Contains(EnergyModels, model.Model) {
+		return fmt.Errorf("unknown energy model %q, expected one of %v", model.Model, EnergyModels)
+	}
+	if !slices.Contains(DielectricModels, model.Dielectric) {
+		return fmt.Errorf("unknown dielectric %q, expected one of %v", model.Dielectric, DielectricModels)
+	}
+	if model.Constant <= 0 {
+		return fmt.Errorf("energy constant must be positive, got %v", model.Constant)
+	}
+	if model.Cutoff < 0 {
+		return fmt.Errorf("energy cutoff must not be negative, got %v", model.Cutoff)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package energy
+
+import (
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+)
+
+func TestCalculateEnergyPositive(t *testing.T) {
+	protein := moltest.Protein(1.0, 1.0)
+	ligand := moltest.LigandWithCharges(1.0, 1.0)
+
+	energy := CalculateEnergy(protein, ligand)
+
+	if energy <= 0 {
+		t.Errorf("Expected positive energy, got %f", energy)
+	}
+}
+
+func TestCalculateEnergyNegative(t *testing.T) {
+	protein := moltest.Protein(1.0, 1.0)
+	ligand := moltest.LigandWithCharges(-1.0, -1.0)
+
+	energy := CalculateEnergy(protein, ligand)
+
+	if energy >= 0 {
+		t.Errorf("Expected negative energy, got %f", energy)
+	}
+}
+
+func TestChargedAtoms(t *testing.T) {
+	protein := moltest.Protein(1.0, 0.0)
+	ligand := moltest.LigandWithCharges(-1.0, 0.5)
+	charged := ChargedAtoms(protein)
+	if len(charged.Atoms) != 1 {
+		t.Fatalf("Expected 1 charged atom, got %d", len(charged.Atoms))
+	}
+	model := DefaultEnergyModel()
+	model.Dielectric = "distance"
+	for _, m := range []EnergyModel{DefaultEnergyModel(), model} {
+		if full, reduced := m.Energy(protein, ligand), m.Energy(charged, ligand); full != reduced {
+			t.Errorf("Expected the same energy without uncharged atoms, got %g and %g", full, reduced)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// ENRICHMENTFRACTIONS are the fractions of the ranked library at which enrichment factors are reported
+var ENRICHMENTFRACTIONS = []float64{0.01, 0.05, 0.10}
+
+// BEDROCALPHA is the early-recognition parameter of BEDROC; 20 weights the top ~8% of the ranking
+const BEDROCALPHA = 20.0
+
+// ScreeningScore is the docking score of one molecule of a labelled screening set
+type ScreeningScore struct {
+	Name   string
+	Active bool
+	Energy float64
+}
+
+// EnrichmentReport holds the virtual screening metrics of a ranked set of actives and decoys
+type EnrichmentReport struct {
+	Actives           int
+	Decoys            int
+	ROCAUC            float64
+	BEDROC            float64
+	BEDROCAlpha       float64
+	EnrichmentFactors map[string]float64
+}
+
+// MarshalJSON writes undefined metrics (NaN when a set has no actives or no decoys) as null.
+// Input: an EnrichmentReport
+// Output: the JSON encoding and an error or nil
+func (report EnrichmentReport) MarshalJSON() ([]byte, error) {
+	factors := make(map[string]interface{}, len(report.EnrichmentFactors))
+	for key, value := range report.EnrichmentFactors {
+		factors[key] = analysis.FiniteOrNil(value)
+	}
+	return json.Marshal(map[string]interface{}{
+		"actives":            report.Actives,
+		"decoys":             report.Decoys,
+		"roc_auc":            analysis.FiniteOrNil(report.ROCAUC),
+		"bedroc":             analysis.FiniteOrNil(report.BEDROC),
+		"bedroc_alpha":       report.BEDROCAlpha,
+		"enrichment_factors": factors,
+	})
+}
+
+// RankScreeningScores sorts the scores from best (lowest energy) to worst. Ties keep their input order.
+// Input: a slice of ScreeningScore
+// Output: a sorted copy of the slice
+func RankScreeningScores(scores []ScreeningScore) []ScreeningScore {
+	ranked := append([]ScreeningScore(nil), scores...)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Energy < ranked[j].Energy })
+	return ranked
+}
+
+// ROCAUC computes the area under the ROC curve as the probability that a random active scores better than
+// a random decoy (the Mann-Whitney statistic), counting ties as one half.
+// Input: a slice of ScreeningScore
+// Output: a float64 AUC (NaN without both actives and decoys)
+func ROCAUC(scores []ScreeningScore) float64 {
+	goodness := make([]float64, len(scores))
+	actives := 0
+	for i, score := range scores {
+		goodness[i] = -score.Energy
+		if score.Active {
+			actives++
+		}
+	}
+	decoys := len(scores) - actives
+	if actives == 0 || decoys == 0 {
+		return math.NaN()
+	}
+	ranks := analysis.Ranks(goodness)
+	var rankSum float64
+	for i, score := range scores {
+		if score.Active {
+			rankSum += ranks[i]
+		}
+	}
+	return (rankSum - float64(actives*(actives+1))/2) / float64(actives*decoys)
+}
+
+// BEDROC computes the Boltzmann-enhanced discrimination of ROC (Truchon & Bayly, 2007), which weights actives
+// found early in the ranking exponentially with parameter alpha.
+// Input: a ranked slice of ScreeningScore (best first), a float64 alpha
+// Output: a float64 BEDROC between 0 and 1 (NaN without both actives and decoys)
+func BEDROC(ranked []ScreeningScore, alpha float64) float64 {
+	total := float64(len(ranked))
+	var actives, sum float64
+	for i, score := range ranked {
+		if score.Active {
+			actives++
+			sum += math.Exp(-alpha * float64(i+1) / total)
+		}
+	}
+	if actives == 0 || actives == total {
+		return math.NaN()
+	}
+	ratio := actives / total
+	rie := sum / (actives * (1 - math.Exp(-alpha)) / (total * (math.Exp(alpha/total) - 1)))
+	return rie*ratio*math.Sinh(alpha/2)/(math.Cosh(alpha/2)-math.Cosh(alpha/2-alpha*ratio)) + 1/(1-math.Exp(alpha*(1-ratio)))
+}
+
+// EnrichmentFactor computes the ratio of the active rate in the top fraction of the ranking to the active rate
+// of the whole set. The top fraction holds at least one molecule.
+// Input: a ranked slice of ScreeningScore (best first), a float64 fraction
+// Output: a float64 enrichment factor (NaN without actives)
+func EnrichmentFactor(ranked []ScreeningScore, fraction float64) float64 {
+	actives := 0
+	for _, score := range ranked {
+		if score.Active {
+			actives++
+		}
+	}
+	if actives == 0 {
+		return math.NaN()
+	}
+	selected := int(math.Ceil(fraction * float64(len(ranked))))
+	if selected < 1 {
+		selected = 1
+	}
+	if selected > len(ranked) {
+		selected = len(ranked)
+	}
+	found := 0
+	for _, score := range ranked[:selected] {
+		if score.Active {
+			found++
+		}
+	}
+	return (float64(found) / float64(selected)) / (float64(actives) / float64(len(ranked)))
+}
+
+// EvaluateEnrichment computes ROC AUC, BEDROC and the enrichment factors at ENRICHMENTFRACTIONS.
+// Input: a slice of ScreeningScore
+// Output: an EnrichmentReport
+func EvaluateEnrichment(scores []ScreeningScore) EnrichmentReport {
+	ranked := RankScreeningScores(scores)
+	report := EnrichmentReport{BEDROCAlpha: BEDROCALPHA, EnrichmentFactors: make(map[string]float64)}
+	for _, score := range scores {
+		if score.Active {
+			report.Actives++
+		} else {
+			report.Decoys++
+		}
+	}
+	report.ROCAUC = ROCAUC(scores)
+	report.BEDROC = BEDROC(ranked, BEDROCALPHA)
+	for _, fraction := range ENRICHMENTFRACTIONS {
+		report.EnrichmentFactors[fractionKey(fraction)] = EnrichmentFactor(ranked, fraction)
+	}
+	return report
+}
+
+// fractionKey formats a library fraction as a map key such as "EF1%".
+// Input: a float64 fraction
+// Output: a string
+func fractionKey(fraction float64) string {
+	return "EF" + strconv.FormatFloat(100*fraction, 'f', -1, 64) + "%"
+}
+
+// ROCCurve returns the (false positive rate, true positive rate) points of a ranking, moving through tied
+// energies in one step.
+// Input: a ranked slice of ScreeningScore (best first)
+// Output: plotter.XYs from (0, 0) to (1, 1)
+func ROCCurve(ranked []ScreeningScore) plotter.XYs {
+	actives, decoys := 0.0, 0.0
+	for _, score := range ranked {
+		if score.Active {
+			actives++
+		} else {
+			decoys++
+		}
+	}
+	points := plotter.XYs{{X: 0, Y: 0}}
+	var tp, fp float64
+	for i, score := range ranked {
+		if score.Active {
+			tp++
+		} else {
+			fp++
+		}
+		if i+1 < len(ranked) && ranked[i+1].Energy == score.Energy {
+			continue
+		}
+		points = append(points, plotter.XY{X: fp / math.Max(decoys, 1), Y: tp / math.Max(actives, 1)})
+	}
+	return points
+}
+
+// EnrichmentCurve returns the (fraction of library screened, fraction of actives found) points of a ranking.
+// Input: a ranked slice of ScreeningScore (best first)
+// Output: plotter.XYs from (0, 0) to (1, 1)
+func EnrichmentCurve(ranked []ScreeningScore) plotter.XYs {
+	actives := 0.0
+	for _, score := range ranked {
+		if score.Active {
+			actives++
+		}
+	}
+	points := plotter.XYs{{X: 0, Y: 0}}
+	found := 0.0
+	for i, score := range ranked {
+		if score.Active {
+			found++
+		}
+		points = append(points, plotter.XY{X: float64(i+1) / float64(len(ranked)), Y: found / math.Max(actives, 1)})
+	}
+	return points
+}
+
+// plotCurveWithDiagonal plots a curve over the unit square against the diagonal of a random ranking.
+// Input: plotter.XYs points, a string fileName (without extension), a string title, string axis labels, a PlotOptions
+// Output: none (saves a plot)
+func plotCurveWithDiagonal(points plotter.XYs, fileName, title, xLabel, yLabel string, options PlotOptions) {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = xLabel
+	p.Y.Label.Text = yLabel
+	p.X.Min, p.X.Max, p.Y.Min, p.Y.Max = 0, 1, 0, 1
+
+	line, err := plotter.NewLine(points)
+	Check(err)
+	line.Width = vg.Points(1.5)
+	line.Color = options.ink()
+	p.Add(line)
+	p.Legend.Add("Metropolis energy", line)
+
+	random, err := plotter.NewLine(plotter.XYs{{X: 0, Y: 0}, {X: 1, Y: 1}})
+	Check(err)
+	random.Color = color.Gray{Y: 150}
+	random.Dashes = []vg.Length{vg.Points(4), vg.Points(4)}
+	p.Add(random)
+	p.Legend.Add("Random", random)
+	p.Legend.Left, p.Legend.Top = false, false
+
+	savePlot(p, 6*vg.Inch, 4*vg.Inch, fileName, options)
+}
+
+// ReadScreeningScores reads a scores CSV with name, energy and active columns, where active is 1/0 or true/false.
+// Input: a string filename
+// Output: a slice of ScreeningScore and an error or nil
+func ReadScreeningScores(filename string) ([]ScreeningScore, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%s is empty", filename)
+	}
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	nameColumn, okName := firstColumn(columns, []string{"name", "ligand", "id"})
+	energyColumn, okEnergy := firstColumn(columns, simulationEnergyColumns)
+	activeColumn, okActive := firstColumn(columns, []string{"active", "label"})
+	if !okName || !okEnergy || !okActive {
+		return nil, fmt.Errorf("%s needs name, energy and active columns", filename)
+	}
+	var scores []ScreeningScore
+	for line, record := range records[1:] {
+		energy, err := strconv.ParseFloat(strings.TrimSpace(record[energyColumn]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", filename, line+2, err)
+		}
+		active, err := strconv.ParseBool(strings.TrimSpace(record[activeColumn]))
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", filename, line+2, err)
+		}
+		scores = append(scores, ScreeningScore{Name: record[nameColumn], Active: active, Energy: energy})
+	}
+	return scores, nil
+}
+
+// WriteScreeningScores writes the ranked scores with their rank, in the format read by ReadScreeningScores.
+// Input: a string filename, a ranked slice of ScreeningScore
+// Output: an error or nil
+func WriteScreeningScores(filename string, ranked []ScreeningScore) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	if err := writer.Write([]string{"rank", "name", "active", "energy"}); err != nil {
+		return err
+	}
+	for i, score := range ranked {
+		if err := writer.Write([]string{strconv.Itoa(i + 1), score.Name, strconv.FormatBool(score.Active), strconv.FormatFloat(score.Energy, 'g', -1, 64)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// DockScreeningSet docks every active and decoy against the protein with the DockingSimulation of the run
+// configuration and returns their minimum energies. Ligands further from the protein than the configured
+// shift threshold are moved next to it first, as in the simulate command.
+// Input: a Molecule protein, slices of NamedMolecule actives and decoys, a RunConfig config
+// Output: a slice of ScreeningScore and the error of a flexible residue or a restraint that cannot be used
+func DockScreeningSet(protein molecule.Molecule, actives, decoys []molio.NamedMolecule, config RunConfig) ([]ScreeningScore, error) {
+	var scores []ScreeningScore
+	var ligands []molecule.Molecule
+	for _, set := range []struct {
+		molecules []molio.NamedMolecule
+		active    bool
+	}{{actives, true}, {decoys, false}} {
+		for _, ligand := range set.molecules {
+			scores = append(scores, ScreeningScore{Name: ligand.Name, Active: set.active})
+			start := ligand.Molecule
+			if config.Inputs.ShiftThreshold > 0 {
+				start = sampling.ShiftLigandCloserByThreshold(start, protein, config.Inputs.ShiftThreshold)
+			}
+			ligands = append(ligands, start)
+		}
+	}
+	sim, err := config.DockingSimulation(protein, ligands)
+	if err != nil {
+		return nil, err
+	}
+	_, energies, _ := sampling.RunSimulation(protein, ligands, sim, false)
+	for i := range scores {
+		scores[i].Energy = energies[i]
+	}
+	return scores, nil
+}
+
+// EnrichmentMain is the entry point of the "enrichment" command. It docks labelled actives and decoys against
+// a target (or reads the scores of an earlier run with -scores) and writes the ranked scores.csv,
+// enrichment.json, and ROC and enrichment curve plots.
+// Usage: enrichment [flags] protein actives decoys outputDir  or  enrichment -scores scores.csv outputDir
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the reports and prints the metrics)
+func EnrichmentMain(args []string) {
+	flags := commandFlags("enrichment", "protein actives decoys outputDir",
+		"Docks labelled actives and decoys against the protein and writes the ranked scores.csv, enrichment.json and\n"+
+			"ROC and enrichment curves. actives and decoys are directories of ligand files or multi-molecule .mol2(.gz)\n"+
+			"files. With -scores, evaluates the scores CSV of an earlier run instead: enrichment -scores scores.csv outputDir\n"+
+			"Docking runs write the resolved run-config.json with the results.")
+	scoresFile := flags.String("scores", "", "evaluate an existing scores CSV (name, energy, active) instead of docking")
+	defaults := DefaultRunConfig()
+	configFile := flags.String("config", "", "run configuration (.json or .toml); flags override its values")
+	defaults.AddSimulationFlags(flags)
+	flags.Float64Var(&defaults.Inputs.ShiftThreshold, "shift", defaults.Inputs.ShiftThreshold, "move each ligand within this distance (Å) of the protein first (0 keeps the input pose)")
+	plotOptions := DefaultPlotOptions()
+	plotOptions.AddFlags(flags)
+	flags.Parse(args)
+	Check(plotOptions.Validate())
+
+	provenance := NewProvenance("enrichment")
+	var scores []ScreeningScore
+	var outputDir string
+	switch {
+	case *scoresFile != "" && flags.NArg() == 1:
+		var err error
+		scores, err = ReadScreeningScores(*scoresFile)
+		Check(err)
+		outputDir = flags.Arg(0)
+		provenance.Stage("load scores")
+		Check(provenance.Input("scores", *scoresFile))
+	case *scoresFile == "" && flags.NArg() == 4:
+		config, err := ResolveRunConfig(flags, *configFile, defaults)
+		exitOnConfigError(err)
+		outputDir = flags.Arg(3)
+		config.Outputs.Dir = outputDir
+		Check(os.MkdirAll(outputDir, 0755))
+		Check(SaveRunConfig(filepath.Join(outputDir, runConfigName(*configFile)), config))
+		provenance.SetConfig(config)
+		provenance.Stage("load inputs")
+		protein, err := molio.LoadReceptor(flags.Arg(0))
+		warnOrCheck(err)
+		actives, err := molio.LoadLigandSet(flags.Arg(1))
+		warnOrCheck(err)
+		decoys, err := molio.LoadLigandSet(flags.Arg(2))
+		warnOrCheck(err)
+		Check(provenance.Input("protein", flags.Arg(0)))
+		Check(provenance.InputSet("active", flags.Arg(1)))
+		Check(provenance.InputSet("decoy", flags.Arg(2)))
+		provenance.Stage("docking")
+		fmt.Printf("Docking %d actives and %d decoys\n", len(actives), len(decoys))
+		scores, err = DockScreeningSet(protein, actives, decoys, config)
+		exitOnConfigError(err)
+	default:
+		flags.Usage()
+		return
+	}
+
+	provenance.Stage("enrichment")
+	report := EvaluateEnrichment(scores)
+	fmt.Printf("Actives: %d, decoys: %d\n", report.Actives, report.Decoys)
+	fmt.Printf("ROC AUC: %.3f\n", report.ROCAUC)
+	fmt.Printf("BEDROC (alpha = %.0f): %.3f\n", report.BEDROCAlpha, report.BEDROC)
+	for _, fraction := range ENRICHMENTFRACTIONS {
+		fmt.Printf("EF at %.0f%%: %.2f\n", 100*fraction, report.EnrichmentFactors[fractionKey(fraction)])
+	}
+
+	provenance.Stage("write results")
+	Check(os.MkdirAll(outputDir, 0755))
+	ranked := RankScreeningScores(scores)
+	Check(WriteScreeningScores(filepath.Join(outputDir, "scores.csv"), ranked))
+	data, err := json.MarshalIndent(report, "", "  ")
+	Check(err)
+	Check(os.WriteFile(filepath.Join(outputDir, "enrichment.json"), append(data, '\n'), 0644))
+	plotCurveWithDiagonal(ROCCurve(ranked), filepath.Join(outputDir, "roc"), fmt.Sprintf("ROC curve (AUC = %.3f)", report.ROCAUC), "False positive rate", "True positive rate", plotOptions)
+	plotCurveWithDiagonal(EnrichmentCurve(ranked), filepath.Join(outputDir, "enrichment"), "Enrichment curve", "Fraction of library screened", "Fraction of actives found", plotOptions)
+	Check(provenance.Save(outputDir))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// createMockScreeningScores creates n molecules with increasing energies, the given positions being actives.
+// Input: an int n, the indices of the actives
+// Output: a slice of ScreeningScore
+func createMockScreeningScores(n int, actives ...int) []ScreeningScore {
+	scores := make([]ScreeningScore, n)
+	for i := range scores {
+		scores[i] = ScreeningScore{Name: "mol", Energy: float64(i)}
+	}
+	for _, i := range actives {
+		scores[i].Active = true
+	}
+	return scores
+}
+
+func TestEnrichmentMetricsPerfectRanking(t *testing.T) {
+	report := EvaluateEnrichment(createMockScreeningScores(100, 0, 1))
+	if report.ROCAUC != 1 {
+		t.Errorf("Expected AUC 1, got %f", report.ROCAUC)
+	}
+	if math.Abs(report.BEDROC-1) > 1e-6 {
+		t.Errorf("Expected BEDROC 1, got %f", report.BEDROC)
+	}
+	// both actives are in the top 1% (one molecule) and 5% (five molecules)
+	if report.EnrichmentFactors["EF1%"] != 50 || report.EnrichmentFactors["EF5%"] != 20 || report.EnrichmentFactors["EF10%"] != 10 {
+		t.Errorf("Unexpected enrichment factors %v", report.EnrichmentFactors)
+	}
+}
+
+func TestEnrichmentMetricsWorstRanking(t *testing.T) {
+	report := EvaluateEnrichment(createMockScreeningScores(100, 98, 99))
+	if report.ROCAUC != 0 || report.BEDROC > 1e-3 || report.EnrichmentFactors["EF10%"] != 0 {
+		t.Errorf("Expected no enrichment, got %+v", report)
+	}
+	tied := createMockScreeningScores(4, 0)
+	for i := range tied {
+		tied[i].Energy = 1
+	}
+	if auc := ROCAUC(tied); auc != 0.5 {
+		t.Errorf("Expected AUC 0.5 for tied scores, got %f", auc)
+	}
+}
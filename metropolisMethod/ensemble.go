@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+)
+
+// EnsembleAggregates are the ways ensemble docking combines the energies of a ligand over the snapshots
+var EnsembleAggregates = []string{"best", "boltzmann", "mean"}
+
+// EnsembleLigand is the ensemble docking result of one ligand
+type EnsembleLigand struct {
+	Ligand       string
+	Rank         int
+	Score        float64 // the selected aggregate
+	Best         float64 // lowest energy over the snapshots
+	Boltzmann    float64 // Boltzmann-weighted mean energy over the snapshots
+	Mean         float64 // mean energy over the snapshots
+	BestSnapshot string  // snapshot with the lowest energy
+	bestIndex    int     // column of the best snapshot in the matrix, -1 when no snapshot was docked
+}
+
+// AggregateEnsemble combines the energies of every ligand over the snapshots of an ensemble and ranks the
+// ligands by the selected aggregate, lowest first. The Boltzmann weights use the given temperature, in the energy
+// units of the Metropolis criterion. Snapshots that were not docked are left out.
+// Input: a CrossDockMatrix with one column per snapshot, a string aggregate (one of EnsembleAggregates), a float64 temperature
+// Output: a slice of EnsembleLigand, ranked
+func AggregateEnsemble(matrix CrossDockMatrix, aggregate string, temperature float64) []EnsembleLigand {
+	results := make([]EnsembleLigand, len(matrix.Ligands))
+	for i, energies := range matrix.Energies {
+		result := EnsembleLigand{Ligand: matrix.Ligands[i], Best: math.NaN(), Boltzmann: math.NaN(), Mean: math.NaN(), bestIndex: -1}
+		var docked []float64
+		for j, energy := range energies {
+			if math.IsNaN(energy) {
+				continue
+			}
+			docked = append(docked, energy)
+			if result.bestIndex < 0 || energy < energies[result.bestIndex] {
+				result.bestIndex = j
+			}
+		}
+		if len(docked) > 0 {
+			result.Best, result.BestSnapshot = energies[result.bestIndex], matrix.Receptors[result.bestIndex]
+			result.Boltzmann = analysis.BoltzmannAverage(docked, temperature)
+			result.Mean = analysis.Mean(docked)
+		}
+		switch aggregate {
+		case "boltzmann":
+			result.Score = result.Boltzmann
+		case "mean":
+			result.Score = result.Mean
+		default:
+			result.Score = result.Best
+		}
+		results[i] = result
+	}
+	slices.SortStableFunc(results, func(a, b EnsembleLigand) int {
+		switch {
+		case math.IsNaN(a.Score) != math.IsNaN(b.Score):
+			if math.IsNaN(b.Score) {
+				return -1
+			}
+			return 1
+		case a.Score < b.Score:
+			return -1
+		case a.Score > b.Score:
+			return 1
+		}
+		return 0
+	})
+	for i := range results {
+		results[i].Rank = i + 1
+	}
+	return results
+}
+
+// WriteEnsembleTable writes the ranked ensemble results as CSV.
+// Input: a string fileName, a slice of EnsembleLigand
+// Output: an error or nil
+func WriteEnsembleTable(fileName string, results []EnsembleLigand) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"rank", "ligand", "score", "best", "boltzmann", "mean", "best_snapshot"})
+	for _, result := range results {
+		writer.Write([]string{strconv.Itoa(result.Rank), result.Ligand, formatOptional(result.Score), formatOptional(result.Best),
+			formatOptional(result.Boltzmann), formatOptional(result.Mean), result.BestSnapshot})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// chargeSnapshots charges the snapshots without partial charges with ChargeReceptor, as LoadReceptor does for the
+// receptors of the other commands.
+// Input: a slice of NamedMolecule snapshots (charged in place)
+// Output: the int number of snapshots that were charged
+func chargeSnapshots(snapshots []molio.NamedMolecule) int {
+	count := 0
+	for i := range snapshots {
+		var charged bool
+		if snapshots[i].Molecule, charged = molio.ChargeReceptor(snapshots[i].Molecule); charged {
+			count++
+		}
+	}
+	return count
+}
+
+// ensembleFiles returns the files an ensemble is read from: the file itself or the frames of a directory.
+// Input: a string path
+// Output: a slice of strings files and an error or nil
+func ensembleFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return []string{path}, err
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if ext := strings.ToLower(filepath.Ext(entry.Name())); !entry.IsDir() && (ext == ".mol2" || ext == ".pdb" || ext == ".pqr") {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// EnsembleMain is the entry point of the "ensemble" command, which docks every ligand of the data directory into
+// every snapshot of a receptor ensemble and ranks the ligands by an aggregate of their energies.
+// Usage: ensemble [flags] trajectory.pdb|frames/
+// Input: a slice of strings args (without the command name)
+// Output: none (writes to <output>/ensemble/<ensemble name>/)
+func EnsembleMain(args []string) {
+	defaults := DefaultRunConfig()
+	defaults.Inputs.Protein = ""
+	flags := commandFlags("ensemble", "trajectory.pdb|frames/",
+		"Docks every ligand of the data directory into every receptor snapshot, the models of a multi-model PDB or PQR\n"+
+			"file (such as the protein file written by split -models) or the .pdb, .pqr and .mol2 frames of a directory.\n"+
+			"Snapshots without charges get AMBER ff14SB charges as with the receptor command. Writes to\n"+
+			"<output>/ensemble/<ensemble name>/: energies.csv with one column per snapshot, ensemble.csv ranking the ligands\n"+
+			"by the -aggregate score with the best, Boltzmann-weighted (at the final temperature) and mean energies and the\n"+
+			"best snapshot of each ligand, a heatmap, an energy plot, report.html with the pose of each ligand in its best\n"+
+			"snapshot, every pose in poses/<snapshot>/, the resolved run configuration and the provenance manifest.")
+	configFile := flags.String("config", "", "run configuration (.json or .toml); flags override its values")
+	aggregate := flags.String("aggregate", "best", "score that ranks the ligands: "+strings.Join(EnsembleAggregates, ", "))
+	flags.StringVar(&defaults.Inputs.Dir, "dir", defaults.Inputs.Dir, "data directory")
+	flags.IntVar(&defaults.Inputs.Limit, "limit", defaults.Inputs.Limit, "number of ligands to use (0 for all)")
+	flags.Float64Var(&defaults.Inputs.ShiftThreshold, "shift", defaults.Inputs.ShiftThreshold, "move each ligand within this distance (Å) of each snapshot first (0 keeps the input pose)")
+	defaults.AddSimulationFlags(flags)
+	defaults.AddOutputFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return
+	}
+	config, err := ResolveRunConfig(flags, *configFile, defaults)
+	exitOnConfigError(err)
+	if !slices.Contains(EnsembleAggregates, *aggregate) {
+		exitOnConfigError(fmt.Errorf("unknown aggregate %q, expected one of %v", *aggregate, EnsembleAggregates))
+	}
+	ligandFiles, err := config.LigandFiles()
+	exitOnConfigError(err)
+	if len(ligandFiles) == 0 {
+		exitOnConfigError(fmt.Errorf("no ligand files in %s", config.Inputs.Dir))
+	}
+
+	provenance := NewProvenance("ensemble")
+	provenance.SetConfig(config)
+	provenance.Parameter("aggregate", *aggregate)
+	provenance.Stage("load inputs")
+	snapshots, err := molio.LoadEnsemble(flags.Arg(0))
+	warnOrCheck(err)
+	files, err := ensembleFiles(flags.Arg(0))
+	Check(err)
+	for _, file := range files {
+		Check(provenance.Input("snapshots", file))
+	}
+	if charged := chargeSnapshots(snapshots); charged > 0 {
+		fmt.Printf("Assigned AMBER ff14SB charges to %d of %d snapshots\n", charged, len(snapshots))
+	}
+	name := fileStem(filepath.Clean(flags.Arg(0)))
+	outputDir := filepath.Join(config.Outputs.Dir, "ensemble", name)
+	Check(os.MkdirAll(outputDir, 0755))
+	Check(SaveRunConfig(filepath.Join(outputDir, runConfigName(*configFile)), config))
+
+	provenance.Stage("docking")
+	fmt.Printf("Docking %d ligands into %d snapshots\n", len(ligandFiles), len(snapshots))
+	start := time.Now()
+	matrix, err := DockMatrix(context.Background(), snapshots, ligandFiles, config, outputDir, provenance)
+	exitOnConfigError(err)
+	end := time.Since(start)
+
+	provenance.Stage("results")
+	temperature := config.Temperature.End
+	results := AggregateEnsemble(matrix, *aggregate, temperature)
+	Check(WriteCrossDockMatrix(filepath.Join(outputDir, "energies.csv"), matrix))
+	Check(WriteEnsembleTable(filepath.Join(outputDir, "ensemble.csv"), results))
+	plotOptions := config.Outputs.Plot
+	plotCrossDockHeatMap(matrix, filepath.Join(outputDir, "heatmap"), "Ensemble docking energy (z-score per ligand)", "Snapshot", plotOptions)
+	labels := make([]string, len(results))
+	scores := make([]float64, len(results))
+	for i, result := range results {
+		labels[i], scores[i] = result.Ligand, result.Score
+	}
+	plotEnergy(labels, scores, filepath.Join(outputDir, "energies"), plotOptions)
+
+	provenance.Stage("report")
+	report := ScreeningReport{
+		Title:        fmt.Sprintf("Ensemble docking of %d ligands into %d snapshots of %s", len(results), len(snapshots), name),
+		Created:      time.Now(),
+		EnergyColumn: strings.ToUpper((*aggregate)[:1]) + (*aggregate)[1:] + " energy",
+		Parameters: []ReportParameter{
+			{"Ensemble", flags.Arg(0)},
+			{"Snapshots", strconv.Itoa(len(snapshots))},
+			{"Ligands", config.Inputs.Dir + " (" + strconv.Itoa(len(ligandFiles)) + " files)"},
+			{"Aggregate", *aggregate},
+			{"Boltzmann temperature", strconv.FormatFloat(temperature, 'g', -1, 64)},
+			{"Iterations", strconv.Itoa(config.Iterations)},
+			{"Seed", strconv.FormatInt(config.Seed, 10)},
+			{"Energy model", config.Energy.Model + ", " + config.Energy.Dielectric + " dielectric"},
+			{"Temperature", config.Simulation().Schedule.String()},
+			{"Processors", strconv.Itoa(config.Parallel.Walkers)},
+			{"Simulation time", end.Round(time.Millisecond).String()},
+		},
+	}
+	ligandIndex := make(map[string]int)
+	for i, label := range matrix.Ligands {
+		ligandIndex[label] = i
+	}
+	for _, result := range results {
+		if result.bestIndex < 0 {
+			continue
+		}
+		i := ligandIndex[result.Ligand]
+		reference, err := molio.ParseMol2(ligandFiles[i])
+		warnOrCheck(err)
+		pose := matrix.Poses[i][result.bestIndex]
+		ligand := NewReportLigand(result.Ligand, snapshots[result.bestIndex].Molecule, pose, result.Score, reference, nil)
+		ligand.Snapshot = result.BestSnapshot
+		report.Ligands = append(report.Ligands, ligand)
+	}
+	energySVG, err := RenderSVG(func(fileName string, options PlotOptions) { plotEnergy(labels, scores, fileName, options) }, plotOptions)
+	Check(err)
+	report.Plots = append(report.Plots, ReportPlot{Title: "Ensemble energy of each ligand", SVG: energySVG})
+	heatSVG, err := RenderSVG(func(fileName string, options PlotOptions) {
+		plotCrossDockHeatMap(matrix, fileName, "Energy per snapshot (z-score per ligand)", "Snapshot", options)
+	}, plotOptions)
+	Check(err)
+	report.Plots = append(report.Plots, ReportPlot{Title: "Energy of each ligand in each snapshot", SVG: heatSVG})
+	Check(SaveHTMLReport(filepath.Join(outputDir, "report.html"), report))
+	Check(provenance.Save(outputDir))
+	fmt.Printf("Ranked %d ligands over %d snapshots in %s\n", len(results), len(snapshots), filepath.Join(outputDir, "ensemble.csv"))
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAggregateEnsemble(t *testing.T) {
+	matrix := CrossDockMatrix{
+		Ligands:   []string{"a", "b", "c"},
+		Receptors: []string{"model_1", "model_2"},
+		Energies:  [][]float64{{-4, -2}, {-1, -5}, {math.NaN(), math.NaN()}},
+	}
+	results := AggregateEnsemble(matrix, "mean", 0)
+	if results[0].Ligand != "a" || results[0].Score != -3 || results[0].BestSnapshot != "model_1" || results[0].Rank != 1 {
+		t.Errorf("Expected a first by mean energy, got %+v", results[0])
+	}
+	if results[1].Ligand != "b" || results[1].Best != -5 || results[1].BestSnapshot != "model_2" || results[1].Boltzmann != -5 {
+		t.Errorf("Unexpected result for b %+v", results[1])
+	}
+	if results[2].Ligand != "c" || results[2].BestSnapshot != "" || !math.IsNaN(results[2].Score) {
+		t.Errorf("Expected the undocked ligand last, got %+v", results[2])
+	}
+	if results := AggregateEnsemble(matrix, "best", 0); results[0].Ligand != "b" {
+		t.Errorf("Expected b first by best energy, got %+v", results[0])
+	}
+}
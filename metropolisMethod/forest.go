@@ -0,0 +1,420 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+)
+
+// defaultForestFeatures and defaultForestTarget are the MM/PBSA energy terms and affinity columns of the PLAS20K
+// training table (machineLearningMethods/pythonMLModels/5kdata.csv)
+var (
+	defaultForestFeatures = []string{"electrostatic (kcal/mol)", "polar_solvation (kcal/mol)", "non_polar_solvation (kcal/mol)", "vdW (kcal/mol)"}
+	defaultForestTarget   = "binding_affinity (kcal/mol)"
+)
+
+// RegressionTree is a node of a regression tree; a leaf has no children and predicts its Value
+type RegressionTree struct {
+	Value     float64         `json:"value"`
+	Feature   int             `json:"feature,omitempty"`
+	Threshold float64         `json:"threshold,omitempty"`
+	Left      *RegressionTree `json:"left,omitempty"`
+	Right     *RegressionTree `json:"right,omitempty"`
+}
+
+// ForestSettings are the hyperparameters of a random forest
+type ForestSettings struct {
+	Trees    int   `json:"trees"`
+	MaxDepth int   `json:"max_depth"`
+	MinLeaf  int   `json:"min_leaf"` // minimum number of samples in a leaf
+	Seed     int64 `json:"seed"`
+}
+
+// RegressionMetrics are the errors of predictions against known values
+type RegressionMetrics struct {
+	N    int     `json:"n"`
+	MSE  float64 `json:"mse"`
+	RMSE float64 `json:"rmse"`
+	MAE  float64 `json:"mae"`
+	R2   float64 `json:"r2"`
+}
+
+// RandomForest is a trained forest of regression trees together with the columns it reads, so a saved model can
+// be applied to any table with those columns
+type RandomForest struct {
+	Features []string           `json:"features"`
+	Target   string             `json:"target"`
+	Settings ForestSettings     `json:"settings"`
+	Test     *RegressionMetrics `json:"test,omitempty"` // metrics on the held-out rows, when there were any
+	Trees    []*RegressionTree  `json:"trees"`
+}
+
+// DefaultForestSettings returns 100 trees of depth at most 25 with at least 5 samples per leaf.
+// Input: none
+// Output: a ForestSettings
+func DefaultForestSettings() ForestSettings {
+	return ForestSettings{Trees: 100, MaxDepth: 25, MinLeaf: 5, Seed: 42}
+}
+
+// TrainRandomForest grows each tree on a bootstrap sample of the rows, choosing every split among a random
+// subset of sqrt(features)+1 features.
+// Input: a feature matrix X (one row per sample), a slice of float64 targets y, the string feature and target
+// names, a ForestSettings
+// Output: a RandomForest
+func TrainRandomForest(X [][]float64, y []float64, features []string, target string, settings ForestSettings) RandomForest {
+	source := rand.New(rand.NewSource(settings.Seed))
+	forest := RandomForest{Features: features, Target: target, Settings: settings, Trees: make([]*RegressionTree, settings.Trees)}
+	for t := range forest.Trees {
+		sample := make([]int, len(X))
+		for i := range sample {
+			sample[i] = source.Intn(len(X))
+		}
+		forest.Trees[t] = growTree(X, y, sample, 0, settings, source)
+	}
+	return forest
+}
+
+// growTree recursively splits the samples until the depth limit, the leaf size or a constant target is reached.
+// Input: the feature matrix X and targets y, a slice of row indices, an int depth, a ForestSettings, a *rand.Rand
+// Output: a *RegressionTree
+func growTree(X [][]float64, y []float64, indices []int, depth int, settings ForestSettings, source *rand.Rand) *RegressionTree {
+	sum := 0.0
+	for _, i := range indices {
+		sum += y[i]
+	}
+	node := &RegressionTree{Value: sum / float64(len(indices))}
+	if depth >= settings.MaxDepth || len(indices) < 2*settings.MinLeaf {
+		return node
+	}
+	numFeatures := len(X[0])
+	features := source.Perm(numFeatures)[:int(math.Min(float64(numFeatures), math.Sqrt(float64(numFeatures))+1))]
+	feature, threshold, ok := bestSplit(X, y, indices, features, settings.MinLeaf)
+	if !ok {
+		return node
+	}
+	var left, right []int
+	for _, i := range indices {
+		if X[i][feature] <= threshold {
+			left = append(left, i)
+		} else {
+			right = append(right, i)
+		}
+	}
+	node.Feature, node.Threshold = feature, threshold
+	node.Left = growTree(X, y, left, depth+1, settings, source)
+	node.Right = growTree(X, y, right, depth+1, settings, source)
+	return node
+}
+
+// bestSplit finds the feature and threshold that minimise the summed squared error of the two sides.
+// Input: the feature matrix X and targets y, a slice of row indices, a slice of candidate features, an int minLeaf
+// Output: the int feature, the float64 threshold and a bool reporting whether any split reduces the error
+func bestSplit(X [][]float64, y []float64, indices []int, features []int, minLeaf int) (int, float64, bool) {
+	n := len(indices)
+	totalSum, totalSquares := 0.0, 0.0
+	for _, i := range indices {
+		totalSum += y[i]
+		totalSquares += y[i] * y[i]
+	}
+	bestError := totalSquares - totalSum*totalSum/float64(n) - 1e-12
+	bestFeature, bestThreshold, found := 0, 0.0, false
+	sorted := make([]int, n)
+	for _, feature := range features {
+		copy(sorted, indices)
+		sort.Slice(sorted, func(a, b int) bool { return X[sorted[a]][feature] < X[sorted[b]][feature] })
+		leftSum, leftSquares := 0.0, 0.0
+		for k := 0; k < n-1; k++ {
+			value := y[sorted[k]]
+			leftSum += value
+			leftSquares += value * value
+			leftCount, rightCount := float64(k+1), float64(n-k-1)
+			if k+1 < minLeaf || n-k-1 < minLeaf {
+				continue
+			}
+			current, next := X[sorted[k]][feature], X[sorted[k+1]][feature]
+			if current == next {
+				continue
+			}
+			rightSum, rightSquares := totalSum-leftSum, totalSquares-leftSquares
+			splitError := leftSquares - leftSum*leftSum/leftCount + rightSquares - rightSum*rightSum/rightCount
+			if splitError < bestError {
+				bestError, bestFeature, bestThreshold, found = splitError, feature, (current+next)/2, true
+			}
+		}
+	}
+	return bestFeature, bestThreshold, found
+}
+
+// Predict follows the splits of a tree down to a leaf.
+// Input: a *RegressionTree, a slice of float64 features
+// Output: a float64 prediction
+func (tree *RegressionTree) Predict(x []float64) float64 {
+	for tree.Left != nil && tree.Right != nil {
+		if x[tree.Feature] <= tree.Threshold {
+			tree = tree.Left
+		} else {
+			tree = tree.Right
+		}
+	}
+	return tree.Value
+}
+
+// Predict averages the predictions of all trees of the forest.
+// Input: a RandomForest, a slice of float64 features in the order of forest.Features
+// Output: a float64 prediction
+func (forest RandomForest) Predict(x []float64) float64 {
+	sum := 0.0
+	for _, tree := range forest.Trees {
+		sum += tree.Predict(x)
+	}
+	return sum / float64(len(forest.Trees))
+}
+
+// EvaluateRegression computes the mean squared, root mean squared and mean absolute errors and R² of predictions.
+// Input: slices of float64 predicted and actual values of equal length
+// Output: a RegressionMetrics
+func EvaluateRegression(predicted, actual []float64) RegressionMetrics {
+	metrics := RegressionMetrics{N: len(actual)}
+	if len(actual) == 0 {
+		return metrics
+	}
+	mean := analysis.Mean(actual)
+	var totalSquares float64
+	for i := range actual {
+		diff := predicted[i] - actual[i]
+		metrics.MSE += diff * diff
+		metrics.MAE += math.Abs(diff)
+		totalSquares += (actual[i] - mean) * (actual[i] - mean)
+	}
+	metrics.R2 = 1 - metrics.MSE/totalSquares
+	metrics.MSE /= float64(len(actual))
+	metrics.MAE /= float64(len(actual))
+	metrics.RMSE = math.Sqrt(metrics.MSE)
+	return metrics
+}
+
+// ReadFeatureTable reads the given columns of a CSV file with a header row. The first column is the row id.
+// Rows with an empty or non-numeric value in any of the columns are skipped.
+// Input: a string filename, a slice of column names (case-insensitive)
+// Output: the string ids, a matrix with one row per kept row and one column per name, the int number of skipped
+// rows, and an error or nil
+func ReadFeatureTable(filename string, names []string) ([]string, [][]float64, int, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("reading %s: %w", filename, err)
+	}
+	if len(records) < 2 {
+		return nil, nil, 0, fmt.Errorf("%s has no data rows", filename)
+	}
+	headers := make(map[string]int)
+	for i, header := range records[0] {
+		headers[strings.ToLower(strings.TrimSpace(header))] = i
+	}
+	columns := make([]int, len(names))
+	for i, name := range names {
+		column, ok := headers[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, nil, 0, fmt.Errorf("%s has no column %q", filename, name)
+		}
+		columns[i] = column
+	}
+	var ids []string
+	var rows [][]float64
+	skipped := 0
+	for _, record := range records[1:] {
+		row := make([]float64, len(columns))
+		valid := true
+		for i, column := range columns {
+			if column >= len(record) {
+				valid = false
+				break
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(record[column]), 64)
+			if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+				valid = false
+				break
+			}
+			row[i] = value
+		}
+		if !valid {
+			skipped++
+			continue
+		}
+		ids = append(ids, record[0])
+		rows = append(rows, row)
+	}
+	return ids, rows, skipped, nil
+}
+
+// SaveRandomForest writes a trained forest as JSON.
+// Input: a string fileName, a RandomForest
+// Output: an error or nil
+func SaveRandomForest(fileName string, forest RandomForest) error {
+	data, err := json.Marshal(forest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, data, 0644)
+}
+
+// LoadRandomForest reads a forest saved by SaveRandomForest.
+// Input: a string fileName
+// Output: a RandomForest and an error or nil
+func LoadRandomForest(fileName string) (RandomForest, error) {
+	var forest RandomForest
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return forest, err
+	}
+	if err := json.Unmarshal(data, &forest); err != nil {
+		return forest, fmt.Errorf("reading %s: %w", fileName, err)
+	}
+	if len(forest.Trees) == 0 || len(forest.Features) == 0 {
+		return forest, fmt.Errorf("%s is not a trained random forest", fileName)
+	}
+	return forest, nil
+}
+
+// printRegressionMetrics prints the errors of a set of predictions.
+// Input: a string title, a RegressionMetrics
+// Output: none (prints to standard output)
+func printRegressionMetrics(title string, metrics RegressionMetrics) {
+	fmt.Printf("%s (%d rows):\n", title, metrics.N)
+	fmt.Printf("  Mean Squared Error: %.4f\n", metrics.MSE)
+	fmt.Printf("  Root Mean Squared Error: %.4f\n", metrics.RMSE)
+	fmt.Printf("  Mean Absolute Error: %.4f\n", metrics.MAE)
+	fmt.Printf("  R2: %.4f\n", metrics.R2)
+}
+
+// TrainMain is the entry point of the "train" command, which trains a random forest on a table of energy terms,
+// reports its errors on held-out rows and saves the model for the predict command.
+// Usage: train [flags] data.csv model.json
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the model)
+func TrainMain(args []string) {
+	settings := DefaultForestSettings()
+	flags := commandFlags("train", "data.csv model.json",
+		"Trains a random forest regressor on the feature columns of data.csv (the first column is the row id),\n"+
+			"prints the errors on a random held-out fraction of the rows and saves the model as JSON.")
+	features := flags.String("features", strings.Join(defaultForestFeatures, ","), "comma-separated feature columns")
+	target := flags.String("target", defaultForestTarget, "target column")
+	testFraction := flags.Float64("test", 0.2, "fraction of rows held out for testing")
+	flags.IntVar(&settings.Trees, "trees", settings.Trees, "number of trees")
+	flags.IntVar(&settings.MaxDepth, "depth", settings.MaxDepth, "maximum tree depth")
+	flags.IntVar(&settings.MinLeaf, "min-leaf", settings.MinLeaf, "minimum samples per leaf")
+	flags.Int64Var(&settings.Seed, "seed", settings.Seed, "random seed of the split and the trees")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return
+	}
+	if settings.Trees < 1 || settings.MaxDepth < 1 || settings.MinLeaf < 1 || *testFraction < 0 || *testFraction >= 1 {
+		fmt.Println("trees, depth and min-leaf must be positive and test must be in [0, 1)")
+		return
+	}
+	featureNames := strings.Split(*features, ",")
+	for i := range featureNames {
+		featureNames[i] = strings.TrimSpace(featureNames[i])
+	}
+	_, rows, skipped, err := ReadFeatureTable(flags.Arg(0), append(append([]string(nil), featureNames...), *target))
+	Check(err)
+	if skipped > 0 {
+		fmt.Printf("Skipped %d rows with missing values\n", skipped)
+	}
+	numTest := int(float64(len(rows)) * *testFraction)
+	if len(rows)-numTest < 2*settings.MinLeaf {
+		fmt.Printf("Only %d training rows, at least %d are needed\n", len(rows)-numTest, 2*settings.MinLeaf)
+		return
+	}
+	order := rand.New(rand.NewSource(settings.Seed)).Perm(len(rows))
+	split := func(indices []int) ([][]float64, []float64) {
+		X, y := make([][]float64, len(indices)), make([]float64, len(indices))
+		for i, index := range indices {
+			X[i], y[i] = rows[index][:len(featureNames)], rows[index][len(featureNames)]
+		}
+		return X, y
+	}
+	trainX, trainY := split(order[numTest:])
+	testX, testY := split(order[:numTest])
+
+	fmt.Printf("Training %d trees on %d rows\n", settings.Trees, len(trainX))
+	forest := TrainRandomForest(trainX, trainY, featureNames, *target, settings)
+	if numTest > 0 {
+		predictions := make([]float64, len(testX))
+		for i := range testX {
+			predictions[i] = forest.Predict(testX[i])
+		}
+		metrics := EvaluateRegression(predictions, testY)
+		forest.Test = &metrics
+		printRegressionMetrics("Test set", metrics)
+	}
+	Check(SaveRandomForest(flags.Arg(1), forest))
+	fmt.Println("Model saved to", flags.Arg(1))
+}
+
+// PredictMain is the entry point of the "predict" command, which applies a trained random forest to a table.
+// Usage: predict [flags] model.json data.csv predictions.csv
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the predictions)
+func PredictMain(args []string) {
+	flags := commandFlags("predict", "model.json data.csv predictions.csv",
+		"Predicts the target of every row of data.csv that has the model's feature columns and writes the row id and\n"+
+			"prediction to predictions.csv. When data.csv also has the target column, the actual values and errors are reported.")
+	flags.Parse(args)
+	if flags.NArg() != 3 {
+		flags.Usage()
+		return
+	}
+	forest, err := LoadRandomForest(flags.Arg(0))
+	Check(err)
+	columns := append(append([]string(nil), forest.Features...), forest.Target)
+	ids, rows, skipped, err := ReadFeatureTable(flags.Arg(1), columns)
+	hasTarget := err == nil
+	if !hasTarget {
+		ids, rows, skipped, err = ReadFeatureTable(flags.Arg(1), forest.Features)
+		Check(err)
+	}
+	if skipped > 0 {
+		fmt.Printf("Skipped %d rows with missing values\n", skipped)
+	}
+
+	file, err := os.Create(flags.Arg(2))
+	Check(err)
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	header := []string{"id", "predicted"}
+	if hasTarget {
+		header = append(header, "actual")
+	}
+	Check(writer.Write(header))
+	predictions := make([]float64, len(rows))
+	actual := make([]float64, len(rows))
+	for i, row := range rows {
+		predictions[i] = forest.Predict(row[:len(forest.Features)])
+		record := []string{ids[i], strconv.FormatFloat(predictions[i], 'f', 4, 64)}
+		if hasTarget {
+			actual[i] = row[len(forest.Features)]
+			record = append(record, strconv.FormatFloat(actual[i], 'f', 4, 64))
+		}
+		Check(writer.Write(record))
+	}
+	writer.Flush()
+	Check(writer.Error())
+	if hasTarget {
+		printRegressionMetrics("Predictions", EvaluateRegression(predictions, actual))
+	}
+	fmt.Printf("Wrote %d predictions to %s\n", len(rows), flags.Arg(2))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+func TestRandomForest(t *testing.T) {
+	source := rand.New(rand.NewSource(1))
+	X := make([][]float64, 300)
+	y := make([]float64, len(X))
+	for i := range X {
+		X[i] = []float64{source.Float64() * 10, source.Float64()}
+		y[i] = 2*X[i][0] + 0.1*X[i][1]
+	}
+	settings := DefaultForestSettings()
+	settings.Trees = 20
+	forest := TrainRandomForest(X, y, []string{"a", "b"}, "y", settings)
+	predictions := make([]float64, len(X))
+	for i := range X {
+		predictions[i] = forest.Predict(X[i])
+	}
+	metrics := EvaluateRegression(predictions, y)
+	if metrics.R2 < 0.95 || metrics.N != len(X) {
+		t.Errorf("Expected the forest to fit a linear target with R2 > 0.95, got %+v", metrics)
+	}
+
+	fileName := filepath.Join(t.TempDir(), "model.json")
+	if err := SaveRandomForest(fileName, forest); err != nil {
+		t.Fatalf("Unexpected error saving: %v", err)
+	}
+	loaded, err := LoadRandomForest(fileName)
+	if err != nil {
+		t.Fatalf("Unexpected error loading: %v", err)
+	}
+	x := []float64{4.2, 0.5}
+	if math.Abs(loaded.Predict(x)-forest.Predict(x)) > 1e-12 || loaded.Target != "y" {
+		t.Errorf("Expected the loaded model to predict %v, got %v", forest.Predict(x), loaded.Predict(x))
+	}
+}
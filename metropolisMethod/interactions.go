@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+)
+
+// SaveInteractionReports writes the interaction table and per-residue table of every ligand pose, plus
+// fingerprints.csv and the Tanimoto similarity matrix of the fingerprints across ligands. Each pose is paired with
+// its own receptor, which differ only in their side chains after a flexible screen.
+// Input: a slice of Molecule receptors (one per pose), a slice of NamedMolecule poses, a string outputDir
+// Output: an error or nil
+func SaveInteractionReports(receptors []molecule.Molecule, poses []molio.NamedMolecule, outputDir string) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return err
+	}
+	fingerprints := make([]analysis.InteractionFingerprint, len(poses))
+	for k, pose := range poses {
+		interactions := analysis.DetectInteractions(receptors[k], pose.Molecule)
+		fingerprints[k] = analysis.NewInteractionFingerprint(receptors[k], interactions)
+		if err := analysis.WriteInteractionTable(filepath.Join(outputDir, pose.Name+"_interactions.csv"), interactions); err != nil {
+			return err
+		}
+		if err := analysis.WriteResidueInteractionTable(filepath.Join(outputDir, pose.Name+"_residues.csv"), interactions); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d interactions with %d residues\n", pose.Name, len(interactions), countResidues(interactions))
+	}
+
+	file, err := os.Create(filepath.Join(outputDir, "fingerprints.csv"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"ligand", "fingerprint", "interactions"})
+	for k, pose := range poses {
+		writer.Write([]string{pose.Name, fingerprints[k].String(), strings.Join(fingerprints[k].SetBits(), " ")})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	similarity, err := os.Create(filepath.Join(outputDir, "fingerprint_similarity.csv"))
+	if err != nil {
+		return err
+	}
+	defer similarity.Close()
+	writer = csv.NewWriter(similarity)
+	header := []string{"ligand"}
+	for _, pose := range poses {
+		header = append(header, pose.Name)
+	}
+	writer.Write(header)
+	for a := range poses {
+		row := []string{poses[a].Name}
+		for b := range poses {
+			row = append(row, fmt.Sprintf("%.3f", analysis.Tanimoto(fingerprints[a], fingerprints[b])))
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// countResidues returns the number of distinct residues among the interactions.
+// Input: a slice of Interaction
+// Output: an int
+func countResidues(interactions []analysis.Interaction) int {
+	seen := make(map[string]bool)
+	for _, interaction := range interactions {
+		seen[interaction.Residue] = true
+	}
+	return len(seen)
+}
+
+// InteractionsMain is the entry point of the "interactions" command, which analyses docked poses.
+// Usage: interactions protein ligand [ligand...] outputDir
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the interaction reports)
+func InteractionsMain(args []string) {
+	flags := commandFlags("interactions", "protein ligand [ligand...] outputDir",
+		"Detects the hydrogen bonds, salt bridges, π-stacking, cation-π, hydrophobic contacts and metal coordination of\n"+
+			"each ligand pose with the protein and writes an interaction report per ligand to the output directory.")
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) < 3 {
+		flags.Usage()
+		return
+	}
+	outputDir := args[len(args)-1]
+	provenance := NewProvenance("interactions")
+	provenance.Stage("load inputs")
+	receptor, err := molio.LoadReceptorStructure(args[0])
+	warnOrCheck(err)
+	Check(provenance.Input("protein", args[0]))
+	var poses []molio.NamedMolecule
+	var receptors []molecule.Molecule
+	for _, ligandFile := range args[1 : len(args)-1] {
+		ligand, err := molio.LoadReceptorStructure(ligandFile)
+		warnOrCheck(err)
+		Check(provenance.Input("ligand", ligandFile))
+		name := strings.TrimSuffix(filepath.Base(ligandFile), filepath.Ext(ligandFile))
+		poses = append(poses, molio.NamedMolecule{Name: name, Molecule: ligand})
+		receptors = append(receptors, receptor)
+	}
+	provenance.Stage("interactions")
+	Check(SaveInteractionReports(receptors, poses, outputDir))
+	Check(provenance.Save(outputDir))
+	fmt.Println("Interaction reports written to", outputDir)
+}
@@ -0,0 +1,132 @@
+// Package moltest holds the small test molecules shared by the tests of the metropolis packages.
+package moltest
+
+import (
+	"math"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// Pocket creates a receptor with a lysine side chain and a phenylalanine ring 3.8 Å below the origin.
+// Input: none
+// Output: a Molecule
+func Pocket() molecule.Molecule {
+	atoms := []molecule.Atom{
+		{Name: "CE", ResName: "LYS", ResSeq: 10, Chain: "A", Element: "C", Position: molecule.Position3d{X: 5.5, Y: 0, Z: 0}},
+		{Name: "NZ", ResName: "LYS", ResSeq: 10, Chain: "A", Element: "N", Position: molecule.Position3d{X: 4.2, Y: 0, Z: 0}},
+	}
+	for k, name := range []string{"CG", "CD1", "CE1", "CZ", "CE2", "CD2"} {
+		angle := float64(k) * math.Pi / 3
+		atoms = append(atoms, molecule.Atom{Name: name, ResName: "PHE", ResSeq: 20, Chain: "A", Element: "C",
+			Position: molecule.Position3d{X: 1.39 * math.Cos(angle), Y: 1.39 * math.Sin(angle), Z: -3.8}})
+	}
+	return molecule.Molecule{Atoms: atoms}
+}
+
+// Benzoate creates a benzoate ion with its carboxylate pointing along +x.
+// Input: none
+// Output: a Molecule
+func Benzoate() molecule.Molecule {
+	var ligand molecule.Molecule
+	for k := 0; k < 6; k++ {
+		angle := float64(k) * math.Pi / 3
+		ligand.Atoms = append(ligand.Atoms, molecule.Atom{Name: "C" + string(rune('1'+k)), Element: "C", Type: "C.ar",
+			Position: molecule.Position3d{X: 1.39 * math.Cos(angle), Y: 1.39 * math.Sin(angle)}})
+		ligand.Bonds = append(ligand.Bonds, molecule.Bond{A: k, B: (k + 1) % 6, Order: "ar"})
+	}
+	ligand.Atoms = append(ligand.Atoms,
+		molecule.Atom{Name: "C7", Element: "C", Type: "C.2", Position: molecule.Position3d{X: 2.89}},
+		molecule.Atom{Name: "O1", Element: "O", Type: "O.co2", FormalCharge: -1, Position: molecule.Position3d{X: 3.5, Y: 1.08}},
+		molecule.Atom{Name: "O2", Element: "O", Type: "O.co2", Position: molecule.Position3d{X: 3.5, Y: -1.08}},
+	)
+	ligand.Bonds = append(ligand.Bonds, molecule.Bond{A: 0, B: 6, Order: "1"}, molecule.Bond{A: 6, B: 7, Order: "ar"}, molecule.Bond{A: 6, B: 8, Order: "ar"})
+	return ligand
+}
+
+// Protein creates a mock protein molecule with two atoms at specified charges and fixed positions.
+// Input: two float64 charges charge1 and charge2
+// Output: a Molecule
+func Protein(charge1, charge2 float64) molecule.Molecule {
+	return molecule.Molecule{
+		Atoms: []molecule.Atom{
+			{Position: molecule.Position3d{X: 0, Y: 0, Z: 0}, Charge: charge1},
+			{Position: molecule.Position3d{X: 1, Y: 1, Z: 1}, Charge: charge2},
+		},
+	}
+}
+
+// Ligands generates a specified number of mock ligands with default atomic properties.
+// Input: an int count
+// Output: a slice of Molecule
+func Ligands(count int) []molecule.Molecule {
+	ligands := make([]molecule.Molecule, count)
+	for i := 0; i < count; i++ {
+		ligands[i] = Ligand()
+	}
+	return ligands
+}
+
+// Ligand creates a mock ligand molecule with two atoms of fixed charges and positions
+// Input: none
+// Output: a Molecule
+func Ligand() molecule.Molecule {
+	return molecule.Molecule{
+		Atoms: []molecule.Atom{
+			{Position: molecule.Position3d{X: 4, Y: 4, Z: 4}, Charge: 1},
+			{Position: molecule.Position3d{X: 3, Y: 3, Z: 3}, Charge: -1},
+		},
+	}
+}
+
+// LigandWithCharges creates a mock ligand molecule with two atoms having specified charges and fixed positions.
+// Input: two float64 charges charge1 and charge2
+// Output: a Molecule
+func LigandWithCharges(charge1, charge2 float64) molecule.Molecule {
+	return molecule.Molecule{
+		Atoms: []molecule.Atom{
+			{Position: molecule.Position3d{X: 4, Y: 4, Z: 4}, Charge: charge1},
+			{Position: molecule.Position3d{X: 3, Y: 3, Z: 3}, Charge: charge2},
+		},
+	}
+}
+
+// AlmostEqual compares two float64 values and checks if they are within a specified tolerance epsilon.
+// Input: two float64 values a and b, a float64 epsilon
+// Output: a bool indicating if the values are approximately equal
+func AlmostEqual(a, b float64, epsilon float64) bool {
+	return math.Abs(a-b) <= epsilon
+}
+
+// Acetate creates an acetate ion with both C-O bonds written as single bonds.
+// Input: none
+// Output: a Molecule
+func Acetate() molecule.Molecule {
+	return molecule.Molecule{
+		Atoms: []molecule.Atom{
+			{Name: "C1", Element: "C", Position: molecule.Position3d{X: 0, Y: 0, Z: 0}},
+			{Name: "C2", Element: "C", Position: molecule.Position3d{X: 1.52, Y: 0, Z: 0}},
+			{Name: "O1", Element: "O", Position: molecule.Position3d{X: 2.15, Y: 1.08, Z: 0}},
+			{Name: "O2", Element: "O", Position: molecule.Position3d{X: 2.15, Y: -1.08, Z: 0}},
+		},
+		Bonds: []molecule.Bond{{A: 0, B: 1, Order: "1"}, {A: 1, B: 2, Order: "2"}, {A: 1, B: 3, Order: "1"}},
+	}
+}
+
+// Serine creates a serine residue 45 of chain A without hydrogens, its side chain free to turn about χ1.
+// Input: none
+// Output: a Molecule
+func Serine() molecule.Molecule {
+	atom := func(name, element string, x, y, z float64) molecule.Atom {
+		return molecule.Atom{Name: name, Element: element, ResName: "SER", ResSeq: 45, Chain: "A", Position: molecule.Position3d{X: x, Y: y, Z: z}}
+	}
+	return molecule.Molecule{
+		Atoms: []molecule.Atom{
+			atom("N", "N", -1.46, 0, 0),
+			atom("CA", "C", 0, 0, 0),
+			atom("C", "C", 0.5, -0.7, 1.2),
+			atom("O", "O", 0.5, -0.7, 2.4),
+			atom("CB", "C", 0.5, 1.44, 0),
+			atom("OG", "O", 0, 2.1, 1.2),
+		},
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MAXQUEUEDJOBS is the number of jobs that may wait to run before submissions are refused
+const MAXQUEUEDJOBS = 100
+
+// JOBFILE is the name of the file that stores a job in its folder
+const JOBFILE = "job.json"
+
+// JobStatus is the state of a job: queued, running, done, failed or cancelled
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobDone      JobStatus = "done"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Job is a screen submitted to a JobManager
+type Job struct {
+	ID        string        `json:"id"`
+	Status    JobStatus     `json:"status"`
+	Priority  int           `json:"priority"` // higher runs first; equal priorities run in submission order
+	Error     string        `json:"error,omitempty"`
+	Submitted time.Time     `json:"submitted"`
+	Started   *time.Time    `json:"started,omitempty"`
+	Finished  *time.Time    `json:"finished,omitempty"`
+	Config    RunConfig     `json:"config"`
+	Result    *ScreenResult `json:"result,omitempty"`
+	Files     []string      `json:"files,omitempty"` // output files relative to the job folder
+}
+
+// JobRunner runs the screen of a job; it must return soon after the context is cancelled
+type JobRunner func(ctx context.Context, config RunConfig) (ScreenResult, error)
+
+// JobManager queues jobs and runs up to a set number of them at a time, highest priority first. Every job is kept
+// as job.json in its own folder, next to its outputs, so jobs that were queued or running when the process stopped
+// are queued again when it restarts.
+type JobManager struct {
+	dir        string
+	maxRunning int
+	run        JobRunner
+	mutex      sync.Mutex
+	jobs       map[string]*Job
+	order      []string                      // job ids in submission order
+	cancels    map[string]context.CancelFunc // of the running jobs
+	stopping   map[string]bool               // running jobs whose cancellation was requested
+	wake       chan struct{}
+}
+
+// NewJobManager creates a job manager keeping its jobs in dir and loads the jobs stored there.
+// Input: a string dir, an int maxRunning (jobs run at the same time), a JobRunner run
+// Output: a *JobManager and an error or nil
+func NewJobManager(dir string, maxRunning int, run JobRunner) (*JobManager, error) {
+	if maxRunning < 1 {
+		return nil, fmt.Errorf("at least one job must be allowed to run, got %d", maxRunning)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	manager := &JobManager{
+		dir:        dir,
+		maxRunning: maxRunning,
+		run:        run,
+		jobs:       make(map[string]*Job),
+		cancels:    make(map[string]context.CancelFunc),
+		stopping:   make(map[string]bool),
+		wake:       make(chan struct{}, 1),
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name(), JOBFILE))
+		if !entry.IsDir() || os.IsNotExist(err) {
+			continue
+		}
+		job := &Job{}
+		if err == nil {
+			err = json.Unmarshal(data, job)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("loading job %s: %w", entry.Name(), err)
+		}
+		if job.Status == JobRunning {
+			job.Status, job.Started = JobQueued, nil
+			manager.save(job)
+		}
+		manager.jobs[job.ID] = job
+		manager.order = append(manager.order, job.ID)
+	}
+	slices.SortStableFunc(manager.order, func(a, b string) int {
+		return manager.jobs[a].Submitted.Compare(manager.jobs[b].Submitted)
+	})
+	return manager, nil
+}
+
+// save writes a job to its folder; the caller holds the lock. A job that cannot be saved stays in memory.
+// Input: a *JobManager, a *Job
+// Output: none
+func (manager *JobManager) save(job *Job) {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err == nil {
+		folder := filepath.Join(manager.dir, job.ID)
+		temporary := filepath.Join(folder, JOBFILE+".tmp")
+		if err = os.MkdirAll(folder, 0755); err == nil {
+			if err = os.WriteFile(temporary, data, 0644); err == nil {
+				err = os.Rename(temporary, filepath.Join(folder, JOBFILE))
+			}
+		}
+	}
+	if err != nil {
+		log.Printf("Saving job %s: %v", job.ID, err)
+	}
+}
+
+// signal wakes the scheduler of Run.
+// Input: a *JobManager
+// Output: none
+func (manager *JobManager) signal() {
+	select {
+	case manager.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Submit queues the screen of a run configuration; its outputs go to the folder of the job.
+// Input: a *JobManager, a RunConfig config, an int priority
+// Output: the queued Job and an error or nil when the queue is full
+func (manager *JobManager) Submit(config RunConfig, priority int) (Job, error) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	queued := 0
+	for _, job := range manager.jobs {
+		if job.Status == JobQueued {
+			queued++
+		}
+	}
+	if queued >= MAXQUEUEDJOBS {
+		return Job{}, fmt.Errorf("%d jobs are already queued", queued)
+	}
+	job := &Job{ID: newID(), Status: JobQueued, Priority: priority, Submitted: time.Now(), Config: config}
+	job.Config.Outputs.Dir = filepath.Join(manager.dir, job.ID)
+	manager.jobs[job.ID] = job
+	manager.order = append(manager.order, job.ID)
+	manager.save(job)
+	manager.signal()
+	return *job, nil
+}
+
+// Get returns a copy of a job.
+// Input: a *JobManager, a string id
+// Output: the Job and a bool reporting whether it exists
+func (manager *JobManager) Get(id string) (Job, bool) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	job, ok := manager.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// List returns a copy of every job in submission order.
+// Input: a *JobManager
+// Output: a slice of Job
+func (manager *JobManager) List() []Job {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	jobs := make([]Job, len(manager.order))
+	for i, id := range manager.order {
+		jobs[i] = *manager.jobs[id]
+	}
+	return jobs
+}
+
+// Cancel cancels a queued job at once, or stops the simulation of a running job, which is then marked cancelled
+// when it returns.
+// Input: a *JobManager, a string id
+// Output: the Job and an error or nil when the job does not exist or has finished
+func (manager *JobManager) Cancel(id string) (Job, error) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	job, ok := manager.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("no job %q", id)
+	}
+	switch job.Status {
+	case JobQueued:
+		finished := time.Now()
+		job.Status, job.Finished = JobCancelled, &finished
+		manager.save(job)
+	case JobRunning:
+		manager.stopping[id] = true
+		manager.cancels[id]()
+	default:
+		return *job, fmt.Errorf("job %s is %s", id, job.Status)
+	}
+	return *job, nil
+}
+
+// next returns the queued job to run next: the highest priority, then the earliest submitted. The caller holds
+// the lock.
+// Input: a *JobManager
+// Output: a *Job, or nil when none is queued
+func (manager *JobManager) next() *Job {
+	var next *Job
+	for _, id := range manager.order {
+		job := manager.jobs[id]
+		if job.Status == JobQueued && (next == nil || job.Priority > next.Priority) {
+			next = job
+		}
+	}
+	return next
+}
+
+// Run starts queued jobs whenever fewer than maxRunning are running, until the context is cancelled. It then stops
+// the running jobs and waits for them; they are queued again, so they run after a restart.
+// Input: a *JobManager, a context.Context ctx
+// Output: none
+func (manager *JobManager) Run(ctx context.Context) {
+	var running sync.WaitGroup
+	for {
+		manager.mutex.Lock()
+		for len(manager.cancels) < manager.maxRunning && ctx.Err() == nil {
+			job := manager.next()
+			if job == nil {
+				break
+			}
+			jobContext, cancel := context.WithCancel(ctx)
+			started := time.Now()
+			job.Status, job.Started = JobRunning, &started
+			manager.cancels[job.ID] = cancel
+			manager.save(job)
+			running.Add(1)
+			go func(id string, config RunConfig) {
+				defer running.Done()
+				manager.execute(ctx, jobContext, id, config)
+			}(job.ID, job.Config)
+		}
+		manager.mutex.Unlock()
+		select {
+		case <-ctx.Done():
+			running.Wait()
+			return
+		case <-manager.wake:
+		}
+	}
+}
+
+// execute runs one job and records how it ended. Runner panics fail the job instead of stopping the process.
+// Input: a *JobManager, the context.Context of Run, the context.Context of the job, a string id, a RunConfig config
+// Output: none
+func (manager *JobManager) execute(ctx, jobContext context.Context, id string, config RunConfig) {
+	var result ScreenResult
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%v", r)
+			}
+		}()
+		result, err = manager.run(jobContext, config)
+		return err
+	}()
+	files, _ := listFiles(config.Outputs.Dir)
+
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	defer manager.signal()
+	job := manager.jobs[id]
+	manager.cancels[id]()
+	delete(manager.cancels, id)
+	finished := time.Now()
+	job.Finished, job.Files = &finished, slices.DeleteFunc(files, func(file string) bool { return file == JOBFILE })
+	switch {
+	case err == nil:
+		job.Status, job.Result = JobDone, &result
+		log.Printf("Job %s done in %v", id, finished.Sub(*job.Started).Round(time.Millisecond))
+	case manager.stopping[id]:
+		job.Status = JobCancelled
+		delete(manager.stopping, id)
+		log.Printf("Job %s cancelled", id)
+	case ctx.Err() != nil:
+		job.Status, job.Started, job.Finished = JobQueued, nil, nil
+		log.Printf("Job %s stopped by the shutdown, queued for the next start", id)
+	default:
+		job.Status, job.Error = JobFailed, err.Error()
+		log.Printf("Job %s failed: %v", id, err)
+	}
+	manager.save(job)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForStatus polls a job until it has the given status.
+func waitForStatus(t *testing.T, manager *JobManager, id string, status JobStatus) Job {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if job, _ := manager.Get(id); job.Status == status {
+			return job
+		}
+	}
+	job, _ := manager.Get(id)
+	t.Fatalf("Expected job %s to be %s, got %+v", id, status, job)
+	return job
+}
+
+func TestJobManagerPriorities(t *testing.T) {
+	var mutex sync.Mutex
+	var order []int
+	manager, err := NewJobManager(t.TempDir(), 1, func(ctx context.Context, config RunConfig) (ScreenResult, error) {
+		mutex.Lock()
+		order = append(order, config.Iterations)
+		mutex.Unlock()
+		if config.Iterations == 4 {
+			panic("engine failure")
+		}
+		return ScreenResult{Protein: "test"}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for i, priority := range []int{0, 5, 0, 1} {
+		config := DefaultRunConfig()
+		config.Iterations = i + 1
+		job, err := manager.Submit(config, priority)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, job.ID)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go manager.Run(ctx)
+	for _, id := range ids[:3] {
+		waitForStatus(t, manager, id, JobDone)
+	}
+	if job := waitForStatus(t, manager, ids[3], JobFailed); job.Error != "engine failure" {
+		t.Errorf("Expected a panic to fail the job, got %+v", job)
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	if want := []int{2, 4, 1, 3}; !slices.Equal(order, want) {
+		t.Errorf("Expected the jobs to run by priority then submission, %v, got %v", want, order)
+	}
+}
+
+func TestJobManagerCancelAndRestart(t *testing.T) {
+	dir := t.TempDir()
+	started := make(chan string, 10)
+	waitForCancel := func(ctx context.Context, config RunConfig) (ScreenResult, error) {
+		started <- config.Outputs.Dir
+		<-ctx.Done()
+		return ScreenResult{}, ctx.Err()
+	}
+	manager, err := NewJobManager(dir, 1, waitForCancel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := manager.Submit(DefaultRunConfig(), 0)
+	second, _ := manager.Submit(DefaultRunConfig(), 0)
+	third, _ := manager.Submit(DefaultRunConfig(), 0)
+	ctx, stop := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		manager.Run(ctx)
+		close(stopped)
+	}()
+
+	<-started
+	if _, err := manager.Cancel(second.ID); err != nil {
+		t.Fatalf("Unexpected error cancelling a queued job: %v", err)
+	}
+	if _, err := manager.Cancel(first.ID); err != nil {
+		t.Fatalf("Unexpected error cancelling a running job: %v", err)
+	}
+	waitForStatus(t, manager, first.ID, JobCancelled)
+	waitForStatus(t, manager, third.ID, JobRunning)
+	if _, err := manager.Cancel(first.ID); err == nil {
+		t.Errorf("Expected an error cancelling a cancelled job")
+	}
+
+	stop()
+	<-stopped
+	restarted, err := NewJobManager(dir, 1, waitForCancel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]JobStatus{first.ID: JobCancelled, second.ID: JobCancelled, third.ID: JobQueued}
+	jobs := restarted.List()
+	if len(jobs) != 3 {
+		t.Fatalf("Expected the 3 jobs to be loaded again, got %d", len(jobs))
+	}
+	for i, job := range jobs {
+		if job.ID != []string{first.ID, second.ID, third.ID}[i] || job.Status != statuses[job.ID] {
+			t.Errorf("Expected job %d to be %s after the restart, got %s %s", i, statuses[job.ID], job.ID, job.Status)
+		}
+	}
+}
@@ -1,51 +1,90 @@
 package main
 
 import (
+	"context"
 	"encoding/csv"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
 )
 
 func main() {
-	//TestMethodRMSD()
-	RunMultipleLigands()
-	//RShinyAppMain(os.Args)
+	os.Exit(RunCommand(os.Args[1:]))
 }
 
-func RShinyAppMain(args []string) {
-	if len(args) < 3 {
-		fmt.Println("Please provide right inputs.")
+// SimulateMain is the entry point of the "simulate" command used by the R Shiny app. It docks every ligand file
+// against the protein and writes simulations.csv with one binding energy per ligand, in argument order, next to
+// copies of the protein and of the ligand file with the lowest energy.
+// Usage: simulate [flags] protein ligand [ligand...]
+// Input: a slice of strings args (without the command name)
+// Output: none (writes to the output directory)
+func SimulateMain(args []string) {
+	defaults := DefaultRunConfig()
+	defaults.Inputs = InputConfig{}
+	flags := commandFlags("simulate", "protein ligand [ligand...]",
+		"Docks each ligand against the protein and writes simulations.csv (one BindingEnergy row per ligand),\n"+
+			"a copy of the protein, a copy of the ligand file with the lowest energy and the resolved run-config.json\n"+
+			"to the output directory. The protein and ligands may instead come from the inputs of -config.")
+	configFile := flags.String("config", "", "run configuration (.json or .toml); flags override its values")
+	defaults.AddSimulationFlags(flags)
+	defaults.AddOutputFlags(flags)
+	flags.Parse(args)
+	config, err := ResolveRunConfig(flags, *configFile, defaults)
+	exitOnConfigError(err)
+	if flags.NArg() >= 2 {
+		config.Inputs.Protein, config.Inputs.Ligands = flags.Arg(0), flags.Args()[1:]
+	}
+	if flags.NArg() == 1 || config.Inputs.Protein == "" || len(config.Inputs.Ligands) == 0 {
+		flags.Usage()
 		return
 	}
+	exitOnConfigError(config.ValidateInputs())
+	provenance := NewProvenance("simulate")
+	provenance.SetConfig(config)
+	provenance.Stage("load inputs")
+	outputDir := &config.Outputs.Dir
+	proteinFilePath := config.ProteinPath()
+	ligandFilePaths := config.Inputs.Ligands
 
-	proteinFilePath := args[1]
-	ligandFilePaths := args[2:] // All arguments after the first are ligand file paths
-
-	protein, err2 := ParseMol2(proteinFilePath)
-	Check(err2)
+	protein, err2 := molio.LoadReceptor(proteinFilePath)
+	warnOrCheck(err2)
+	Check(provenance.Input("protein", proteinFilePath))
+	ligands := make([]molecule.Molecule, len(ligandFilePaths))
+	for i, ligandFilePath := range ligandFilePaths {
+		ligand, err := molio.ParseMol2(ligandFilePath)
+		warnOrCheck(err)
+		if config.Inputs.ShiftThreshold > 0 {
+			ligand = sampling.ShiftLigandCloserByThreshold(ligand, protein, config.Inputs.ShiftThreshold)
+		}
+		ligands[i] = ligand
+		Check(provenance.Input("ligand", ligandFilePath))
+	}
+	sim, err := config.DockingSimulation(protein, ligands)
+	exitOnConfigError(err)
+	Check(os.MkdirAll(*outputDir, 0755))
+	Check(SaveRunConfig(filepath.Join(*outputDir, runConfigName(*configFile)), config))
 
 	results := make([]string, len(ligandFilePaths))
 
 	minEnergy := 1e20     // Set an initial large value for comparison
 	minEnergyLigand := "" // To store the path of the ligand with minimum energy
 
+	provenance.Stage("simulation")
 	for i, ligandFilePath := range ligandFilePaths {
-		ligand, err := ParseMol2(ligandFilePath)
-		Check(err)
-
-		iterations := 3000
-		rotate := true
-		numProcs := runtime.NumCPU()
-
 		// Perform energy minimization
-		newLigand := SimulateEnergyMinimizationParallel(protein, ligand, iterations, rotate, TEMPERATURE, numProcs)
-		newEnergy := CalculateEnergy(protein, newLigand)
+		newLigand, receptor, _, err := sampling.SimulateFlexibleLigand(context.Background(), protein, ligands[i], i, sim, false)
+		Check(err)
+		newEnergy := sim.Energy.Energy(receptor, newLigand)
 
 		// Update the minimum energy and ligand file path
 		if newEnergy < minEnergy {
@@ -60,11 +99,11 @@ func RShinyAppMain(args []string) {
 		fmt.Println("Finish i ligand here!:", i)
 	}
 
+	provenance.Stage("write results")
 	// Copy the ligand with the minimum energy to the output directory
 	if minEnergyLigand != "" {
-		outputDir := "../output"
 		baseName := filepath.Base(minEnergyLigand) // Get the original file name
-		outputLigandPath := filepath.Join(outputDir, baseName)
+		outputLigandPath := filepath.Join(*outputDir, baseName)
 
 		err := CopyFile(minEnergyLigand, outputLigandPath)
 		if err != nil {
@@ -75,11 +114,10 @@ func RShinyAppMain(args []string) {
 	}
 
 	// Copy the protein to the output directory
-	outputDir := "../output"
 	baseName := filepath.Base(proteinFilePath) // Get the original file name
-	outputProteinPath := filepath.Join(outputDir, baseName)
+	outputProteinPath := filepath.Join(*outputDir, baseName)
 
-	err := CopyFile(proteinFilePath, outputProteinPath)
+	err = CopyFile(proteinFilePath, outputProteinPath)
 	if err != nil {
 		log.Fatalf("Failed to copy the protein file: %v", err)
 	}
@@ -87,7 +125,7 @@ func RShinyAppMain(args []string) {
 	fmt.Println("Protein with minimum energy copied to:", outputProteinPath)
 
 	// Create a CSV file
-	outFile, err := os.Create("../output/simulations.csv")
+	outFile, err := os.Create(filepath.Join(*outputDir, "simulations.csv"))
 	if err != nil {
 		log.Fatalf("Failed to create output file: %v", err)
 	}
@@ -110,51 +148,362 @@ func RShinyAppMain(args []string) {
 			log.Fatalf("Failed to write row: %v", err)
 		}
 	}
+	writer.Flush()
+	Check(writer.Error())
 	fmt.Println("Binding Energy data written to simulations.csv")
+	Check(provenance.Save(*outputDir))
 }
 
-func TestMethodRMSD() {
-	dir := "Data/mol2_files"
-	iterations := 1000
-	rotate := false
-	numProcs := runtime.NumCPU()
-	numProteins := 2
-	MultipleProteinRMSD(dir, iterations, rotate, numProteins, numProcs)
+// RedockMain is the entry point of the "redock" command. It redocks the ligand of each complex in a data directory
+// from a random pose, prints the RMSD to the crystal pose and plots the RMSD of every complex.
+// Usage: redock [flags]
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the RMSD curve plot)
+func RedockMain(args []string) {
+	defaults := DefaultRunConfig()
+	defaults.Iterations, defaults.Moves.Rotate = 1000, false
+	defaults.Inputs = InputConfig{Dir: "Data/mol2_files", Limit: 2}
+	defaults.Outputs.Dir, defaults.Outputs.Plot = "Output/rmsd_curve", DefaultPlotOptions()
+	flags := commandFlags("redock", "",
+		"Redocks the ligand of each <pdb>_protein.mol2 / <pdb>_ligand.mol2 pair in the data directory from a random pose\n"+
+			"and plots the RMSD between the docked and crystal poses. Use -mode kabsch for conformers and symmetry or\n"+
+			"symmetry-kabsch to ignore flipped symmetric groups. The resolved run-config.json is written next to the plot.")
+	configFile := flags.String("config", "", "run configuration (.json or .toml); flags override its values")
+	flags.StringVar(&defaults.Inputs.Dir, "dir", defaults.Inputs.Dir, "data directory with <pdb>_protein.mol2 and <pdb>_ligand.mol2 files")
+	flags.IntVar(&defaults.Inputs.Limit, "limit", defaults.Inputs.Limit, "number of complexes to redock (0 for all)")
+	modeName := flags.String("mode", analysis.RMSDInPlace.String(), "RMSD mode: inplace, kabsch, symmetry or symmetry-kabsch")
+	defaults.AddSimulationFlags(flags)
+	defaults.AddOutputFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return
+	}
+	config, err := ResolveRunConfig(flags, *configFile, defaults)
+	exitOnConfigError(err)
+	mode, err := analysis.ParseRMSDMode(*modeName)
+	Check(err)
+	provenance := NewProvenance("redock")
+	provenance.SetConfig(config)
+	provenance.Parameter("mode", mode.String())
+	Check(os.MkdirAll(config.Outputs.Dir, 0755))
+	Check(SaveRunConfig(filepath.Join(config.Outputs.Dir, runConfigName(*configFile)), config))
+	provenance.Stage("redocking")
+	MultipleProteinRMSD(config.Inputs.Dir, config.Inputs.Limit, config, mode, config.Outputs.Dir, config.Outputs.Plot, provenance)
+	Check(provenance.Save(config.Outputs.Dir))
 }
 
-func RunMultipleLigands() {
-	dir := "Data/mol2_files"
-	ligandFiles, err := findFilesWithSubstring(dir, "ligand")
+// RMSDMain is the entry point of the "rmsd" command, which prints the RMSD between two poses of the same ligand.
+// Usage: rmsd [flags] pose reference
+// Input: a slice of strings args (without the command name)
+// Output: none (prints the RMSD)
+func RMSDMain(args []string) {
+	flags := commandFlags("rmsd", "pose reference",
+		"Prints the RMSD in Å between two poses of the same ligand (mol2, pdb or pqr files with the same atoms).")
+	modeName := flags.String("mode", analysis.RMSDSymmetry.String(), "RMSD mode: inplace, kabsch, symmetry or symmetry-kabsch")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return
+	}
+	mode, err := analysis.ParseRMSDMode(*modeName)
+	Check(err)
+	pose, err := molio.LoadMolecule(flags.Arg(0))
+	warnOrCheck(err)
+	reference, err := molio.LoadMolecule(flags.Arg(1))
+	warnOrCheck(err)
+	if len(pose.Atoms) != len(reference.Atoms) {
+		fmt.Printf("%s has %d atoms but %s has %d\n", flags.Arg(0), len(pose.Atoms), flags.Arg(1), len(reference.Atoms))
+		return
+	}
+	fmt.Printf("%s RMSD: %.4f\n", mode, analysis.CalculateRMSDMode(pose, reference, mode))
+}
+
+// ScreenMain is the entry point of the "screen" command, which docks the ligands of a data directory against one
+// protein and writes the energy plot, traces, ensemble plots, interaction reports and an HTML report.
+// Usage: screen [flags]
+// Input: a slice of strings args (without the command name)
+// Output: none (writes to the output directory)
+func ScreenMain(args []string) {
+	defaults := DefaultRunConfig()
+	flags := commandFlags("screen", "",
+		"Docks the files of the data directory whose name contains \"ligand\" against the protein and writes\n"+
+			"the results to <output>/<pdb>/: the energy plot and table, the lowest-energy pose, walker traces, ensemble plots,\n"+
+			"interaction reports, report.html and the resolved run configuration. Runs can be described by a JSON or TOML\n"+
+			"file given with -config; flags override its values. With -flexible or -flexible-distance the side chains of\n"+
+			"those receptor residues turn between library rotamers, and the receptor of each ligand is written to receptors/.\n"+
+			"The [[restraints]] of the configuration file guide the search; their energies are written to restraints.csv.")
+	configFile := flags.String("config", "", "run configuration (.json or .toml); flags override its values")
+	defaults.AddInputFlags(flags)
+	defaults.AddSimulationFlags(flags)
+	defaults.AddFlexibleFlags(flags)
+	defaults.AddOutputFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return
+	}
+	config, err := ResolveRunConfig(flags, *configFile, defaults)
+	exitOnConfigError(err)
+	exitOnConfigError(config.ValidateInputs())
+	_, err = RunMultipleLigands(context.Background(), config, runConfigName(*configFile))
 	Check(err)
-	iterations := 3000
-	rotate := true
-	numProcs := runtime.NumCPU()
-	ligandFiles = ligandFiles[:5]
-	ligands := make([]Molecule, len(ligandFiles))
+}
+
+// runConfigName is the name of the resolved run configuration written next to the results: run-config.toml when
+// the run was described by a TOML file, run-config.json otherwise.
+// Input: a string configFile (empty for none)
+// Output: a string file name
+func runConfigName(configFile string) string {
+	if format, err := configFormat(configFile); err == nil && format == "toml" {
+		return "run-config.toml"
+	}
+	return "run-config.json"
+}
+
+// exitOnConfigError prints the problems of an invalid run configuration and exits with status 1.
+// Input: an error or nil
+// Output: none
+func exitOnConfigError(err error) {
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// ScreenLigand is the result of one ligand of a screen
+type ScreenLigand struct {
+	Label     string  `json:"label"`
+	File      string  `json:"file"`
+	Energy    float64 `json:"energy"`
+	Pose      string  `json:"pose"`                // MOL2 file of the final pose, in the poses folder of the output directory
+	Receptor  string  `json:"receptor,omitempty"`  // receptor with the final side chains, in the receptors folder, when they are flexible
+	Restraint float64 `json:"restraint,omitempty"` // restraint energy of the pose, not part of Energy
+}
+
+// ScreenResult summarises a screen run by RunMultipleLigands
+type ScreenResult struct {
+	Protein   string         `json:"protein"`
+	OutputDir string         `json:"output_dir"`
+	Ligands   []ScreenLigand `json:"ligands"`
+	Duration  time.Duration  `json:"duration_ns"`
+}
+
+// RunMultipleLigands docks the ligands of a run configuration against its protein and saves the final pose of
+// every ligand and every result of the screen selected by the outputs, with the resolved configuration. With
+// flexible side chains the receptor of every ligand is saved too, in the format of the protein file. With
+// restraints the energy of every restraint is written to restraints.csv, apart from the binding energy.
+// The run stops without writing results when the context is cancelled.
+// Input: a context.Context ctx, a RunConfig config, a string configName (the file name of the resolved configuration)
+// Output: a ScreenResult (writes to <outputs.dir>/<pdb>/), and ctx.Err() when the run was cancelled, the error
+// of a flexible residue or a restraint that cannot be used, or the error of an input or output file
+func RunMultipleLigands(ctx context.Context, config RunConfig, configName string) (ScreenResult, error) {
+	provenance := NewProvenance("screen")
+	provenance.SetConfig(config)
+	provenance.Stage("load inputs")
+	ligandFiles, err := config.LigandFiles()
+	if err != nil {
+		return ScreenResult{}, err
+	}
+	plotOptions := config.Outputs.Plot
+	ligands := make([]molecule.Molecule, len(ligandFiles))
 	for i := range ligandFiles {
-		ligand, err := ParseMol2(ligandFiles[i])
-		Check(err)
+		ligand, err := molio.ParseMol2(ligandFiles[i])
+		if err := warn(err); err != nil {
+			return ScreenResult{}, err
+		}
 		ligands[i] = ligand
 	}
-	proteinFile := "223l_protein.mol2"
-	protein, err2 := ParseMol2(dir + "/" + proteinFile)
-	Check(err2)
+	proteinPath := config.ProteinPath()
+	protein, err := molio.LoadReceptor(proteinPath)
+	if err := warn(err); err != nil {
+		return ScreenResult{}, err
+	}
+	if err := provenance.Input("protein", proteinPath); err != nil {
+		return ScreenResult{}, err
+	}
+	for _, ligandFile := range ligandFiles {
+		if err := provenance.Input("ligand", ligandFile); err != nil {
+			return ScreenResult{}, err
+		}
+	}
+	references := make([]molecule.Molecule, len(ligands))
+	for i := range ligands {
+		references[i] = molecule.CopyLigand(ligands[i])
+		if config.Inputs.ShiftThreshold > 0 {
+			ligands[i] = sampling.ShiftLigandCloserByThreshold(ligands[i], protein, config.Inputs.ShiftThreshold)
+		}
+	}
+	sim, err := config.DockingSimulation(protein, ligands)
+	if err != nil {
+		return ScreenResult{}, err
+	}
+	if sim.Flexible != nil {
+		fmt.Println("Flexible side chains:", strings.Join(sim.Flexible.IDs(), " "))
+		provenance.Parameter("flexible residues", strings.Join(sim.Flexible.IDs(), " "))
+	}
+	for _, restraint := range sim.Restraints {
+		if restraint.Reference != "" {
+			if err := provenance.Input("restraint reference", restraint.Reference); err != nil {
+				return ScreenResult{}, err
+			}
+		}
+	}
+	outputs := config.Outputs
+	traced := outputs.Traces || outputs.Distributions || outputs.Report
 	fmt.Println("Starting simulation")
+	provenance.Stage("simulation")
 	start := time.Now()
-	minLigands, energyList := SimulateMultipleLigandsParallel(protein, ligands, iterations, rotate, TEMPERATURE, numProcs)
+	minLigands, receptors, energyList, traces, err := sampling.RunFlexibleSimulation(ctx, protein, ligands, sim, traced)
 	end := time.Since(start)
+	if err != nil {
+		return ScreenResult{}, err
+	}
 	fmt.Println("Time taken for simulation: ", end)
 	ligandLabels := make([]string, len(ligandFiles))
 	for i := range ligandFiles {
-		ligandLabels[i] = ExtractFileLabel(ligandFiles[i])
+		ligandLabels[i] = molio.ExtractFileLabel(ligandFiles[i])
+	}
+	proteinPDB := molio.ExtractFileLabel(proteinPath)
+	outputDir := filepath.Join(outputs.Dir, proteinPDB) + "/"
+	if err := os.MkdirAll(outputDir+"poses", 0755); err != nil {
+		return ScreenResult{}, err
+	}
+	if err := SaveRunConfig(outputDir+configName, config); err != nil {
+		return ScreenResult{}, err
 	}
-	proteinPDB := ExtractFileLabel(proteinFile)
-	outputDir := "Output/" + proteinPDB + "/"
-	err3 := os.MkdirAll(outputDir, 0755)
-	Check(err3)
+	provenance.Stage("energies and poses")
 	saveName := outputDir + proteinPDB + "-protein"
-	plotEnergy(ligandLabels, energyList, saveName)
-	SaveMinimumEnergyLigand(energyList, ligandFiles, outputDir+"minLigand_"+proteinFile, minLigands)
+	plotEnergy(ligandLabels, energyList, saveName, plotOptions)
+	if err := saveEnergiesToCSV(saveName+"-energies.csv", ligandLabels, energyList); err != nil {
+		return ScreenResult{}, err
+	}
+	if err := SaveMinimumEnergyLigand(energyList, ligandFiles, outputDir+"minLigand_"+filepath.Base(proteinPath), minLigands); err != nil {
+		return ScreenResult{}, err
+	}
+	result := ScreenResult{Protein: proteinPDB, OutputDir: outputDir, Duration: end}
+	for i := range minLigands {
+		pose := filepath.Join(outputDir, "poses", ligandLabels[i]+".mol2")
+		if err := UpdateMol2Coordinates(ligandFiles[i], pose, minLigands[i]); err != nil {
+			return ScreenResult{}, err
+		}
+		result.Ligands = append(result.Ligands, ScreenLigand{Label: ligandLabels[i], File: ligandFiles[i], Energy: energyList[i], Pose: pose})
+	}
+	if sim.Flexible != nil {
+		if err := os.MkdirAll(outputDir+"receptors", 0755); err != nil {
+			return ScreenResult{}, err
+		}
+		for i := range receptors {
+			receptorFile := filepath.Join(outputDir, "receptors", ligandLabels[i]+filepath.Ext(proteinPath))
+			if err := SaveReceptor(proteinPath, receptorFile, receptors[i]); err != nil {
+				return ScreenResult{}, err
+			}
+			result.Ligands[i].Receptor = receptorFile
+		}
+		if err := WriteSideChainTable(filepath.Join(outputDir, "receptors", "side_chains.csv"), ligandLabels, receptors, sim.Flexible); err != nil {
+			return ScreenResult{}, err
+		}
+	}
+	var restraintTerms [][]sampling.RestraintTerm
+	if len(sim.Restraints) > 0 {
+		restraintTerms = make([][]sampling.RestraintTerm, len(minLigands))
+		for i := range minLigands {
+			restraints, err := sampling.NewRestraintSet(sim.Restraints, receptors[i], ligands[i])
+			if err != nil {
+				return ScreenResult{}, err
+			}
+			restraintTerms[i] = restraints.Terms(receptors[i], minLigands[i])
+			result.Ligands[i].Restraint = RestraintEnergy(restraintTerms[i])
+		}
+		if err := WriteRestraintTable(outputDir+"restraints.csv", ligandLabels, restraintTerms); err != nil {
+			return ScreenResult{}, err
+		}
+	}
+	if outputs.Traces || outputs.Distributions {
+		provenance.Stage("traces and distributions")
+	}
+	for i := range traces {
+		if outputs.Traces {
+			if err := SaveSimulationTrace(traces[i], saveName+"-"+ligandLabels[i]+"-trace", plotOptions); err != nil {
+				return ScreenResult{}, err
+			}
+		}
+		if outputs.Distributions {
+			if err := SaveSampleDistributions(traces[i], saveName+"-"+ligandLabels[i], DefaultDistributionOptions(), plotOptions); err != nil {
+				return ScreenResult{}, err
+			}
+		}
+	}
+	if outputs.Interactions {
+		provenance.Stage("interactions")
+		poses := make([]molio.NamedMolecule, len(minLigands))
+		for i := range minLigands {
+			poses[i] = molio.NamedMolecule{Name: ligandLabels[i], Molecule: minLigands[i]}
+		}
+		if err := SaveInteractionReports(receptors, poses, outputDir+"interactions"); err != nil {
+			return ScreenResult{}, err
+		}
+	}
+	if outputs.Report {
+		provenance.Stage("report")
+		ligandSource := config.Inputs.Dir + " (" + strconv.Itoa(len(ligands)) + " files)"
+		if len(config.Inputs.Ligands) > 0 {
+			ligandSource = strconv.Itoa(len(ligands)) + " files"
+		}
+		report := ScreeningReport{
+			Title:   "Screening of " + strconv.Itoa(len(ligands)) + " ligands against " + proteinPDB,
+			Created: time.Now(),
+			Parameters: []ReportParameter{
+				{"Protein", proteinPath},
+				{"Ligands", ligandSource},
+				{"Iterations", strconv.Itoa(sim.Iterations)},
+				{"Seed", strconv.FormatInt(sim.Seed, 10)},
+				{"Energy model", sim.Energy.Model + ", " + sim.Energy.Dielectric + " dielectric"},
+				{"Rotation moves", strconv.FormatBool(sim.Moves.Rotate)},
+				{"Step size (Å)", strconv.FormatFloat(sim.Moves.StepSize, 'g', -1, 64)},
+				{"Temperature", sim.Schedule.String()},
+				{"Processors", strconv.Itoa(sim.Walkers)},
+				{"Min. intra-ligand distance (Å)", strconv.FormatFloat(sim.Moves.MinDistance, 'g', -1, 64)},
+				{"Max. rotation angle (rad)", strconv.FormatFloat(sim.Moves.MaxAngle, 'g', -1, 64)},
+				{"Simulation time", end.Round(time.Millisecond).String()},
+			},
+		}
+		if sim.Flexible != nil {
+			report.Parameters = append(report.Parameters, ReportParameter{"Flexible side chains", strings.Join(sim.Flexible.IDs(), " ")})
+		}
+		for _, restraint := range sim.Restraints {
+			report.Parameters = append(report.Parameters, ReportParameter{"Restraint", restraint.String()})
+		}
+		for i := range minLigands {
+			ligand := NewReportLigand(ligandLabels[i], receptors[i], minLigands[i], energyList[i], references[i], traces[i])
+			if restraintTerms != nil {
+				ligand.Restraint = RestraintEnergy(restraintTerms[i])
+			}
+			report.Ligands = append(report.Ligands, ligand)
+		}
+		energySVG, err := RenderSVG(func(fileName string, options PlotOptions) { plotEnergy(ligandLabels, energyList, fileName, options) }, plotOptions)
+		if err != nil {
+			return ScreenResult{}, err
+		}
+		report.Plots = append(report.Plots, ReportPlot{Title: "Binding energy of each ligand", SVG: energySVG})
+		for i := range traces {
+			walkers := traces[i]
+			traceSVG, err := RenderSVG(func(fileName string, options PlotOptions) {
+				plotWalkerSeries(walkers, func(w sampling.WalkerTrace) []float64 { return w.Energy }, fileName, "Energy trace of "+ligandLabels[i], "Protein Ligand Binding Energy", options)
+			}, plotOptions)
+			if err != nil {
+				return ScreenResult{}, err
+			}
+			report.Plots = append(report.Plots, ReportPlot{Title: "Energy trace of " + ligandLabels[i], SVG: traceSVG})
+		}
+		if err := SaveHTMLReport(outputDir+"report.html", report); err != nil {
+			return ScreenResult{}, err
+		}
+	}
+	if err := provenance.Save(outputDir); err != nil {
+		return ScreenResult{}, err
+	}
+	return result, nil
 }
 
 // CopyFile copies a file from src to dst
@@ -181,3 +530,26 @@ func Check(err error) {
 		panic(err)
 	}
 }
+
+// findFilesWithSubstring searches for files in the specified root directory that contain the given substring in their filenames and returns a list of matching file paths.
+// Input: a string rootDir, a string searchString
+// Output: a slice of strings containing matching file paths, and an error or nil
+func findFilesWithSubstring(rootDir, searchString string) ([]string, error) {
+	var matchingFiles []string
+
+	// Walk through the directory
+	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Check if it's a file and contains the search string
+		if !info.IsDir() && strings.Contains(info.Name(), searchString) {
+			matchingFiles = append(matchingFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matchingFiles, nil
+}
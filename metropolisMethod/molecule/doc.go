@@ -0,0 +1,4 @@
+// Package molecule is the molecular model shared by the metropolis packages: atoms with their coordinates, charges
+// and PDB/MOL2 annotations, bonds, residues, the element table, 3D vector helpers and the topology perception
+// (bond inference, hybridization and aromatic rings) built on them.
+package molecule
@@ -0,0 +1,123 @@
+package molecule
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ElementData holds the per-element properties needed by the parsers and the preparation steps.
+type ElementData struct {
+	Symbol         string
+	Mass           float64 // atomic mass in Da
+	CovalentRadius float64 // covalent radius in Å
+	VdWRadius      float64 // van der Waals radius in Å
+}
+
+// elementTable lists the elements commonly found in protein–ligand complexes.
+var elementTable = map[string]ElementData{
+	"H":  {"H", 1.008, 0.31, 1.20},
+	"C":  {"C", 12.011, 0.76, 1.70},
+	"N":  {"N", 14.007, 0.71, 1.55},
+	"O":  {"O", 15.999, 0.66, 1.52},
+	"F":  {"F", 18.998, 0.57, 1.47},
+	"NA": {"Na", 22.990, 1.66, 2.27},
+	"MG": {"Mg", 24.305, 1.41, 1.73},
+	"P":  {"P", 30.974, 1.07, 1.80},
+	"S":  {"S", 32.06, 1.05, 1.80},
+	"CL": {"Cl", 35.45, 1.02, 1.75},
+	"K":  {"K", 39.098, 2.03, 2.75},
+	"CA": {"Ca", 40.078, 1.76, 2.31},
+	"MN": {"Mn", 54.938, 1.39, 2.05},
+	"FE": {"Fe", 55.845, 1.32, 2.04},
+	"CO": {"Co", 58.933, 1.26, 2.00},
+	"NI": {"Ni", 58.693, 1.24, 1.63},
+	"CU": {"Cu", 63.546, 1.32, 1.40},
+	"ZN": {"Zn", 65.38, 1.22, 1.39},
+	"SE": {"Se", 78.971, 1.20, 1.90},
+	"BR": {"Br", 79.904, 1.20, 1.85},
+	"I":  {"I", 126.90, 1.39, 1.98},
+}
+
+// LookupElement returns the element data for a symbol in any letter case.
+// Input: a string symbol
+// Output: the ElementData and a bool reporting whether the element is known
+func LookupElement(symbol string) (ElementData, bool) {
+	data, ok := elementTable[strings.ToUpper(strings.TrimSpace(symbol))]
+	return data, ok
+}
+
+// NormalizeElement converts an element symbol to its canonical capitalisation ("CL" -> "Cl").
+// Input: a string symbol
+// Output: the canonical symbol, or "" if the element is unknown
+func NormalizeElement(symbol string) string {
+	data, ok := LookupElement(symbol)
+	if !ok {
+		return ""
+	}
+	return data.Symbol
+}
+
+// InferElementFromPDBName infers the element of a PDB atom from its 4-character name field (columns 13-16).
+// Following the PDB convention, a one-letter element is right-justified into column 14, so a leading space or digit
+// means the element is the next letter, while a two-letter element such as "FE" starts in column 13.
+// Input: a string name holding columns 13-16, a bool hetAtm indicating a HETATM record
+// Output: the inferred element symbol, or "" if none could be inferred
+func InferElementFromPDBName(name string, hetAtm bool) string {
+	if len(name) == 0 {
+		return ""
+	}
+	// Names taken from a full-width field keep their alignment
+	if len(name) == 4 && name[0] != ' ' && !unicode.IsDigit(rune(name[0])) {
+		// Two-letter elements only appear left-aligned in HETATM records or for ions such as "CL" and "NA"
+		if hetAtm {
+			if symbol := NormalizeElement(name[0:2]); symbol != "" {
+				return symbol
+			}
+		}
+		// Hydrogens with four-character names, e.g. "HG21"
+		return NormalizeElement(name[0:1])
+	}
+	trimmed := strings.TrimLeftFunc(name, func(r rune) bool {
+		return r == ' ' || unicode.IsDigit(r)
+	})
+	if trimmed == "" {
+		return ""
+	}
+	return InferElementFromName(trimmed)
+}
+
+// InferElementFromName infers an element from a free-form atom name, e.g. "CA1", "Cl3" or "O2'".
+// Mixed-case names such as "Cl" are read as two-letter elements, while upper-case names are read as one letter
+// unless the two-letter reading is the only valid element.
+// Input: a string name
+// Output: the inferred element symbol, or "" if none could be inferred
+func InferElementFromName(name string) string {
+	letters := strings.TrimLeftFunc(name, func(r rune) bool { return !unicode.IsLetter(r) })
+	if letters == "" {
+		return ""
+	}
+	if len(letters) >= 2 && unicode.IsLetter(rune(letters[1])) {
+		two := letters[0:2]
+		if unicode.IsLower(rune(letters[1])) {
+			if symbol := NormalizeElement(two); symbol != "" {
+				return symbol
+			}
+		}
+		if NormalizeElement(letters[0:1]) == "" {
+			return NormalizeElement(two)
+		}
+	}
+	return NormalizeElement(letters[0:1])
+}
+
+// ElementFromSybylType returns the element encoded in a SYBYL atom type such as "C.ar" or "Cl".
+// Input: a string sybylType
+// Output: the element symbol, or "" if unknown
+func ElementFromSybylType(sybylType string) string {
+	base := strings.SplitN(sybylType, ".", 2)[0]
+	switch base {
+	case "LP", "Du", "Any", "Hal", "Het", "Hev":
+		return ""
+	}
+	return NormalizeElement(base)
+}
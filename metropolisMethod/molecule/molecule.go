@@ -0,0 +1,161 @@
+package molecule
+
+import "math"
+
+// Molecule is a set of atoms and the bonds between them, such as a protein receptor or a ligand
+type Molecule struct {
+	Atoms []Atom
+	Bonds []Bond
+}
+
+// Bond connects two atoms of a Molecule by their indices into the atoms slice
+type Bond struct {
+	A, B  int    // 0-based atom indices
+	Order string // SYBYL bond type: "1", "2", "3", "ar", "am", "du", "un" or "nc"
+}
+
+// Atom is one atom of a Molecule with its coordinates, partial charge and the annotations of its input file
+type Atom struct {
+	Position     Position3d //Coordinates
+	Charge       float64    // Charge
+	Serial       int        // atom serial number from the input file
+	Name         string     // atom name, e.g. "CA"
+	Element      string     // element symbol, e.g. "C" or "Fe"
+	Type         string     // atom type, e.g. the SYBYL type "C.ar"
+	ResName      string     // residue name, e.g. "ALA"
+	ResSeq       int        // residue sequence number
+	ICode        string     // residue insertion code
+	Chain        string     // chain identifier
+	AltLoc       string     // alternate location indicator
+	Occupancy    float64    // occupancy
+	BFactor      float64    // temperature factor
+	FormalCharge int        // formal charge, e.g. +1 for "1+"
+	Radius       float64    // atomic radius in Å (PQR files only)
+	HetAtm       bool       // true for HETATM records
+}
+
+// Position3d is a point or vector in Å
+type Position3d struct {
+	X, Y, Z float64
+}
+
+// Normalize scales the vector to have a magnitude of 1
+func (v *Position3d) Normalize() {
+	mag := v.Magnitude()
+	if mag > 0 {
+		v.X /= mag
+		v.Y /= mag
+		v.Z /= mag
+	}
+}
+
+// Magnitude calculates the vector's magnitude
+func (v Position3d) Magnitude() float64 {
+	return math.Sqrt(v.X*v.X + v.Y*v.Y + v.Z*v.Z)
+}
+
+// Dot calculates the dot product between two vectors
+func (v Position3d) Dot(other Position3d) float64 {
+	return v.X*other.X + v.Y*other.Y + v.Z*other.Z
+}
+
+// Add performs vector addition
+func (v Position3d) Add(other Position3d) Position3d {
+	return Position3d{X: v.X + other.X, Y: v.Y + other.Y, Z: v.Z + other.Z}
+}
+
+// Scale multiplies the vector by a scalar value
+func (v Position3d) Scale(scalar float64) Position3d {
+	return Position3d{X: v.X * scalar, Y: v.Y * scalar, Z: v.Z * scalar}
+}
+
+// Cross returns the cross product a × b.
+// Input: two Position3d a and b
+// Output: a Position3d
+func Cross(a, b Position3d) Position3d {
+	return Position3d{X: a.Y*b.Z - a.Z*b.Y, Y: a.Z*b.X - a.X*b.Z, Z: a.X*b.Y - a.Y*b.X}
+}
+
+// RotateAtom rotates a 3D position around a specified axis by an angle using Rodrigues' rotation formula.
+// Input: a Position3d pos, a Position3d axis, a float64 theta
+// Output: a rotated Position3d
+func RotateAtom(pos, axis Position3d, theta float64) Position3d {
+	cosTheta := math.Cos(theta)
+	sinTheta := math.Sin(theta)
+	dot := pos.Dot(axis)
+
+	// Rodrigues' rotation formula
+	x := pos.X*cosTheta + sinTheta*(axis.Y*pos.Z-axis.Z*pos.Y) + axis.X*dot*(1-cosTheta)
+	y := pos.Y*cosTheta + sinTheta*(axis.Z*pos.X-axis.X*pos.Z) + axis.Y*dot*(1-cosTheta)
+	z := pos.Z*cosTheta + sinTheta*(axis.X*pos.Y-axis.Y*pos.X) + axis.Z*dot*(1-cosTheta)
+
+	return Position3d{X: x, Y: y, Z: z}
+}
+
+// FindClosestAtomDistance finds the minimum distance and the corresponding atom positions between the ligand and the protein.
+// Input: a Molecule ligand, a Molecule protein
+// Output: a float64 minimum distance and corresponding closest atom positions
+func FindClosestAtomDistance(ligand, protein Molecule) (float64, Position3d, Position3d) {
+	minDistance := math.MaxFloat64
+	var ligandAtomPos, proteinAtomPos Position3d
+	for _, ligAtom := range ligand.Atoms {
+		for _, protAtom := range protein.Atoms {
+			dist := Distance(ligAtom.Position, protAtom.Position)
+			if dist < minDistance {
+				minDistance = dist
+				ligandAtomPos = ligAtom.Position
+				proteinAtomPos = protAtom.Position
+			}
+		}
+	}
+	return minDistance, ligandAtomPos, proteinAtomPos
+}
+
+// Distance calculates the Euclidean distance between two 3D vectors.
+// Input: two Position3d vectors a and b
+// Output: a float64 distance
+func Distance(a, b Position3d) float64 {
+	dx := a.X - b.X
+	dy := a.Y - b.Y
+	dz := a.Z - b.Z
+	return math.Sqrt(dx*dx + dy*dy + dz*dz)
+}
+
+// CopyLigand creates a deep copy of the given ligand to avoid reference sharing.
+// Input: a Molecule ligand
+// Output: a deep copy of the Molecule ligand
+func CopyLigand(ligand Molecule) Molecule {
+	newAtoms := make([]Atom, len(ligand.Atoms))
+	copy(newAtoms, ligand.Atoms)
+	newBonds := make([]Bond, len(ligand.Bonds))
+	copy(newBonds, ligand.Bonds)
+	return Molecule{
+		Atoms: newAtoms,
+		Bonds: newBonds,
+	}
+}
+
+// Positions returns the atom coordinates of a molecule.
+// Input: a Molecule m
+// Output: a slice of Position3d
+func Positions(m Molecule) []Position3d {
+	positions := make([]Position3d, len(m.Atoms))
+	for i, atom := range m.Atoms {
+		positions[i] = atom.Position
+	}
+	return positions
+}
+
+// Centroid returns the geometric center of a set of points.
+// Input: a slice of Position3d points
+// Output: a Position3d centroid
+func Centroid(points []Position3d) Position3d {
+	var c Position3d
+	for _, p := range points {
+		c = c.Add(p)
+	}
+	if len(points) > 0 {
+		c = c.Scale(1 / float64(len(points)))
+	}
+	return c
+}
@@ -0,0 +1,94 @@
+package molecule_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+func TestCopyLigand(t *testing.T) {
+	ligand := moltest.LigandWithCharges(2.3, -8.7)
+	copied := molecule.CopyLigand(ligand)
+
+	if &ligand.Atoms[0] == &copied.Atoms[0] {
+		t.Errorf("Expected ligand to be deep copied")
+	}
+	if ligand.Atoms[0] != ligand.Atoms[0] || ligand.Atoms[1] != ligand.Atoms[1] {
+		t.Errorf("Ligand atom fields don't match")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	pos1 := molecule.Position3d{X: 1, Y: 2, Z: 3}
+	pos2 := molecule.Position3d{X: 4, Y: 6, Z: 8}
+	expected := math.Sqrt(50)
+	result := molecule.Distance(pos1, pos2)
+
+	if result != expected {
+		t.Errorf("Expected distance %f, got %f", expected, result)
+	}
+}
+
+func TestRotateAtom(t *testing.T) {
+	pos := molecule.Position3d{X: 1, Y: 0, Z: 0}
+	axis := molecule.Position3d{X: 0, Y: 0, Z: 1}
+	theta := math.Pi / 2
+	expected := molecule.Position3d{X: 0, Y: 1, Z: 0}
+	result := molecule.RotateAtom(pos, axis, theta)
+
+	if !moltest.AlmostEqual(result.X, expected.X, 1e-6) ||
+		!moltest.AlmostEqual(result.Y, expected.Y, 1e-6) ||
+		!moltest.AlmostEqual(result.Z, expected.Z, 1e-6) {
+		t.Errorf("Expected rotated position %v, got %v", expected, result)
+	}
+}
+
+func TestDihedral(t *testing.T) {
+	a := molecule.Position3d{X: 1, Y: 0, Z: 0}
+	b := molecule.Position3d{}
+	c := molecule.Position3d{X: 0, Y: 0, Z: 1}
+	tests := []struct {
+		d        molecule.Position3d
+		expected float64
+	}{
+		{molecule.Position3d{X: 1, Y: 0, Z: 1}, 0},
+		{molecule.Position3d{X: 0.5, Y: math.Sqrt(3) / 2, Z: 1}, math.Pi / 3},
+		{molecule.Position3d{X: 0.5, Y: -math.Sqrt(3) / 2, Z: 1}, -math.Pi / 3},
+		{molecule.Position3d{X: -1, Y: 0, Z: 2}, math.Pi},
+	}
+	for _, test := range tests {
+		if got := molecule.Dihedral(a, b, c, test.d); !moltest.AlmostEqual(math.Abs(got), math.Abs(test.expected), 1e-9) || got*test.expected < 0 {
+			t.Errorf("Dihedral with d at %v: expected %g, got %g", test.d, test.expected, got)
+		}
+	}
+}
+
+func TestFindClosestAtomDistance(t *testing.T) {
+	protein := moltest.Protein(2.0, 4.0)
+	ligand := moltest.Ligand()
+	expected := math.Sqrt(12.0)
+	distance, _, _ := molecule.FindClosestAtomDistance(ligand, protein)
+	if distance != expected {
+		t.Errorf("Expected distance %f, got %f", expected, distance)
+	}
+}
+
+func TestFindResidue(t *testing.T) {
+	residues := []molecule.Residue{
+		{Name: "SER", Chain: "A", Seq: 45},
+		{Name: "GLY", Chain: "A", Seq: 52, ICode: "A"},
+		{Name: "TYR", Chain: "B", Seq: 45},
+	}
+	for spec, expected := range map[string]string{"A:45": "A:SER45", "B:TYR45": "B:TYR45", "A:52A": "A:GLY52A", "gly52A": "A:GLY52A"} {
+		if residue, err := molecule.FindResidue(residues, spec); err != nil || residue.ID() != expected {
+			t.Errorf("FindResidue(%q): expected %s, got %s and %v", spec, expected, residue.ID(), err)
+		}
+	}
+	for _, spec := range []string{"45", "A:46", "A:TYR45", "SER", "A:45XY"} {
+		if _, err := molecule.FindResidue(residues, spec); err == nil {
+			t.Errorf("FindResidue(%q): expected an error", spec)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package molecule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ResidueIndexOf maps every atom to the index of its residue in SplitResidues order.
+// Input: a Molecule m
+// Output: a slice of residue indices
+func ResidueIndexOf(m Molecule) []int {
+	index := make([]int, len(m.Atoms))
+	for r, residue := range SplitResidues(m) {
+		for _, i := range residue.Atoms {
+			index[i] = r
+		}
+	}
+	return index
+}
+
+// Residue groups the atoms of one residue of a Molecule
+type Residue struct {
+	Name  string
+	Chain string
+	Seq   int
+	ICode string
+	Atoms []int // indices into the Molecule's atoms
+}
+
+// ID returns a readable residue identifier such as "A:HIS57", "A:GLY52A", or "HIS57" without a chain.
+// Input: none
+// Output: a string
+func (r Residue) ID() string {
+	if r.Chain == "" {
+		return fmt.Sprintf("%s%d%s", r.Name, r.Seq, r.ICode)
+	}
+	return fmt.Sprintf("%s:%s%d%s", r.Chain, r.Name, r.Seq, r.ICode)
+}
+
+// AtomIndex returns the index of the atom with the given name in the residue, or -1.
+// Input: a Molecule m, a string name
+// Output: an int atom index
+func (r Residue) AtomIndex(m Molecule, name string) int {
+	for _, i := range r.Atoms {
+		if m.Atoms[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SplitResidues groups consecutive atoms sharing chain, residue number, insertion code and residue name.
+// Input: a Molecule m
+// Output: a slice of Residues in file order
+func SplitResidues(m Molecule) []Residue {
+	var residues []Residue
+	for i, atom := range m.Atoms {
+		n := len(residues)
+		if n == 0 || residues[n-1].Chain != atom.Chain || residues[n-1].Seq != atom.ResSeq ||
+			residues[n-1].ICode != atom.ICode || residues[n-1].Name != atom.ResName {
+			residues = append(residues, Residue{Name: atom.ResName, Chain: atom.Chain, Seq: atom.ResSeq, ICode: atom.ICode})
+			n++
+		}
+		residues[n-1].Atoms = append(residues[n-1].Atoms, i)
+	}
+	return residues
+}
+
+// FindResidue finds a residue from an identifier such as "A:TYR45", "A:45", "TYR45" or "45", with an optional
+// insertion code as in "A:GLY52A". Without a chain the identifier must match a single residue.
+// Input: a slice of Residues, a string spec
+// Output: the Residue and an error when the identifier is malformed or matches no residue or several
+func FindResidue(residues []Residue, spec string) (Residue, error) {
+	chain, rest, hasChain := strings.Cut(strings.TrimSpace(spec), ":")
+	if !hasChain {
+		chain, rest = "", chain
+	}
+	start := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) })
+	if start < 0 {
+		return Residue{}, fmt.Errorf("residue %q has no number", spec)
+	}
+	end := start + 1
+	for end < len(rest) && unicode.IsDigit(rune(rest[end])) {
+		end++
+	}
+	name, iCode := strings.ToUpper(rest[:start]), rest[end:]
+	seq, err := strconv.Atoi(rest[start:end])
+	if err != nil || len(iCode) > 1 {
+		return Residue{}, fmt.Errorf("residue %q is not like A:TYR45, A:45, TYR45 or 45", spec)
+	}
+	var matches []Residue
+	for _, residue := range residues {
+		if residue.Seq == seq && residue.ICode == iCode && (!hasChain || residue.Chain == chain) && (name == "" || residue.Name == name) {
+			matches = append(matches, residue)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Residue{}, fmt.Errorf("residue %q not found", spec)
+	case 1:
+		return matches[0], nil
+	}
+	return Residue{}, fmt.Errorf("residue %q matches %d residues, give the chain as in %s", spec, len(matches), matches[0].ID())
+}
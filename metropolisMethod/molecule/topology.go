@@ -0,0 +1,310 @@
+package molecule
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Hybridization of an atom as used by the charge and hydrogen placement models
+type Hybridization int
+
+const (
+	HybridUnknown Hybridization = iota
+	HybridSP
+	HybridSP2
+	HybridSP3
+)
+
+// bondTolerance is added to the sum of covalent radii when inferring bonds from coordinates
+const bondTolerance = 0.45
+
+// InferBonds connects every pair of atoms closer than the sum of their covalent radii plus a tolerance.
+// It is used for molecules read from PDB files, which carry no bond table. Bond orders are left as "un".
+// Input: a Molecule m
+// Output: a slice of Bonds
+func InferBonds(m Molecule) []Bond {
+	bonds := make([]Bond, 0, len(m.Atoms))
+	radii := make([]float64, len(m.Atoms))
+	for i, atom := range m.Atoms {
+		radii[i] = covalentRadius(atom)
+	}
+	for i := 0; i < len(m.Atoms); i++ {
+		for j := i + 1; j < len(m.Atoms); j++ {
+			// Alternate locations of the same atom are never bonded to each other
+			if m.Atoms[i].AltLoc != "" && m.Atoms[j].AltLoc != "" && m.Atoms[i].AltLoc != m.Atoms[j].AltLoc {
+				continue
+			}
+			d := Distance(m.Atoms[i].Position, m.Atoms[j].Position)
+			if d > 0.4 && d < radii[i]+radii[j]+bondTolerance {
+				bonds = append(bonds, Bond{A: i, B: j, Order: "un"})
+			}
+		}
+	}
+	return bonds
+}
+
+// covalentRadius returns the covalent radius of an atom, defaulting to carbon for unknown elements.
+// Input: an Atom
+// Output: a float64 radius in Å
+func covalentRadius(atom Atom) float64 {
+	if data, ok := LookupElement(atom.Element); ok {
+		return data.CovalentRadius
+	}
+	return 0.76
+}
+
+// Neighbors builds the adjacency list of a molecule from its bond table.
+// Input: a Molecule m
+// Output: a slice where entry i lists the indices of atoms bonded to atom i
+func Neighbors(m Molecule) [][]int {
+	neighbors := make([][]int, len(m.Atoms))
+	for _, bond := range m.Bonds {
+		neighbors[bond.A] = append(neighbors[bond.A], bond.B)
+		neighbors[bond.B] = append(neighbors[bond.B], bond.A)
+	}
+	return neighbors
+}
+
+// EnsureBonds infers a bond table from coordinates if the molecule has none.
+// Input: a pointer to a Molecule m
+// Output: none (m.bonds is filled in place)
+func EnsureBonds(m *Molecule) {
+	if len(m.Bonds) == 0 && len(m.Atoms) > 1 {
+		m.Bonds = InferBonds(*m)
+	}
+}
+
+// AtomHybridization determines the hybridization of atom i. SYBYL atom types are used when present,
+// then explicit bond orders, and finally the bond angles and lengths, which also work without hydrogens.
+// Input: a Molecule m, the adjacency list neighbors, an int index i
+// Output: a Hybridization
+func AtomHybridization(m Molecule, neighbors [][]int, i int) Hybridization {
+	if h := hybridizationFromSybyl(m.Atoms[i].Type); h != HybridUnknown {
+		return h
+	}
+	maxOrder := ""
+	for _, bond := range m.Bonds {
+		if bond.A != i && bond.B != i {
+			continue
+		}
+		switch bond.Order {
+		case "3":
+			return HybridSP
+		case "2", "ar", "am":
+			maxOrder = bond.Order
+		}
+	}
+	if maxOrder != "" {
+		return HybridSP2
+	}
+	return hybridizationFromGeometry(m, neighbors, i)
+}
+
+// hybridizationFromSybyl maps the SYBYL type suffix to a hybridization.
+// Input: a string sybylType such as "C.ar"
+// Output: a Hybridization, HybridUnknown if the type does not encode one
+func hybridizationFromSybyl(sybylType string) Hybridization {
+	parts := strings.SplitN(sybylType, ".", 2)
+	if len(parts) != 2 {
+		return HybridUnknown
+	}
+	switch parts[1] {
+	case "1":
+		return HybridSP
+	case "2", "ar", "am", "pl3", "co2", "cat", "O", "O2":
+		return HybridSP2
+	case "3", "4":
+		return HybridSP3
+	}
+	return HybridUnknown
+}
+
+// hybridizationFromGeometry estimates the hybridization from the mean bond angle around the atom,
+// or from the bond length for terminal atoms.
+// Input: a Molecule m, the adjacency list neighbors, an int index i
+// Output: a Hybridization
+func hybridizationFromGeometry(m Molecule, neighbors [][]int, i int) Hybridization {
+	element := m.Atoms[i].Element
+	if element == "H" || element == "F" || element == "Cl" || element == "Br" || element == "I" {
+		return HybridSP3
+	}
+	nbrs := neighbors[i]
+	switch {
+	case len(nbrs) >= 4:
+		return HybridSP3
+	case len(nbrs) >= 2:
+		sum, count := 0.0, 0
+		for a := 0; a < len(nbrs); a++ {
+			for b := a + 1; b < len(nbrs); b++ {
+				sum += BondAngle(m.Atoms[nbrs[a]].Position, m.Atoms[i].Position, m.Atoms[nbrs[b]].Position)
+				count++
+			}
+		}
+		mean := sum / float64(count) * 180 / math.Pi
+		if mean > 155 {
+			return HybridSP
+		}
+		if mean > 115 {
+			return HybridSP2
+		}
+		return HybridSP3
+	case len(nbrs) == 1:
+		d := Distance(m.Atoms[i].Position, m.Atoms[nbrs[0]].Position)
+		switch element {
+		case "O":
+			if d < 1.30 {
+				return HybridSP2
+			}
+		case "N":
+			if d < 1.20 {
+				return HybridSP
+			}
+			if d < 1.35 {
+				return HybridSP2
+			}
+		case "C":
+			if d < 1.25 {
+				return HybridSP
+			}
+			if d < 1.42 {
+				return HybridSP2
+			}
+		}
+	}
+	return HybridSP3
+}
+
+// BondAngle returns the angle a-b-c in radians.
+// Input: three Position3d a, b (the vertex) and c
+// Output: a float64 angle in radians
+func BondAngle(a, b, c Position3d) float64 {
+	u := a.Add(b.Scale(-1))
+	v := c.Add(b.Scale(-1))
+	denom := u.Magnitude() * v.Magnitude()
+	if denom == 0 {
+		return 0
+	}
+	cos := u.Dot(v) / denom
+	return math.Acos(math.Max(-1, math.Min(1, cos)))
+}
+
+// Dihedral returns the dihedral angle a-b-c-d in radians, between -π and π. Following the IUPAC convention it is
+// positive when, looking from b towards c, the bond to d is turned clockwise from the bond to a.
+// Input: four Position3d a, b, c and d
+// Output: a float64 angle in radians
+func Dihedral(a, b, c, d Position3d) float64 {
+	b1 := b.Add(a.Scale(-1))
+	b2 := c.Add(b.Scale(-1))
+	b3 := d.Add(c.Scale(-1))
+	n1 := Cross(b1, b2)
+	n2 := Cross(b2, b3)
+	return math.Atan2(Cross(n1, n2).Dot(b2)/b2.Magnitude(), n1.Dot(n2))
+}
+
+// Ring is a ring of atoms with its geometric centre and plane normal
+type Ring struct {
+	Atoms    []int
+	Centroid Position3d
+	Normal   Position3d
+}
+
+// FindRings returns every simple cycle of the bond graph with at most maxSize atoms, each listed once.
+// Input: the adjacency list neighbors, an int maxSize
+// Output: a slice of rings, each a slice of atom indices in ring order
+func FindRings(neighbors [][]int, maxSize int) [][]int {
+	var rings [][]int
+	seen := make(map[string]bool)
+	path := []int{}
+	onPath := make([]bool, len(neighbors))
+	var walk func(start, current int)
+	walk = func(start, current int) {
+		for _, next := range neighbors[current] {
+			if next == start && len(path) >= 3 {
+				key := ringKey(path)
+				if !seen[key] {
+					seen[key] = true
+					rings = append(rings, append([]int(nil), path...))
+				}
+				continue
+			}
+			// only walk through atoms with a larger index than the start so each ring is found from its smallest atom
+			if next <= start || onPath[next] || len(path) == maxSize {
+				continue
+			}
+			path = append(path, next)
+			onPath[next] = true
+			walk(start, next)
+			onPath[next] = false
+			path = path[:len(path)-1]
+		}
+	}
+	for start := range neighbors {
+		path = append(path[:0], start)
+		onPath[start] = true
+		walk(start, start)
+		onPath[start] = false
+	}
+	return rings
+}
+
+// ringKey returns an order-independent key of a ring's atoms.
+// Input: a slice of atom indices
+// Output: a string key
+func ringKey(atoms []int) string {
+	sorted := append([]int(nil), atoms...)
+	sort.Ints(sorted)
+	return fmt.Sprint(sorted)
+}
+
+// NewRing computes the centroid and unit normal of a ring from the positions of its atoms.
+// Input: a Molecule m, a slice of atom indices in ring order
+// Output: a Ring
+func NewRing(m Molecule, atoms []int) Ring {
+	points := make([]Position3d, len(atoms))
+	for k, i := range atoms {
+		points[k] = m.Atoms[i].Position
+	}
+	ring := Ring{Atoms: atoms, Centroid: Centroid(points)}
+	// Newell's method gives a stable normal for slightly puckered rings
+	for k := range points {
+		a, b := points[k].Add(ring.Centroid.Scale(-1)), points[(k+1)%len(points)].Add(ring.Centroid.Scale(-1))
+		ring.Normal = ring.Normal.Add(Cross(a, b))
+	}
+	ring.Normal.Normalize()
+	return ring
+}
+
+// maxRingDeviation is the largest distance (Å) of a ring atom from the ring plane for the ring to count as planar
+const maxRingDeviation = 0.25
+
+// AromaticRings finds the five- and six-membered aromatic rings of a molecule: rings whose atoms all have an
+// aromatic SYBYL type, or, without types, planar rings of sp2 atoms.
+// Input: a Molecule m, its adjacency list
+// Output: a slice of Ring
+func AromaticRings(m Molecule, neighbors [][]int) []Ring {
+	var rings []Ring
+	for _, atoms := range FindRings(neighbors, 6) {
+		if len(atoms) < 5 {
+			continue
+		}
+		ring := NewRing(m, atoms)
+		aromaticTypes, sp2, planar := true, true, true
+		for _, i := range atoms {
+			if !strings.HasSuffix(m.Atoms[i].Type, ".ar") {
+				aromaticTypes = false
+			}
+			if AtomHybridization(m, neighbors, i) != HybridSP2 {
+				sp2 = false
+			}
+			if math.Abs(m.Atoms[i].Position.Add(ring.Centroid.Scale(-1)).Dot(ring.Normal)) > maxRingDeviation {
+				planar = false
+			}
+		}
+		if aromaticTypes || (sp2 && planar) {
+			rings = append(rings, ring)
+		}
+	}
+	return rings
+}
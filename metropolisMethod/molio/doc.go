@@ -0,0 +1,6 @@
+// Package molio reads and writes molecules: MOL2 files (single or multi-molecule, optionally gzipped), PDB and PQR
+// files with alternate locations and models, and PDB complexes split into protein and ligand records.
+// LoadMolecule picks the reader from the file extension, and LoadLigand and LoadReceptor also give molecules without
+// partial charges Gasteiger-Marsili and AMBER ff14SB charges. Problems a molecule survives, malformed lines and
+// assigned charges, are returned as errors for which IsWarning holds.
+package molio
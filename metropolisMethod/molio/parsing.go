@@ -0,0 +1,467 @@
+package molio
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/prepare"
+)
+
+// ParsePDB parses a PDB file to extract atomic coordinates, names, residues and elements, returning a Molecule containing the parsed atoms.
+// It reads the first model and keeps the highest-occupancy alternate location of each atom. Files ending in .pqr are read as PQR.
+// Malformed lines are skipped and reported through a PDBErrors error alongside the atoms that were parsed.
+// PDB files carry no partial charges: every atom keeps its formal charge, and LoadLigand or the receptor loaders
+// assign partial charges.
+// Input: a string filename
+// Output: a Molecule and an error
+func ParsePDB(filename string) (molecule.Molecule, error) {
+	return ParsePDBWithOptions(filename, DefaultPDBOptions())
+}
+
+// ParsePQR parses a PQR file, which carries a partial charge and a radius for every atom.
+// Input: a string filename
+// Output: a Molecule and an error
+func ParsePQR(filename string) (molecule.Molecule, error) {
+	opts := DefaultPDBOptions()
+	opts.PQR = true
+	return ParsePDBWithOptions(filename, opts)
+}
+
+// LoadMolecule reads a structure with the parser matching its file extension: .mol2 files with ParseMol2,
+// .pqr files with ParsePQR and anything else with ParsePDB. PDB files are read without partial charges; use
+// LoadLigand for a ligand to dock.
+// Input: a string filename
+// Output: a Molecule and an error
+func LoadMolecule(filename string) (molecule.Molecule, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".mol2":
+		return ParseMol2(filename)
+	case ".pqr":
+		return ParsePQR(filename)
+	default:
+		return ParsePDB(filename)
+	}
+}
+
+// LoadLigand reads a ligand with LoadMolecule and gives it Gasteiger-Marsili charges when its file carries no
+// partial charges, as PDB files never do, reporting a ChargeWarning.
+// Input: a string filename
+// Output: a Molecule and an error (possibly warnings, see IsWarning, together with a usable Molecule)
+func LoadLigand(filename string) (molecule.Molecule, error) {
+	mol, err := LoadMolecule(filename)
+	if len(mol.Atoms) > 0 && prepare.PartialChargesMissing(mol) {
+		prepare.AssignGasteigerCharges(&mol)
+		err = errors.Join(err, ChargeWarning{File: filename, Charges: "Gasteiger-Marsili"})
+	}
+	return mol, err
+}
+
+// ChargeWarning reports a file without partial charges whose molecule was given charges on loading. Like
+// PDBErrors, it is returned together with a usable Molecule.
+type ChargeWarning struct {
+	File    string
+	Charges string // the charges assigned instead, e.g. "Gasteiger-Marsili"
+}
+
+func (warning ChargeWarning) Error() string {
+	return fmt.Sprintf("%s has no partial charges, assigning %s charges", warning.File, warning.Charges)
+}
+
+// IsWarning reports whether err only carries problems the molecule read with it survives: PDBErrors and
+// ChargeWarnings, on their own, wrapped or combined with errors.Join.
+// Input: an error err
+// Output: true if err is non-nil and every error in it is a warning
+func IsWarning(err error) bool {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, inner := range joined.Unwrap() {
+			if !IsWarning(inner) {
+				return false
+			}
+		}
+		return len(joined.Unwrap()) > 0
+	}
+	var lineErrors PDBErrors
+	var charges ChargeWarning
+	return errors.As(err, &lineErrors) || errors.As(err, &charges)
+}
+
+// NamedMolecule is a molecule of a ligand set with the name it is reported under
+type NamedMolecule struct {
+	Name     string
+	Molecule molecule.Molecule
+}
+
+// LoadLigandSet reads every molecule of a ligand set: either a directory of .mol2, .pdb and .pqr files (each
+// named after its file) or a multi-molecule .mol2 or .mol2.gz file such as the DUD-E actives and decoys
+// (each named after its MOLECULE record). Molecules without partial charges get Gasteiger-Marsili charges.
+// Input: a string path
+// Output: a slice of NamedMolecule and an error (possibly the warnings of the files of a directory)
+func LoadLigandSet(path string) ([]NamedMolecule, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var set []NamedMolecule
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		var warnings []error
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || (ext != ".mol2" && ext != ".pdb" && ext != ".pqr") {
+				continue
+			}
+			mol, err := LoadLigand(filepath.Join(path, entry.Name()))
+			if err != nil && !IsWarning(err) {
+				return nil, err
+			}
+			if err != nil {
+				warnings = append(warnings, err)
+			}
+			set = append(set, NamedMolecule{Name: strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())), Molecule: mol})
+		}
+		return set, errors.Join(warnings...)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	records, err := ReadMol2Records(reader, 0)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	for i, record := range records {
+		if record.ChargesMissing || prepare.AllChargesZero(record.Molecule) {
+			prepare.AssignGasteigerCharges(&record.Molecule)
+		}
+		name := record.Name
+		if name == "" {
+			name = fmt.Sprintf("%s_%d", ExtractFileLabel(path), i+1)
+		}
+		set = append(set, NamedMolecule{Name: name, Molecule: record.Molecule})
+	}
+	return set, nil
+}
+
+// LoadEnsemble reads the receptor snapshots of an ensemble: either every model of a multi-model PDB or PQR file
+// (named model_1, model_2, ... in file order) or a directory of .pdb, .pqr and .mol2 frames (each named after its
+// file, in name order). No charges are assigned.
+// Input: a string path
+// Output: a slice of NamedMolecule and an error (possibly a PDBErrors together with usable snapshots)
+func LoadEnsemble(path string) ([]NamedMolecule, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []NamedMolecule
+	var lineErrors PDBErrors
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.IsDir() || (ext != ".mol2" && ext != ".pdb" && ext != ".pqr") {
+				continue
+			}
+			var mol molecule.Molecule
+			if ext == ".mol2" {
+				mol, _, err = ReadMol2(filepath.Join(path, entry.Name()))
+			} else {
+				mol, err = ParsePDBWithOptions(filepath.Join(path, entry.Name()), DefaultPDBOptions())
+			}
+			if errs, recoverable := err.(PDBErrors); recoverable {
+				lineErrors = append(lineErrors, errs...)
+			} else if err != nil {
+				return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+			}
+			snapshots = append(snapshots, NamedMolecule{Name: strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())), Molecule: mol})
+		}
+	} else {
+		models, err := ParsePDBModels(path, DefaultPDBOptions())
+		if errs, recoverable := err.(PDBErrors); recoverable {
+			lineErrors = errs
+		} else if err != nil {
+			return nil, err
+		}
+		for i, model := range models {
+			snapshots = append(snapshots, NamedMolecule{Name: fmt.Sprintf("model_%d", i+1), Molecule: model})
+		}
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("%s has no receptor snapshots", path)
+	}
+	for _, snapshot := range snapshots {
+		if len(snapshot.Molecule.Atoms) == 0 {
+			return nil, fmt.Errorf("%s: snapshot %s has no atoms", path, snapshot.Name)
+		}
+	}
+	if len(lineErrors) > 0 {
+		return snapshots, lineErrors
+	}
+	return snapshots, nil
+}
+
+// ParseMol2 parses a MOL2 file, extracting atomic information from the ATOM section, including coordinates and charges,
+// and the bond table from the BOND section, and returns a Molecule.
+// If the file declares NO_CHARGES, omits the charge column, or has only zero charges, Gasteiger-Marsili charges are assigned and a ChargeWarning is returned with the molecule.
+// Input: a string filename
+// Output: a Molecule and an error
+func ParseMol2(filename string) (molecule.Molecule, error) {
+	mol, chargesMissing, err := ReadMol2(filename)
+	if err != nil {
+		return mol, err
+	}
+	if chargesMissing || prepare.AllChargesZero(mol) {
+		prepare.AssignGasteigerCharges(&mol)
+		return mol, ChargeWarning{File: filename, Charges: "Gasteiger-Marsili"}
+	}
+	return mol, nil
+}
+
+// Mol2Record is one molecule of a MOL2 file together with its name from the MOLECULE record
+type Mol2Record struct {
+	Name           string
+	Molecule       molecule.Molecule
+	ChargesMissing bool
+}
+
+// ReadMol2 reads the atoms and bonds of the first molecule in a MOL2 file without modifying its charges.
+// Input: a string filename
+// Output: a Molecule, a bool reporting whether the file carries no charges, and an error
+func ReadMol2(filename string) (molecule.Molecule, bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return molecule.Molecule{}, false, err
+	}
+	defer file.Close()
+
+	records, err := ReadMol2Records(file, 1)
+	if err != nil || len(records) == 0 {
+		return molecule.Molecule{}, false, err
+	}
+	return records[0].Molecule, records[0].ChargesMissing, nil
+}
+
+// ReadMol2Records reads the molecules of a (multi-molecule) MOL2 stream without modifying their charges.
+// Input: an io.Reader r, an int limit on the number of molecules to read (0 reads all)
+// Output: a slice of Mol2Record and an error
+func ReadMol2Records(r io.Reader, limit int) ([]Mol2Record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var records []Mol2Record
+	var current *Mol2Record
+	section := ""
+	moleculeLine := 0
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Check for a section start
+		if strings.HasPrefix(line, "@<TRIPOS>") {
+			section = strings.TrimPrefix(strings.TrimSpace(line), "@<TRIPOS>")
+			moleculeLine = 0
+			// A file without a MOLECULE record still holds one molecule
+			if section == "MOLECULE" || current == nil {
+				if section == "MOLECULE" && limit > 0 && len(records) == limit {
+					break
+				}
+				records = append(records, Mol2Record{})
+				current = &records[len(records)-1]
+			}
+			continue
+		}
+		if strings.TrimSpace(line) == "" || current == nil {
+			continue
+		}
+		mol := &current.Molecule
+
+		switch section {
+		case "MOLECULE":
+			moleculeLine++
+			switch moleculeLine {
+			case 1:
+				current.Name = strings.TrimSpace(line)
+			case 4:
+				// The fourth line of the MOLECULE record is the charge type
+				if strings.TrimSpace(line) == "NO_CHARGES" {
+					current.ChargesMissing = true
+				}
+			}
+		case "ATOM":
+			fields := strings.Fields(line)
+			if len(fields) < 6 {
+				continue
+			}
+
+			// Extract atom details
+			id, _ := strconv.Atoi(fields[0])          // Atom ID
+			x, _ := strconv.ParseFloat(fields[2], 64) // X coordinate
+			y, _ := strconv.ParseFloat(fields[3], 64) // Y coordinate
+			z, _ := strconv.ParseFloat(fields[4], 64) // Z coordinate
+
+			atom := molecule.Atom{
+				Serial:    id,
+				Name:      fields[1], // Atom Name
+				Position:  molecule.Position3d{X: x, Y: y, Z: z},
+				Type:      fields[5], // Atom Type
+				Element:   molecule.ElementFromSybylType(fields[5]),
+				Occupancy: 1.0,
+			}
+			if atom.Element == "" {
+				atom.Element = molecule.InferElementFromName(atom.Name)
+			}
+			if len(fields) >= 8 {
+				atom.ResSeq, _ = strconv.Atoi(fields[6])
+				// Substructure names such as "MET1" or "BME900" carry the residue number
+				atom.ResName = strings.TrimRightFunc(fields[7], func(r rune) bool { return r >= '0' && r <= '9' })
+			}
+			if len(fields) >= 9 {
+				atom.Charge, _ = strconv.ParseFloat(fields[8], 64) // Partial charge
+			} else {
+				current.ChargesMissing = true
+			}
+			mol.Atoms = append(mol.Atoms, atom)
+		case "BOND":
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				continue
+			}
+			a, errA := strconv.Atoi(fields[1])
+			b, errB := strconv.Atoi(fields[2])
+			if errA != nil || errB != nil {
+				continue
+			}
+			mol.Bonds = append(mol.Bonds, molecule.Bond{A: a - 1, B: b - 1, Order: fields[3]})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Drop empty molecules and bonds that point outside the atom table, e.g. in truncated files
+	valid := records[:0]
+	for _, record := range records {
+		mol := &record.Molecule
+		if len(mol.Atoms) == 0 {
+			continue
+		}
+		validBonds := mol.Bonds[:0]
+		for _, bond := range mol.Bonds {
+			if bond.A >= 0 && bond.B >= 0 && bond.A < len(mol.Atoms) && bond.B < len(mol.Atoms) {
+				validBonds = append(validBonds, bond)
+			}
+		}
+		mol.Bonds = validBonds
+		valid = append(valid, record)
+	}
+	return valid, nil
+}
+
+// SaveMol2 saves a Molecule to a MOL2 file, writing its atoms and bonds.
+// Atoms without a name or type are written with default properties.
+// Input: a string filename, a Molecule m
+// Output: an error or nil
+func SaveMol2(filename string, m molecule.Molecule) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	WriteMol2(writer, m, "Generated Molecule", "USER_CHARGES")
+	return writer.Flush()
+}
+
+// WriteMol2 writes a Molecule as one MOL2 MOLECULE record.
+// Input: an io.Writer w, a Molecule m, a string name, a string chargeType such as "GASTEIGER"
+// Output: none
+func WriteMol2(w io.Writer, m molecule.Molecule, name, chargeType string) {
+	// Write MOL2 header
+	fmt.Fprintf(w, "@<TRIPOS>MOLECULE\n")
+	fmt.Fprintf(w, "%s\n", name)
+	fmt.Fprintf(w, "%d %d 0 0 0\n", len(m.Atoms), len(m.Bonds)) // Number of atoms and bonds
+	fmt.Fprintf(w, "SMALL\n")
+	fmt.Fprintf(w, "%s\n\n", chargeType)
+
+	// Write ATOM section
+	fmt.Fprintf(w, "@<TRIPOS>ATOM\n")
+	for i, atom := range m.Atoms {
+		atomName := atom.Name
+		if atomName == "" {
+			atomName = "C" // default to "C" for simplicity
+		}
+		atomType := atom.Type
+		if atomType == "" {
+			atomType = atom.Element
+		}
+		if atomType == "" {
+			atomType = "C.3"
+		}
+		resSeq := atom.ResSeq
+		if resSeq == 0 {
+			resSeq = 1
+		}
+		substName := "MOLECULE"
+		if atom.ResName != "" {
+			substName = atom.ResName + strconv.Itoa(resSeq)
+		}
+		fmt.Fprintf(w, "%7d %-4s %10.4f %10.4f %10.4f %-5s %4d  %-8s %10.4f\n",
+			i+1,                                               // Atom ID
+			atomName,                                          // Atom name
+			atom.Position.X, atom.Position.Y, atom.Position.Z, // Coordinates
+			atomType,    // Atom type
+			resSeq,      // Substructure ID
+			substName,   // Substructure name
+			atom.Charge) // Charge
+	}
+
+	// Write BOND section
+	if len(m.Bonds) > 0 {
+		fmt.Fprintf(w, "@<TRIPOS>BOND\n")
+		for i, bond := range m.Bonds {
+			order := bond.Order
+			if order == "" {
+				order = "1"
+			}
+			fmt.Fprintf(w, "%6d %5d %5d %4s\n", i+1, bond.A+1, bond.B+1, order)
+		}
+	}
+}
+
+// ExtractFileLabel extracts the ligand label from a file path by removing the file extension and prefix.
+// Input: a string filePath
+// Output: a string file label
+func ExtractFileLabel(filePath string) string {
+	// Extract the base file name
+	base := filepath.Base(filePath)
+	// Split the base name by "_" and remove the extension
+	parts := strings.Split(base, "_")
+	if len(parts) > 0 {
+		return strings.TrimSuffix(parts[0], ".mol2")
+	}
+	return base
+}
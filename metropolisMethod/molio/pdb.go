@@ -0,0 +1,558 @@
+package molio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// AltLocPolicy selects which alternate location is kept when an atom has several
+type AltLocPolicy int
+
+const (
+	AltLocHighestOccupancy AltLocPolicy = iota // keep the alternate location with the highest occupancy (ties keep the first)
+	AltLocFirst                                // keep the first alternate location encountered in the file
+	AltLocByID                                 // keep the alternate location named by PDBOptions.AltLocID
+	AltLocAll                                  // keep every alternate location
+)
+
+// PDBOptions controls how ParsePDBWithOptions reads a PDB or PQR file
+type PDBOptions struct {
+	AltLoc   AltLocPolicy
+	AltLocID string // used with AltLocByID, e.g. "B"
+	Model    int    // MODEL serial number to read; 0 selects the first model in the file
+	PQR      bool   // read the file as PQR (whitespace separated, charge and radius after the coordinates)
+	Strict   bool   // fail on the first malformed line instead of collecting errors
+}
+
+// DefaultPDBOptions returns the options used by ParsePDB: first model, highest-occupancy alternate locations.
+// Input: none
+// Output: a PDBOptions
+func DefaultPDBOptions() PDBOptions {
+	return PDBOptions{AltLoc: AltLocHighestOccupancy}
+}
+
+// PDBLineError records a malformed record in a PDB or PQR file
+type PDBLineError struct {
+	Line   int    // 1-based line number
+	Record string // the offending line
+	Err    error
+}
+
+func (e PDBLineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e PDBLineError) Unwrap() error {
+	return e.Err
+}
+
+// PDBErrors collects every malformed line found in a file. The parsers return it alongside the atoms that were read
+// successfully, so callers may choose to warn and continue.
+type PDBErrors []PDBLineError
+
+func (errs PDBErrors) Error() string {
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	return fmt.Sprintf("%d malformed lines, first: %v", len(errs), errs[0])
+}
+
+// pdbModel holds the atoms of one MODEL block while reading
+type pdbModel struct {
+	serial int
+	atoms  []molecule.Atom
+}
+
+// ParsePDBWithOptions parses a PDB file (or a PQR file when opts.PQR is set) and returns the selected model.
+// Malformed ATOM/HETATM lines are skipped and reported through a PDBErrors error unless opts.Strict is set,
+// in which case the first malformed line aborts the parse.
+// Input: a string filename, a PDBOptions opts
+// Output: a Molecule and an error (possibly a PDBErrors together with a usable Molecule)
+func ParsePDBWithOptions(filename string, opts PDBOptions) (molecule.Molecule, error) {
+	models, err := ParsePDBModels(filename, opts)
+	if len(models) == 0 {
+		return molecule.Molecule{}, err
+	}
+	return models[0], err
+}
+
+// ParsePDBModels parses every MODEL block of a PDB file. If opts.Model is non-zero only that model is returned.
+// Input: a string filename, a PDBOptions opts
+// Output: a slice of Molecules (one per model) and an error
+func ParsePDBModels(filename string, opts PDBOptions) ([]molecule.Molecule, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if strings.EqualFold(filepath.Ext(filename), ".pqr") {
+		opts.PQR = true
+	}
+	return ReadPDBModels(file, opts)
+}
+
+// ReadPDBModels reads PDB or PQR records from r, grouping atoms by MODEL block.
+// Input: an io.Reader r, a PDBOptions opts
+// Output: a slice of Molecules (one per model) and an error
+func ReadPDBModels(r io.Reader, opts PDBOptions) ([]molecule.Molecule, error) {
+	scanner := bufio.NewScanner(r)
+	var models []pdbModel
+	current := pdbModel{serial: 1}
+	inModel := false
+	var lineErrors PDBErrors
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		record := strings.TrimSpace(safeColumns(line, 0, 6))
+		switch record {
+		case "MODEL":
+			serial, err := strconv.Atoi(strings.TrimSpace(line[min(len(line), 6):]))
+			if err != nil {
+				serial = len(models) + 1
+			}
+			if inModel || len(current.atoms) > 0 {
+				models = append(models, current)
+			}
+			current = pdbModel{serial: serial}
+			inModel = true
+		case "ENDMDL":
+			models = append(models, current)
+			current = pdbModel{serial: current.serial + 1}
+			inModel = false
+		case "ATOM", "HETATM":
+			var atom molecule.Atom
+			var err error
+			if opts.PQR {
+				atom, err = parsePQRAtomLine(line)
+			} else {
+				atom, err = parsePDBAtomLine(line)
+			}
+			if err != nil {
+				lineError := PDBLineError{Line: lineNumber, Record: line, Err: err}
+				if opts.Strict {
+					return nil, lineError
+				}
+				lineErrors = append(lineErrors, lineError)
+				continue
+			}
+			current.atoms = append(current.atoms, atom)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(current.atoms) > 0 {
+		models = append(models, current)
+	}
+
+	molecules := make([]molecule.Molecule, 0, len(models))
+	for _, model := range models {
+		if opts.Model != 0 && model.serial != opts.Model {
+			continue
+		}
+		molecules = append(molecules, molecule.Molecule{Atoms: SelectAltLocs(model.atoms, opts.AltLoc, opts.AltLocID)})
+	}
+	if opts.Model != 0 && len(molecules) == 0 {
+		return nil, fmt.Errorf("model %d not found", opts.Model)
+	}
+	if len(lineErrors) > 0 {
+		return molecules, lineErrors
+	}
+	return molecules, nil
+}
+
+// parsePDBAtomLine parses a fixed-column PDB ATOM or HETATM record.
+// Input: a string line
+// Output: an Atom and an error
+func parsePDBAtomLine(line string) (molecule.Atom, error) {
+	if len(line) < 54 {
+		return molecule.Atom{}, fmt.Errorf("record too short for coordinates (%d columns)", len(line))
+	}
+	atom := molecule.Atom{
+		HetAtm:    strings.HasPrefix(line, "HETATM"),
+		Name:      strings.TrimSpace(line[12:16]),
+		AltLoc:    strings.TrimSpace(line[16:17]),
+		ResName:   strings.TrimSpace(line[17:20]),
+		Chain:     strings.TrimSpace(line[21:22]),
+		ICode:     strings.TrimSpace(line[26:27]),
+		Occupancy: 1.0,
+	}
+	var err error
+	if serial := strings.TrimSpace(line[6:11]); serial != "" {
+		// Files of more than 99,999 atoms write hybrid-36 serials, e.g. "A0000" for 100000
+		if atom.Serial, err = DecodeHybrid36(serial, 5); err != nil {
+			return molecule.Atom{}, fmt.Errorf("invalid atom serial %q", line[6:11])
+		}
+	}
+	// Residue numbers above 9999 are hybrid-36 as well, e.g. "A000" for 10000
+	if atom.ResSeq, err = DecodeHybrid36(strings.TrimSpace(line[22:26]), 4); err != nil {
+		return molecule.Atom{}, fmt.Errorf("invalid residue number %q", line[22:26])
+	}
+	if atom.Position.X, err = strconv.ParseFloat(strings.TrimSpace(line[30:38]), 64); err != nil {
+		return molecule.Atom{}, fmt.Errorf("invalid x coordinate %q", line[30:38])
+	}
+	if atom.Position.Y, err = strconv.ParseFloat(strings.TrimSpace(line[38:46]), 64); err != nil {
+		return molecule.Atom{}, fmt.Errorf("invalid y coordinate %q", line[38:46])
+	}
+	if atom.Position.Z, err = strconv.ParseFloat(strings.TrimSpace(line[46:54]), 64); err != nil {
+		return molecule.Atom{}, fmt.Errorf("invalid z coordinate %q", line[46:54])
+	}
+	if field := strings.TrimSpace(safeColumns(line, 54, 60)); field != "" {
+		if atom.Occupancy, err = strconv.ParseFloat(field, 64); err != nil {
+			return molecule.Atom{}, fmt.Errorf("invalid occupancy %q", field)
+		}
+	}
+	if field := strings.TrimSpace(safeColumns(line, 60, 66)); field != "" {
+		if atom.BFactor, err = strconv.ParseFloat(field, 64); err != nil {
+			return molecule.Atom{}, fmt.Errorf("invalid temperature factor %q", field)
+		}
+	}
+	if field := strings.TrimSpace(safeColumns(line, 76, 78)); field != "" {
+		if !isLetters(field) {
+			return molecule.Atom{}, fmt.Errorf("invalid element %q", field)
+		}
+		atom.Element = molecule.NormalizeElement(field)
+		if atom.Element == "" {
+			// Keep elements missing from elementTable, e.g. "HG", in canonical capitalisation
+			atom.Element = strings.ToUpper(field[0:1]) + strings.ToLower(field[1:])
+		}
+	} else {
+		atom.Element = molecule.InferElementFromPDBName(line[12:16], atom.HetAtm)
+	}
+	if field := strings.TrimSpace(safeColumns(line, 78, 80)); field != "" {
+		if atom.FormalCharge, err = ParseFormalCharge(field); err != nil {
+			return molecule.Atom{}, err
+		}
+	}
+	// PDB files carry no partial charges; the formal charge is the only charge information available
+	atom.Charge = float64(atom.FormalCharge)
+	return atom, nil
+}
+
+// parsePQRAtomLine parses a whitespace-separated PQR record:
+// ATOM serial name resName [chain] resSeq x y z charge radius
+// Input: a string line
+// Output: an Atom and an error
+func parsePQRAtomLine(line string) (molecule.Atom, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 10 {
+		return molecule.Atom{}, fmt.Errorf("PQR record has %d fields, need at least 10", len(fields))
+	}
+	n := len(fields)
+	values := make([]float64, 5)
+	for i := range values {
+		v, err := strconv.ParseFloat(fields[n-5+i], 64)
+		if err != nil {
+			return molecule.Atom{}, fmt.Errorf("invalid PQR number %q", fields[n-5+i])
+		}
+		values[i] = v
+	}
+	atom := molecule.Atom{
+		HetAtm:    fields[0] == "HETATM",
+		Name:      fields[2],
+		ResName:   fields[3],
+		Position:  molecule.Position3d{X: values[0], Y: values[1], Z: values[2]},
+		Charge:    values[3],
+		Radius:    values[4],
+		Occupancy: 1.0,
+	}
+	var err error
+	if atom.Serial, err = DecodeHybrid36(fields[1], 5); err != nil {
+		return molecule.Atom{}, fmt.Errorf("invalid atom serial %q", fields[1])
+	}
+	// The chain identifier is optional, so the residue number is the last field before the coordinates
+	resField := fields[n-6]
+	if n-6 > 4 {
+		atom.Chain = fields[4]
+	}
+	digits := strings.TrimRightFunc(resField, func(r rune) bool { return r < '0' || r > '9' })
+	atom.ICode = resField[len(digits):]
+	if atom.ResSeq, err = DecodeHybrid36(digits, 4); err != nil {
+		return molecule.Atom{}, fmt.Errorf("invalid residue number %q", resField)
+	}
+	atom.Element = molecule.InferElementFromName(atom.Name)
+	return atom, nil
+}
+
+// DecodeHybrid36 decodes a PDB number field that may be written in hybrid-36: decimal up to the largest number of
+// width digits, then base 36 with upper-case letters ("A0000" follows 99999), then with lower-case letters.
+// Input: a string field (without surrounding spaces), an int width of the field
+// Output: an int value and an error when the field is neither decimal nor hybrid-36
+func DecodeHybrid36(field string, width int) (int, error) {
+	if field == "" {
+		return 0, fmt.Errorf("empty number")
+	}
+	if value, err := strconv.Atoi(field); err == nil {
+		return value, nil
+	}
+	if len(field) != width {
+		return 0, fmt.Errorf("invalid hybrid-36 number %q", field)
+	}
+	upper := field[0] >= 'A' && field[0] <= 'Z'
+	if !upper && (field[0] < 'a' || field[0] > 'z') {
+		return 0, fmt.Errorf("invalid hybrid-36 number %q", field)
+	}
+	value := 0
+	for _, r := range field {
+		var digit int
+		switch {
+		case r >= '0' && r <= '9':
+			digit = int(r - '0')
+		case upper && r >= 'A' && r <= 'Z':
+			digit = int(r-'A') + 10
+		case !upper && r >= 'a' && r <= 'z':
+			digit = int(r-'a') + 10
+		default:
+			return 0, fmt.Errorf("invalid hybrid-36 number %q", field)
+		}
+		value = value*36 + digit
+	}
+	power, decimal := 1, 1
+	for i := 0; i < width-1; i++ {
+		power *= 36
+		decimal *= 10
+	}
+	decimal *= 10
+	// Upper-case numbers start at 10^width for "A000…"; lower-case ones follow the 26·36^(width-1) upper-case ones
+	if upper {
+		return value - 10*power + decimal, nil
+	}
+	return value + 16*power + decimal, nil
+}
+
+// WriteBFactors writes a molecule as PDB with per-atom values, e.g. interaction energies, in the B-factor column.
+// The values are scaled so the largest magnitude becomes ±99.99, which fits the column; negative values stay
+// negative.
+// Input: an io.Writer w, a Molecule m, a slice of float64 values (one per atom)
+// Output: none (writes to w)
+func WriteBFactors(w io.Writer, m molecule.Molecule, values []float64) {
+	largest := 0.0
+	for _, value := range values {
+		largest = math.Max(largest, math.Abs(value))
+	}
+	colored := molecule.CopyLigand(m)
+	for i := range colored.Atoms {
+		colored.Atoms[i].BFactor = 0
+		if largest > 0 {
+			colored.Atoms[i].BFactor = 99.99 * values[i] / largest
+		}
+	}
+	WritePDB(w, colored, false)
+}
+
+// EncodeHybrid36 writes value in a PDB number field of the given width, switching to hybrid-36 once the value no
+// longer fits in decimal. It is the inverse of DecodeHybrid36.
+// Input: an int value, an int width of the field
+// Output: a string of at most width characters and an error when the value is out of the hybrid-36 range
+func EncodeHybrid36(value, width int) (string, error) {
+	power, decimal := 1, 1
+	for i := 0; i < width-1; i++ {
+		power *= 36
+		decimal *= 10
+	}
+	low := -(decimal - 1)
+	decimal *= 10
+	switch {
+	case value >= low && value < decimal:
+		return strconv.Itoa(value), nil
+	case value < low:
+		return "", fmt.Errorf("number %d is below the range of a %d-column field", value, width)
+	case value < decimal+26*power:
+		return base36(value-decimal+10*power, width, 'A'), nil
+	case value < decimal+52*power:
+		return base36(value-decimal-16*power, width, 'a'), nil
+	}
+	return "", fmt.Errorf("number %d exceeds the hybrid-36 range of a %d-column field", value, width)
+}
+
+// base36 writes value as width base-36 digits, using letters starting at first for the digits 10 to 35.
+// Input: an int value, an int width, a byte first ('A' or 'a')
+// Output: a string
+func base36(value, width int, first byte) string {
+	digits := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		digit := value % 36
+		if digit < 10 {
+			digits[i] = byte('0' + digit)
+		} else {
+			digits[i] = first + byte(digit-10)
+		}
+		value /= 36
+	}
+	return string(digits)
+}
+
+// hybrid36Field encodes value for WritePDB, filling the field with asterisks when it cannot be represented.
+// Input: an int value, an int width
+// Output: a string
+func hybrid36Field(value, width int) string {
+	field, err := EncodeHybrid36(value, width)
+	if err != nil {
+		return strings.Repeat("*", width)
+	}
+	return field
+}
+
+// ParseFormalCharge parses the PDB formal charge field (columns 79-80), e.g. "2+" or "1-".
+// Input: a string field
+// Output: an int charge and an error
+func ParseFormalCharge(field string) (int, error) {
+	field = strings.TrimSpace(field)
+	if len(field) != 2 {
+		return 0, fmt.Errorf("invalid formal charge %q", field)
+	}
+	magnitude, err := strconv.Atoi(field[0:1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid formal charge %q", field)
+	}
+	switch field[1] {
+	case '+':
+		return magnitude, nil
+	case '-':
+		return -magnitude, nil
+	}
+	return 0, fmt.Errorf("invalid formal charge %q", field)
+}
+
+// SelectAltLocs applies an alternate location policy to a list of atoms, preserving file order.
+// Atoms are grouped by chain, residue number, insertion code and atom name.
+// Input: a slice of Atoms, an AltLocPolicy policy, a string altLocID used by AltLocByID
+// Output: the filtered slice of Atoms with the AltLoc field cleared on the kept atoms
+func SelectAltLocs(atoms []molecule.Atom, policy AltLocPolicy, altLocID string) []molecule.Atom {
+	if policy == AltLocAll {
+		return atoms
+	}
+	type atomKey struct {
+		chain, iCode, name string
+		resSeq             int
+	}
+	chosen := make(map[atomKey]int) // index of the kept atom for each key
+	for i, atom := range atoms {
+		if atom.AltLoc == "" {
+			continue
+		}
+		key := atomKey{atom.Chain, atom.ICode, atom.Name, atom.ResSeq}
+		best, seen := chosen[key]
+		switch policy {
+		case AltLocFirst:
+			if !seen {
+				chosen[key] = i
+			}
+		case AltLocHighestOccupancy:
+			if !seen || atom.Occupancy > atoms[best].Occupancy {
+				chosen[key] = i
+			}
+		case AltLocByID:
+			// Fall back to the first alternate location when the requested one is missing for this atom
+			if !seen || (atom.AltLoc == altLocID && atoms[best].AltLoc != altLocID) {
+				chosen[key] = i
+			}
+		}
+	}
+	selected := make([]molecule.Atom, 0, len(atoms))
+	for i, atom := range atoms {
+		if atom.AltLoc != "" {
+			key := atomKey{atom.Chain, atom.ICode, atom.Name, atom.ResSeq}
+			if chosen[key] != i {
+				continue
+			}
+			atom.AltLoc = ""
+		}
+		selected = append(selected, atom)
+	}
+	return selected
+}
+
+// safeColumns returns line[start:end] clipped to the length of the line, so short records never panic.
+// Input: a string line, two ints start and end
+// Output: the (possibly empty) substring
+func safeColumns(line string, start, end int) string {
+	if start >= len(line) {
+		return ""
+	}
+	if end > len(line) {
+		end = len(line)
+	}
+	return line[start:end]
+}
+
+// isLetters reports whether s consists only of ASCII letters.
+// Input: a string s
+// Output: a bool
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// WritePDB writes the atoms of a Molecule as PDB ATOM/HETATM records, or as PQR records with the partial
+// charge and radius in place of occupancy and temperature factor when pqr is set.
+// Input: an io.Writer w, a Molecule m, a bool pqr
+// Output: none
+func WritePDB(w io.Writer, m molecule.Molecule, pqr bool) {
+	for i, atom := range m.Atoms {
+		record := "ATOM  "
+		if atom.HetAtm {
+			record = "HETATM"
+		}
+		name := atom.Name
+		if name == "" {
+			name = atom.Element
+		}
+		// One-letter elements start in column 14, following the PDB convention
+		if len(name) < 4 && len(atom.Element) < 2 {
+			name = " " + name
+		}
+		resName := atom.ResName
+		if resName == "" {
+			resName = "UNL"
+		}
+		chain := atom.Chain
+		if chain == "" {
+			chain = " "
+		}
+		iCode := atom.ICode
+		if iCode == "" {
+			iCode = " "
+		}
+		// Serials above 99999 and residue numbers above 9999 are written in hybrid-36, as DecodeHybrid36 reads them
+		serial := hybrid36Field(i+1, 5)
+		resSeq := hybrid36Field(atom.ResSeq, 4)
+		if pqr {
+			radius := atom.Radius
+			if radius == 0 {
+				if data, ok := molecule.LookupElement(atom.Element); ok {
+					radius = data.VdWRadius
+				}
+			}
+			fmt.Fprintf(w, "%-6s%5s %-4s %3s %1s%4s%1s   %8.3f%8.3f%8.3f %7.4f %6.4f\n",
+				record, serial, name, resName, chain, resSeq, iCode,
+				atom.Position.X, atom.Position.Y, atom.Position.Z, atom.Charge, radius)
+			continue
+		}
+		charge := ""
+		if atom.FormalCharge > 0 {
+			charge = fmt.Sprintf("%d+", atom.FormalCharge)
+		} else if atom.FormalCharge < 0 {
+			charge = fmt.Sprintf("%d-", -atom.FormalCharge)
+		}
+		fmt.Fprintf(w, "%-6s%5s %-4s%1s%3s %1s%4s%1s   %8.3f%8.3f%8.3f%6.2f%6.2f          %2s%2s\n",
+			record, serial, name, atom.AltLoc, resName, chain, resSeq, iCode,
+			atom.Position.X, atom.Position.Y, atom.Position.Z, atom.Occupancy, atom.BFactor,
+			strings.ToUpper(atom.Element), charge)
+	}
+	fmt.Fprintln(w, "END")
+}
@@ -0,0 +1,214 @@
+package molio
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+const testPDB = `HEADER    TEST
+MODEL        1
+ATOM      1  N   ALA A   1      11.104   6.134  -6.504  1.00  0.00           N
+ATOM      2  CA AALA A   1      11.639   6.071  -5.147  0.40  0.00           C
+ATOM      3  CA BALA A   1      11.700   6.100  -5.100  0.60  0.00           C
+ATOM      4  CB  ALA A   1A     12.000   7.000  -5.000  1.00  0.00
+HETATM    5 ZN    ZN A 101       1.000   2.000   3.000  1.00  0.00          ZN2+
+HETATM    6 CL    CL A 102       4.000   5.000   6.000
+ATOM      7  C   ALA A   2
+ENDMDL
+MODEL        2
+ATOM      1  N   ALA A   1      21.104   6.134  -6.504  1.00  0.00           N
+ENDMDL
+`
+
+func TestReadPDBModelsAltLocAndErrors(t *testing.T) {
+	models, err := ReadPDBModels(strings.NewReader(testPDB), DefaultPDBOptions())
+	var lineErrors PDBErrors
+	if !errors.As(err, &lineErrors) || len(lineErrors) != 1 || lineErrors[0].Line != 9 {
+		t.Fatalf("Expected one line error on line 9, got %v", err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("Expected 2 models, got %d", len(models))
+	}
+	atoms := models[0].Atoms
+	if len(atoms) != 5 {
+		t.Fatalf("Expected 5 atoms after altloc selection, got %d", len(atoms))
+	}
+	if atoms[1].Position.X != 11.700 || atoms[1].AltLoc != "" {
+		t.Errorf("Expected highest-occupancy altloc B to be kept, got %+v", atoms[1])
+	}
+	if atoms[2].ICode != "A" || atoms[2].Element != "C" {
+		t.Errorf("Expected insertion code A and inferred element C, got %q %q", atoms[2].ICode, atoms[2].Element)
+	}
+	if atoms[3].Element != "Zn" || atoms[3].FormalCharge != 2 || atoms[3].Charge != 2 {
+		t.Errorf("Expected Zn 2+, got %q %d", atoms[3].Element, atoms[3].FormalCharge)
+	}
+	if atoms[4].Element != "Cl" {
+		t.Errorf("Expected short HETATM line to infer Cl, got %q", atoms[4].Element)
+	}
+}
+
+func TestReadPDBModelsSelection(t *testing.T) {
+	opts := DefaultPDBOptions()
+	opts.Model = 2
+	opts.AltLoc = AltLocByID
+	opts.AltLocID = "A"
+	models, _ := ReadPDBModels(strings.NewReader(testPDB), opts)
+	if len(models) != 1 || models[0].Atoms[0].Position.X != 21.104 {
+		t.Fatalf("Expected only model 2, got %v", models)
+	}
+
+	opts.Model = 0
+	models, _ = ReadPDBModels(strings.NewReader(testPDB), opts)
+	if models[0].Atoms[1].Position.X != 11.639 {
+		t.Errorf("Expected altloc A to be kept, got %v", models[0].Atoms[1].Position)
+	}
+
+	opts.Strict = true
+	if _, err := ReadPDBModels(strings.NewReader(testPDB), opts); err == nil {
+		t.Errorf("Expected strict mode to fail on the malformed line")
+	}
+}
+
+func TestLoadEnsemble(t *testing.T) {
+	dir := t.TempDir()
+	trajectory := filepath.Join(dir, "trajectory.pdb")
+	os.WriteFile(trajectory, []byte(testPDB), 0644)
+	snapshots, err := LoadEnsemble(trajectory)
+	var lineErrors PDBErrors
+	if !errors.As(err, &lineErrors) || len(snapshots) != 2 || snapshots[1].Name != "model_2" || snapshots[1].Molecule.Atoms[0].Position.X != 21.104 {
+		t.Fatalf("Expected both models with the malformed line reported, got %v and %v", snapshots, err)
+	}
+
+	frames := filepath.Join(dir, "frames")
+	os.Mkdir(frames, 0755)
+	for _, name := range []string{"frame2.pdb", "frame1.pdb"} {
+		os.WriteFile(filepath.Join(frames, name), []byte(testPDB[strings.Index(testPDB, "MODEL        2"):]), 0644)
+	}
+	os.WriteFile(filepath.Join(frames, "notes.txt"), []byte("not a frame"), 0644)
+	snapshots, err = LoadEnsemble(frames)
+	if err != nil || len(snapshots) != 2 || snapshots[0].Name != "frame1" || len(snapshots[0].Molecule.Atoms) != 1 {
+		t.Errorf("Expected the two frames in name order, got %v and %v", snapshots, err)
+	}
+}
+
+func TestReadPQR(t *testing.T) {
+	pqr := "ATOM      1  N   LYS A   1     -8.655   5.770   8.371 -0.3479 1.8240\n" +
+		"ATOM      2  NZ  LYS     1      1.000   2.000   3.000  0.3854 1.8240\n"
+	opts := DefaultPDBOptions()
+	opts.PQR = true
+	models, err := ReadPDBModels(strings.NewReader(pqr), opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	atoms := models[0].Atoms
+	if atoms[0].Charge != -0.3479 || atoms[0].Radius != 1.8240 || atoms[0].Chain != "A" {
+		t.Errorf("Unexpected PQR atom %+v", atoms[0])
+	}
+	if atoms[1].Chain != "" || atoms[1].ResSeq != 1 || atoms[1].Element != "N" {
+		t.Errorf("Unexpected chainless PQR atom %+v", atoms[1])
+	}
+}
+
+func TestInferElementFromPDBName(t *testing.T) {
+	cases := map[string]string{" CA ": "C", "CA  ": "Ca", "HG21": "H", "FE  ": "Fe", "1HB ": "H", " OXT": "O"}
+	for name, expected := range cases {
+		hetAtm := expected == "Ca" || expected == "Fe"
+		if got := molecule.InferElementFromPDBName(name, hetAtm); got != expected {
+			t.Errorf("InferElementFromPDBName(%q) = %q, expected %q", name, got, expected)
+		}
+	}
+}
+
+func TestDecodeHybrid36(t *testing.T) {
+	cases := map[string]int{"99999": 99999, "A0000": 100000, "A0001": 100001, "ZZZZZ": 100000 + 26*36*36*36*36 - 1, "a0000": 100000 + 26*36*36*36*36}
+	for field, expected := range cases {
+		if got, err := DecodeHybrid36(field, 5); err != nil || got != expected {
+			t.Errorf("DecodeHybrid36(%q) = %d, %v, expected %d", field, got, err, expected)
+		}
+	}
+	for _, field := range []string{"A000", "Ab000", "a0A00", "*1234"} {
+		if _, err := DecodeHybrid36(field, 5); err == nil {
+			t.Errorf("Expected %q to be rejected", field)
+		}
+	}
+	line := "ATOM  A0000  CA  ALA AA000      11.639   6.071  -5.147  1.00  0.00           C\n" +
+		"ATOM  A*000  CB  ALA A   1      12.000   7.000  -5.000  1.00  0.00           C\n"
+	models, err := ReadPDBModels(strings.NewReader(line), DefaultPDBOptions())
+	var lineErrors PDBErrors
+	if !errors.As(err, &lineErrors) || len(lineErrors) != 1 || lineErrors[0].Line != 2 || models[0].Atoms[0].Serial != 100000 || models[0].Atoms[0].ResSeq != 10000 {
+		t.Errorf("Expected serial 100000, residue 10000 and the line with a malformed serial reported, got %v and %v", models, err)
+	}
+}
+
+func TestEncodeHybrid36RoundTrip(t *testing.T) {
+	for _, width := range []int{4, 5} {
+		for _, value := range []int{-999, 0, 9999, 10000, 99999, 100000, 1000000, 60000000, 100000 + 26*36*36*36*36} {
+			field, err := EncodeHybrid36(value, width)
+			if err != nil {
+				continue
+			}
+			if len(field) > width {
+				t.Errorf("EncodeHybrid36(%d, %d) = %q, longer than the field", value, width, field)
+			}
+			if got, err := DecodeHybrid36(field, width); err != nil || got != value {
+				t.Errorf("EncodeHybrid36(%d, %d) = %q decodes to %d, %v", value, width, field, got, err)
+			}
+		}
+	}
+	if field, _ := EncodeHybrid36(100000, 5); field != "A0000" {
+		t.Errorf("Expected 100000 to encode as A0000, got %q", field)
+	}
+	if _, err := EncodeHybrid36(10000+52*36*36*36, 4); err == nil {
+		t.Error("Expected a residue number beyond the hybrid-36 range to be rejected")
+	}
+
+	// A structure with more than 99,999 atoms keeps its serials and residue numbers through WritePDB and ReadPDBModels
+	atoms := make([]molecule.Atom, 100001)
+	for i := range atoms {
+		atoms[i] = molecule.Atom{Name: "CA", Element: "C", ResName: "ALA", Chain: "A", ResSeq: i + 1, Occupancy: 1}
+	}
+	var buffer bytes.Buffer
+	WritePDB(&buffer, molecule.Molecule{Atoms: atoms}, false)
+	models, err := ReadPDBModels(&buffer, DefaultPDBOptions())
+	if err != nil || len(models[0].Atoms) != len(atoms) {
+		t.Fatalf("Expected %d atoms back, got %v", len(atoms), err)
+	}
+	last := models[0].Atoms[len(atoms)-1]
+	if last.Serial != 100001 || last.ResSeq != 100001 {
+		t.Errorf("Expected serial and residue 100001, got %d and %d", last.Serial, last.ResSeq)
+	}
+}
+
+func TestWriteBFactors(t *testing.T) {
+	ligand := molecule.Molecule{Atoms: []molecule.Atom{
+		{Name: "C1", Element: "C", Position: molecule.Position3d{X: 0, Y: 0, Z: 0}},
+		{Name: "O1", Element: "O", Position: molecule.Position3d{X: 1.2, Y: 0, Z: 0}},
+	}}
+	var buffer bytes.Buffer
+	WriteBFactors(&buffer, ligand, []float64{-2, 0})
+	models, err := ReadPDBModels(&buffer, DefaultPDBOptions())
+	if err != nil || models[0].Atoms[0].BFactor != -99.99 || models[0].Atoms[1].BFactor != 0 {
+		t.Errorf("Expected B-factors -99.99 and 0, got %+v and %v", models, err)
+	}
+}
+
+func TestParsePDBKeepsFormalCharges(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ligand.pdb")
+	os.WriteFile(file, []byte("HETATM    1  C1  MOH A   1      21.104   6.134  -6.504  1.00  0.00           C\n"+
+		"HETATM    2  O1  MOH A   1      22.104   6.134  -6.504  1.00  0.00           O\n"), 0644)
+	parsed, err := ParsePDB(file)
+	if err != nil || len(parsed.Atoms) != 2 || parsed.Atoms[0].Charge != 0 {
+		t.Fatalf("Expected ParsePDB to read the atoms without charges, got %+v and %v", parsed.Atoms, err)
+	}
+	ligand, err := LoadLigand(file)
+	var warning ChargeWarning
+	if !errors.As(err, &warning) || !IsWarning(err) || ligand.Atoms[0].Charge == 0 {
+		t.Errorf("Expected LoadLigand to assign partial charges with a warning, got %+v and %v", ligand.Atoms, err)
+	}
+}
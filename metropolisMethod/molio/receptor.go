@@ -0,0 +1,49 @@
+package molio
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/prepare"
+)
+
+// LoadReceptorStructure reads a protein from a PDB, PQR or MOL2 file without assigning any charges.
+// Input: a string filename
+// Output: a Molecule and an error
+func LoadReceptorStructure(filename string) (molecule.Molecule, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".mol2":
+		mol, _, err := ReadMol2(filename)
+		return mol, err
+	default:
+		return ParsePDBWithOptions(filename, DefaultPDBOptions())
+	}
+}
+
+// LoadReceptor reads a receptor to dock against with LoadReceptorStructure and charges it with ChargeReceptor, so
+// every docking command scores a receptor file with the same charges. Assigned charges are reported through a
+// ChargeWarning.
+// Input: a string filename
+// Output: a Molecule and an error (possibly warnings, see IsWarning, together with a usable Molecule)
+func LoadReceptor(filename string) (molecule.Molecule, error) {
+	receptor, err := LoadReceptorStructure(filename)
+	receptor, charged := ChargeReceptor(receptor)
+	if charged {
+		err = errors.Join(err, ChargeWarning{File: filename, Charges: "AMBER ff14SB"})
+	}
+	return receptor, err
+}
+
+// ChargeReceptor gives a receptor without partial charges, as read from a PDB file, the AMBER ff14SB charges of
+// prepare.PrepareReceptor. Atoms that match no residue template keep their formal charge.
+// Input: a Molecule receptor
+// Output: the charged Molecule and a bool reporting whether charges were assigned
+func ChargeReceptor(receptor molecule.Molecule) (molecule.Molecule, bool) {
+	if len(receptor.Atoms) == 0 || !prepare.PartialChargesMissing(receptor) {
+		return receptor, false
+	}
+	charged, _ := prepare.PrepareReceptor(receptor, prepare.DefaultReceptorOptions())
+	return charged, true
+}
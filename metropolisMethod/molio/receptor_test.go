@@ -0,0 +1,32 @@
+package molio
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/prepare"
+)
+
+func TestLoadReceptorCharges(t *testing.T) {
+	var pdb bytes.Buffer
+	WritePDB(&pdb, moltest.Serine(), false)
+	file := filepath.Join(t.TempDir(), "serine.pdb")
+	os.WriteFile(file, pdb.Bytes(), 0644)
+	receptor, err := LoadReceptor(file)
+	if !IsWarning(err) {
+		t.Fatalf("Expected a charge warning, got %v", err)
+	}
+	structure, _ := LoadReceptorStructure(file)
+	expected, _ := prepare.PrepareReceptor(structure, prepare.DefaultReceptorOptions())
+	for i := range expected.Atoms {
+		if !moltest.AlmostEqual(receptor.Atoms[i].Charge, expected.Atoms[i].Charge, 1e-9) {
+			t.Errorf("Expected the ff14SB charge %g on %s, got %g", expected.Atoms[i].Charge, receptor.Atoms[i].Name, receptor.Atoms[i].Charge)
+		}
+	}
+	if charged, again := ChargeReceptor(receptor); again || charged.Atoms[0].Charge != receptor.Atoms[0].Charge {
+		t.Errorf("Expected a charged receptor to keep its charges")
+	}
+}
@@ -0,0 +1,110 @@
+package molio
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SplitComplex copies the records of the first model of a PDB complex: ATOM records to protein and HETATM
+// records to ligand. Waters (HOH) are dropped unless keepWater is set. Files without MODEL records are one model.
+// With allModels the protein gets the ATOM records of every model between their MODEL and ENDMDL records, such as
+// the snapshots of an MD trajectory, while the ligand still comes from the first model.
+// Input: an io.Reader r, io.Writers protein and ligand, bools keepWater and allModels
+// Output: the int numbers of protein and ligand records written and an error or nil
+func SplitComplex(r io.Reader, protein, ligand io.Writer, keepWater, allModels bool) (int, int, error) {
+	numProtein, numLigand := 0, 0
+	models := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "MODEL"):
+			models++
+			if models > 1 && !allModels {
+				return numProtein, numLigand, scanner.Err()
+			}
+			if allModels {
+				if _, err := io.WriteString(protein, line+"\n"); err != nil {
+					return numProtein, numLigand, err
+				}
+			}
+		case strings.HasPrefix(line, "ENDMDL"):
+			if !allModels {
+				return numProtein, numLigand, scanner.Err()
+			}
+			if _, err := io.WriteString(protein, line+"\n"); err != nil {
+				return numProtein, numLigand, err
+			}
+		case strings.HasPrefix(line, "ATOM"):
+			if _, err := io.WriteString(protein, line+"\n"); err != nil {
+				return numProtein, numLigand, err
+			}
+			numProtein++
+		case strings.HasPrefix(line, "HETATM"):
+			// residue name is in columns 18-20
+			if models > 1 || !keepWater && strings.TrimSpace(safeColumns(line, 17, 20)) == "HOH" {
+				continue
+			}
+			if _, err := io.WriteString(ligand, line+"\n"); err != nil {
+				return numProtein, numLigand, err
+			}
+			numLigand++
+		}
+	}
+	return numProtein, numLigand, scanner.Err()
+}
+
+// complexBaseName strips the directory and the .gz, .pdb and .ent extensions from a complex file name.
+// Input: a string fileName
+// Output: a string base name, e.g. "1abc" for "PDB/1abc.pdb.gz"
+func complexBaseName(fileName string) string {
+	base := strings.TrimSuffix(filepath.Base(fileName), ".gz")
+	return strings.TrimSuffix(strings.TrimSuffix(base, ".pdb"), ".ent")
+}
+
+// SplitComplexFile splits a PDB complex, optionally gzipped, into <base>_protein.pdb and <base>_ligand.pdb in outputDir.
+// With allModels the protein file keeps every model of the complex.
+// Input: a string inputFile, a string outputDir, bools keepWater and allModels
+// Output: the string protein and ligand file names and an error or nil
+func SplitComplexFile(inputFile, outputDir string, keepWater, allModels bool) (string, string, error) {
+	file, err := os.Open(inputFile)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if strings.HasSuffix(inputFile, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return "", "", fmt.Errorf("%s: %w", inputFile, err)
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+
+	base := filepath.Join(outputDir, complexBaseName(inputFile))
+	proteinFile, ligandFile := base+"_protein.pdb", base+"_ligand.pdb"
+	var protein, ligand strings.Builder
+	numProtein, numLigand, err := SplitComplex(reader, &protein, &ligand, keepWater, allModels)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %w", inputFile, err)
+	}
+	if numProtein == 0 {
+		return "", "", fmt.Errorf("%s has no ATOM records", inputFile)
+	}
+	if numLigand == 0 {
+		return "", "", fmt.Errorf("%s has no ligand HETATM records", inputFile)
+	}
+	if err := os.WriteFile(proteinFile, []byte(protein.String()), 0644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(ligandFile, []byte(ligand.String()), 0644); err != nil {
+		return "", "", err
+	}
+	return proteinFile, ligandFile, nil
+}
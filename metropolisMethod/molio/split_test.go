@@ -0,0 +1,46 @@
+package molio
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitComplex(t *testing.T) {
+	complex := strings.Join([]string{
+		"HEADER    TEST",
+		"MODEL        1",
+		"ATOM      1  N   ALA A   1      11.104   6.134  -6.504  1.00  0.00           N",
+		"ATOM      2  CA  ALA A   1      11.639   6.071  -5.147  1.00  0.00           C",
+		"HETATM    3  C1  LIG A 101       1.000   2.000   3.000  1.00  0.00           C",
+		"HETATM    4  O   HOH A 201       5.000   5.000   5.000  1.00  0.00           O",
+		"ENDMDL",
+		"MODEL        2",
+		"ATOM      1  N   ALA A   1      12.104   6.134  -6.504  1.00  0.00           N",
+		"ENDMDL",
+	}, "\n")
+	var protein, ligand strings.Builder
+	numProtein, numLigand, err := SplitComplex(strings.NewReader(complex), &protein, &ligand, false, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if numProtein != 2 || numLigand != 1 {
+		t.Errorf("Expected 2 protein and 1 ligand records from the first model, got %d and %d", numProtein, numLigand)
+	}
+	if strings.Contains(ligand.String(), "HOH") || !strings.Contains(ligand.String(), "LIG") {
+		t.Errorf("Expected the ligand without water, got %q", ligand.String())
+	}
+	_, numLigand, _ = SplitComplex(strings.NewReader(complex), &protein, &ligand, true, false)
+	if numLigand != 2 {
+		t.Errorf("Expected the water to be kept with keepWater, got %d ligand records", numLigand)
+	}
+	protein.Reset()
+	ligand.Reset()
+	numProtein, numLigand, _ = SplitComplex(strings.NewReader(complex), &protein, &ligand, false, true)
+	models, _ := ReadPDBModels(strings.NewReader(protein.String()), DefaultPDBOptions())
+	if numProtein != 3 || numLigand != 1 || len(models) != 2 || len(models[1].Atoms) != 1 {
+		t.Errorf("Expected both models in the protein and the ligand of the first, got %d and %d records in %d models", numProtein, numLigand, len(models))
+	}
+	if complexBaseName("PDB/1abc.pdb.gz") != "1abc" {
+		t.Errorf("Expected base name 1abc, got %s", complexBaseName("PDB/1abc.pdb.gz"))
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+	"gonum.org/v1/plot/vg/vgpdf"
+	"gonum.org/v1/plot/vg/vgsvg"
+)
+
+// PlotOptions controls the output format, size, text and style of every plot. Zero sizes and empty texts keep
+// the defaults of each plot; the bar, sorting, label and highlight options apply to plots of one value per label.
+type PlotOptions struct {
+	Format     string    `json:"format" toml:"format"`             // "png", "svg" or "pdf"
+	DPI        int       `json:"dpi" toml:"dpi"`                   // resolution of PNG output
+	Width      vg.Length `json:"width_pt" toml:"width_pt"`         // 0 keeps the plot's default width
+	Height     vg.Length `json:"height_pt" toml:"height_pt"`       // 0 keeps the plot's default height
+	Title      string    `json:"title" toml:"title"`               // replaces the plot title when set
+	XLabel     string    `json:"xlabel" toml:"xlabel"`             // replaces the x axis label when set
+	YLabel     string    `json:"ylabel" toml:"ylabel"`             // replaces the y axis label when set
+	LogX       bool      `json:"logx" toml:"logx"`                 // logarithmic x axis, skipped when the axis includes values <= 0
+	LogY       bool      `json:"logy" toml:"logy"`                 // logarithmic y axis, skipped when the axis includes values <= 0
+	Theme      string    `json:"theme" toml:"theme"`               // "light" or "dark"
+	FontSize   vg.Length `json:"font_size_pt" toml:"font_size_pt"` // 0 keeps the default text sizes
+	Bars       bool      `json:"bars" toml:"bars"`                 // draw one value per label as bars instead of a line
+	Sort       bool      `json:"sort" toml:"sort"`                 // sort the labels by value, lowest first
+	Labels     bool      `json:"labels" toml:"labels"`             // write the real labels on the x axis instead of indices
+	LabelAngle float64   `json:"label_angle" toml:"label_angle"`   // rotation of real x labels in degrees
+	Highlight  bool      `json:"highlight" toml:"highlight"`       // mark the lowest value, such as the best ligand
+}
+
+// plotFormats are the supported output formats
+var plotFormats = []string{"png", "svg", "pdf"}
+
+// plotThemes are the supported colour themes
+var plotThemes = []string{"light", "dark"}
+
+// DefaultPlotOptions returns the original plot style: PNG at 96 DPI, default sizes and texts, a light theme,
+// lines with index labels and no highlight.
+// Input: none
+// Output: a PlotOptions
+func DefaultPlotOptions() PlotOptions {
+	return PlotOptions{Format: "png", DPI: 96, Theme: "light", LabelAngle: 45}
+}
+
+// RankedPlotOptions returns the defaults with bars sorted by value, real rotated labels and the best value highlighted.
+// Input: none
+// Output: a PlotOptions
+func RankedPlotOptions() PlotOptions {
+	options := DefaultPlotOptions()
+	options.Bars, options.Sort, options.Labels, options.Highlight = true, true, true, true
+	return options
+}
+
+// AddFlags registers the plot options as flags of a command, with the current values as defaults.
+// Input: a *PlotOptions, a *flag.FlagSet
+// Output: none (the flags write into the options when parsed)
+func (options *PlotOptions) AddFlags(flags *flag.FlagSet) {
+	flags.StringVar(&options.Format, "format", options.Format, "plot format: "+strings.Join(plotFormats, ", "))
+	flags.IntVar(&options.DPI, "dpi", options.DPI, "resolution of PNG plots")
+	flags.Var(lengthValue{&options.Width, vg.Inch}, "width", "plot width in inches (default: per plot)")
+	flags.Var(lengthValue{&options.Height, vg.Inch}, "height", "plot height in inches (default: per plot)")
+	flags.StringVar(&options.Title, "title", options.Title, "replace the plot titles")
+	flags.StringVar(&options.XLabel, "xlabel", options.XLabel, "replace the x axis labels")
+	flags.StringVar(&options.YLabel, "ylabel", options.YLabel, "replace the y axis labels")
+	flags.BoolVar(&options.LogX, "logx", options.LogX, "logarithmic x axis")
+	flags.BoolVar(&options.LogY, "logy", options.LogY, "logarithmic y axis")
+	flags.StringVar(&options.Theme, "theme", options.Theme, "plot theme: "+strings.Join(plotThemes, ", "))
+	flags.Var(lengthValue{&options.FontSize, vg.Points(1)}, "font-size", "text size in points (default: per plot)")
+	flags.BoolVar(&options.Bars, "bars", options.Bars, "draw values as bars")
+	flags.BoolVar(&options.Sort, "sort", options.Sort, "sort bars by value")
+	flags.BoolVar(&options.Labels, "labels", options.Labels, "write real labels instead of indices")
+	flags.Float64Var(&options.LabelAngle, "label-angle", options.LabelAngle, "rotation of real labels in degrees")
+	flags.BoolVar(&options.Highlight, "highlight", options.Highlight, "highlight the lowest value")
+}
+
+// lengthValue is a flag holding a vg.Length, given in multiples of unit such as inches or points
+type lengthValue struct {
+	target *vg.Length
+	unit   vg.Length
+}
+
+// String returns the length in units, or "" when it is not set.
+// Input: a lengthValue
+// Output: a string
+func (value lengthValue) String() string {
+	if value.target == nil || *value.target == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(float64(*value.target/value.unit), 'g', -1, 64)
+}
+
+// Set parses a positive length in units.
+// Input: a lengthValue, a string flag value
+// Output: an error or nil
+func (value lengthValue) Set(text string) error {
+	length, err := strconv.ParseFloat(text, 64)
+	if err != nil || length <= 0 {
+		return fmt.Errorf("invalid length %q", text)
+	}
+	*value.target = vg.Length(length) * value.unit
+	return nil
+}
+
+// Validate checks the format, theme and resolution.
+// Input: a PlotOptions
+// Output: an error or nil
+func (options PlotOptions) Validate() error {
+	if !containsString(plotFormats, options.Format) {
+		return fmt.Errorf("unknown plot format %q, expected one of %v", options.Format, plotFormats)
+	}
+	if !containsString(plotThemes, options.Theme) {
+		return fmt.Errorf("unknown plot theme %q, expected one of %v", options.Theme, plotThemes)
+	}
+	if options.DPI < 1 {
+		return fmt.Errorf("dpi must be at least 1, got %d", options.DPI)
+	}
+	return nil
+}
+
+// containsString reports whether values contains value.
+// Input: a slice of strings values, a string value
+// Output: a bool
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// apply sets the text overrides, log scales, theme and font size on a plot whose plotters have been added.
+// Input: a PlotOptions, a *plot.Plot p
+// Output: none (updates p)
+func (options PlotOptions) apply(p *plot.Plot) {
+	if options.Title != "" {
+		p.Title.Text = options.Title
+	}
+	if options.XLabel != "" {
+		p.X.Label.Text = options.XLabel
+	}
+	if options.YLabel != "" {
+		p.Y.Label.Text = options.YLabel
+	}
+	if options.LogX {
+		setLogScale(&p.X, "x")
+	}
+	if options.LogY {
+		setLogScale(&p.Y, "y")
+	}
+	if options.FontSize > 0 {
+		p.Title.TextStyle.Font.Size = options.FontSize * 1.2
+		for _, axis := range []*plot.Axis{&p.X, &p.Y} {
+			axis.Label.TextStyle.Font.Size = options.FontSize
+			axis.Tick.Label.Font.Size = options.FontSize * 0.85
+		}
+		p.Legend.TextStyle.Font.Size = options.FontSize * 0.85
+	}
+	if options.Theme == "dark" {
+		foreground := color.Gray{Y: 230}
+		p.BackgroundColor = color.Gray{Y: 25}
+		p.Title.TextStyle.Color = foreground
+		p.Legend.TextStyle.Color = foreground
+		for _, axis := range []*plot.Axis{&p.X, &p.Y} {
+			axis.Color = foreground
+			axis.Label.TextStyle.Color = foreground
+			axis.Tick.Color = foreground
+			axis.Tick.Label.Color = foreground
+		}
+	}
+}
+
+// ink returns the colour of the main data series, black on the light theme and light grey on the dark one.
+// Input: a PlotOptions
+// Output: a color.Color
+func (options PlotOptions) ink() color.Color {
+	if options.Theme == "dark" {
+		return color.Gray{Y: 230}
+	}
+	return color.Black
+}
+
+// setLogScale switches an axis to a logarithmic scale when all of its range is positive.
+// Input: a *plot.Axis, a string name of the axis for the warning
+// Output: none (updates the axis)
+func setLogScale(axis *plot.Axis, name string) {
+	if axis.Min <= 0 || math.IsInf(axis.Min, 0) {
+		log.Printf("Log scale on the %s axis skipped: it includes values <= 0", name)
+		return
+	}
+	axis.Scale = plot.LogScale{}
+	axis.Tick.Marker = plot.LogTicks{Prec: -1}
+}
+
+// canvas creates a canvas of the option's format and resolution.
+// Input: a PlotOptions, the vg.Length width and height
+// Output: a vg.CanvasWriterTo and an error or nil
+func (options PlotOptions) canvas(width, height vg.Length) (vg.CanvasWriterTo, error) {
+	switch options.Format {
+	case "png":
+		return vgimg.PngCanvas{Canvas: vgimg.NewWith(vgimg.UseWH(width, height), vgimg.UseDPI(options.DPI))}, nil
+	case "svg":
+		return vgsvg.New(width, height), nil
+	case "pdf":
+		return vgpdf.New(width, height), nil
+	}
+	return nil, fmt.Errorf("unknown plot format %q", options.Format)
+}
+
+// size returns the option's width and height, or the plot's defaults for the ones that are not set.
+// Input: a PlotOptions, the default vg.Length width and height
+// Output: the vg.Length width and height
+func (options PlotOptions) size(width, height vg.Length) (vg.Length, vg.Length) {
+	if options.Width > 0 {
+		width = options.Width
+	}
+	if options.Height > 0 {
+		height = options.Height
+	}
+	return width, height
+}
+
+// saveDrawing renders a drawing function on a canvas of the option's format and size and writes it to
+// fileName with the format's extension.
+// Input: a function drawing on a draw.Canvas, the default vg.Length width and height, a string fileName
+// (without extension), a PlotOptions
+// Output: an error or nil
+func saveDrawing(drawing func(draw.Canvas), width, height vg.Length, fileName string, options PlotOptions) error {
+	width, height = options.size(width, height)
+	canvas, err := options.canvas(width, height)
+	if err != nil {
+		return err
+	}
+	drawing(draw.New(canvas))
+	file, err := os.Create(fileName + "." + options.Format)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := canvas.WriteTo(file); err != nil {
+		return err
+	}
+	log.Printf("Plot saved as %s.%s", fileName, options.Format)
+	return nil
+}
+
+// savePlot applies the options to a plot and saves it.
+// Input: a *plot.Plot p, the default vg.Length width and height, a string fileName (without extension), a PlotOptions
+// Output: none (saves the plot)
+func savePlot(p *plot.Plot, width, height vg.Length, fileName string, options PlotOptions) {
+	options.apply(p)
+	Check(saveDrawing(p.Draw, width, height, fileName, options))
+}
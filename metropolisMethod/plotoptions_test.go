@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlotOptionsFormats(t *testing.T) {
+	dir := t.TempDir()
+	labels := []string{"1a0i", "223l", "227l"}
+	energies := []float64{-2, -5, 1}
+	for _, format := range plotFormats {
+		options := RankedPlotOptions()
+		options.Format, options.Theme = format, "dark"
+		if err := options.Validate(); err != nil {
+			t.Fatalf("Unexpected error for %s: %v", format, err)
+		}
+		plotEnergy(labels, energies, filepath.Join(dir, "energy"), options)
+		if info, err := os.Stat(filepath.Join(dir, "energy."+format)); err != nil || info.Size() == 0 {
+			t.Errorf("Expected a non-empty energy.%s, got %v", format, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "energy.csv")); err == nil {
+		t.Errorf("Expected no index mapping CSV when real labels are written")
+	}
+	options := DefaultPlotOptions()
+	options.Format = "gif"
+	if options.Validate() == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
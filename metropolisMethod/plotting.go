@@ -4,44 +4,65 @@ import (
 	"bufio"
 	"encoding/csv"
 	"fmt"
+	"image/color"
 	"log"
+	"math"
 	"os"
-	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
 	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
 )
 
-// plotRMSD plots RMSD values for various proteins, saves the plot as a PNG, and writes corresponding indices to a CSV file.
-// Input: a slice of strings x, a slice of float64 y, a string fileName
+// plotRMSD plots RMSD values for various proteins, saves the plot, and writes corresponding indices to a CSV file.
+// Input: a slice of strings x, a slice of float64 y, a string fileName, a PlotOptions
 // Output: none (saves a plot and CSV file)
-func plotRMSD(x []string, y []float64, fileName string) {
-	plotXY(x, y, fileName, "RMSD for various proteins", "Protein_index", "RMSD Value")
+func plotRMSD(x []string, y []float64, fileName string, options PlotOptions) {
+	plotXY(x, y, fileName, "RMSD for various proteins", "Protein_index", "RMSD Value", options)
 }
 
-// plotEnergy plots ligand binding energy values, saves the plot as a PNG, and writes corresponding indices to a CSV file.
-// Input: a slice of strings x, a slice of float64 y, a string fileName
+// plotEnergy plots ligand binding energy values, saves the plot, and writes corresponding indices to a CSV file.
+// Input: a slice of strings x, a slice of float64 y, a string fileName, a PlotOptions
 // Output: none (saves a plot and CSV file)
-func plotEnergy(x []string, y []float64, fileName string) {
-	plotXY(x, y, fileName, "Energy of various ligands", "Ligand_index", "Protein Ligand Binding Energy")
+func plotEnergy(x []string, y []float64, fileName string, options PlotOptions) {
+	plotXY(x, y, fileName, "Energy of various ligands", "Ligand_index", "Protein Ligand Binding Energy", options)
 }
 
-// plotXY creates a plot using provided data and labels and saves it as a PNG
-// Input: a slice of strings x, a slice of float64 y, a string fileName, a string title, a string xLabel, a string yLabel
-// Output: none (saves a plot and CSV file)
-func plotXY(x []string, y []float64, fileName string, title, xLabel, yLabel string) {
-	points := make(plotter.XYs, len(x))
-	for i := range x {
-		points[i].X = float64(i) // Numeric representation of X
-		points[i].Y = y[i]
+// plotXY creates a plot of one value per label using provided data and labels and saves it. Depending on the
+// options the values are drawn as a line or bars, optionally sorted, with the real labels or their indices on
+// the x axis (the index to label mapping is then saved as a CSV file), and the lowest value highlighted.
+// Input: a slice of strings x, a slice of float64 y, a string fileName, a string title, a string xLabel, a string yLabel, a PlotOptions
+// Output: none (saves a plot and, with index labels, a CSV file)
+func plotXY(x []string, y []float64, fileName string, title, xLabel, yLabel string, options PlotOptions) {
+	order := make([]int, len(x))
+	for i := range order {
+		order[i] = i
+	}
+	if options.Sort {
+		sort.SliceStable(order, func(a, b int) bool { return y[order[a]] < y[order[b]] })
 	}
-	x_ind := make([]string, len(x))
-	for i := range x {
-		x_ind[i] = strconv.Itoa(i)
+	best := 0
+	for i := range y {
+		if y[i] < y[best] {
+			best = i
+		}
 	}
+	values := make(plotter.Values, len(order))
+	x_ind := make([]string, len(order))
+	for i, k := range order {
+		values[i] = y[k]
+		if options.Labels {
+			x_ind[i] = x[k]
+		} else {
+			x_ind[i] = strconv.Itoa(k)
+		}
+	}
+
 	// Create a new plot
 	p := plot.New()
 
@@ -49,20 +70,74 @@ func plotXY(x []string, y []float64, fileName string, title, xLabel, yLabel stri
 	p.Title.Text = title
 	p.X.Label.Text = xLabel
 	p.Y.Label.Text = yLabel
+	if options.Labels {
+		p.X.Label.Text = strings.TrimSuffix(xLabel, "_index")
+	}
 
-	// Add a line to the plot
-	line, err := plotter.NewLine(points)
-	Check(err)
-	p.Add(line)
+	highlight := color.RGBA{R: 220, G: 50, B: 47, A: 255}
+	bestPosition := 0
+	for i, k := range order {
+		if k == best {
+			bestPosition = i
+		}
+	}
+	if options.Bars {
+		bars, err := plotter.NewBarChart(values, vg.Points(14))
+		Check(err)
+		bars.Color = color.Gray{Y: 140}
+		bars.LineStyle.Color = options.ink()
+		p.Add(bars)
+		if options.Highlight && len(values) > 0 {
+			// overlay a bar of the best value only, with invisible zero-height bars elsewhere
+			bestValues := make(plotter.Values, len(values))
+			bestValues[bestPosition] = values[bestPosition]
+			bestBar, err := plotter.NewBarChart(bestValues, vg.Points(14))
+			Check(err)
+			bestBar.Color = highlight
+			bestBar.LineStyle.Width = 0
+			p.Add(bestBar)
+			p.Legend.Add("Best: "+x[best], bestBar)
+		}
+	} else {
+		points := make(plotter.XYs, len(values))
+		for i := range values {
+			points[i].X = float64(i) // Numeric representation of X
+			points[i].Y = values[i]
+		}
+		// Add a line to the plot
+		line, err := plotter.NewLine(points)
+		Check(err)
+		line.Color = options.ink()
+		p.Add(line)
+		if options.Highlight && len(values) > 0 {
+			marker, err := plotter.NewScatter(plotter.XYs{points[bestPosition]})
+			Check(err)
+			marker.GlyphStyle.Color = highlight
+			marker.GlyphStyle.Shape = draw.CircleGlyph{}
+			marker.GlyphStyle.Radius = vg.Points(4)
+			p.Add(marker)
+			p.Legend.Add("Best: "+x[best], marker)
+		}
+	}
 
 	// Customize the X-axis with string labels
 	p.NominalX(x_ind...)
+	if options.Labels {
+		p.X.Tick.Label.Rotation = options.LabelAngle * math.Pi / 180
+		if options.LabelAngle != 0 {
+			p.X.Tick.Label.XAlign, p.X.Tick.Label.YAlign = draw.XRight, draw.YCenter
+		}
+	}
 
 	// Save the plot to a file
-	err2 := p.Save(6*vg.Inch, 4*vg.Inch, fileName+".png")
-	Check(err2)
-	log.Printf("Plot saved as %s.png", fileName)
-	saveMappingToCSV(fileName+".csv", x)
+	width := 6 * vg.Inch
+	if options.Labels && len(x) > 20 {
+		width = vg.Length(len(x)) * 0.3 * vg.Inch
+	}
+	savePlot(p, width, 4*vg.Inch, fileName, options)
+	if !options.Labels {
+		saveMappingToCSV(fileName+".csv", x)
+	}
 }
 
 // saveMappingToCSV saves the mapping of plot indices to labels in a CSV file.
@@ -89,24 +164,33 @@ func saveMappingToCSV(fileName string, labels []string) {
 	log.Printf("Mapping of plot indices to labels saved to %s", fileName)
 }
 
-// ExtractFileLabel extracts the ligand label from a file path by removing the file extension and prefix.
-// Input: a string filePath
-// Output: a string file label
-func ExtractFileLabel(filePath string) string {
-	// Extract the base file name
-	base := filepath.Base(filePath)
-	// Split the base name by "_" and remove the extension
-	parts := strings.Split(base, "_")
-	if len(parts) > 0 {
-		return strings.TrimSuffix(parts[0], ".mol2")
-	}
-	return base
+// saveEnergiesToCSV saves the binding energy of each label, in the format read by the correlate command.
+// Input: a string fileName, a slice of strings labels, a slice of float64 energies
+// Output: an error or nil (saves a CSV file)
+func saveEnergiesToCSV(fileName string, labels []string, energies []float64) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"pdb_id", "energy"})
+	for i, label := range labels {
+		writer.Write([]string{label, strconv.FormatFloat(energies[i], 'g', -1, 64)})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	log.Printf("Energies saved to %s", fileName)
+	return nil
 }
 
 // SaveMinimumEnergyLigand identifies the ligand with minimum energy and saves its structure to a specified MOL2 file.
 // Input: a slice of float64 energyList, a slice of strings ligandFiles, a string fileName, a slice of Molecule minLigands
-// Output: none (saves the best ligand structure)
-func SaveMinimumEnergyLigand(energyList []float64, ligandFiles []string, fileName string, minLigands []Molecule) {
+// Output: an error or nil (saves the best ligand structure)
+func SaveMinimumEnergyLigand(energyList []float64, ligandFiles []string, fileName string, minLigands []molecule.Molecule) error {
 	minIndex := 0
 	minEnergy := 0.0
 	for i, energy := range energyList {
@@ -115,15 +199,17 @@ func SaveMinimumEnergyLigand(energyList []float64, ligandFiles []string, fileNam
 			minEnergy = energy
 		}
 	}
-	err4 := UpdateMol2Coordinates(ligandFiles[minIndex], fileName, minLigands[minIndex])
-	Check(err4)
+	if err := UpdateMol2Coordinates(ligandFiles[minIndex], fileName, minLigands[minIndex]); err != nil {
+		return err
+	}
 	fmt.Printf("Wrote min ligand file to %s", fileName)
+	return nil
 }
 
 // UpdateMol2Coordinates updates a MOL2 file with new atomic coordinates from a specified Molecule and saves the updated file.
 // Input: a string originalFile, a string updatedFile, a Molecule newMolecule
 // Output: an error or nil
-func UpdateMol2Coordinates(originalFile, updatedFile string, newMolecule Molecule) error {
+func UpdateMol2Coordinates(originalFile, updatedFile string, newMolecule molecule.Molecule) error {
 	// Open the original file
 	file, err := os.Open(originalFile)
 	if err != nil {
@@ -157,11 +243,11 @@ func UpdateMol2Coordinates(originalFile, updatedFile string, newMolecule Molecul
 			inAtomSection = false
 		}
 
-		if inAtomSection && atomIndex < len(newMolecule.atoms) {
+		if inAtomSection && atomIndex < len(newMolecule.Atoms) {
 			// Update the atom line with new coordinates
 			parts := strings.Fields(line)
 			if len(parts) >= 9 {
-				atom := newMolecule.atoms[atomIndex]
+				atom := newMolecule.Atoms[atomIndex]
 				parts[2] = fmt.Sprintf("%.4f", atom.Position.X) // X coordinate
 				parts[3] = fmt.Sprintf("%.4f", atom.Position.Y) // Y coordinate
 				parts[4] = fmt.Sprintf("%.4f", atom.Position.Z) // Z coordinate
@@ -180,7 +266,7 @@ func UpdateMol2Coordinates(originalFile, updatedFile string, newMolecule Molecul
 		return fmt.Errorf("error reading file: %v", err)
 	}
 
-	if atomIndex != len(newMolecule.atoms) {
+	if atomIndex != len(newMolecule.Atoms) {
 		return fmt.Errorf("mismatch between number of atoms in molecule and file")
 	}
 
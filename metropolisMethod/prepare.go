@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/prepare"
+)
+
+// PrepareMain is the entry point of the "prepare" command, the preparation stage between splitPDB and simulation.
+// Given a protein and a ligand file it writes <label>_protein.pqr and <label>_ligand.mol2 into the output directory;
+// given the splitPDB output directory it prepares every <label>_protein/<label>_ligand pair it contains.
+// Usage: prepare protein.pdb ligand.pdb outputDir [pH]  or  prepare PDB_splitted outputDir [pH]
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the prepared files and prints a summary)
+func PrepareMain(args []string) {
+	flags := commandFlags("prepare", "protein.pdb ligand.pdb outputDir [pH]",
+		"Writes <label>_protein.pqr and <label>_ligand.mol2 to the output directory: a protonated receptor with AMBER\n"+
+			"ff14SB charges and a protonated ligand with Gasteiger-Marsili charges, at pH 7.4 by default. Given the output\n"+
+			"directory of split instead, prepares every pair it contains: prepare splitDir outputDir [pH]")
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) < 2 || len(args) > 4 {
+		flags.Usage()
+		return
+	}
+	var pairs [][2]string
+	var outputDir string
+	pH := prepare.DEFAULTPH
+	if info, err := os.Stat(args[0]); err == nil && info.IsDir() {
+		outputDir = args[1]
+		if len(args) > 2 {
+			pH = parsePH(args[2])
+		}
+		proteinFiles, err := findFilesWithSubstring(args[0], "_protein.")
+		Check(err)
+		for _, proteinFile := range proteinFiles {
+			ligandFile := strings.Replace(proteinFile, "_protein.", "_ligand.", 1)
+			if _, err := os.Stat(ligandFile); err == nil {
+				pairs = append(pairs, [2]string{proteinFile, ligandFile})
+			} else {
+				fmt.Println("Skipping protein without ligand:", proteinFile)
+			}
+		}
+	} else {
+		if len(args) < 3 {
+			flags.Usage()
+			return
+		}
+		pairs = append(pairs, [2]string{args[0], args[1]})
+		outputDir = args[2]
+		if len(args) > 3 {
+			pH = parsePH(args[3])
+		}
+	}
+	Check(os.MkdirAll(outputDir, 0755))
+	provenance := NewProvenance("prepare")
+	provenance.Parameter("pH", strconv.FormatFloat(pH, 'g', -1, 64))
+	provenance.Stage("preparation")
+
+	opts := prepare.DefaultReceptorOptions()
+	for _, pair := range pairs {
+		label := molio.ExtractFileLabel(pair[0])
+		fmt.Printf("Preparing %s at pH %.2f\n", label, pH)
+
+		protein, err := molio.LoadReceptorStructure(pair[0])
+		warnOrCheck(err)
+		Check(provenance.Input("protein", pair[0]))
+		receptor, report, changes := prepare.PrepareProtein(protein, pH, opts)
+		PrintReceptorReport(report)
+		for _, change := range changes {
+			fmt.Println("  protonation:", change)
+		}
+		proteinOut := filepath.Join(outputDir, label+"_protein.pqr")
+		file, err := os.Create(proteinOut)
+		Check(err)
+		writer := bufio.NewWriter(file)
+		molio.WritePDB(writer, receptor, true)
+		Check(writer.Flush())
+		file.Close()
+
+		ligand, err := molio.LoadReceptorStructure(pair[1])
+		warnOrCheck(err)
+		Check(provenance.Input("ligand", pair[1]))
+		prepared, ligandChanges := prepare.PrepareLigand(ligand, pH)
+		for _, change := range ligandChanges {
+			fmt.Println("  ligand:", change)
+		}
+		ligandOut := filepath.Join(outputDir, label+"_ligand.mol2")
+		file, err = os.Create(ligandOut)
+		Check(err)
+		writer = bufio.NewWriter(file)
+		molio.WriteMol2(writer, prepared, label+"_ligand", "GASTEIGER")
+		Check(writer.Flush())
+		file.Close()
+		fmt.Println("Prepared files written to:", proteinOut, ligandOut)
+	}
+	Check(provenance.Save(outputDir))
+}
+
+// parsePH parses a pH argument, panicking on invalid values like the other commands do for bad input.
+// Input: a string value
+// Output: a float64 pH
+func parsePH(value string) float64 {
+	pH, err := strconv.ParseFloat(value, 64)
+	Check(err)
+	if pH < 0 || pH > 14 {
+		panic(fmt.Sprintf("pH %v out of range 0-14", pH))
+	}
+	return pH
+}
+
+// warnOrCheck prints warnings of the molecule loaders (malformed PDB lines, assigned charges) and panics on any
+// other error.
+// Input: an error
+// Output: none
+func warnOrCheck(err error) {
+	Check(warn(err))
+}
+
+// warn prints warnings of the molecule loaders and returns any other error, for functions that return errors.
+// Input: an error
+// Output: the error, or nil when it was nil or only carried warnings
+func warn(err error) error {
+	if !molio.IsWarning(err) {
+		return err
+	}
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Println("Warning:", line)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package prepare
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// gasteigerIterations is the number of charge equalisation cycles; the damping factor halves each cycle
+const gasteigerIterations = 6
+
+// gasteigerHydrogenCation is the electronegativity of the hydrogen cation used as the denominator for hydrogen donors
+const gasteigerHydrogenCation = 20.02
+
+// GasteigerParams holds the coefficients of the orbital electronegativity polynomial χ(q) = a + b·q + c·q²
+type GasteigerParams struct {
+	A, B, C float64
+}
+
+// gasteigerTable holds the Gasteiger-Marsili (1980) parameters keyed by element and hybridization
+var gasteigerTable = map[string]map[molecule.Hybridization]GasteigerParams{
+	"H":  {molecule.HybridSP3: {7.17, 6.24, -0.56}},
+	"C":  {molecule.HybridSP3: {7.98, 9.18, 1.88}, molecule.HybridSP2: {8.79, 9.32, 1.51}, molecule.HybridSP: {10.39, 9.45, 0.73}},
+	"N":  {molecule.HybridSP3: {11.54, 10.82, 1.36}, molecule.HybridSP2: {12.87, 11.15, 0.85}, molecule.HybridSP: {15.68, 11.70, -0.27}},
+	"O":  {molecule.HybridSP3: {14.18, 12.92, 1.39}, molecule.HybridSP2: {17.07, 13.79, 0.47}},
+	"F":  {molecule.HybridSP3: {14.66, 13.85, 2.31}},
+	"Cl": {molecule.HybridSP3: {11.00, 9.69, 1.35}},
+	"Br": {molecule.HybridSP3: {10.08, 8.47, 1.16}},
+	"I":  {molecule.HybridSP3: {9.90, 7.96, 0.96}},
+	"S":  {molecule.HybridSP3: {10.14, 9.13, 1.38}, molecule.HybridSP2: {10.88, 9.49, 1.33}},
+	"P":  {molecule.HybridSP3: {8.90, 8.24, 0.96}},
+}
+
+// LookupGasteigerParams returns the parameters for an element and hybridization, falling back to the sp3
+// parameters of the element. Unknown elements (e.g. metals) report false and keep their formal charge.
+// Input: a string element, a Hybridization
+// Output: the GasteigerParams and a bool reporting whether parameters exist
+func LookupGasteigerParams(element string, hybridization molecule.Hybridization) (GasteigerParams, bool) {
+	byHybrid, ok := gasteigerTable[element]
+	if !ok {
+		return GasteigerParams{}, false
+	}
+	if params, ok := byHybrid[hybridization]; ok {
+		return params, true
+	}
+	if params, ok := byHybrid[molecule.HybridSP3]; ok {
+		return params, true
+	}
+	for _, params := range byHybrid {
+		return params, true
+	}
+	return GasteigerParams{}, false
+}
+
+// GasteigerCharges computes Gasteiger-Marsili partial charges from the bond graph and element types.
+// Charges start at the formal charge of each atom and are equalised along bonds for gasteigerIterations
+// damped cycles. Bonds are inferred from coordinates when the molecule has none.
+// Input: a Molecule m
+// Output: a slice of float64 charges, one per atom
+func GasteigerCharges(m molecule.Molecule) []float64 {
+	molecule.EnsureBonds(&m)
+	neighbors := molecule.Neighbors(m)
+	n := len(m.Atoms)
+	params := make([]GasteigerParams, n)
+	known := make([]bool, n)
+	charges := make([]float64, n)
+	for i, atom := range m.Atoms {
+		params[i], known[i] = LookupGasteigerParams(atom.Element, molecule.AtomHybridization(m, neighbors, i))
+		charges[i] = float64(atom.FormalCharge)
+	}
+
+	chi := make([]float64, n)
+	damping := 1.0
+	for iteration := 0; iteration < gasteigerIterations; iteration++ {
+		damping *= 0.5
+		for i := range chi {
+			q := charges[i]
+			chi[i] = params[i].A + params[i].B*q + params[i].C*q*q
+		}
+		delta := make([]float64, n)
+		for _, bond := range m.Bonds {
+			i, j := bond.A, bond.B
+			if !known[i] || !known[j] {
+				continue
+			}
+			// Electrons flow from the less electronegative atom, scaled by that atom's cation electronegativity
+			donor, acceptor := i, j
+			if chi[j] < chi[i] {
+				donor, acceptor = j, i
+			}
+			denominator := params[donor].A + params[donor].B + params[donor].C
+			if m.Atoms[donor].Element == "H" {
+				denominator = gasteigerHydrogenCation
+			}
+			if denominator == 0 {
+				continue
+			}
+			transfer := damping * (chi[acceptor] - chi[donor]) / denominator
+			delta[donor] += transfer
+			delta[acceptor] -= transfer
+		}
+		for i := range charges {
+			charges[i] += delta[i]
+		}
+	}
+	return charges
+}
+
+// AssignGasteigerCharges replaces the charges of a molecule with Gasteiger-Marsili charges,
+// inferring a bond table from coordinates if necessary.
+// Input: a pointer to a Molecule m
+// Output: none (m is updated in place)
+func AssignGasteigerCharges(m *molecule.Molecule) {
+	molecule.EnsureBonds(m)
+	charges := GasteigerCharges(*m)
+	for i := range m.Atoms {
+		m.Atoms[i].Charge = charges[i]
+	}
+}
+
+// AllChargesZero reports whether every atom of the molecule has a zero (or non-finite) charge.
+// Input: a Molecule m
+// Output: a bool
+func AllChargesZero(m molecule.Molecule) bool {
+	for _, atom := range m.Atoms {
+		if atom.Charge != 0 && !math.IsNaN(atom.Charge) && !math.IsInf(atom.Charge, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// PartialChargesMissing reports whether a molecule carries no partial charges: every charge is a whole number, as
+// when only the formal charges of a PDB file were read. Non-finite charges count as missing.
+// Input: a Molecule m
+// Output: a bool
+func PartialChargesMissing(m molecule.Molecule) bool {
+	for _, atom := range m.Atoms {
+		if !math.IsNaN(atom.Charge) && !math.IsInf(atom.Charge, 0) && atom.Charge != math.Round(atom.Charge) {
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateMol2Charges copies a MOL2 file, replacing the charge column of every atom and the charge type line.
+// Input: a string originalFile, a string updatedFile, a Molecule newMolecule with the new charges, a string chargeType such as "GASTEIGER"
+// Output: an error or nil
+func UpdateMol2Charges(originalFile, updatedFile string, newMolecule molecule.Molecule, chargeType string) error {
+	data, err := os.ReadFile(originalFile)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %v", err)
+	}
+
+	output, err := os.Create(updatedFile)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %v", err)
+	}
+	defer output.Close()
+	writer := bufio.NewWriter(output)
+
+	atomIndex := 0
+	section := ""
+	moleculeLine := 0
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if strings.HasPrefix(line, "@<TRIPOS>") {
+			section = strings.TrimPrefix(strings.TrimSpace(line), "@<TRIPOS>")
+			moleculeLine = 0
+			writer.WriteString(line + "\n")
+			continue
+		}
+		switch {
+		case section == "MOLECULE":
+			moleculeLine++
+			if moleculeLine == 4 {
+				line = chargeType
+			}
+		case section == "ATOM" && atomIndex < len(newMolecule.Atoms):
+			parts := strings.Fields(line)
+			if len(parts) >= 6 {
+				// Pad missing substructure columns so the charge lands in column 9
+				if len(parts) == 6 {
+					parts = append(parts, "1")
+				}
+				if len(parts) == 7 {
+					parts = append(parts, "UNL1")
+				}
+				charge := fmt.Sprintf("%.4f", newMolecule.Atoms[atomIndex].Charge)
+				if len(parts) >= 9 {
+					parts[8] = charge
+				} else {
+					parts = append(parts, charge)
+				}
+				line = strings.Join(parts, " ")
+				atomIndex++
+			}
+		}
+		writer.WriteString(line + "\n")
+	}
+
+	if atomIndex != len(newMolecule.Atoms) {
+		return fmt.Errorf("mismatch between number of atoms in molecule and file")
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,62 @@
+package prepare
+
+import (
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// createMockMercaptoethanol creates 2-mercaptoethanol (BME) heavy atoms with SYBYL types as written by Open Babel.
+// Input: none
+// Output: a Molecule without charges or bonds
+func createMockMercaptoethanol() molecule.Molecule {
+	return molecule.Molecule{
+		Atoms: []molecule.Atom{
+			{Name: "C1", Type: "C.3", Element: "C", Position: molecule.Position3d{X: 43.985, Y: 16.830, Z: 32.376}},
+			{Name: "C2", Type: "C.3", Element: "C", Position: molecule.Position3d{X: 42.959, Y: 17.937, Z: 32.183}},
+			{Name: "O1", Type: "O.3", Element: "O", Position: molecule.Position3d{X: 45.088, Y: 17.167, Z: 31.544}},
+			{Name: "S2", Type: "S.3", Element: "S", Position: molecule.Position3d{X: 43.438, Y: 18.952, Z: 30.770}},
+		},
+	}
+}
+
+func TestGasteigerChargesMatchOpenBabel(t *testing.T) {
+	ligand := createMockMercaptoethanol()
+	AssignGasteigerCharges(&ligand)
+
+	if len(ligand.Bonds) != 3 {
+		t.Fatalf("Expected 3 inferred bonds, got %d", len(ligand.Bonds))
+	}
+	expected := []float64{0.1946, 0.1041, -0.2194, -0.0793}
+	total := 0.0
+	for i, atom := range ligand.Atoms {
+		if !moltest.AlmostEqual(atom.Charge, expected[i], 1e-4) {
+			t.Errorf("Atom %s: expected charge %.4f, got %.4f", atom.Name, expected[i], atom.Charge)
+		}
+		total += atom.Charge
+	}
+	if !moltest.AlmostEqual(total, 0, 1e-9) {
+		t.Errorf("Expected charges to sum to zero, got %f", total)
+	}
+}
+
+func TestAllChargesZero(t *testing.T) {
+	if !AllChargesZero(createMockMercaptoethanol()) {
+		t.Errorf("Expected uncharged molecule to be detected")
+	}
+	if AllChargesZero(moltest.Ligand()) {
+		t.Errorf("Expected charged molecule not to be flagged")
+	}
+}
+
+func TestPartialChargesMissing(t *testing.T) {
+	if !PartialChargesMissing(moltest.Ligand()) {
+		t.Errorf("Expected formal charges alone not to count as partial charges")
+	}
+	mercaptoethanol := createMockMercaptoethanol()
+	AssignGasteigerCharges(&mercaptoethanol)
+	if PartialChargesMissing(mercaptoethanol) {
+		t.Errorf("Expected Gasteiger charges to count as partial charges")
+	}
+}
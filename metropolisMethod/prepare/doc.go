@@ -0,0 +1,3 @@
+// Package prepare turns raw structures into simulation inputs: Gasteiger-Marsili ligand charges, AMBER ff14SB
+// receptor charges and atom types from residue templates, pH-dependent protonation and hydrogen placement.
+package prepare
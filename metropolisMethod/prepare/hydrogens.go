@@ -0,0 +1,406 @@
+package prepare
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// tetrahedralAngle is the ideal sp3 bond angle in radians (109.47°)
+var tetrahedralAngle = math.Acos(-1.0 / 3.0)
+
+// trigonalAngle is the ideal sp2 bond angle in radians (120°)
+const trigonalAngle = 2 * math.Pi / 3
+
+// sp2AmberTypes lists the AMBER atom types of trigonal heavy atoms used to place protein hydrogens
+var sp2AmberTypes = map[string]bool{
+	"C": true, "CA": true, "CB": true, "CC": true, "CN": true, "CO": true, "CR": true, "CV": true, "CW": true, "C*": true,
+	"N": true, "NA": true, "NB": true, "N2": true, "O": true, "O2": true,
+}
+
+// HydrogenBondLength returns the ideal X–H bond length in Å for a heavy atom element.
+// Input: a string element
+// Output: a float64 bond length
+func HydrogenBondLength(element string) float64 {
+	switch element {
+	case "N":
+		return 1.01
+	case "O":
+		return 0.96
+	case "S":
+		return 1.34
+	}
+	return 1.09
+}
+
+// HydrogenPositions computes ideal positions for k hydrogens on a heavy atom from the positions of its existing
+// neighbours. For a single neighbour, reference (a neighbour of that neighbour) fixes the dihedral so that sp3
+// hydrogens are staggered and sp2 hydrogens lie in the plane; pass hasReference=false when there is none.
+// Input: the center Position3d, a slice of neighbour Position3d, a reference Position3d and a bool hasReference,
+// the Hybridization of the center, an int k and a float64 bond length
+// Output: a slice of up to k Position3d hydrogen positions
+func HydrogenPositions(center molecule.Position3d, neighbors []molecule.Position3d, reference molecule.Position3d, hasReference bool, hybridization molecule.Hybridization, k int, bondLength float64) []molecule.Position3d {
+	if k <= 0 {
+		return nil
+	}
+	units := make([]molecule.Position3d, len(neighbors))
+	for i, n := range neighbors {
+		units[i] = n.Add(center.Scale(-1))
+		units[i].Normalize()
+	}
+	place := func(direction molecule.Position3d) molecule.Position3d {
+		direction.Normalize()
+		return center.Add(direction.Scale(bondLength))
+	}
+	var directions []molecule.Position3d
+
+	switch len(units) {
+	case 0:
+		// Isolated atom (water oxygen, ammonia): start along x and continue as if x were a neighbour
+		first := molecule.Position3d{X: 1}
+		ghost := center.Add(first)
+		rest := HydrogenPositions(center, []molecule.Position3d{ghost}, molecule.Position3d{}, false, hybridization, k-1, bondLength)
+		return append([]molecule.Position3d{place(first)}, rest...)
+	case 1:
+		u := units[0]
+		if hybridization == molecule.HybridSP {
+			directions = append(directions, u.Scale(-1))
+			break
+		}
+		v := perpendicularTo(u, reference.Add(neighbors[0].Scale(-1)), hasReference)
+		w := molecule.Cross(u, v)
+		angle := tetrahedralAngle
+		phis := []float64{math.Pi, math.Pi / 3, -math.Pi / 3} // staggered relative to the reference
+		if hybridization == molecule.HybridSP2 {
+			angle = trigonalAngle
+			phis = []float64{math.Pi, 0} // in the plane, anti to the reference first
+		}
+		for _, phi := range phis {
+			radial := v.Scale(math.Cos(phi)).Add(w.Scale(math.Sin(phi)))
+			directions = append(directions, u.Scale(math.Cos(angle)).Add(radial.Scale(math.Sin(angle))))
+		}
+	case 2:
+		bisector := units[0].Add(units[1]).Scale(-1)
+		bisector.Normalize()
+		if hybridization != molecule.HybridSP3 {
+			directions = append(directions, bisector)
+			break
+		}
+		normal := molecule.Cross(units[0], units[1])
+		normal.Normalize()
+		half := tetrahedralAngle / 2
+		directions = append(directions,
+			bisector.Scale(math.Cos(half)).Add(normal.Scale(math.Sin(half))),
+			bisector.Scale(math.Cos(half)).Add(normal.Scale(-math.Sin(half))))
+	default:
+		sum := molecule.Position3d{}
+		for _, u := range units {
+			sum = sum.Add(u)
+		}
+		if sum.Magnitude() < 1e-6 {
+			return nil // planar or fully substituted, no room for a hydrogen
+		}
+		directions = append(directions, sum.Scale(-1))
+	}
+
+	positions := make([]molecule.Position3d, 0, k)
+	for i := 0; i < k && i < len(directions); i++ {
+		positions = append(positions, place(directions[i]))
+	}
+	return positions
+}
+
+// perpendicularTo returns a unit vector perpendicular to the unit vector u, taken from the component of hint
+// orthogonal to u when available, or an arbitrary perpendicular otherwise.
+// Input: a unit Position3d u, a Position3d hint, a bool useHint
+// Output: a unit Position3d
+func perpendicularTo(u, hint molecule.Position3d, useHint bool) molecule.Position3d {
+	if useHint {
+		v := hint.Add(u.Scale(-hint.Dot(u)))
+		if v.Magnitude() > 1e-6 {
+			v.Normalize()
+			return v
+		}
+	}
+	axis := molecule.Position3d{X: 1}
+	if math.Abs(u.X) > 0.9 {
+		axis = molecule.Position3d{Y: 1}
+	}
+	v := molecule.Cross(u, axis)
+	v.Normalize()
+	return v
+}
+
+// AddProteinHydrogens places every hydrogen of the residue templates that is missing from the structure.
+// Residue names must already name the protonation state (ASH, HIP, LYN, ...), as set by ProtonateProtein.
+// Hydrogens that are not part of the residue's template are removed. Residues without a template are left unchanged.
+// Input: a Molecule protein, a ReceptorOptions opts
+// Output: a new Molecule with hydrogens, ordered residue by residue, and the number of hydrogens added
+func AddProteinHydrogens(protein molecule.Molecule, opts ReceptorOptions) (molecule.Molecule, int) {
+	molecule.EnsureBonds(&protein)
+	neighbors := molecule.Neighbors(protein)
+	residues := molecule.SplitResidues(protein)
+	names, _, _ := resolveResidueNames(protein, residues, opts)
+	nTerm, cTerm := findTermini(protein, residues, names)
+
+	result := molecule.Molecule{Atoms: make([]molecule.Atom, 0, 2*len(protein.Atoms))}
+	newIndex := make([]int, len(protein.Atoms))
+	var newBonds []molecule.Bond
+	added := 0
+	for r, residue := range residues {
+		template, ok := terminalResidueTemplate(names[r], nTerm[r], cTerm[r])
+		present := make(map[string]int)
+		for _, i := range residue.Atoms {
+			newIndex[i] = -1
+			atom := protein.Atoms[i]
+			if ok {
+				name := canonicalAtomName(atom.Name, template)
+				if _, known := template.Atoms[name]; !known && atom.Element == "H" {
+					continue // hydrogen of a different protonation state
+				}
+				present[name] = i
+			}
+			newIndex[i] = len(result.Atoms)
+			result.Atoms = append(result.Atoms, atom)
+		}
+		if !ok {
+			continue
+		}
+		// Collect the missing hydrogens of each heavy atom, in template order
+		missing := make(map[string][]string)
+		var parents []string
+		for _, name := range template.Names {
+			if _, ok := present[name]; ok || !strings.HasPrefix(name, "H") {
+				continue
+			}
+			parent := template.HydrogenParent(name)
+			if _, ok := present[parent]; !ok {
+				continue
+			}
+			if len(missing[parent]) == 0 {
+				parents = append(parents, parent)
+			}
+			missing[parent] = append(missing[parent], name)
+		}
+		for _, parentName := range parents {
+			parent := present[parentName]
+			hybridization := molecule.HybridSP3
+			if sp2AmberTypes[template.Atoms[parentName].Type] {
+				hybridization = molecule.HybridSP2
+			}
+			positions := hydrogenPositionsFor(protein, neighbors, parent, hybridization, len(missing[parentName]))
+			for h, position := range positions {
+				atom := protein.Atoms[parent]
+				atom.Name = missing[parentName][h]
+				atom.Element = "H"
+				atom.Type = ""
+				atom.Charge = 0
+				atom.FormalCharge = 0
+				atom.Position = position
+				newBonds = append(newBonds, molecule.Bond{A: -1 - parent, B: len(result.Atoms), Order: "1"})
+				result.Atoms = append(result.Atoms, atom)
+				added++
+			}
+		}
+	}
+
+	// Remap the original bonds and the bonds of the new hydrogens (whose parent is stored as -1-oldIndex)
+	for _, bond := range protein.Bonds {
+		a, b := newIndex[bond.A], newIndex[bond.B]
+		if a >= 0 && b >= 0 {
+			result.Bonds = append(result.Bonds, molecule.Bond{A: a, B: b, Order: bond.Order})
+		}
+	}
+	for _, bond := range newBonds {
+		bond.A = newIndex[-1-bond.A]
+		result.Bonds = append(result.Bonds, bond)
+	}
+	return result, added
+}
+
+// hydrogenPositionsFor places k hydrogens on atom i of m using its bonded heavy neighbours.
+// Input: a Molecule m, its adjacency list, an int atom index i, a Hybridization, an int k
+// Output: a slice of Position3d
+func hydrogenPositionsFor(m molecule.Molecule, neighbors [][]int, i int, hybridization molecule.Hybridization, k int) []molecule.Position3d {
+	var positions []molecule.Position3d
+	reference, hasReference := molecule.Position3d{}, false
+	for _, n := range neighbors[i] {
+		positions = append(positions, m.Atoms[n].Position)
+	}
+	if len(neighbors[i]) == 1 {
+		first := neighbors[i][0]
+		for _, n := range neighbors[first] {
+			if n != i && m.Atoms[n].Element != "H" {
+				reference, hasReference = m.Atoms[n].Position, true
+				break
+			}
+		}
+	}
+	return HydrogenPositions(m.Atoms[i].Position, positions, reference, hasReference, hybridization, k, HydrogenBondLength(m.Atoms[i].Element))
+}
+
+// ligandValence returns the number of bonds an uncharged atom of the element normally forms.
+// Input: a string element
+// Output: an int valence, 0 for elements that never carry hydrogens here
+func ligandValence(element string) int {
+	switch element {
+	case "C":
+		return 4
+	case "N":
+		return 3
+	case "O", "S":
+		return 2
+	}
+	return 0
+}
+
+// bondOrderValue converts a SYBYL bond type to a bond order, reporting false for unknown orders.
+// Input: a string order
+// Output: a float64 order and a bool
+func bondOrderValue(order string) (float64, bool) {
+	switch order {
+	case "1":
+		return 1, true
+	case "2":
+		return 2, true
+	case "3":
+		return 3, true
+	case "ar":
+		return 1.5, true
+	case "am":
+		return 1, true
+	}
+	return 0, false
+}
+
+// ImplicitHydrogenCount estimates how many hydrogens atom i of a ligand is missing. Bond orders are used when the
+// bond table has them; otherwise the count follows from the geometric hybridization and the number of neighbours.
+// Formal charges raise (N+) or lower (O-) the valence.
+// Input: a Molecule m, its adjacency list, an int atom index i
+// Output: an int number of hydrogens to add
+func ImplicitHydrogenCount(m molecule.Molecule, neighbors [][]int, i int) int {
+	atom := m.Atoms[i]
+	valence := ligandValence(atom.Element)
+	if valence == 0 {
+		return 0
+	}
+	valence += atom.FormalCharge
+	if atom.Type == "N.4" && atom.FormalCharge == 0 {
+		valence = 4
+	}
+	if atom.Type == "O.co2" {
+		return 0 // carboxylate and phosphate oxygens; carboxylic acids are handled through overrides
+	}
+	if atom.Element == "S" && len(neighbors[i]) > 1 {
+		return 0 // sulfides, sulfoxides and sulfonyls
+	}
+
+	sum, known := 0.0, true
+	for _, bond := range m.Bonds {
+		if bond.A != i && bond.B != i {
+			continue
+		}
+		order, ok := bondOrderValue(bond.Order)
+		if !ok {
+			known = false
+			break
+		}
+		sum += order
+	}
+	if known {
+		return max(0, valence-int(math.Floor(sum+1e-6)))
+	}
+
+	degree := len(neighbors[i])
+	switch atom.Element {
+	case "C":
+		switch molecule.AtomHybridization(m, neighbors, i) {
+		case molecule.HybridSP:
+			return max(0, 2-degree)
+		case molecule.HybridSP2:
+			return max(0, 3-degree)
+		}
+		return max(0, 4-degree)
+	case "N":
+		if molecule.AtomHybridization(m, neighbors, i) == molecule.HybridSP3 {
+			return max(0, valence-degree)
+		}
+		if degree == 1 {
+			return 2
+		}
+		if degree == 2 && isAmideNitrogen(m, neighbors, i) {
+			return 1
+		}
+		return 0
+	case "O", "S":
+		if molecule.AtomHybridization(m, neighbors, i) == molecule.HybridSP2 && atom.Element == "O" {
+			return 0
+		}
+		return max(0, valence-degree)
+	}
+	return 0
+}
+
+// isAmideNitrogen reports whether nitrogen i is bonded to a carbon that carries a double-bonded (sp2) oxygen.
+// Input: a Molecule m, its adjacency list, an int atom index i
+// Output: a bool
+func isAmideNitrogen(m molecule.Molecule, neighbors [][]int, i int) bool {
+	for _, c := range neighbors[i] {
+		if m.Atoms[c].Element != "C" {
+			continue
+		}
+		for _, o := range neighbors[c] {
+			if m.Atoms[o].Element == "O" && len(neighbors[o]) == 1 && molecule.AtomHybridization(m, neighbors, o) == molecule.HybridSP2 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddLigandHydrogens adds the implicit hydrogens of a ligand at ideal geometry, bonding each to its heavy atom.
+// Counts can be overridden per atom (used for carboxylic acids, whose protonated oxygen is chosen by ProtonateLigand).
+// Input: a Molecule ligand, a map from atom index to hydrogen count overriding ImplicitHydrogenCount (may be nil)
+// Output: a new Molecule with hydrogens appended and the number of hydrogens added
+func AddLigandHydrogens(ligand molecule.Molecule, overrides map[int]int) (molecule.Molecule, int) {
+	result := molecule.CopyLigand(ligand)
+	molecule.EnsureBonds(&result)
+	neighbors := molecule.Neighbors(result)
+	heavyAtoms := len(result.Atoms)
+	added := 0
+	for i := 0; i < heavyAtoms; i++ {
+		if result.Atoms[i].Element == "H" {
+			continue
+		}
+		k, ok := overrides[i]
+		if !ok {
+			k = ImplicitHydrogenCount(result, neighbors, i)
+		}
+		if k == 0 {
+			continue
+		}
+		hybridization := molecule.AtomHybridization(result, neighbors, i)
+		if result.Atoms[i].Element == "N" && result.Atoms[i].FormalCharge > 0 {
+			hybridization = molecule.HybridSP3
+		}
+		for _, position := range hydrogenPositionsFor(result, neighbors, i, hybridization, k) {
+			added++
+			parent := result.Atoms[i]
+			result.Atoms = append(result.Atoms, molecule.Atom{
+				Name:      fmt.Sprintf("H%d", added),
+				Element:   "H",
+				Type:      "H",
+				Position:  position,
+				ResName:   parent.ResName,
+				ResSeq:    parent.ResSeq,
+				Chain:     parent.Chain,
+				HetAtm:    parent.HetAtm,
+				Occupancy: 1.0,
+			})
+			result.Bonds = append(result.Bonds, molecule.Bond{A: i, B: len(result.Atoms) - 1, Order: "1"})
+		}
+	}
+	return result, added
+}
@@ -0,0 +1,283 @@
+package prepare
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// DEFAULTPH is the pH used by the prepare command when none is given
+const DEFAULTPH = 7.4
+
+// Tabulated model pKa values of titratable groups
+const (
+	PKAASP         = 3.9
+	PKAGLU         = 4.3
+	PKAHIS         = 6.0
+	PKACYS         = 8.3
+	PKATYR         = 10.1
+	PKALYS         = 10.5
+	PKAAMINE       = 10.0 // aliphatic ligand amines
+	PKACARBOXYLATE = 4.5  // ligand carboxylic acids
+)
+
+// ProtonateProtein renames titratable residues to the template of their dominant protonation state at the given pH:
+// ASP/ASH, GLU/GLH, HIS (HID/HIE)/HIP, CYS/CYM, TYR/TYM and LYS/LYN. Cysteines in disulfides stay CYX and neutral
+// histidine tautomers are chosen as in PrepareReceptor.
+// Input: a Molecule protein, a float64 pH, a ReceptorOptions opts
+// Output: a copy of the protein with updated residue names and a list of the changes made
+func ProtonateProtein(protein molecule.Molecule, pH float64, opts ReceptorOptions) (molecule.Molecule, []string) {
+	result := molecule.CopyLigand(protein)
+	residues := molecule.SplitResidues(result)
+	names, _, _ := resolveResidueNames(result, residues, opts)
+	var changes []string
+	for r, residue := range residues {
+		state := names[r]
+		switch names[r] {
+		case "ASP", "ASH":
+			state = protonationState(pH, PKAASP, "ASH", "ASP")
+		case "GLU", "GLH":
+			state = protonationState(pH, PKAGLU, "GLH", "GLU")
+		case "HID", "HIE", "HIP":
+			if pH < PKAHIS {
+				state = "HIP"
+			} else if names[r] == "HIP" {
+				state = opts.HisDefault
+			}
+		case "CYS", "CYM":
+			state = protonationState(pH, PKACYS, "CYS", "CYM")
+		case "TYR", "TYM":
+			state = protonationState(pH, PKATYR, "TYR", "TYM")
+		case "LYS", "LYN":
+			state = protonationState(pH, PKALYS, "LYS", "LYN")
+		}
+		if state != residue.Name {
+			changes = append(changes, fmt.Sprintf("%s -> %s", residue.ID(), state))
+			for _, i := range residue.Atoms {
+				result.Atoms[i].ResName = state
+			}
+		}
+	}
+	return result, changes
+}
+
+// protonationState picks the protonated form below the pKa and the deprotonated form at or above it.
+// Input: a float64 pH, a float64 pKa, the protonated and deprotonated names
+// Output: the name of the dominant state
+func protonationState(pH, pKa float64, protonated, deprotonated string) string {
+	if pH < pKa {
+		return protonated
+	}
+	return deprotonated
+}
+
+// ProtonateLigand sets formal charges on the titratable groups of a ligand at the given pH: aliphatic amines are
+// protonated below PKAAMINE and deprotonated at or above it, and carboxylic acids are deprotonated at or above
+// PKACARBOXYLATE. Acidic hydrogens of deprotonated groups are removed. The returned overrides fix the hydrogen count
+// of carboxyl oxygens for AddLigandHydrogens. Only groups whose state changes are listed.
+// Input: a Molecule ligand, a float64 pH
+// Output: the updated ligand, a map of hydrogen count overrides, and a list of the changes made
+func ProtonateLigand(ligand molecule.Molecule, pH float64) (molecule.Molecule, map[int]int, []string) {
+	result := molecule.CopyLigand(ligand)
+	molecule.EnsureBonds(&result)
+	neighbors := molecule.Neighbors(result)
+	overrides := make(map[int]int)
+	remove := make(map[int]bool)
+	var changes []string
+
+	for i, atom := range result.Atoms {
+		switch {
+		case atom.Element == "N" && IsAliphaticAmine(result, neighbors, i):
+			if pH < PKAAMINE && atom.FormalCharge == 0 {
+				result.Atoms[i].FormalCharge = 1
+				if result.Atoms[i].Type == "N.3" {
+					result.Atoms[i].Type = "N.4"
+				}
+				changes = append(changes, fmt.Sprintf("amine %s protonated", atom.Name))
+			} else if pH >= PKAAMINE && atom.FormalCharge == 1 {
+				result.Atoms[i].FormalCharge = 0
+				if result.Atoms[i].Type == "N.4" {
+					result.Atoms[i].Type = "N.3"
+				}
+				// An ammonium group written with explicit hydrogens gives one of them up
+				for _, h := range neighbors[i] {
+					if result.Atoms[h].Element == "H" {
+						remove[h] = true
+						break
+					}
+				}
+				changes = append(changes, fmt.Sprintf("amine %s deprotonated", atom.Name))
+			}
+		case atom.Element == "C":
+			hydroxyl, carbonyl, ok := CarboxylOxygens(result, neighbors, i)
+			if !ok {
+				continue
+			}
+			if pH >= PKACARBOXYLATE {
+				changed := result.Atoms[hydroxyl].FormalCharge != -1
+				result.Atoms[hydroxyl].FormalCharge = -1
+				overrides[hydroxyl], overrides[carbonyl] = 0, 0
+				for _, h := range neighbors[hydroxyl] {
+					if result.Atoms[h].Element == "H" {
+						remove[h] = true
+						changed = true
+					}
+				}
+				if strings.Contains(result.Atoms[hydroxyl].Type, ".") {
+					result.Atoms[hydroxyl].Type, result.Atoms[carbonyl].Type = "O.co2", "O.co2"
+				}
+				if changed {
+					changes = append(changes, fmt.Sprintf("carboxylic acid on %s deprotonated", atom.Name))
+				}
+			} else {
+				changed := result.Atoms[hydroxyl].FormalCharge != 0 || result.Atoms[hydroxyl].Type == "O.co2"
+				result.Atoms[hydroxyl].FormalCharge = 0
+				overrides[carbonyl] = 0
+				overrides[hydroxyl] = 1
+				if HasHydrogenNeighbor(result, neighbors, hydroxyl) {
+					overrides[hydroxyl] = 0
+				}
+				if strings.Contains(result.Atoms[hydroxyl].Type, ".") {
+					result.Atoms[hydroxyl].Type, result.Atoms[carbonyl].Type = "O.3", "O.2"
+				}
+				if changed {
+					changes = append(changes, fmt.Sprintf("carboxylic acid on %s protonated", atom.Name))
+				}
+			}
+		}
+	}
+	if len(remove) > 0 {
+		var newIndex []int
+		result, newIndex = RemoveAtoms(result, remove)
+		remapped := make(map[int]int, len(overrides))
+		for i, k := range overrides {
+			remapped[newIndex[i]] = k
+		}
+		overrides = remapped
+	}
+	return result, overrides, changes
+}
+
+// IsAliphaticAmine reports whether nitrogen i is an sp3 amine bonded only to sp3 carbons and hydrogens,
+// excluding amides, anilines, sulfonamides and other delocalised nitrogens.
+// Input: a Molecule m, its adjacency list, an int atom index i
+// Output: a bool
+func IsAliphaticAmine(m molecule.Molecule, neighbors [][]int, i int) bool {
+	if m.Atoms[i].Type != "" && m.Atoms[i].Type != "N.3" && m.Atoms[i].Type != "N.4" {
+		return false
+	}
+	heavy := 0
+	for _, n := range neighbors[i] {
+		switch m.Atoms[n].Element {
+		case "H":
+			continue
+		case "C":
+			if molecule.AtomHybridization(m, neighbors, n) != molecule.HybridSP3 {
+				return false
+			}
+			heavy++
+		default:
+			return false
+		}
+	}
+	return heavy > 0 && heavy <= 3 && molecule.AtomHybridization(m, neighbors, i) == molecule.HybridSP3
+}
+
+// CarboxylOxygens finds the two terminal oxygens of a carboxyl group on carbon c. The hydroxyl oxygen is the one
+// with the longer C–O bond (or the one already carrying a hydrogen or the negative charge).
+// Input: a Molecule m, its adjacency list, an int carbon index c
+// Output: the hydroxyl and carbonyl oxygen indices and a bool reporting whether c is a carboxyl carbon
+func CarboxylOxygens(m molecule.Molecule, neighbors [][]int, c int) (int, int, bool) {
+	var oxygens []int
+	carbons := 0
+	for _, n := range neighbors[c] {
+		switch m.Atoms[n].Element {
+		case "O":
+			heavy := 0
+			for _, nn := range neighbors[n] {
+				if m.Atoms[nn].Element != "H" {
+					heavy++
+				}
+			}
+			if heavy != 1 {
+				return 0, 0, false // esters and anhydrides
+			}
+			oxygens = append(oxygens, n)
+		case "C":
+			carbons++
+		case "H":
+		default:
+			return 0, 0, false
+		}
+	}
+	if len(oxygens) != 2 || carbons != 1 {
+		return 0, 0, false
+	}
+	hydroxyl, carbonyl := oxygens[0], oxygens[1]
+	switch {
+	case HasHydrogenNeighbor(m, neighbors, carbonyl):
+		hydroxyl, carbonyl = carbonyl, hydroxyl
+	case HasHydrogenNeighbor(m, neighbors, hydroxyl):
+	case m.Atoms[carbonyl].FormalCharge < 0:
+		hydroxyl, carbonyl = carbonyl, hydroxyl
+	case m.Atoms[hydroxyl].FormalCharge < 0:
+	case molecule.Distance(m.Atoms[c].Position, m.Atoms[carbonyl].Position) > molecule.Distance(m.Atoms[c].Position, m.Atoms[hydroxyl].Position):
+		hydroxyl, carbonyl = carbonyl, hydroxyl
+	}
+	return hydroxyl, carbonyl, true
+}
+
+// HasHydrogenNeighbor reports whether atom i is bonded to a hydrogen.
+// Input: a Molecule m, its adjacency list, an int atom index i
+// Output: a bool
+func HasHydrogenNeighbor(m molecule.Molecule, neighbors [][]int, i int) bool {
+	for _, n := range neighbors[i] {
+		if m.Atoms[n].Element == "H" {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveAtoms deletes the marked atoms and every bond that touches them, renumbering the remaining bonds.
+// Input: a Molecule m, a map of atom indices to remove
+// Output: the new Molecule and a slice mapping old indices to new ones (-1 for removed atoms)
+func RemoveAtoms(m molecule.Molecule, remove map[int]bool) (molecule.Molecule, []int) {
+	result := molecule.Molecule{}
+	newIndex := make([]int, len(m.Atoms))
+	for i, atom := range m.Atoms {
+		if remove[i] {
+			newIndex[i] = -1
+			continue
+		}
+		newIndex[i] = len(result.Atoms)
+		result.Atoms = append(result.Atoms, atom)
+	}
+	for _, bond := range m.Bonds {
+		if a, b := newIndex[bond.A], newIndex[bond.B]; a >= 0 && b >= 0 {
+			result.Bonds = append(result.Bonds, molecule.Bond{A: a, B: b, Order: bond.Order})
+		}
+	}
+	return result, newIndex
+}
+
+// PrepareProtein protonates a protein at the given pH, adds its hydrogens and assigns template charges.
+// Input: a Molecule protein, a float64 pH, a ReceptorOptions opts
+// Output: the prepared Molecule, the ReceptorReport, and the list of protonation changes
+func PrepareProtein(protein molecule.Molecule, pH float64, opts ReceptorOptions) (molecule.Molecule, ReceptorReport, []string) {
+	protonated, changes := ProtonateProtein(protein, pH, opts)
+	withHydrogens, _ := AddProteinHydrogens(protonated, opts)
+	receptor, report := PrepareReceptor(withHydrogens, opts)
+	return receptor, report, changes
+}
+
+// PrepareLigand protonates a ligand at the given pH, adds its hydrogens and assigns Gasteiger-Marsili charges.
+// Input: a Molecule ligand, a float64 pH
+// Output: the prepared Molecule and the list of protonation changes
+func PrepareLigand(ligand molecule.Molecule, pH float64) (molecule.Molecule, []string) {
+	protonated, overrides, changes := ProtonateLigand(ligand, pH)
+	withHydrogens, _ := AddLigandHydrogens(protonated, overrides)
+	AssignGasteigerCharges(&withHydrogens)
+	return withHydrogens, changes
+}
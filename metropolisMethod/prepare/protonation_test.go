@@ -0,0 +1,101 @@
+package prepare
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+func TestProtonateProteinAtLowAndHighPH(t *testing.T) {
+	protein := createMockDipeptide()
+
+	acidic, _ := ProtonateProtein(protein, 2.0, DefaultReceptorOptions())
+	if acidic.Atoms[14].ResName != "LYS" {
+		t.Errorf("Expected LYS at pH 2, got %s", acidic.Atoms[14].ResName)
+	}
+	basic, changes := ProtonateProtein(protein, 12.0, DefaultReceptorOptions())
+	if basic.Atoms[14].ResName != "LYN" || len(changes) != 1 {
+		t.Errorf("Expected LYS2 -> LYN at pH 12, got %s %v", basic.Atoms[14].ResName, changes)
+	}
+}
+
+func TestAddProteinHydrogens(t *testing.T) {
+	protein, _ := ProtonateProtein(createMockDipeptide(), 7.4, DefaultReceptorOptions())
+	withHydrogens, added := AddProteinHydrogens(protein, DefaultReceptorOptions())
+
+	// ALA: H1-H3, HA, HB1-HB3; LYS: H, HA, HB2-HE3 (8), HZ1-HZ3
+	if added != 20 {
+		t.Errorf("Expected 20 hydrogens, got %d", added)
+	}
+	_, report := PrepareReceptor(withHydrogens, DefaultReceptorOptions())
+	if math.Abs(report.NetCharge-3) > 1e-6 {
+		t.Errorf("Expected net charge +3 after adding hydrogens, got %f", report.NetCharge)
+	}
+	for _, atom := range withHydrogens.Atoms {
+		if atom.Element == "H" && atom.Name == "HA" && atom.ResSeq == 1 {
+			if d := molecule.Distance(atom.Position, withHydrogens.Atoms[1].Position); math.Abs(d-1.09) > 0.01 {
+				t.Errorf("Expected a C-H bond of 1.09, got %f", d)
+			}
+		}
+	}
+}
+
+func TestPrepareLigandCarboxylate(t *testing.T) {
+	// acetic acid heavy atoms
+	ligand := molecule.Molecule{
+		Atoms: []molecule.Atom{
+			{Name: "C1", Element: "C", Position: molecule.Position3d{X: 0, Y: 0, Z: 0}},
+			{Name: "C2", Element: "C", Position: molecule.Position3d{X: 1.52, Y: 0, Z: 0}},
+			{Name: "O1", Element: "O", Position: molecule.Position3d{X: 2.12, Y: 1.05, Z: 0}},
+			{Name: "O2", Element: "O", Position: molecule.Position3d{X: 2.18, Y: -1.17, Z: 0}},
+		},
+	}
+	deprotonated, _ := PrepareLigand(ligand, 7.4)
+	protonated, _ := PrepareLigand(ligand, 2.0)
+	if len(deprotonated.Atoms) != 7 || len(protonated.Atoms) != 8 {
+		t.Errorf("Expected 7 and 8 atoms, got %d and %d", len(deprotonated.Atoms), len(protonated.Atoms))
+	}
+	total := 0.0
+	for _, atom := range deprotonated.Atoms {
+		total += atom.Charge
+	}
+	if math.Abs(total+1) > 1e-6 {
+		t.Errorf("Expected acetate charge -1, got %f", total)
+	}
+}
+
+func TestProtonateLigandReportsOnlyChanges(t *testing.T) {
+	// methylammonium with explicit hydrogens
+	ligand := molecule.Molecule{
+		Atoms: []molecule.Atom{
+			{Name: "C1", Element: "C", Type: "C.3", Position: molecule.Position3d{X: 0, Y: 0, Z: 0}},
+			{Name: "N1", Element: "N", Type: "N.4", FormalCharge: 1, Position: molecule.Position3d{X: 1.47, Y: 0, Z: 0}},
+			{Name: "H1", Element: "H", Position: molecule.Position3d{X: 1.81, Y: 0.96, Z: 0}},
+			{Name: "H2", Element: "H", Position: molecule.Position3d{X: 1.81, Y: -0.48, Z: 0.83}},
+			{Name: "H3", Element: "H", Position: molecule.Position3d{X: 1.81, Y: -0.48, Z: -0.83}},
+		},
+	}
+	neutral, _, changes := ProtonateLigand(ligand, 12.0)
+	if len(changes) != 1 || len(neutral.Atoms) != 4 || neutral.Atoms[1].FormalCharge != 0 || neutral.Atoms[1].Type != "N.3" {
+		t.Errorf("Expected the amine to lose a proton at pH 12, got %v and %+v", changes, neutral.Atoms)
+	}
+	if _, _, changes := ProtonateLigand(ligand, 7.4); len(changes) != 0 {
+		t.Errorf("Expected no changes for an amine already protonated at pH 7.4, got %v", changes)
+	}
+
+	acetate := molecule.Molecule{
+		Atoms: []molecule.Atom{
+			{Name: "C1", Element: "C", Position: molecule.Position3d{X: 0, Y: 0, Z: 0}},
+			{Name: "C2", Element: "C", Position: molecule.Position3d{X: 1.52, Y: 0, Z: 0}},
+			{Name: "O1", Element: "O", Position: molecule.Position3d{X: 2.15, Y: 1.08, Z: 0}},
+			{Name: "O2", Element: "O", FormalCharge: -1, Position: molecule.Position3d{X: 2.15, Y: -1.08, Z: 0}},
+		},
+	}
+	if _, _, changes := ProtonateLigand(acetate, 7.4); len(changes) != 0 {
+		t.Errorf("Expected no changes for a carboxylate at pH 7.4, got %v", changes)
+	}
+	if _, _, changes := ProtonateLigand(acetate, 2.0); len(changes) != 1 {
+		t.Errorf("Expected the carboxylate to be protonated at pH 2, got %v", changes)
+	}
+}
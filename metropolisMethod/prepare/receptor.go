@@ -0,0 +1,387 @@
+package prepare
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// disulfideCutoff is the maximum SG–SG distance in Å for two cysteines to be treated as a disulfide
+const disulfideCutoff = 2.5
+
+// peptideBondCutoff is the maximum C–N distance in Å between consecutive residues of an unbroken chain
+const peptideBondCutoff = 2.0
+
+// ReceptorOptions controls PrepareReceptor
+type ReceptorOptions struct {
+	// HisDefault is used for neutral histidines whose protonated nitrogen cannot be decided from hydrogens or
+	// hydrogen-bond partners: "HID", "HIE" or "HIP"
+	HisDefault string
+}
+
+// DefaultReceptorOptions returns the options used by the receptor command.
+// Input: none
+// Output: a ReceptorOptions
+func DefaultReceptorOptions() ReceptorOptions {
+	return ReceptorOptions{HisDefault: "HIE"}
+}
+
+// ReceptorReport summarises the result of PrepareReceptor
+type ReceptorReport struct {
+	Residues     int
+	NetCharge    float64
+	Disulfides   []string          // pairs of bonded cysteines, e.g. "A:CYS6-A:CYS127"
+	Histidines   map[string]string // residue ID -> chosen tautomer
+	NTermini     []string
+	CTermini     []string
+	Untyped      []string // atoms that matched no template
+	MissingHeavy []string // template heavy atoms absent from the structure
+}
+
+// PrepareReceptor assigns AMBER ff14SB-style partial charges and atom types to a protein from residue templates.
+// Cysteines in disulfides become CYX, histidines are assigned a tautomer, chain termini are patched and common
+// ions receive their formal charge. When hydrogens are absent from the structure, the charge of each missing
+// hydrogen is added to its heavy atom so that residue net charges are preserved.
+// Atoms that match no template keep their existing charge and are listed in the report.
+// Input: a Molecule protein, a ReceptorOptions opts
+// Output: the charged and typed Molecule and a ReceptorReport
+func PrepareReceptor(protein molecule.Molecule, opts ReceptorOptions) (molecule.Molecule, ReceptorReport) {
+	receptor := molecule.CopyLigand(protein)
+	residues := molecule.SplitResidues(receptor)
+	report := ReceptorReport{Residues: len(residues)}
+
+	var names []string
+	names, report.Disulfides, report.Histidines = resolveResidueNames(receptor, residues, opts)
+	nTerm, cTerm := findTermini(receptor, residues, names)
+
+	for r, residue := range residues {
+		if ion, ok := ionTemplates[names[r]]; ok && len(residue.Atoms) == 1 {
+			atom := &receptor.Atoms[residue.Atoms[0]]
+			atom.Type = ion.Type
+			atom.Charge = ion.Charge
+			continue
+		}
+		template, ok := terminalResidueTemplate(names[r], nTerm[r], cTerm[r])
+		if !ok {
+			for _, i := range residue.Atoms {
+				report.Untyped = append(report.Untyped, fmt.Sprintf("%s %s", residue.ID(), receptor.Atoms[i].Name))
+			}
+			continue
+		}
+		if nTerm[r] {
+			report.NTermini = append(report.NTermini, residue.ID())
+		}
+		if cTerm[r] {
+			report.CTermini = append(report.CTermini, residue.ID())
+		}
+		untyped, missing := applyResidueTemplate(&receptor, residue, template)
+		report.Untyped = append(report.Untyped, untyped...)
+		report.MissingHeavy = append(report.MissingHeavy, missing...)
+	}
+
+	for _, atom := range receptor.Atoms {
+		report.NetCharge += atom.Charge
+	}
+	return receptor, report
+}
+
+// resolveResidueNames maps every residue onto a template name, resolving aliases, disulfides and histidine tautomers.
+// Input: a Molecule m, its Residues, a ReceptorOptions opts
+// Output: the template names, the disulfide descriptions and the chosen histidine tautomers
+func resolveResidueNames(m molecule.Molecule, residues []molecule.Residue, opts ReceptorOptions) ([]string, []string, map[string]string) {
+	names := make([]string, len(residues))
+	for r, residue := range residues {
+		names[r] = residue.Name
+		if alias, ok := residueAliases[residue.Name]; ok {
+			names[r] = alias
+		}
+	}
+	disulfides := assignDisulfides(m, residues, names)
+	histidines := make(map[string]string)
+	for r, residue := range residues {
+		if names[r] == "HIS" {
+			names[r] = chooseHistidineTautomer(m, residue, opts.HisDefault)
+			histidines[residue.ID()] = names[r]
+		}
+	}
+	return names, disulfides, histidines
+}
+
+// terminalResidueTemplate looks up a residue template and applies the N- and C-terminal patches.
+// Input: a string template name, two bools marking the termini
+// Output: the ResidueTemplate and a bool reporting whether the template exists
+func terminalResidueTemplate(name string, nTerm, cTerm bool) (ResidueTemplate, bool) {
+	template, ok := residueTemplates[name]
+	if !ok {
+		return ResidueTemplate{}, false
+	}
+	if nTerm {
+		template = nTerminalTemplate(template)
+	}
+	if cTerm {
+		template = cTerminalTemplate(template)
+	}
+	return template, true
+}
+
+// applyResidueTemplate sets the types and charges of one residue from its template.
+// Input: a pointer to the receptor Molecule, the Residue, the ResidueTemplate to apply
+// Output: the untyped atom descriptions and the missing heavy atom descriptions
+func applyResidueTemplate(receptor *molecule.Molecule, residue molecule.Residue, template ResidueTemplate) ([]string, []string) {
+	var untyped, missing []string
+	present := make(map[string]int)
+	for _, i := range residue.Atoms {
+		name := canonicalAtomName(receptor.Atoms[i].Name, template)
+		if _, ok := template.Atoms[name]; !ok {
+			untyped = append(untyped, fmt.Sprintf("%s %s", residue.ID(), receptor.Atoms[i].Name))
+			continue
+		}
+		present[name] = i
+		receptor.Atoms[i].Type = template.Atoms[name].Type
+		receptor.Atoms[i].Charge = template.Atoms[name].Charge
+	}
+	for _, name := range template.Names {
+		if _, ok := present[name]; ok {
+			continue
+		}
+		if !strings.HasPrefix(name, "H") {
+			missing = append(missing, fmt.Sprintf("%s %s", residue.ID(), name))
+			// Unresolved terminal oxygens are common; keep the carboxylate charge on the remaining oxygen
+			if o, ok := present["O"]; ok && name == "OXT" {
+				receptor.Atoms[o].Charge += template.Atoms[name].Charge
+			}
+			continue
+		}
+		// Fold the charge of an absent hydrogen into its heavy atom (united-atom style)
+		if parent, ok := present[template.HydrogenParent(name)]; ok {
+			receptor.Atoms[parent].Charge += template.Atoms[name].Charge
+		}
+	}
+	return untyped, missing
+}
+
+// canonicalAtomName maps an atom name onto the template's naming, handling common aliases and the
+// older HB1/HB2 numbering of methylene hydrogens.
+// Input: a string name, a ResidueTemplate template
+// Output: the name to look up in the template
+func canonicalAtomName(name string, template ResidueTemplate) string {
+	if _, ok := template.Atoms[name]; ok {
+		return name
+	}
+	if alias, ok := atomNameAliases[name]; ok {
+		return alias
+	}
+	// PDB v2 names such as "1HB" put the hydrogen number first
+	if len(name) > 1 && name[0] >= '1' && name[0] <= '3' {
+		rotated := name[1:] + name[0:1]
+		if _, ok := template.Atoms[rotated]; ok {
+			return rotated
+		}
+		name = rotated
+	}
+	if strings.HasPrefix(name, "H") && strings.HasSuffix(name, "1") {
+		renumbered := name[:len(name)-1] + "3"
+		if _, ok := template.Atoms[renumbered]; ok {
+			return renumbered
+		}
+	}
+	return name
+}
+
+// assignDisulfides renames cysteines whose SG atoms are within disulfideCutoff of each other to CYX.
+// Input: a Molecule, its Residues and the working residue names (updated in place)
+// Output: descriptions of the disulfide bonds found
+func assignDisulfides(m molecule.Molecule, residues []molecule.Residue, names []string) []string {
+	var sulfurs []int // residue indices of cysteines with an SG atom
+	for r, residue := range residues {
+		if (names[r] == "CYS" || names[r] == "CYX") && residue.AtomIndex(m, "SG") >= 0 {
+			sulfurs = append(sulfurs, r)
+		}
+	}
+	var bonds []string
+	for a := 0; a < len(sulfurs); a++ {
+		for b := a + 1; b < len(sulfurs); b++ {
+			ra, rb := residues[sulfurs[a]], residues[sulfurs[b]]
+			d := molecule.Distance(m.Atoms[ra.AtomIndex(m, "SG")].Position, m.Atoms[rb.AtomIndex(m, "SG")].Position)
+			if d < disulfideCutoff {
+				names[sulfurs[a]] = "CYX"
+				names[sulfurs[b]] = "CYX"
+				bonds = append(bonds, ra.ID()+"-"+rb.ID())
+			}
+		}
+	}
+	return bonds
+}
+
+// chooseHistidineTautomer picks HID, HIE or HIP for a histidine. Explicit HD1/HE2 hydrogens decide first;
+// otherwise the ring nitrogen closest to an acceptor oxygen from another residue is protonated, and
+// hisDefault is used when neither nitrogen has a partner within 3.2 Å.
+// Input: a Molecule m, the histidine Residue, a string hisDefault
+// Output: the template name
+func chooseHistidineTautomer(m molecule.Molecule, residue molecule.Residue, hisDefault string) string {
+	hasHD1 := residue.AtomIndex(m, "HD1") >= 0
+	hasHE2 := residue.AtomIndex(m, "HE2") >= 0
+	switch {
+	case hasHD1 && hasHE2:
+		return "HIP"
+	case hasHD1:
+		return "HID"
+	case hasHE2:
+		return "HIE"
+	}
+	nd1, ne2 := residue.AtomIndex(m, "ND1"), residue.AtomIndex(m, "NE2")
+	if nd1 >= 0 && ne2 >= 0 {
+		inResidue := make(map[int]bool)
+		for _, i := range residue.Atoms {
+			inResidue[i] = true
+		}
+		closest := func(n int) float64 {
+			best := math.MaxFloat64
+			for i, atom := range m.Atoms {
+				if !inResidue[i] && atom.Element == "O" {
+					best = math.Min(best, molecule.Distance(atom.Position, m.Atoms[n].Position))
+				}
+			}
+			return best
+		}
+		dND1, dNE2 := closest(nd1), closest(ne2)
+		if math.Min(dND1, dNE2) < 3.2 {
+			if dND1 < dNE2 {
+				return "HID"
+			}
+			return "HIE"
+		}
+	}
+	if hisDefault == "" {
+		return "HIE"
+	}
+	return hisDefault
+}
+
+// findTermini marks the first and last amino acid of each chain segment. A new segment starts at a chain
+// change or where the previous residue's C is too far from this residue's N; OXT always marks a C-terminus.
+// Input: a Molecule m, its Residues and their working names
+// Output: two bool slices marking N-terminal and C-terminal residues
+func findTermini(m molecule.Molecule, residues []molecule.Residue, names []string) ([]bool, []bool) {
+	nTerm := make([]bool, len(residues))
+	cTerm := make([]bool, len(residues))
+	previous := -1 // index of the previous amino acid residue
+	for r, residue := range residues {
+		if !isAminoAcidTemplate(names[r]) {
+			continue
+		}
+		n := residue.AtomIndex(m, "N")
+		start := previous < 0 || residues[previous].Chain != residue.Chain
+		if !start {
+			c := residues[previous].AtomIndex(m, "C")
+			start = c < 0 || n < 0 || molecule.Distance(m.Atoms[c].Position, m.Atoms[n].Position) > peptideBondCutoff
+		}
+		if start {
+			nTerm[r] = true
+			if previous >= 0 {
+				cTerm[previous] = true
+			}
+		}
+		if residue.AtomIndex(m, "OXT") >= 0 || residue.AtomIndex(m, "OT2") >= 0 {
+			cTerm[r] = true
+		}
+		previous = r
+	}
+	if previous >= 0 {
+		cTerm[previous] = true
+	}
+	return nTerm, cTerm
+}
+
+// isAminoAcidTemplate reports whether a template name is an amino acid with a backbone.
+// Input: a string name
+// Output: a bool
+func isAminoAcidTemplate(name string) bool {
+	template, ok := residueTemplates[name]
+	if !ok {
+		return false
+	}
+	_, hasCA := template.Atoms["CA"]
+	return hasCA
+}
+
+// nTerminalTemplate patches a residue template into its charged N-terminal form: the backbone H is replaced by
+// H1/H2/H3 on an N3 nitrogen and CA absorbs the difference so the residue gains one unit of charge.
+// Input: a ResidueTemplate
+// Output: the patched ResidueTemplate
+func nTerminalTemplate(t ResidueTemplate) ResidueTemplate {
+	patched := copyTemplate(t)
+	target := t.NetCharge() + 1
+	delete(patched.Atoms, "H")
+	hydrogens := []string{"H1", "H2", "H3"}
+	nitrogen := TemplateAtom{Type: "N3", Charge: 0.1414}
+	hydrogen := TemplateAtom{Type: "H", Charge: 0.1997}
+	if t.Name == "PRO" {
+		hydrogens = []string{"H2", "H3"}
+		nitrogen.Charge = -0.2020
+		hydrogen.Charge = 0.3120
+	}
+	patched.Atoms["N"] = nitrogen
+	for _, name := range hydrogens {
+		patched.Atoms[name] = hydrogen
+	}
+	patched.Names = replaceNames(patched.Names, "H", hydrogens)
+	balanceOnCA(&patched, target)
+	return patched
+}
+
+// cTerminalTemplate patches a residue template into its charged C-terminal form with two O2 carboxylate oxygens.
+// Input: a ResidueTemplate
+// Output: the patched ResidueTemplate
+func cTerminalTemplate(t ResidueTemplate) ResidueTemplate {
+	patched := copyTemplate(t)
+	target := t.NetCharge() - 1
+	patched.Atoms["C"] = TemplateAtom{Type: "C", Charge: 0.7731}
+	patched.Atoms["O"] = TemplateAtom{Type: "O2", Charge: -0.8055}
+	patched.Atoms["OXT"] = TemplateAtom{Type: "O2", Charge: -0.8055}
+	patched.Names = append(patched.Names, "OXT")
+	balanceOnCA(&patched, target)
+	return patched
+}
+
+// balanceOnCA shifts the CA charge so the template's net charge equals target.
+// Input: a pointer to a ResidueTemplate, a float64 target charge
+// Output: none
+func balanceOnCA(t *ResidueTemplate, target float64) {
+	ca := t.Atoms["CA"]
+	ca.Charge += target - t.NetCharge()
+	t.Atoms["CA"] = ca
+}
+
+// copyTemplate returns a deep copy of a ResidueTemplate.
+// Input: a ResidueTemplate
+// Output: the copy
+func copyTemplate(t ResidueTemplate) ResidueTemplate {
+	copied := ResidueTemplate{Name: t.Name, Names: append([]string(nil), t.Names...), Atoms: make(map[string]TemplateAtom)}
+	for name, atom := range t.Atoms {
+		copied.Atoms[name] = atom
+	}
+	return copied
+}
+
+// replaceNames replaces one entry of a name list with several, or appends them if the entry is absent.
+// Input: a slice of names, the name to replace, the replacement names
+// Output: the new slice
+func replaceNames(names []string, old string, replacements []string) []string {
+	result := make([]string, 0, len(names)+len(replacements))
+	replaced := false
+	for _, name := range names {
+		if name == old {
+			result = append(result, replacements...)
+			replaced = true
+			continue
+		}
+		result = append(result, name)
+	}
+	if !replaced {
+		result = append(result, replacements...)
+	}
+	return result
+}
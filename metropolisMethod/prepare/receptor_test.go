@@ -0,0 +1,75 @@
+package prepare
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// createMockDipeptide creates a heavy-atom ALA-LYS dipeptide with a C-terminal OXT and a zinc ion.
+// Input: none
+// Output: a Molecule
+func createMockDipeptide() molecule.Molecule {
+	atom := func(name, resName string, resSeq int, x, y, z float64) molecule.Atom {
+		return molecule.Atom{Name: name, ResName: resName, ResSeq: resSeq, Chain: "A", Element: molecule.InferElementFromName(name), Position: molecule.Position3d{X: x, Y: y, Z: z}}
+	}
+	return molecule.Molecule{
+		Atoms: []molecule.Atom{
+			atom("N", "ALA", 1, 0.000, 0.000, 0.000),
+			atom("CA", "ALA", 1, 1.458, 0.000, 0.000),
+			atom("C", "ALA", 1, 2.009, 1.420, 0.000),
+			atom("O", "ALA", 1, 1.251, 2.390, 0.000),
+			atom("CB", "ALA", 1, 1.988, -0.773, -1.199),
+			atom("N", "LYS", 2, 3.332, 1.536, 0.000),
+			atom("CA", "LYS", 2, 3.970, 2.845, 0.000),
+			atom("C", "LYS", 2, 5.486, 2.700, 0.000),
+			atom("O", "LYS", 2, 6.009, 1.580, 0.000),
+			atom("OXT", "LYS", 2, 6.150, 3.750, 0.000),
+			atom("CB", "LYS", 2, 3.500, 3.700, 1.200),
+			atom("CG", "LYS", 2, 3.900, 5.150, 1.200),
+			atom("CD", "LYS", 2, 3.400, 6.000, 2.400),
+			atom("CE", "LYS", 2, 3.800, 7.450, 2.400),
+			atom("NZ", "LYS", 2, 3.300, 8.300, 3.600),
+			{Name: "ZN", ResName: "ZN", ResSeq: 101, Chain: "A", Element: "Zn", HetAtm: true, Position: molecule.Position3d{X: 20, Y: 20, Z: 20}},
+			{Name: "C1", ResName: "LIG", ResSeq: 201, Chain: "A", Element: "C", HetAtm: true, Position: molecule.Position3d{X: 30, Y: 30, Z: 30}},
+		},
+	}
+}
+
+func TestPrepareReceptorCharges(t *testing.T) {
+	receptor, report := PrepareReceptor(createMockDipeptide(), DefaultReceptorOptions())
+
+	// ALA N-terminus (+1), LYS side chain (+1), C-terminus (-1) and Zn2+ (+2)
+	if math.Abs(report.NetCharge-3) > 1e-6 {
+		t.Errorf("Expected net charge +3, got %f", report.NetCharge)
+	}
+	if len(report.NTermini) != 1 || len(report.CTermini) != 1 {
+		t.Errorf("Expected one N- and one C-terminus, got %v %v", report.NTermini, report.CTermini)
+	}
+	if len(report.Untyped) != 1 || report.Untyped[0] != "A:LIG201 C1" {
+		t.Errorf("Expected the ligand atom to be reported as untyped, got %v", report.Untyped)
+	}
+	if receptor.Atoms[0].Type != "N3" || receptor.Atoms[14].Type != "N3" || receptor.Atoms[15].Type != "Zn" {
+		t.Errorf("Unexpected atom types %q %q %q", receptor.Atoms[0].Type, receptor.Atoms[14].Type, receptor.Atoms[15].Type)
+	}
+}
+
+func TestPrepareReceptorDisulfideAndHistidine(t *testing.T) {
+	protein := molecule.Molecule{
+		Atoms: []molecule.Atom{
+			{Name: "SG", ResName: "CYS", ResSeq: 6, Element: "S", Position: molecule.Position3d{X: 0, Y: 0, Z: 0}},
+			{Name: "SG", ResName: "CYS", ResSeq: 30, Element: "S", Position: molecule.Position3d{X: 2.04, Y: 0, Z: 0}},
+			{Name: "ND1", ResName: "HIS", ResSeq: 40, Element: "N", Position: molecule.Position3d{X: 10, Y: 0, Z: 0}},
+			{Name: "NE2", ResName: "HIS", ResSeq: 40, Element: "N", Position: molecule.Position3d{X: 12, Y: 0, Z: 0}},
+			{Name: "OD1", ResName: "ASP", ResSeq: 41, Element: "O", Position: molecule.Position3d{X: 7.2, Y: 0, Z: 0}},
+		},
+	}
+	_, report := PrepareReceptor(protein, DefaultReceptorOptions())
+	if len(report.Disulfides) != 1 || report.Disulfides[0] != "CYS6-CYS30" {
+		t.Errorf("Expected one disulfide, got %v", report.Disulfides)
+	}
+	if report.Histidines["HIS40"] != "HID" {
+		t.Errorf("Expected HID for a histidine donating from ND1, got %v", report.Histidines)
+	}
+}
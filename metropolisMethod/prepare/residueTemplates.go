@@ -0,0 +1,179 @@
+package prepare
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TemplateAtom is the AMBER atom type and partial charge of one atom in a residue template
+type TemplateAtom struct {
+	Type   string
+	Charge float64
+}
+
+// ResidueTemplate lists the atoms of a residue in file order, including hydrogens
+type ResidueTemplate struct {
+	Name  string
+	Names []string // atom names in template order
+	Atoms map[string]TemplateAtom
+}
+
+// residueTemplateData holds the AMBER ff14SB internal residue charges and atom types,
+// one residue per line as "RES: NAME TYPE CHARGE, NAME TYPE CHARGE, ...".
+const residueTemplateData = `
+ALA: N N -0.4157, H H 0.2719, CA CX 0.0337, HA H1 0.0823, CB CT -0.1825, HB1 HC 0.0603, HB2 HC 0.0603, HB3 HC 0.0603, C C 0.5973, O O -0.5679
+GLY: N N -0.4157, H H 0.2719, CA CX -0.0252, HA2 H1 0.0698, HA3 H1 0.0698, C C 0.5973, O O -0.5679
+SER: N N -0.4157, H H 0.2719, CA CX -0.0249, HA H1 0.0843, CB 2C 0.2117, HB2 H1 0.0352, HB3 H1 0.0352, OG OH -0.6546, HG HO 0.4275, C C 0.5973, O O -0.5679
+CYS: N N -0.4157, H H 0.2719, CA CX 0.0213, HA H1 0.1124, CB 2C -0.1231, HB2 H1 0.1112, HB3 H1 0.1112, SG SH -0.3119, HG HS 0.1933, C C 0.5973, O O -0.5679
+CYX: N N -0.4157, H H 0.2719, CA CX 0.0429, HA H1 0.0766, CB 2C -0.0790, HB2 H1 0.0910, HB3 H1 0.0910, SG S -0.1081, C C 0.5973, O O -0.5679
+CYM: N N -0.4157, H H 0.2719, CA CX -0.0351, HA H1 0.0508, CB 2C -0.2413, HB2 H1 0.1122, HB3 H1 0.1122, SG SH -0.8844, C C 0.5973, O O -0.5679
+THR: N N -0.4157, H H 0.2719, CA CX -0.0389, HA H1 0.1007, CB 3C 0.3654, HB H1 0.0043, CG2 CT -0.2438, HG21 HC 0.0642, HG22 HC 0.0642, HG23 HC 0.0642, OG1 OH -0.6761, HG1 HO 0.4102, C C 0.5973, O O -0.5679
+VAL: N N -0.4157, H H 0.2719, CA CX -0.0875, HA H1 0.0969, CB 3C 0.2985, HB HC -0.0297, CG1 CT -0.3192, HG11 HC 0.0791, HG12 HC 0.0791, HG13 HC 0.0791, CG2 CT -0.3192, HG21 HC 0.0791, HG22 HC 0.0791, HG23 HC 0.0791, C C 0.5973, O O -0.5679
+LEU: N N -0.4157, H H 0.2719, CA CX -0.0518, HA H1 0.0922, CB 2C -0.1102, HB2 HC 0.0457, HB3 HC 0.0457, CG 3C 0.3531, HG HC -0.0361, CD1 CT -0.4121, HD11 HC 0.1000, HD12 HC 0.1000, HD13 HC 0.1000, CD2 CT -0.4121, HD21 HC 0.1000, HD22 HC 0.1000, HD23 HC 0.1000, C C 0.5973, O O -0.5679
+ILE: N N -0.4157, H H 0.2719, CA CX -0.0597, HA H1 0.0869, CB 3C 0.1303, HB HC 0.0187, CG2 CT -0.3204, HG21 HC 0.0882, HG22 HC 0.0882, HG23 HC 0.0882, CG1 2C -0.0430, HG12 HC 0.0236, HG13 HC 0.0236, CD1 CT -0.0660, HD11 HC 0.0186, HD12 HC 0.0186, HD13 HC 0.0186, C C 0.5973, O O -0.5679
+MET: N N -0.4157, H H 0.2719, CA CX -0.0237, HA H1 0.0880, CB 2C 0.0342, HB2 HC 0.0241, HB3 HC 0.0241, CG 2C 0.0018, HG2 H1 0.0440, HG3 H1 0.0440, SD S -0.2737, CE CT -0.0536, HE1 H1 0.0684, HE2 H1 0.0684, HE3 H1 0.0684, C C 0.5973, O O -0.5679
+PRO: N N -0.2548, CD CT 0.0192, HD2 H1 0.0391, HD3 H1 0.0391, CG CT 0.0189, HG2 HC 0.0213, HG3 HC 0.0213, CB CT -0.0070, HB2 HC 0.0253, HB3 HC 0.0253, CA CX -0.0266, HA H1 0.0641, C C 0.5896, O O -0.5748
+PHE: N N -0.4157, H H 0.2719, CA CX -0.0024, HA H1 0.0978, CB CT -0.0343, HB2 HC 0.0295, HB3 HC 0.0295, CG CA 0.0118, CD1 CA -0.1256, HD1 HA 0.1330, CE1 CA -0.1704, HE1 HA 0.1430, CZ CA -0.1072, HZ HA 0.1297, CE2 CA -0.1704, HE2 HA 0.1430, CD2 CA -0.1256, HD2 HA 0.1330, C C 0.5973, O O -0.5679
+TYR: N N -0.4157, H H 0.2719, CA CX -0.0014, HA H1 0.0876, CB CT -0.0152, HB2 HC 0.0295, HB3 HC 0.0295, CG CA -0.0011, CD1 CA -0.1906, HD1 HA 0.1699, CE1 CA -0.2341, HE1 HA 0.1656, CZ C 0.3226, OH OH -0.5579, HH HO 0.3992, CE2 CA -0.2341, HE2 HA 0.1656, CD2 CA -0.1906, HD2 HA 0.1699, C C 0.5973, O O -0.5679
+TRP: N N -0.4157, H H 0.2719, CA CX -0.0275, HA H1 0.1123, CB CT -0.0050, HB2 HC 0.0339, HB3 HC 0.0339, CG C* -0.1415, CD1 CW -0.1638, HD1 H4 0.2062, NE1 NA -0.3418, HE1 H 0.3412, CE2 CN 0.1380, CZ2 CA -0.2601, HZ2 HA 0.1572, CH2 CA -0.1134, HH2 HA 0.1417, CZ3 CA -0.1972, HZ3 HA 0.1447, CE3 CA -0.2387, HE3 HA 0.1700, CD2 CB 0.1243, C C 0.5973, O O -0.5679
+ASP: N N -0.5163, H H 0.2936, CA CX 0.0381, HA H1 0.0880, CB 2C -0.0303, HB2 HC -0.0122, HB3 HC -0.0122, CG CO 0.7994, OD1 O2 -0.8014, OD2 O2 -0.8014, C C 0.5366, O O -0.5819
+GLU: N N -0.5163, H H 0.2936, CA CX 0.0397, HA H1 0.1105, CB 2C 0.0560, HB2 HC -0.0173, HB3 HC -0.0173, CG 2C 0.0136, HG2 HC -0.0425, HG3 HC -0.0425, CD CO 0.8054, OE1 O2 -0.8188, OE2 O2 -0.8188, C C 0.5366, O O -0.5819
+ASH: N N -0.4157, H H 0.2719, CA CX 0.0341, HA H1 0.0864, CB 2C -0.0316, HB2 HC 0.0488, HB3 HC 0.0488, CG C 0.6462, OD1 O -0.5554, OD2 OH -0.6376, HD2 HO 0.4747, C C 0.5973, O O -0.5679
+GLH: N N -0.4157, H H 0.2719, CA CX 0.0145, HA H1 0.0779, CB 2C -0.0071, HB2 HC 0.0256, HB3 HC 0.0256, CG 2C -0.0174, HG2 HC 0.0430, HG3 HC 0.0430, CD C 0.6801, OE1 O -0.5838, OE2 OH -0.6511, HE2 HO 0.4641, C C 0.5973, O O -0.5679
+ASN: N N -0.4157, H H 0.2719, CA CX 0.0143, HA H1 0.1048, CB 2C -0.2041, HB2 HC 0.0797, HB3 HC 0.0797, CG C 0.7130, OD1 O -0.5931, ND2 N -0.9191, HD21 H 0.4196, HD22 H 0.4196, C C 0.5973, O O -0.5679
+GLN: N N -0.4157, H H 0.2719, CA CX -0.0031, HA H1 0.0850, CB 2C -0.0036, HB2 HC 0.0171, HB3 HC 0.0171, CG 2C -0.0645, HG2 HC 0.0352, HG3 HC 0.0352, CD C 0.6951, OE1 O -0.6086, NE2 N -0.9407, HE21 H 0.4251, HE22 H 0.4251, C C 0.5973, O O -0.5679
+LYS: N N -0.3479, H H 0.2747, CA CX -0.2400, HA H1 0.1426, CB C8 -0.0094, HB2 HC 0.0362, HB3 HC 0.0362, CG C8 0.0187, HG2 HC 0.0103, HG3 HC 0.0103, CD C8 -0.0479, HD2 HC 0.0621, HD3 HC 0.0621, CE C8 -0.0143, HE2 HP 0.1135, HE3 HP 0.1135, NZ N3 -0.3854, HZ1 H 0.3400, HZ2 H 0.3400, HZ3 H 0.3400, C C 0.7341, O O -0.5894
+LYN: N N -0.4157, H H 0.2719, CA CX -0.07206, HA H1 0.0994, CB C8 -0.04845, HB2 HC 0.0340, HB3 HC 0.0340, CG C8 0.06612, HG2 HC 0.01041, HG3 HC 0.01041, CD C8 -0.03768, HD2 HC 0.01155, HD3 HC 0.01155, CE C8 0.32604, HE2 HP -0.03358, HE3 HP -0.03358, NZ N3 -1.03581, HZ2 H 0.38604, HZ3 H 0.38604, C C 0.5973, O O -0.5679
+ARG: N N -0.3479, H H 0.2747, CA CX -0.2637, HA H1 0.1560, CB C8 -0.0007, HB2 HC 0.0327, HB3 HC 0.0327, CG C8 0.0390, HG2 HC 0.0285, HG3 HC 0.0285, CD C8 0.0486, HD2 H1 0.0687, HD3 H1 0.0687, NE N2 -0.5295, HE H 0.3456, CZ CA 0.8076, NH1 N2 -0.8627, HH11 H 0.4478, HH12 H 0.4478, NH2 N2 -0.8627, HH21 H 0.4478, HH22 H 0.4478, C C 0.7341, O O -0.5894
+HID: N N -0.4157, H H 0.2719, CA CX 0.0188, HA H1 0.0881, CB CT -0.0462, HB2 HC 0.0402, HB3 HC 0.0402, CG CC -0.0266, ND1 NA -0.3811, HD1 H 0.3649, CE1 CR 0.2057, HE1 H5 0.1392, NE2 NB -0.5727, CD2 CV 0.1292, HD2 H4 0.1147, C C 0.5973, O O -0.5679
+HIE: N N -0.4157, H H 0.2719, CA CX -0.0581, HA H1 0.1360, CB CT -0.0074, HB2 HC 0.0367, HB3 HC 0.0367, CG CC 0.1868, ND1 NB -0.5432, CE1 CR 0.1635, HE1 H5 0.1435, NE2 NA -0.2795, HE2 H 0.3339, CD2 CW -0.2207, HD2 H4 0.1862, C C 0.5973, O O -0.5679
+HOH: O OW -0.8340, H1 HW 0.4170, H2 HW 0.4170
+HIP: N N -0.3479, H H 0.2747, CA CX -0.1354, HA H1 0.1212, CB CT -0.0414, HB2 HC 0.0810, HB3 HC 0.0810, CG CC -0.0012, ND1 NA -0.1513, HD1 H 0.3866, CE1 CR -0.0170, HE1 H5 0.2681, NE2 NA -0.1718, HE2 H 0.3911, CD2 CW -0.1141, HD2 H4 0.2317, C C 0.7341, O O -0.5894
+`
+
+// ionTemplates maps monatomic ion residue names to their AMBER atom type and charge
+var ionTemplates = map[string]TemplateAtom{
+	"NA":  {"Na+", 1},
+	"K":   {"K+", 1},
+	"MG":  {"MG", 2},
+	"CA":  {"C0", 2},
+	"ZN":  {"Zn", 2},
+	"MN":  {"MN", 2},
+	"FE":  {"FE", 2},
+	"FE2": {"FE", 2},
+	"CO":  {"CO", 2},
+	"NI":  {"NI", 2},
+	"CU":  {"CU", 2},
+	"CL":  {"Cl-", -1},
+	"BR":  {"Br-", -1},
+	"IOD": {"I-", -1},
+}
+
+// residueAliases maps alternative residue names (CHARMM histidines, PDB variants) to template names
+var residueAliases = map[string]string{
+	"HSD":  "HID",
+	"HSE":  "HIE",
+	"HSP":  "HIP",
+	"HIH":  "HIP",
+	"CYF":  "CYX",
+	"CYT":  "CYX",
+	"LYP":  "LYS",
+	"ASPH": "ASH",
+	"GLUH": "GLH",
+	"WAT":  "HOH",
+	"TIP3": "HOH",
+}
+
+// atomNameAliases maps alternative atom names to their AMBER names
+var atomNameAliases = map[string]string{
+	"HN":  "H",
+	"OT1": "O",
+	"OT2": "OXT",
+	"O1":  "O",
+	"O2":  "OXT",
+}
+
+// residueTemplates holds the parsed residueTemplateData plus the derived deprotonated tyrosine
+var residueTemplates = withTyrosinate(mustParseResidueTemplates(residueTemplateData))
+
+// withTyrosinate adds a TYM template for tyrosine above its pKa, derived from TYR by removing HH and
+// placing the extra unit of negative charge on the phenolate oxygen.
+// Input: a map of templates
+// Output: the same map with TYM added
+func withTyrosinate(templates map[string]ResidueTemplate) map[string]ResidueTemplate {
+	tyr := templates["TYR"]
+	tym := copyTemplate(tyr)
+	tym.Name = "TYM"
+	tym.Atoms["OH"] = TemplateAtom{Type: "O", Charge: tyr.Atoms["OH"].Charge + tyr.Atoms["HH"].Charge - 1}
+	delete(tym.Atoms, "HH")
+	tym.Names = replaceNames(tym.Names, "HH", nil)
+	templates["TYM"] = tym
+	return templates
+}
+
+// mustParseResidueTemplates parses the residue template table, panicking on malformed entries.
+// Input: a string data in the residueTemplateData format
+// Output: a map from residue name to ResidueTemplate
+func mustParseResidueTemplates(data string) map[string]ResidueTemplate {
+	templates := make(map[string]ResidueTemplate)
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, body, found := strings.Cut(line, ":")
+		if !found {
+			panic(fmt.Sprintf("residue template without name: %q", line))
+		}
+		template := ResidueTemplate{Name: name, Atoms: make(map[string]TemplateAtom)}
+		for _, entry := range strings.Split(body, ",") {
+			fields := strings.Fields(entry)
+			if len(fields) != 3 {
+				panic(fmt.Sprintf("malformed template atom %q in %s", entry, name))
+			}
+			charge, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				panic(fmt.Sprintf("malformed template charge %q in %s", entry, name))
+			}
+			template.Names = append(template.Names, fields[0])
+			template.Atoms[fields[0]] = TemplateAtom{Type: fields[1], Charge: charge}
+		}
+		templates[name] = template
+	}
+	return templates
+}
+
+// NetCharge returns the sum of the template charges.
+// Input: none
+// Output: a float64 charge
+func (t ResidueTemplate) NetCharge() float64 {
+	total := 0.0
+	for _, atom := range t.Atoms {
+		total += atom.Charge
+	}
+	return total
+}
+
+// HydrogenParent returns the heavy atom a template hydrogen is bonded to, following the AMBER naming
+// convention that the hydrogen name repeats the heavy atom's branch letters (HB2 -> CB, HD21 -> ND2, HH -> OH).
+// Input: a string hydrogen atom name
+// Output: the parent atom name, or "" if it cannot be resolved
+func (t ResidueTemplate) HydrogenParent(hydrogen string) string {
+	if t.Name == "HOH" {
+		return "O"
+	}
+	if hydrogen == "H" || hydrogen == "H1" || hydrogen == "H2" || hydrogen == "H3" {
+		return "N"
+	}
+	suffix := strings.TrimPrefix(hydrogen, "H")
+	for len(suffix) > 0 {
+		for _, name := range t.Names {
+			if !strings.HasPrefix(name, "H") && name[1:] == suffix {
+				return name
+			}
+		}
+		suffix = suffix[:len(suffix)-1]
+	}
+	return ""
+}
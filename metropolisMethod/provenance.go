@@ -0,0 +1,397 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/energy"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+)
+
+// MANIFESTFILE is the name of the provenance manifest written next to the results of a run
+const MANIFESTFILE = "manifest.json"
+
+// ManifestFile is an input or output file of a run with its SHA-256 checksum
+type ManifestFile struct {
+	Role   string `json:"role"` // e.g. protein, ligand, reference or output
+	Path   string `json:"path"` // inputs as given to the run (relative to its working directory), outputs relative to the manifest
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// StageTiming is the wall time of one stage of a run
+type StageTiming struct {
+	Name    string    `json:"name"`
+	Started time.Time `json:"started"`
+	Seconds float64   `json:"seconds"`
+}
+
+// CodeVersion identifies the build that produced a run
+type CodeVersion struct {
+	Module    string `json:"module,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Revision  string `json:"revision,omitempty"` // git commit, from the build or from git when run with go run
+	Modified  bool   `json:"modified"`           // the working tree had uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+// HostInfo describes the machine a run used
+type HostInfo struct {
+	Hostname   string `json:"hostname"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+	CPUs       int    `json:"cpus"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+}
+
+// Manifest records how the results in a folder were produced, so they can be traced back and checked later
+type Manifest struct {
+	Command    string             `json:"command"`
+	Arguments  []string           `json:"arguments"` // of the process, which for server jobs are those of serve
+	WorkingDir string             `json:"working_dir"`
+	Inputs     []ManifestFile     `json:"inputs"`
+	Config     *RunConfig         `json:"config,omitempty"`
+	Parameters map[string]string  `json:"parameters,omitempty"` // settings of the command outside the run configuration
+	Constants  map[string]float64 `json:"constants"`
+	Seed       int64              `json:"seed"`
+	Code       CodeVersion        `json:"code"`
+	Host       HostInfo           `json:"host"`
+	Started    time.Time          `json:"started"`
+	Finished   time.Time          `json:"finished"`
+	Stages     []StageTiming      `json:"stages"`
+	Outputs    []ManifestFile     `json:"outputs"` // files of the output folder written during the run
+}
+
+// Provenance collects the manifest of a run while it progresses
+type Provenance struct {
+	Manifest Manifest
+	stage    int // index of the current stage in Manifest.Stages, -1 before the first
+}
+
+// NewProvenance starts the manifest of a run of a command, recording the code, host and engine constants.
+// Input: a string command
+// Output: a *Provenance
+func NewProvenance(command string) *Provenance {
+	workingDir, _ := os.Getwd()
+	hostname, _ := os.Hostname()
+	return &Provenance{
+		Manifest: Manifest{
+			Command:    command,
+			Arguments:  os.Args[1:],
+			WorkingDir: workingDir,
+			Constants: map[string]float64{
+				"energy.K":              energy.K,
+				"sampling.THRESHOLD":    sampling.THRESHOLD,
+				"sampling.MINDISTANCE":  sampling.MINDISTANCE,
+				"sampling.MAXANGLE":     sampling.MAXANGLE,
+				"sampling.TEMPERATURE":  sampling.TEMPERATURE,
+				"sampling.STEPSIZE":     sampling.STEPSIZE,
+				"sampling.TRACEPOINTS":  sampling.TRACEPOINTS,
+				"sampling.POCKETCUTOFF": sampling.POCKETCUTOFF,
+				"CONFIGVERSION":         CONFIGVERSION,
+			},
+			Code: CurrentCodeVersion(),
+			Host: HostInfo{
+				Hostname:   hostname,
+				OS:         runtime.GOOS,
+				Arch:       runtime.GOARCH,
+				CPUs:       runtime.NumCPU(),
+				GOMAXPROCS: runtime.GOMAXPROCS(0),
+			},
+			Started: time.Now(),
+		},
+		stage: -1,
+	}
+}
+
+// CurrentCodeVersion reads the module and VCS information of this build. Binaries built with go build carry the
+// commit; under go run it is asked from git, and left empty outside a repository.
+// Input: none
+// Output: a CodeVersion
+func CurrentCodeVersion() CodeVersion {
+	code := CodeVersion{GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		code.Module, code.Version = build.Main.Path, build.Main.Version
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				code.Revision = setting.Value
+			case "vcs.modified":
+				code.Modified = setting.Value == "true"
+			}
+		}
+	}
+	if code.Revision == "" {
+		if revision, err := exec.Command("git", "rev-parse", "HEAD").Output(); err == nil {
+			code.Revision = strings.TrimSpace(string(revision))
+			status, err := exec.Command("git", "status", "--porcelain", "--untracked-files=no", "--", ".").Output()
+			code.Modified = err == nil && len(strings.TrimSpace(string(status))) > 0
+		}
+	}
+	return code
+}
+
+// FileChecksum returns the size and hexadecimal SHA-256 checksum of a file.
+// Input: a string fileName
+// Output: an int64 size, a string checksum and an error or nil
+func FileChecksum(fileName string) (int64, string, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return 0, "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Input records an input file of the run with its checksum.
+// Input: a *Provenance, a string role, a string path
+// Output: an error or nil when the file cannot be read
+func (provenance *Provenance) Input(role, path string) error {
+	size, checksum, err := FileChecksum(path)
+	if err != nil {
+		return err
+	}
+	provenance.Manifest.Inputs = append(provenance.Manifest.Inputs, ManifestFile{Role: role, Path: path, Size: size, SHA256: checksum})
+	return nil
+}
+
+// InputSet records a ligand set as read by molio.LoadLigandSet: a multi-molecule file, or every .mol2, .pdb and
+// .pqr file of a directory.
+// Input: a *Provenance, a string role, a string path
+// Output: an error or nil when the set cannot be read
+func (provenance *Provenance) InputSet(role, path string) error {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return provenance.Input(role, path)
+	}
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".mol2" && ext != ".pdb" && ext != ".pqr") {
+			continue
+		}
+		if err := provenance.Input(role, filepath.Join(path, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parameter records a setting of the command that is not part of the run configuration.
+// Input: a *Provenance, a string name, a string value
+// Output: none
+func (provenance *Provenance) Parameter(name, value string) {
+	if provenance.Manifest.Parameters == nil {
+		provenance.Manifest.Parameters = make(map[string]string)
+	}
+	provenance.Manifest.Parameters[name] = value
+}
+
+// SetConfig records the resolved run configuration and its seed.
+// Input: a *Provenance, a RunConfig
+// Output: none
+func (provenance *Provenance) SetConfig(config RunConfig) {
+	provenance.Manifest.Config = &config
+	provenance.Manifest.Seed = config.Seed
+}
+
+// Stage ends the current stage of the run and starts timing the next one.
+// Input: a *Provenance, a string name
+// Output: none
+func (provenance *Provenance) Stage(name string) {
+	provenance.endStage()
+	provenance.Manifest.Stages = append(provenance.Manifest.Stages, StageTiming{Name: name, Started: time.Now()})
+	provenance.stage = len(provenance.Manifest.Stages) - 1
+}
+
+// endStage records the duration of the current stage, if any.
+// Input: a *Provenance
+// Output: none
+func (provenance *Provenance) endStage() {
+	if provenance.stage >= 0 {
+		stage := &provenance.Manifest.Stages[provenance.stage]
+		stage.Seconds = time.Since(stage.Started).Seconds()
+		provenance.stage = -1
+	}
+}
+
+// Save finishes the manifest and writes it to dir. The outputs are the files below dir changed since the run
+// started, so results of earlier runs in the same folder are left out.
+// Input: a *Provenance, a string dir
+// Output: an error or nil
+func (provenance *Provenance) Save(dir string) error {
+	provenance.endStage()
+	manifest := &provenance.Manifest
+	manifest.Finished = time.Now()
+	manifest.Outputs = nil
+	files, err := listFiles(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		info, err := os.Stat(path)
+		if err != nil || file == MANIFESTFILE || info.ModTime().Before(manifest.Started.Truncate(time.Second)) {
+			continue
+		}
+		size, checksum, err := FileChecksum(path)
+		if err != nil {
+			return err
+		}
+		manifest.Outputs = append(manifest.Outputs, ManifestFile{Role: "output", Path: file, Size: size, SHA256: checksum})
+	}
+	return provenance.write(filepath.Join(dir, MANIFESTFILE))
+}
+
+// SaveFiles finishes the manifest of a command that writes single files rather than a folder and writes it next
+// to the first of them, as <name>.manifest.json. The outputs are recorded relative to the manifest.
+// Input: a *Provenance, one or more string output file names
+// Output: an error or nil
+func (provenance *Provenance) SaveFiles(outputs ...string) error {
+	provenance.endStage()
+	manifest := &provenance.Manifest
+	manifest.Finished = time.Now()
+	manifest.Outputs = nil
+	manifestFile := strings.TrimSuffix(outputs[0], filepath.Ext(outputs[0])) + ".manifest.json"
+	for _, output := range outputs {
+		size, checksum, err := FileChecksum(output)
+		if err != nil {
+			return err
+		}
+		path, err := filepath.Rel(filepath.Dir(manifestFile), output)
+		if err != nil {
+			return err
+		}
+		manifest.Outputs = append(manifest.Outputs, ManifestFile{Role: "output", Path: filepath.ToSlash(path), Size: size, SHA256: checksum})
+	}
+	return provenance.write(manifestFile)
+}
+
+// write writes the manifest as indented JSON.
+// Input: a *Provenance, a string fileName
+// Output: an error or nil
+func (provenance *Provenance) write(fileName string) error {
+	data, err := json.MarshalIndent(provenance.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fileName, data, 0644)
+}
+
+// LoadManifest reads a manifest file, or the manifest.json of a folder.
+// Input: a string path
+// Output: the Manifest, the string folder of the manifest, and an error or nil
+func LoadManifest(path string) (Manifest, string, error) {
+	var manifest Manifest
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, MANIFESTFILE)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, "", err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	return manifest, filepath.Dir(path), nil
+}
+
+// FileCheck is the result of checking one file of a manifest
+type FileCheck struct {
+	File   ManifestFile
+	Path   string // where the file was looked for
+	Status string // ok, changed or missing
+}
+
+// checkFile compares a file on disk with its manifest entry.
+// Input: a ManifestFile file, a string path to check
+// Output: a FileCheck
+func checkFile(file ManifestFile, path string) FileCheck {
+	check := FileCheck{File: file, Path: path, Status: "ok"}
+	size, checksum, err := FileChecksum(path)
+	switch {
+	case err != nil:
+		check.Status = "missing"
+	case size != file.Size || checksum != file.SHA256:
+		check.Status = "changed"
+	}
+	return check
+}
+
+// VerifyManifest re-computes the checksums of the inputs of a run, and of its outputs when outputs is true.
+// Relative input paths are looked up in the current directory first, then in the working directory of the run.
+// Input: a Manifest, the string folder of the manifest, a bool outputs
+// Output: a slice of FileCheck, inputs first
+func VerifyManifest(manifest Manifest, dir string, outputs bool) []FileCheck {
+	var checks []FileCheck
+	for _, file := range manifest.Inputs {
+		path := file.Path
+		if _, err := os.Stat(path); err != nil && !filepath.IsAbs(path) && manifest.WorkingDir != "" {
+			path = filepath.Join(manifest.WorkingDir, path)
+		}
+		checks = append(checks, checkFile(file, path))
+	}
+	if outputs {
+		for _, file := range manifest.Outputs {
+			checks = append(checks, checkFile(file, filepath.Join(dir, filepath.FromSlash(file.Path))))
+		}
+	}
+	return checks
+}
+
+// VerifyMain is the entry point of the "verify" command, which checks the files of a run against its manifest.
+// Usage: verify [flags] manifest.json|outputDir
+// Input: a slice of strings args (without the command name)
+// Output: none (prints a table and exits with status 1 when a file is missing or changed)
+func VerifyMain(args []string) {
+	flags := commandFlags("verify", "manifest.json|outputDir",
+		"Re-computes the SHA-256 checksums of the input files recorded in a run's manifest.json and reports any that are\n"+
+			"missing or changed, so published results can be traced back to exactly the files they came from. Exits with\n"+
+			"status 1 when a file does not match.")
+	outputs := flags.Bool("outputs", false, "also check the output files recorded in the manifest")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return
+	}
+	manifest, dir, err := LoadManifest(flags.Arg(0))
+	Check(err)
+	fmt.Printf("%s run of %s (%s), finished %s\n", manifest.Command, manifest.Started.Format(time.RFC3339),
+		manifest.Host.Hostname, manifest.Finished.Format(time.RFC3339))
+	current := CurrentCodeVersion()
+	if manifest.Code.Revision != current.Revision || manifest.Code.Modified || current.Modified {
+		fmt.Printf("Note: produced by revision %s (modified: %v), this build is %s (modified: %v)\n",
+			manifest.Code.Revision, manifest.Code.Modified, current.Revision, current.Modified)
+	}
+	checks := VerifyManifest(manifest, dir, *outputs)
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(writer, "status\trole\tfile\n")
+	failed := 0
+	for _, check := range checks {
+		if check.Status != "ok" {
+			failed++
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", check.Status, check.File.Role, check.Path)
+	}
+	writer.Flush()
+	if failed > 0 {
+		fmt.Printf("%d of %d files do not match the manifest\n", failed, len(checks))
+		os.Exit(1)
+	}
+	fmt.Printf("All %d files match the manifest\n", len(checks))
+}
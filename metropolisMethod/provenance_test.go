@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProvenanceManifest(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "ligand.mol2")
+	outputDir := filepath.Join(dir, "output")
+	if err := os.WriteFile(input, []byte("@<TRIPOS>MOLECULE\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(outputDir, "poses"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := filepath.Join(outputDir, "old.csv")
+	os.WriteFile(old, []byte("earlier run"), 0644)
+	os.Chtimes(old, time.Now().Add(-time.Hour), time.Now().Add(-time.Hour))
+
+	provenance := NewProvenance("screen")
+	config := DefaultRunConfig()
+	config.Seed = 42
+	provenance.SetConfig(config)
+	provenance.Stage("load inputs")
+	if err := provenance.Input("ligand", input); err != nil {
+		t.Fatal(err)
+	}
+	if err := provenance.Input("protein", filepath.Join(dir, "missing.pdb")); err == nil {
+		t.Errorf("Expected an error for a missing input")
+	}
+	provenance.Stage("simulation")
+	os.WriteFile(filepath.Join(outputDir, "poses", "ligand.mol2"), []byte("pose"), 0644)
+	if err := provenance.Save(outputDir); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, manifestDir, err := LoadManifest(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Seed != 42 || len(manifest.Inputs) != 1 || len(manifest.Stages) != 2 || manifest.Host.CPUs < 1 || manifest.Constants["sampling.TEMPERATURE"] == 0 {
+		t.Errorf("Expected the seed, input, stages, host and constants to be recorded, got %+v", manifest)
+	}
+	if len(manifest.Outputs) != 1 || manifest.Outputs[0].Path != "poses/ligand.mol2" {
+		t.Errorf("Expected only the output written during the run, got %+v", manifest.Outputs)
+	}
+	for _, check := range VerifyManifest(manifest, manifestDir, true) {
+		if check.Status != "ok" {
+			t.Errorf("Expected %s to match, got %s", check.Path, check.Status)
+		}
+	}
+
+	os.WriteFile(input, []byte("@<TRIPOS>MOLECULE\nedited\n"), 0644)
+	os.Remove(filepath.Join(outputDir, "poses", "ligand.mol2"))
+	checks := VerifyManifest(manifest, manifestDir, true)
+	if len(checks) != 2 || checks[0].Status != "changed" || checks[1].Status != "missing" {
+		t.Errorf("Expected a changed input and a missing output, got %+v", checks)
+	}
+}
+
+func TestProvenanceSaveFiles(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "receptor.pqr")
+	if err := os.WriteFile(output, []byte("ATOM\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewProvenance("receptor").SaveFiles(output); err != nil {
+		t.Fatal(err)
+	}
+	manifest, manifestDir, err := LoadManifest(filepath.Join(dir, "receptor.manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checks := VerifyManifest(manifest, manifestDir, true)
+	if len(checks) != 1 || checks[0].Path != output || checks[0].Status != "ok" {
+		t.Errorf("Expected receptor.pqr to be recorded next to its manifest, got %+v", checks)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/prepare"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+)
+
+// PrintReceptorReport prints a human readable summary of a ReceptorReport.
+// Input: a ReceptorReport
+// Output: none (prints to stdout)
+func PrintReceptorReport(report prepare.ReceptorReport) {
+	fmt.Printf("Residues: %d, net charge: %+.4f\n", report.Residues, report.NetCharge)
+	fmt.Printf("N-termini: %s\n", strings.Join(report.NTermini, " "))
+	fmt.Printf("C-termini: %s\n", strings.Join(report.CTermini, " "))
+	if len(report.Disulfides) > 0 {
+		fmt.Printf("Disulfides: %s\n", strings.Join(report.Disulfides, " "))
+	}
+	histidines := make([]string, 0, len(report.Histidines))
+	for id, tautomer := range report.Histidines {
+		histidines = append(histidines, id+"="+tautomer)
+	}
+	sort.Strings(histidines)
+	if len(histidines) > 0 {
+		fmt.Printf("Histidines: %s\n", strings.Join(histidines, " "))
+	}
+	if len(report.MissingHeavy) > 0 {
+		fmt.Printf("Missing heavy atoms (%d): %s\n", len(report.MissingHeavy), strings.Join(report.MissingHeavy, ", "))
+	}
+	if len(report.Untyped) > 0 {
+		fmt.Printf("Untyped atoms (%d): %s\n", len(report.Untyped), strings.Join(report.Untyped, ", "))
+	}
+}
+
+// SaveReceptor writes a receptor with new coordinates in the format of its input file: a MOL2 file keeps every
+// record of the original with the coordinates replaced, while PDB and PQR files are written anew.
+// Input: a string originalFile, a string fileName, a Molecule receptor read from originalFile
+// Output: an error or nil
+func SaveReceptor(originalFile, fileName string, receptor molecule.Molecule) error {
+	extension := strings.ToLower(filepath.Ext(originalFile))
+	if extension == ".mol2" {
+		return UpdateMol2Coordinates(originalFile, fileName, receptor)
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	molio.WritePDB(writer, receptor, extension == ".pqr")
+	return writer.Flush()
+}
+
+// WriteSideChainTable writes the final χ angles in degrees and the nearest library rotamer of every flexible
+// residue for every ligand, one row per ligand and residue.
+// Input: a string fileName, a slice of string ligand labels, a slice of their Molecule receptors, a *SideChains
+// Output: an error or nil
+func WriteSideChainTable(fileName string, labels []string, receptors []molecule.Molecule, sideChains *sampling.SideChains) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"ligand", "residue", "rotamer", "chi1", "chi2", "chi3", "chi4"})
+	for i, receptor := range receptors {
+		for _, residue := range sideChains.Residues {
+			row := []string{labels[i], residue.Residue.ID(), residue.NearestRotamer(receptor).Name, "", "", "", ""}
+			for k, chi := range residue.Chis {
+				row[3+k] = strconv.FormatFloat(chi.Angle(receptor)*180/math.Pi, 'f', 1, 64)
+			}
+			writer.Write(row)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// PrepareReceptorMain is the entry point of the "receptor" command, which writes a charged and typed receptor as PQR.
+// Usage: receptor input.pdb output.pqr [HID|HIE|HIP]
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the output file and prints a report)
+func PrepareReceptorMain(args []string) {
+	flags := commandFlags("receptor", "input.pdb output.pqr [HID|HIE|HIP]",
+		"Writes the receptor as PQR with AMBER ff14SB-style charges and atom types and prints a report of its termini,\n"+
+			"disulfides, histidine tautomers and untyped atoms. Histidines that cannot be decided become HIE by default.")
+	flags.Parse(args)
+	args = flags.Args()
+	if len(args) < 2 || len(args) > 3 {
+		flags.Usage()
+		return
+	}
+	opts := prepare.DefaultReceptorOptions()
+	if len(args) == 3 {
+		opts.HisDefault = args[2]
+	}
+	provenance := NewProvenance("receptor")
+	provenance.Parameter("histidine", opts.HisDefault)
+	provenance.Stage("preparation")
+	protein, err := molio.LoadReceptorStructure(args[0])
+	warnOrCheck(err)
+	Check(provenance.Input("protein", args[0]))
+	receptor, report := prepare.PrepareReceptor(protein, opts)
+	PrintReceptorReport(report)
+
+	file, err := os.Create(args[1])
+	Check(err)
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	molio.WritePDB(writer, receptor, true)
+	Check(writer.Flush())
+	Check(provenance.SaveFiles(args[1]))
+	fmt.Println("Charged receptor written to:", args[1])
+}
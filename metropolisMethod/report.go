@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+)
+
+// ReportParameter is one run parameter shown at the top of a report
+type ReportParameter struct {
+	Name, Value string
+}
+
+// ReportLigand is one row of the ligand table of a report
+type ReportLigand struct {
+	Name           string
+	Energy         float64
+	Restraint      float64 // restraint energy of the pose, apart from Energy; NaN for a run without restraints
+	RMSD           float64 // symmetry-corrected RMSD from the input pose, NaN when unknown
+	AcceptanceRate float64 // final acceptance rate averaged over walkers, NaN without a trace
+	Displacement   float64 // final RMSD from the starting pose averaged over walkers, NaN without a trace
+	PocketDistance float64 // final centroid distance to the pocket centre averaged over walkers, NaN without a trace
+	Interactions   int
+	Pose           []byte // MOL2 file of the final pose
+	Snapshot       string // receptor snapshot the pose was docked into, empty outside ensemble docking
+}
+
+// ReportPlot is a plot embedded in a report as SVG
+type ReportPlot struct {
+	Title string
+	SVG   []byte
+}
+
+// ScreeningReport holds everything shown in the HTML report of a screening run
+type ScreeningReport struct {
+	Title        string
+	Created      time.Time
+	EnergyColumn string // heading of the energy column, "Energy" when empty
+	Parameters   []ReportParameter
+	Ligands      []ReportLigand
+	Plots        []ReportPlot
+}
+
+// NewReportLigand collects the table row of one docked ligand.
+// Input: a string name, a Molecule protein, the final Molecule pose and its float64 energy, the input Molecule
+// reference pose, and the walker traces of its simulation (may be nil)
+// Output: a ReportLigand
+func NewReportLigand(name string, protein, pose molecule.Molecule, energy float64, reference molecule.Molecule, walkers []sampling.WalkerTrace) ReportLigand {
+	ligand := ReportLigand{
+		Name:           name,
+		Energy:         energy,
+		Restraint:      math.NaN(),
+		RMSD:           math.NaN(),
+		AcceptanceRate: math.NaN(),
+		Displacement:   math.NaN(),
+		PocketDistance: math.NaN(),
+		Interactions:   len(analysis.DetectInteractions(protein, pose)),
+	}
+	if len(reference.Atoms) == len(pose.Atoms) {
+		ligand.RMSD = analysis.CalculateRMSDMode(pose, reference, analysis.RMSDSymmetry)
+	}
+	if len(walkers) > 0 {
+		var rates, displacements, distances []float64
+		for _, walker := range walkers {
+			last := len(walker.Iteration) - 1
+			if last < 0 {
+				continue
+			}
+			rates = append(rates, walker.AcceptanceRate[last])
+			displacements = append(displacements, walker.Displacement[last])
+			distances = append(distances, walker.PocketDistance[last])
+		}
+		if len(rates) > 0 {
+			ligand.AcceptanceRate, ligand.Displacement, ligand.PocketDistance = analysis.Mean(rates), analysis.Mean(displacements), analysis.Mean(distances)
+		}
+	}
+	var mol2 bytes.Buffer
+	molio.WriteMol2(&mol2, pose, name, "USER_CHARGES")
+	ligand.Pose = mol2.Bytes()
+	return ligand
+}
+
+// RenderSVG runs a plotting function with SVG output into a temporary directory and returns the SVG.
+// Input: a function plotting to a fileName (without extension) with the given PlotOptions, a PlotOptions
+// Output: the SVG bytes and an error or nil
+func RenderSVG(plotTo func(fileName string, options PlotOptions), options PlotOptions) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "report")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	options.Format = "svg"
+	fileName := filepath.Join(dir, "plot")
+	plotTo(fileName, options)
+	return os.ReadFile(fileName + ".svg")
+}
+
+// reportCell is a table cell with the text shown and the value it sorts by
+type reportCell struct {
+	Text  string
+	Value string
+}
+
+// numberCell formats a number for the ligand table; NaN is shown as a dash and sorts last.
+// Input: a float64 value, a string format
+// Output: a reportCell
+func numberCell(value float64, format string) reportCell {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return reportCell{Text: "–", Value: "Infinity"}
+	}
+	return reportCell{Text: fmt.Sprintf(format, value), Value: fmt.Sprintf("%g", value)}
+}
+
+// dataURL encodes data as a base64 data URL, so images and files live inside the HTML file.
+// Input: a string MIME type, a slice of bytes data
+// Output: a template.URL
+func dataURL(mimeType string, data []byte) template.URL {
+	return template.URL("data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+// reportTemplate is the single-file HTML report; styles and the table sorting script are inline so it
+// works offline
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 70em; color: #222; }
+h1 { font-size: 1.6em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.7em; text-align: right; }
+th { background: #f0f0f0; }
+td:first-child, th:first-child { text-align: left; }
+table.sortable th { cursor: pointer; user-select: none; }
+table.sortable th::after { content: " \2195"; color: #999; }
+tr.best td { background: #fde3e1; font-weight: bold; }
+.plots { display: flex; flex-wrap: wrap; gap: 1em; }
+figure { margin: 0; }
+figure img { max-width: 34em; border: 1px solid #eee; }
+footer { color: #777; font-size: 0.85em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<h2>Run parameters</h2>
+<table>
+{{range .Parameters}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+<h2>Ligands</h2>
+<p>Click a column header to sort. The best (lowest energy) ligand is highlighted.</p>
+<table class="sortable" id="ligands">
+<thead><tr><th>Ligand</th>{{if .Snapshots}}<th>Best snapshot</th>{{end}}<th>{{.EnergyColumn}}</th>{{if .Restrained}}<th>Restraint energy</th>{{end}}<th>RMSD to input (Å)</th><th>Acceptance rate</th><th>Displacement (Å)</th><th>Pocket distance (Å)</th><th>Interactions</th><th>Pose</th></tr></thead>
+<tbody>
+{{range .Rows}}<tr{{if .Best}} class="best"{{end}}>{{range .Cells}}<td data-value="{{.Value}}">{{.Text}}</td>{{end}}<td><a download="{{.FileName}}" href="{{.Download}}">{{.FileName}}</a></td></tr>
+{{end}}</tbody>
+</table>
+<h2>Plots</h2>
+<div class="plots">
+{{range .Plots}}<figure><img alt="{{.Title}}" src="{{.Source}}"><figcaption>{{.Title}}</figcaption></figure>
+{{end}}</div>
+<footer>Generated {{.Created}}</footer>
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (header, column) {
+    var ascending = true;
+    header.addEventListener("click", function () {
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[column].getAttribute("data-value") || a.cells[column].textContent;
+        var y = b.cells[column].getAttribute("data-value") || b.cells[column].textContent;
+        var nx = parseFloat(x), ny = parseFloat(y);
+        var order = isNaN(nx) || isNaN(ny) ? x.localeCompare(y) : nx - ny;
+        return ascending ? order : -order;
+      });
+      rows.forEach(function (row) { body.appendChild(row); });
+      ascending = !ascending;
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
+
+// WriteHTMLReport writes a screening report as one self-contained HTML file: run parameters, a sortable ligand
+// table with the best ligand highlighted, SVG plots and pose downloads embedded as data URLs.
+// Input: an io.Writer w, a ScreeningReport
+// Output: an error or nil
+func WriteHTMLReport(w io.Writer, report ScreeningReport) error {
+	type row struct {
+		Cells    []reportCell
+		Best     bool
+		FileName string
+		Download template.URL
+	}
+	type figure struct {
+		Title  string
+		Source template.URL
+	}
+	best := -1
+	for i, ligand := range report.Ligands {
+		if best < 0 || ligand.Energy < report.Ligands[best].Energy {
+			best = i
+		}
+	}
+	snapshots, restrained := false, false
+	for _, ligand := range report.Ligands {
+		snapshots = snapshots || ligand.Snapshot != ""
+		restrained = restrained || !math.IsNaN(ligand.Restraint)
+	}
+	energyColumn := report.EnergyColumn
+	if energyColumn == "" {
+		energyColumn = "Energy"
+	}
+	rows := make([]row, len(report.Ligands))
+	for i, ligand := range report.Ligands {
+		cells := []reportCell{{Text: ligand.Name, Value: ligand.Name}}
+		if snapshots {
+			cells = append(cells, reportCell{Text: ligand.Snapshot, Value: ligand.Snapshot})
+		}
+		cells = append(cells, numberCell(ligand.Energy, "%.6g"))
+		if restrained {
+			cells = append(cells, numberCell(ligand.Restraint, "%.4g"))
+		}
+		rows[i] = row{
+			Cells: append(cells,
+				numberCell(ligand.RMSD, "%.2f"),
+				numberCell(ligand.AcceptanceRate, "%.3f"),
+				numberCell(ligand.Displacement, "%.2f"),
+				numberCell(ligand.PocketDistance, "%.2f"),
+				reportCell{Text: fmt.Sprint(ligand.Interactions), Value: fmt.Sprint(ligand.Interactions)},
+			),
+			Best:     i == best,
+			FileName: ligand.Name + "_pose.mol2",
+			Download: dataURL("chemical/x-mol2", ligand.Pose),
+		}
+	}
+	figures := make([]figure, len(report.Plots))
+	for i, p := range report.Plots {
+		figures[i] = figure{Title: p.Title, Source: dataURL("image/svg+xml", p.SVG)}
+	}
+	return reportTemplate.Execute(w, struct {
+		Title        string
+		Created      string
+		EnergyColumn string
+		Snapshots    bool
+		Restrained   bool
+		Parameters   []ReportParameter
+		Rows         []row
+		Plots        []figure
+	}{report.Title, report.Created.Format(time.RFC1123), energyColumn, snapshots, restrained, report.Parameters, rows, figures})
+}
+
+// SaveHTMLReport writes a screening report to fileName.
+// Input: a string fileName, a ScreeningReport
+// Output: an error or nil
+func SaveHTMLReport(fileName string, report ScreeningReport) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := WriteHTMLReport(file, report); err != nil {
+		return err
+	}
+	log.Printf("Report saved as %s", fileName)
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+func TestWriteHTMLReport(t *testing.T) {
+	receptor := moltest.Pocket()
+	ligand := moltest.Benzoate()
+	report := ScreeningReport{
+		Title:      "Mock <screen>",
+		Created:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Parameters: []ReportParameter{{"Iterations", "100"}},
+		Ligands: []ReportLigand{
+			NewReportLigand("benzoate", receptor, ligand, -2, ligand, nil),
+			NewReportLigand("copy", receptor, ligand, 1, molecule.Molecule{}, nil),
+		},
+		Plots: []ReportPlot{{Title: "Energies", SVG: []byte("<svg></svg>")}},
+	}
+	var html bytes.Buffer
+	if err := WriteHTMLReport(&html, report); err != nil {
+		t.Fatal(err)
+	}
+	text := html.String()
+	for _, expected := range []string{"Mock &lt;screen&gt;", `<tr class="best"><td data-value="benzoate">`, "data:image/svg&#43;xml;base64,", `download="benzoate_pose.mol2" href="data:chemical/x-mol2;base64,`} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected the report to contain %q", expected)
+		}
+	}
+	if strings.Contains(text, "Best snapshot") {
+		t.Errorf("Expected no snapshot column outside ensemble docking")
+	}
+	report.Ligands[1].Snapshot, report.EnergyColumn = "model_2", "Boltzmann energy"
+	html.Reset()
+	if err := WriteHTMLReport(&html, report); err != nil {
+		t.Fatal(err)
+	}
+	if text := html.String(); !strings.Contains(text, "<th>Best snapshot</th><th>Boltzmann energy</th>") || !strings.Contains(text, `<td data-value="model_2">model_2</td>`) {
+		t.Errorf("Expected the snapshot column and energy heading of an ensemble report")
+	}
+	if strings.Contains(text, "http://") || strings.Contains(text, "https://") {
+		t.Errorf("Expected a report without external resources")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+)
+
+// RestraintEnergy adds up the restraint energies of a pose.
+// Input: a slice of RestraintTerms
+// Output: a float64 energy
+func RestraintEnergy(terms []sampling.RestraintTerm) float64 {
+	total := 0.0
+	for _, term := range terms {
+		total += term.Energy
+	}
+	return total
+}
+
+// WriteRestraintTable writes the value and energy of every restraint for the final pose of every ligand, one row
+// per ligand and restraint. Values are distances or RMSDs in Å and torsions in degrees.
+// Input: a string fileName, a slice of string ligand labels, a slice of the RestraintTerms of each ligand
+// Output: an error or nil
+func WriteRestraintTable(fileName string, labels []string, terms [][]sampling.RestraintTerm) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"ligand", "restraint", "kind", "form", "atoms", "value", "target", "energy"})
+	for i := range terms {
+		for k, term := range terms[i] {
+			restraint := term.Restraint
+			writer.Write([]string{
+				labels[i],
+				strconv.Itoa(k + 1),
+				restraint.Kind,
+				restraint.Form,
+				strings.Join(restraint.Atoms, " "),
+				strconv.FormatFloat(term.Value, 'f', 3, 64),
+				strconv.FormatFloat(restraint.Target, 'g', -1, 64),
+				strconv.FormatFloat(term.Energy, 'g', 6, 64),
+			})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
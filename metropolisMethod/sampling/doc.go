@@ -0,0 +1,6 @@
+// Package sampling is the Metropolis docking engine. A Simulation holds the iterations, parallel walkers, move set,
+// temperature schedule, energy model and seed of a run; RunSimulation and SimulateLigand minimise ligands against a
+// protein with it and can record a WalkerTrace of every walker. With SideChains, walkers also turn the side chains
+// of flexible receptor residues between the rotamers of a built-in library, and Restraints add distance, position
+// and torsion penalties to the energy they minimise.
+package sampling
@@ -0,0 +1,30 @@
+package sampling_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/energy"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+)
+
+// Embedding the simulator: load a complex, configure a seeded run and dock the ligand.
+func ExampleSimulateLigand() {
+	protein, err := molio.LoadMolecule("Data/mol2_files/223l_protein.mol2")
+	if err != nil && !molio.IsWarning(err) {
+		log.Fatal(err)
+	}
+	ligand, err := molio.LoadMolecule("Data/mol2_files/223l_ligand.mol2")
+	if err != nil && !molio.IsWarning(err) {
+		log.Fatal(err)
+	}
+	sim := sampling.NewSimulation(3000, true, sampling.TEMPERATURE, 4)
+	sim.Schedule = sampling.TemperatureSchedule{Kind: "exponential", Start: 600, End: sampling.TEMPERATURE}
+	sim.Seed = 42
+	docked, _ := sampling.SimulateLigand(protein, ligand, 0, sim, false)
+	fmt.Printf("binding energy: %.4g\n", energy.CalculateEnergy(protein, docked))
+	if err := molio.SaveMol2("docked.mol2", docked); err != nil {
+		log.Fatal(err)
+	}
+}
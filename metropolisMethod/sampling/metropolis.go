@@ -0,0 +1,430 @@
+package sampling
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/energy"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// MultipleLigandSimulationOutput is the result of one goroutine of RunSimulation: its minimised ligands, their energies
+// and, when traced, their walker traces
+type MultipleLigandSimulationOutput struct {
+	Ligand   []molecule.Molecule
+	Energy   []float64
+	Traces   [][]WalkerTrace     // walker traces of each ligand, nil when not traced
+	Receptor []molecule.Molecule // receptor of each ligand, with the final side chains when they are flexible
+	Err      error               // first error of a ligand other than a cancellation, e.g. a restraint whose atoms are missing
+}
+
+// SimulateMultipleLigands simulates energy minimization for multiple ligands sequentially by calling the energy minimization function for each ligand.
+// Input: a Molecule protein, a slice of Molecule ligands, an int iterations, a float64 temperature, an int numProcs
+// Output: a slice of minimized Molecule ligands and corresponding float64 energies
+func SimulateMultipleLigands(protein molecule.Molecule, ligands []molecule.Molecule, iterations int, rotate bool, temperature float64, numProcs int) ([]molecule.Molecule, []float64) {
+	minEnergy := make([]float64, len(ligands))
+	minLigands := make([]molecule.Molecule, len(ligands))
+	for i, ligand := range ligands {
+		ligands[i] = ShiftLigandCloserByThreshold(ligand, protein, THRESHOLD)
+	}
+	for i, ligand := range ligands {
+		minLigands[i] = SimulateEnergyMinimizationParallel(protein, ligand, iterations, rotate, temperature, numProcs)
+		minEnergy[i] = energy.CalculateEnergy(protein, minLigands[i])
+	}
+	return minLigands, minEnergy
+}
+
+// SimulateMultipleLigandsParallel simulates energy minimization for multiple ligands distributing ligands across processors.
+// Input: a Molecule protein, a slice of Molecule ligands, an int iterations, a float64 temperature, an int numProcs
+// Output: a slice of minimized Molecule ligands and corresponding float64 energies, calculated after having distributed them over numProcs
+func SimulateMultipleLigandsParallel(protein molecule.Molecule, ligands []molecule.Molecule, iterations int, rotate bool, temperature float64, numProcs int) ([]molecule.Molecule, []float64) {
+	minLigands, minEnergy, _ := RunSimulation(protein, ligands, NewSimulation(iterations, rotate, temperature, numProcs), false)
+	return minLigands, minEnergy
+}
+
+// SimulateMultipleLigandsParallelTraced is SimulateMultipleLigandsParallel that also records the trace of every walker.
+// Input: a Molecule protein, a slice of Molecule ligands, an int iterations, a float64 temperature, an int numProcs
+// Output: a slice of minimized Molecule ligands, their float64 energies and the walker traces of each ligand
+func SimulateMultipleLigandsParallelTraced(protein molecule.Molecule, ligands []molecule.Molecule, iterations int, rotate bool, temperature float64, numProcs int) ([]molecule.Molecule, []float64, [][]WalkerTrace) {
+	return RunSimulation(protein, ligands, NewSimulation(iterations, rotate, temperature, numProcs), true)
+}
+
+// RunSimulation minimizes every ligand with the parameters of sim, distributing the ligands across sim.Walkers
+// goroutines, and records walker traces when traced is true.
+// Input: a Molecule protein, a slice of Molecule ligands, a Simulation sim, a bool traced
+// Output: a slice of minimized Molecule ligands, their float64 energies under sim.Energy and the walker traces of each ligand (nil when not traced)
+func RunSimulation(protein molecule.Molecule, ligands []molecule.Molecule, sim Simulation, traced bool) ([]molecule.Molecule, []float64, [][]WalkerTrace) {
+	minLigands, minEnergy, traces, _ := RunSimulationContext(context.Background(), protein, ligands, sim, traced)
+	return minLigands, minEnergy, traces
+}
+
+// RunSimulationContext is RunSimulation that stops every walker when the context is cancelled, so a run can be
+// abandoned without leaving goroutines behind.
+// Input: a context.Context ctx, a Molecule protein, a slice of Molecule ligands, a Simulation sim, a bool traced
+// Output: the results of RunSimulation, and ctx.Err() when the run was cancelled (the results are then incomplete)
+func RunSimulationContext(ctx context.Context, protein molecule.Molecule, ligands []molecule.Molecule, sim Simulation, traced bool) ([]molecule.Molecule, []float64, [][]WalkerTrace, error) {
+	minLigands, _, minEnergy, traces, err := RunFlexibleSimulation(ctx, protein, ligands, sim, traced)
+	return minLigands, minEnergy, traces, err
+}
+
+// RunFlexibleSimulation is RunSimulationContext that also returns the receptor each ligand ends up docked against:
+// the protein itself, or a copy with the final side chains of sim.Flexible. The energies are then those of each
+// ligand with its own receptor.
+// Input: the inputs of RunSimulationContext
+// Output: a slice of minimized Molecule ligands, a slice of their Molecule receptors, then the other results of
+// RunSimulationContext; the error is that of the first ligand that could not be simulated, if any, before ctx.Err()
+func RunFlexibleSimulation(ctx context.Context, protein molecule.Molecule, ligands []molecule.Molecule, sim Simulation, traced bool) ([]molecule.Molecule, []molecule.Molecule, []float64, [][]WalkerTrace, error) {
+	numProcs := sim.Walkers
+	minEnergy := make([]float64, 0)
+	minLigands := make([]molecule.Molecule, 0)
+	receptors := make([]molecule.Molecule, 0)
+	var traces [][]WalkerTrace
+	var firstErr error
+	ligandChannels := make([]chan MultipleLigandSimulationOutput, numProcs)
+	for i := range ligandChannels {
+		ligandChannels[i] = make(chan MultipleLigandSimulationOutput, len(ligands))
+	}
+	width := len(ligands) / numProcs
+	for i := 0; i < numProcs; i++ {
+		startIndex := i * width
+		var endIndex int
+		if i != numProcs-1 {
+			endIndex = startIndex + width
+		} else {
+			endIndex = len(ligands)
+		}
+		go simulateLigandsOneProc(ctx, protein, ligands[startIndex:endIndex], startIndex, sim, traced, ligandChannels[i])
+	}
+	for i := 0; i < numProcs; i++ {
+		minLigAndDelta := <-ligandChannels[i]
+		minEnergy = append(minEnergy, minLigAndDelta.Energy...)
+		minLigands = append(minLigands, minLigAndDelta.Ligand...)
+		receptors = append(receptors, minLigAndDelta.Receptor...)
+		if traced {
+			traces = append(traces, minLigAndDelta.Traces...)
+		}
+		if firstErr == nil {
+			firstErr = minLigAndDelta.Err
+		}
+	}
+	if firstErr != nil {
+		return minLigands, receptors, minEnergy, traces, firstErr
+	}
+	return minLigands, receptors, minEnergy, traces, ctx.Err()
+}
+
+// SimulateLigandMinimizationOneProc minimizes ligand energies in a single processor and sends results through a channel.
+// Input: a Molecule protein, a slice of Molecule ligands, the int index of the first ligand in the whole run, a Simulation sim, a bool traced, a channel ligandChannel
+// Output: none (but sends the minimized ligands, their energies and, when traced, the walker traces through the channel ligandChannel)
+func SimulateLigandMinimizationOneProc(protein molecule.Molecule, ligands []molecule.Molecule, first int, sim Simulation, traced bool, ligandChannel chan MultipleLigandSimulationOutput) {
+	simulateLigandsOneProc(context.Background(), protein, ligands, first, sim, traced, ligandChannel)
+}
+
+// simulateLigandsOneProc is SimulateLigandMinimizationOneProc that skips the remaining ligands once ctx is cancelled.
+// Input: a context.Context ctx, then the inputs of SimulateLigandMinimizationOneProc
+// Output: none (sends the results through the channel ligandChannel)
+func simulateLigandsOneProc(ctx context.Context, protein molecule.Molecule, ligands []molecule.Molecule, first int, sim Simulation, traced bool, ligandChannel chan MultipleLigandSimulationOutput) {
+	minEnergy := make([]float64, len(ligands))
+	minLigands := make([]molecule.Molecule, len(ligands))
+	receptors := make([]molecule.Molecule, len(ligands))
+	traces := make([][]WalkerTrace, len(ligands))
+	var firstErr error
+	for i, ligand := range ligands {
+		if ctx.Err() != nil {
+			minLigands[i], receptors[i] = ligand, protein
+			continue
+		}
+		var err error
+		minLigands[i], receptors[i], traces[i], err = SimulateFlexibleLigand(ctx, protein, ligand, first+i, sim, traced)
+		if err != nil && ctx.Err() == nil && firstErr == nil {
+			firstErr = fmt.Errorf("ligand %d: %w", first+i+1, err)
+		}
+		minEnergy[i] = sim.Energy.Energy(receptors[i], minLigands[i])
+	}
+	ligandChannel <- MultipleLigandSimulationOutput{
+		Ligand:   minLigands,
+		Energy:   minEnergy,
+		Traces:   traces,
+		Receptor: receptors,
+		Err:      firstErr,
+	}
+}
+
+// SimulateEnergyMinimization performs energy minimization using the Metropolis criterion
+// Input: a Molecule protein, a Molecule ligand, an int iterations, a float64 temperature
+// Output: a minimized Molecule ligand
+func SimulateEnergyMinimization(protein, ligand molecule.Molecule, iterations int, rotate bool, temperature float64) molecule.Molecule {
+	minLigand, _ := runWalker(nil, protein, ligand, NewSimulation(iterations, rotate, temperature, 1), nil, iterations, nil, nil)
+	return minLigand
+}
+
+// SimulateEnergyMinimizationParallel performs energy minimization using the Metropolis criterion distributed over processors
+// Input: a Molecule protein, a Molecule ligand, an int iterations, a float64 temperature, an int numProcs
+// Output: a minimized Molecule ligand
+func SimulateEnergyMinimizationParallel(protein, ligand molecule.Molecule, iterations int, rotate bool, temperature float64, numProcs int) molecule.Molecule {
+	minLigand, _ := SimulateLigand(protein, ligand, 0, NewSimulation(iterations, rotate, temperature, numProcs), false)
+	return minLigand
+}
+
+// SimulateEnergyMinimizationTraced is SimulateEnergyMinimizationParallel that also records the trace of every walker.
+// Input: a Molecule protein, a Molecule ligand, an int iterations, a float64 temperature, an int numProcs
+// Output: a minimized Molecule ligand and one WalkerTrace per processor
+func SimulateEnergyMinimizationTraced(protein, ligand molecule.Molecule, iterations int, rotate bool, temperature float64, numProcs int) (molecule.Molecule, []WalkerTrace) {
+	return SimulateLigand(protein, ligand, 0, NewSimulation(iterations, rotate, temperature, numProcs), true)
+}
+
+// walkerOutput is the final ligand and receptor of one walker and its trace (nil when not traced)
+type walkerOutput struct {
+	Ligand   molecule.Molecule
+	Receptor molecule.Molecule
+	Trace    *WalkerTrace
+}
+
+// SimulateLigand runs sim.Walkers Metropolis walkers from the same starting pose and combines their final
+// poses with the Metropolis criterion at the final temperature. Each walker gets its own copy of the ligand.
+// With a search box, a ligand starting outside it is first moved to the box centre.
+// Input: a Molecule protein, a Molecule ligand, the int index of the ligand in the run (selects its random sources), a Simulation sim, a bool traced
+// Output: a minimized Molecule ligand and the walker traces in walker order (nil when not traced)
+func SimulateLigand(protein, ligand molecule.Molecule, index int, sim Simulation, traced bool) (molecule.Molecule, []WalkerTrace) {
+	minLigand, traces, _ := SimulateLigandContext(context.Background(), protein, ligand, index, sim, traced)
+	return minLigand, traces
+}
+
+// SimulateLigandContext is SimulateLigand whose walkers stop at their next move once the context is cancelled.
+// Input: a context.Context ctx, then the inputs of SimulateLigand
+// Output: the results of SimulateLigand, and ctx.Err() when the run was cancelled
+func SimulateLigandContext(ctx context.Context, protein, ligand molecule.Molecule, index int, sim Simulation, traced bool) (molecule.Molecule, []WalkerTrace, error) {
+	minLigand, _, traces, err := SimulateFlexibleLigand(ctx, protein, ligand, index, sim, traced)
+	return minLigand, traces, err
+}
+
+// SimulateFlexibleLigand is SimulateLigandContext that also returns the receptor of the chosen walker: the protein
+// itself, or a copy with the final side chains of sim.Flexible. Every walker then turns the side chains of its own
+// copy, and the walkers are combined by their energy including the side-chain strain and the restraints.
+// Input: the inputs of SimulateLigandContext
+// Output: a minimized Molecule ligand, its Molecule receptor, then the other results of SimulateLigandContext, or
+// the input ligand and the error of a restraint whose atoms are missing
+func SimulateFlexibleLigand(ctx context.Context, protein, ligand molecule.Molecule, index int, sim Simulation, traced bool) (molecule.Molecule, molecule.Molecule, []WalkerTrace, error) {
+	restraints, err := NewRestraintSet(sim.Restraints, protein, ligand)
+	if err != nil {
+		return ligand, protein, nil, err
+	}
+	numProcs := sim.Walkers
+	currentLigand, currentReceptor := ligand, protein
+	if sim.Box != nil {
+		currentLigand, _ = sim.Box.PlaceInBox(ligand)
+	}
+	currentEnergy := sim.energy(protein, currentLigand, restraints)
+	width := sim.Iterations / numProcs
+	channels := make([]chan walkerOutput, numProcs)
+	for i := 0; i < numProcs; i++ {
+		channels[i] = make(chan walkerOutput, 1)
+		var trace *WalkerTrace
+		if traced {
+			trace = NewWalkerTrace(width)
+		}
+		go func(start molecule.Molecule, source *rand.Rand, trace *WalkerTrace, c chan walkerOutput) {
+			minLigand, receptor := runWalker(ctx.Done(), protein, start, sim, restraints, width, source, trace)
+			c <- walkerOutput{Ligand: minLigand, Receptor: receptor, Trace: trace}
+		}(molecule.CopyLigand(currentLigand), sim.source(index, i), trace, channels[i])
+	}
+	merge := sim.source(index, numProcs)
+	temperature := sim.Schedule.At(width-1, width)
+	var traces []WalkerTrace
+	for i := 0; i < numProcs; i++ {
+		output := <-channels[i]
+		newEnergy := sim.energy(output.Receptor, output.Ligand, restraints)
+		if acceptMove(currentEnergy, newEnergy, temperature, merge) {
+			currentLigand, currentReceptor = output.Ligand, output.Receptor
+			currentEnergy = newEnergy
+		}
+		if output.Trace != nil {
+			traces = append(traces, *output.Trace)
+		}
+	}
+	return currentLigand, currentReceptor, traces, ctx.Err()
+}
+
+// SimulateEnergyMinimizationOneProc minimizes energy of a protein ligand interaction and sends the minimized ligand through a channel
+// Input: a Molecule protein, a Molecule ligand, an int iterations, a float64 temperature, a channel c
+// Output: none (sends the minimized ligand results through channel c)
+func SimulateEnergyMinimizationOneProc(protein, ligand molecule.Molecule, iterations int, rotate bool, temperature float64, c chan molecule.Molecule) {
+	minLigand, _ := runWalker(nil, protein, ligand, NewSimulation(iterations, rotate, temperature, 1), nil, iterations, nil, nil)
+	c <- minLigand
+}
+
+// runWalker performs the Metropolis moves of one walker, recording them in trace unless it is nil.
+// With flexible side chains, a share sim.Flexible.Fraction of the moves turns a side chain of the walker's own copy
+// of the protein instead of moving the ligand. The restraints of the ligand add to the energy of every pose.
+// The walker stops early when done is closed.
+// Input: a channel done (nil to never stop), a Molecule protein, a Molecule ligand, a Simulation sim, a RestraintSet restraints (nil for none), an int iterations of this walker, a *rand.Rand source (nil for the global source), a *WalkerTrace trace
+// Output: the final Molecule ligand and the final Molecule receptor (protein itself when the receptor is rigid)
+func runWalker(done <-chan struct{}, protein, ligand molecule.Molecule, sim Simulation, restraints RestraintSet, iterations int, source *rand.Rand, trace *WalkerTrace) (molecule.Molecule, molecule.Molecule) {
+	flexible := sim.Flexible
+	receptor := protein
+	// the receptor strain only changes with side-chain moves, so it is kept up to date rather than recomputed
+	strain := 0.0
+	if flexible != nil {
+		receptor = molecule.CopyLigand(protein)
+		strain = flexible.ReceptorEnergy(receptor, sim.Energy)
+	}
+	energyOf := func(ligand molecule.Molecule) float64 {
+		total := sim.Energy.Energy(receptor, ligand) + restraints.Energy(receptor, ligand)
+		if flexible != nil {
+			total += strain + flexible.LigandClash(receptor, ligand, sim.Energy)
+		}
+		return total
+	}
+	currentLigand := ligand
+	currentEnergy := energyOf(currentLigand)
+	if trace != nil {
+		trace.Begin(protein, ligand)
+		trace.Record(0, currentEnergy, false, sim.Schedule.At(0, iterations), currentLigand)
+	}
+	for i := 0; i < iterations; i++ {
+		select {
+		case <-done:
+			return currentLigand, receptor
+		default:
+		}
+		temperature := sim.Schedule.At(i, iterations)
+		accepted := false
+		if flexible != nil && uniform(source) < flexible.Fraction {
+			r := int(uniform(source) * float64(len(flexible.Residues)))
+			saved, savedStrain := flexible.positions(receptor, r), strain
+			strain -= flexible.residueEnergy(receptor, r, sim.Energy, nil)
+			flexible.Turn(receptor, r, source)
+			strain += flexible.residueEnergy(receptor, r, sim.Energy, nil)
+			newEnergy := energyOf(currentLigand)
+			accepted = acceptMove(currentEnergy, newEnergy, temperature, source)
+			if accepted {
+				currentEnergy = newEnergy
+			} else {
+				flexible.restore(receptor, r, saved)
+				strain = savedStrain
+			}
+		} else {
+			newLigand := sim.Moves.Propose(currentLigand, source)
+			if sim.Box == nil || sim.Box.Contains(molecule.Centroid(molecule.Positions(newLigand))) {
+				newEnergy := energyOf(newLigand)
+				accepted = acceptMove(currentEnergy, newEnergy, temperature, source)
+				if accepted {
+					currentLigand = newLigand
+					currentEnergy = newEnergy
+				}
+			}
+		}
+		if trace != nil {
+			trace.Record(i+1, currentEnergy, accepted, temperature, currentLigand)
+		}
+	}
+	return currentLigand, receptor
+}
+
+// AcceptMove determines whether to accept a new ligand state based on the Metropolis criterion.
+// Input: two float64 values for current and new energy, and a float64 temperature
+// Output: a bool indicating acceptance
+func AcceptMove(currentEnergy, newEnergy, temperature float64) bool {
+	return acceptMove(currentEnergy, newEnergy, temperature, nil)
+}
+
+// acceptMove is AcceptMove drawing from the given random source (nil for the global source).
+// Input: two float64 values for current and new energy, a float64 temperature, a *rand.Rand source
+// Output: a bool indicating acceptance
+func acceptMove(currentEnergy, newEnergy, temperature float64, source *rand.Rand) bool {
+	if newEnergy < currentEnergy {
+		return true
+	}
+	deltaE := newEnergy - currentEnergy
+	// Metropolis acceptance criterion
+	probability := math.Exp(-deltaE / temperature)
+	return uniform(source) < probability
+}
+
+// JitterLigand applies random changes to ligand atom positions while ensuring no atom collisions.
+// Input: a Molecule ligand, a float64 minDistance
+// Output: a perturbed Molecule ligand which follows no atom collision based on the provided minDistance
+func JitterLigand(ligand molecule.Molecule, minDistance float64) molecule.Molecule {
+	return MoveSet{StepSize: STEPSIZE, MinDistance: minDistance}.Propose(ligand, nil)
+}
+
+// JitterAndRotateLigand applies random changes to ligand atom positions and rotates it while ensuring no atom collisions.
+// Input: a Molecule ligand, a float64 minDistance
+// Output: a perturbed Molecule ligand which follows no atom collision based on the provided minDistance
+func JitterAndRotateLigand(ligand molecule.Molecule, minDistance float64, maxAngle float64) molecule.Molecule {
+	return MoveSet{Rotate: true, StepSize: STEPSIZE, MaxAngle: maxAngle, MinDistance: minDistance}.Propose(ligand, nil)
+}
+
+// RotateLigand rotates the ligand around a random axis by a random angle within the specified maxAngle.
+// Input: a Molecule ligand, a float64 maxAngle
+// Output: a rotated Molecule ligand
+func RotateLigand(ligand molecule.Molecule, maxAngle float64) molecule.Molecule {
+	return RotateLigandFrom(ligand, maxAngle, nil)
+}
+
+// RotateLigandFrom is RotateLigand drawing from the given random source (nil for the global source).
+// Input: a Molecule ligand, a float64 maxAngle, a *rand.Rand source
+// Output: a rotated Molecule ligand
+func RotateLigandFrom(ligand molecule.Molecule, maxAngle float64, source *rand.Rand) molecule.Molecule {
+	newLigand := ligand
+	// Randomly choose rotation axis
+	axis := molecule.Position3d{
+		X: uniform(source)*2 - 1,
+		Y: uniform(source)*2 - 1,
+		Z: uniform(source)*2 - 1,
+	}
+	axis.Normalize()
+
+	// Randomly choose rotation angle
+	theta := (uniform(source)*2 - 1) * maxAngle
+
+	// Apply rotation to each atom
+	for i := range newLigand.Atoms {
+		newLigand.Atoms[i].Position = molecule.RotateAtom(newLigand.Atoms[i].Position, axis, theta)
+	}
+
+	return newLigand
+}
+
+// IsCollisionFree checks for atomic collisions in the ligand based on a minimum allowed distance.
+// Input: a Molecule ligand, a float64 minimum distance
+// Output: a bool indicating if the ligand is collision-free
+func IsCollisionFree(ligand molecule.Molecule, minDistance float64) bool {
+	for i := 0; i < len(ligand.Atoms); i++ {
+		for j := i + 1; j < len(ligand.Atoms); j++ {
+			dx := ligand.Atoms[i].Position.X - ligand.Atoms[j].Position.X
+			dy := ligand.Atoms[i].Position.Y - ligand.Atoms[j].Position.Y
+			dz := ligand.Atoms[i].Position.Z - ligand.Atoms[j].Position.Z
+			distance := math.Sqrt(dx*dx + dy*dy + dz*dz)
+			if distance < minDistance {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ShiftLigandCloserByThreshold shifts the ligand closer to the protein if its closest atom distance exceeds the threshold.
+// Input: a Molecule ligand, a Molecule protein, a float64 threshold
+// Output: a shifted Molecule ligand
+func ShiftLigandCloserByThreshold(ligand, protein molecule.Molecule, threshold float64) molecule.Molecule {
+	closestDistance, ligandAtom, proteinAtom := molecule.FindClosestAtomDistance(ligand, protein)
+	if closestDistance > threshold {
+		// Calculate shift vector
+		shiftVector := molecule.Position3d{
+			X: proteinAtom.X - ligandAtom.X,
+			Y: proteinAtom.Y - ligandAtom.Y,
+			Z: proteinAtom.Z - ligandAtom.Z,
+		}
+		// Normalize shift vector and scale to close the gap
+		shiftVector.Normalize()
+		shiftVector = shiftVector.Scale(closestDistance - threshold)
+		// Apply the shift to the entire ligand
+		for i := range ligand.Atoms {
+			ligand.Atoms[i].Position = ligand.Atoms[i].Position.Add(shiftVector)
+		}
+	}
+	return ligand
+}
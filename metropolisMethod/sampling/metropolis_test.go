@@ -0,0 +1,137 @@
+package sampling
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+func TestSimulateMultipleLigands(t *testing.T) {
+	protein := moltest.Protein(1.0, -1.0)
+	ligands := moltest.Ligands(3)
+	iterations := 1000
+	temperature := 300.0
+	numProcs := 4
+
+	minLigands, minEnergies := SimulateMultipleLigands(protein, ligands, iterations, true, temperature, numProcs)
+
+	if len(minLigands) != len(ligands) || len(minEnergies) != len(ligands) {
+		t.Fatalf("Expected %d ligands and energies, got %d ligands and %d energies", len(ligands), len(minLigands), len(minEnergies))
+	}
+}
+
+func TestSimulateMultipleLigandsParallel(t *testing.T) {
+	protein := moltest.Protein(1.0, -1.0)
+	ligands := moltest.Ligands(5)
+	iterations := 2000
+	temperature := 300.0
+	numProcs := 2
+
+	minLigands, minEnergies := SimulateMultipleLigandsParallel(protein, ligands, iterations, true, temperature, numProcs)
+
+	if len(minLigands) != len(ligands) || len(minEnergies) != len(ligands) {
+		t.Fatalf("Expected %d ligands and energies, got %d ligands and %d energies", len(ligands), len(minLigands), len(minEnergies))
+	}
+}
+
+func TestSimulateEnergyMinimizationTraced(t *testing.T) {
+	protein := moltest.Protein(1.0, -1.0)
+	ligand := moltest.Ligands(1)[0]
+	iterations := 4000
+	numProcs := 2
+
+	_, traces := SimulateEnergyMinimizationTraced(protein, ligand, iterations, false, 300.0, numProcs)
+
+	if len(traces) != numProcs {
+		t.Fatalf("Expected %d walker traces, got %d", numProcs, len(traces))
+	}
+	for _, trace := range traces {
+		n := len(trace.Iteration)
+		if n != TRACEPOINTS+1 || trace.Iteration[0] != 0 || trace.Iteration[n-1] != iterations/numProcs {
+			t.Errorf("Expected %d samples from 0 to %d, got %d from %v to %v", TRACEPOINTS+1, iterations/numProcs, n, trace.Iteration[0], trace.Iteration[n-1])
+		}
+		if trace.Displacement[0] != 0 || trace.AcceptanceRate[n-1] < 0 || trace.AcceptanceRate[n-1] > 1 {
+			t.Errorf("Unexpected start displacement %g or acceptance rate %g", trace.Displacement[0], trace.AcceptanceRate[n-1])
+		}
+	}
+}
+
+func TestAcceptMove(t *testing.T) {
+	currentEnergy := 10.0
+	newEnergy := 5.0
+	temperature := 300.0
+
+	accepted := AcceptMove(currentEnergy, newEnergy, temperature)
+	if !accepted {
+		t.Errorf("Expected move to be accepted")
+	}
+}
+
+func TestJitterLigand(t *testing.T) {
+	ligand := moltest.Ligand()
+	minDistance := 0.5
+
+	jittered := JitterLigand(ligand, minDistance)
+	if !IsCollisionFree(jittered, minDistance) {
+		t.Errorf("Expected collision-free ligand after jittering")
+	}
+}
+
+func TestRotateLigand(t *testing.T) {
+	ligand := moltest.Ligand()
+	oldLigand := moltest.Ligand()
+	maxAngle := math.Pi / 4
+
+	rotated := RotateLigand(ligand, maxAngle)
+	if rotated.Atoms[0].Position == oldLigand.Atoms[0].Position {
+		t.Errorf("Expected ligand to be rotated")
+	}
+}
+
+func TestShiftLigandCloserByThreshold(t *testing.T) {
+	protein := moltest.Protein(1.0, -1.0)
+	ligand := moltest.Ligand()
+	threshold := 5.0
+
+	shifted := ShiftLigandCloserByThreshold(ligand, protein, threshold)
+	distance1 := molecule.Distance(shifted.Atoms[0].Position, protein.Atoms[0].Position)
+	distance2 := molecule.Distance(shifted.Atoms[1].Position, protein.Atoms[1].Position)
+	if distance1 > threshold && distance2 > threshold {
+		t.Errorf("Expected shifted ligand to be within threshold distance")
+	}
+}
+
+func TestRunSimulationContextCancelled(t *testing.T) {
+	protein := moltest.Protein(1.0, -1.0)
+	ligands := moltest.Ligands(3)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	minLigands, _, _, err := RunSimulationContext(ctx, protein, ligands, NewSimulation(10000000, true, 300, 2), false)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the run to report its cancellation, got %v", err)
+	}
+	if len(minLigands) != len(ligands) || time.Since(start) > 5*time.Second {
+		t.Errorf("Expected a cancelled run to stop at once with every ligand, got %d ligands after %v", len(minLigands), time.Since(start))
+	}
+}
+
+func TestSimulateLigandInBox(t *testing.T) {
+	protein := moltest.Protein(1.0, -1.0)
+	ligand := moltest.Ligand()
+	sim := NewSimulation(2000, true, 300, 2)
+	sim.Seed = 7
+	sim.Box = &Box{Center: molecule.Position3d{X: 20, Y: 20, Z: 20}, Size: molecule.Position3d{X: 2, Y: 2, Z: 2}}
+	if _, moved := sim.Box.PlaceInBox(ligand); !moved {
+		t.Fatalf("Expected the ligand to start outside the box")
+	}
+	pose, _ := SimulateLigand(protein, ligand, 0, sim, false)
+	if centroid := molecule.Centroid(molecule.Positions(pose)); !sim.Box.Contains(centroid) {
+		t.Errorf("Expected the final centroid inside the box, got %+v", centroid)
+	}
+}
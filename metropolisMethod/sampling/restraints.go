@@ -0,0 +1,315 @@
+package sampling
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// RestraintKinds and RestraintForms are the supported restraints and penalty shapes
+var (
+	RestraintKinds = []string{"distance", "position", "torsion"}
+	RestraintForms = []string{"harmonic", "flat-bottom"}
+)
+
+// Restraint is a penalty that guides the search towards what is known from experiment, as written in a run
+// configuration. Its atoms are selections: "ligand" or "ligand@O1,C4" (atom names or 1-based atom numbers) and
+// "receptor:A:HIS57" or "receptor:A:HIS57@NE2"; a selection of several atoms stands for their centroid.
+type Restraint struct {
+	Kind      string    `json:"kind" toml:"kind"`                               // "distance", "position" or "torsion"
+	Form      string    `json:"form" toml:"form"`                               // "harmonic", or "flat-bottom" to leave deviations up to Width unpenalised
+	Atoms     []string  `json:"atoms" toml:"atoms"`                             // two selections for a distance, one for a position, four for a torsion
+	Target    float64   `json:"target" toml:"target"`                           // distance in Å or torsion in degrees; positions aim at 0
+	Width     float64   `json:"width" toml:"width"`                             // half-width of the flat bottom, in Å or degrees
+	Weight    float64   `json:"weight" toml:"weight"`                           // force constant in energy units per Å², or per radian² for torsions
+	Reference string    `json:"reference,omitempty" toml:"reference,omitempty"` // position: file of the reference coordinates, matched by atom order or else by atom name
+	Point     []float64 `json:"point,omitempty" toml:"point,omitempty"`         // position: x, y, z in Å the centroid of the selection is held at
+
+	ReferenceMolecule molecule.Molecule `json:"-" toml:"-"` // the atoms of the Reference file, read by the caller
+}
+
+// Validate checks the kind, form, number of selections and their syntax, the weight and width, and that a
+// position restraint has exactly one of a reference file or a point.
+// Input: a Restraint
+// Output: an error or nil
+func (restraint Restraint) Validate() error {
+	if !slices.Contains(RestraintKinds, restraint.Kind) {
+		return fmt.Errorf("unknown restraint kind %q, expected one of %v", restraint.Kind, RestraintKinds)
+	}
+	if !slices.Contains(RestraintForms, restraint.Form) {
+		return fmt.Errorf("unknown restraint form %q, expected one of %v", restraint.Form, RestraintForms)
+	}
+	if count := map[string]int{"distance": 2, "position": 1, "torsion": 4}[restraint.Kind]; len(restraint.Atoms) != count {
+		return fmt.Errorf("a %s restraint needs %d atom selections, got %d", restraint.Kind, count, len(restraint.Atoms))
+	}
+	for _, text := range restraint.Atoms {
+		if _, err := ParseSelection(text); err != nil {
+			return err
+		}
+	}
+	if restraint.Weight <= 0 {
+		return fmt.Errorf("weight must be positive, got %v", restraint.Weight)
+	}
+	if restraint.Form == "flat-bottom" && restraint.Width <= 0 {
+		return fmt.Errorf("width must be positive for a flat-bottom restraint, got %v", restraint.Width)
+	}
+	if restraint.Form == "harmonic" && restraint.Width != 0 {
+		return fmt.Errorf("only flat-bottom restraints have a width, got %v", restraint.Width)
+	}
+	if restraint.Kind == "position" {
+		if (restraint.Reference == "") == (restraint.Point == nil) {
+			return fmt.Errorf("a position restraint needs either a reference file or a point")
+		}
+		if restraint.Point != nil && len(restraint.Point) != 3 {
+			return fmt.Errorf("point must have 3 coordinates, got %d", len(restraint.Point))
+		}
+	} else if restraint.Reference != "" || restraint.Point != nil {
+		return fmt.Errorf("only position restraints have a reference or a point")
+	}
+	return nil
+}
+
+// String describes the restraint, e.g. "flat-bottom distance ligand@O1–receptor:A:HIS57@NE2".
+// Input: a Restraint
+// Output: a string
+func (restraint Restraint) String() string {
+	return restraint.Form + " " + restraint.Kind + " " + strings.Join(restraint.Atoms, "–")
+}
+
+// Selection is a parsed atom selection of a restraint
+type Selection struct {
+	Ligand  bool
+	Residue string   // receptor residue as given to molecule.FindResidue, empty for the ligand
+	Names   []string // atom names or 1-based ligand atom numbers, empty for every atom
+}
+
+// ParseSelection reads an atom selection: "ligand", "ligand@O1,C4", "receptor:A:HIS57" or "receptor:A:HIS57@NE2".
+// Input: a string text
+// Output: a Selection and an error when the text is not a selection
+func ParseSelection(text string) (Selection, error) {
+	target, atoms, hasAtoms := strings.Cut(strings.TrimSpace(text), "@")
+	var selection Selection
+	switch {
+	case target == "ligand":
+		selection.Ligand = true
+	case strings.HasPrefix(target, "receptor:") && len(target) > len("receptor:"):
+		selection.Residue = strings.TrimPrefix(target, "receptor:")
+	default:
+		return selection, fmt.Errorf("atom selection %q must start with \"ligand\" or \"receptor:<residue>\"", text)
+	}
+	if hasAtoms {
+		for _, name := range strings.Split(atoms, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				selection.Names = append(selection.Names, name)
+			}
+		}
+		if len(selection.Names) == 0 {
+			return selection, fmt.Errorf("atom selection %q names no atoms after @", text)
+		}
+	}
+	return selection, nil
+}
+
+// Resolve finds the atoms of a selection in the receptor or the ligand.
+// Input: a Selection, a Molecule receptor, a Molecule ligand
+// Output: a slice of atom indices into the receptor or the ligand, and an error when a residue or atom is missing
+func (selection Selection) Resolve(receptor, ligand molecule.Molecule) ([]int, error) {
+	if selection.Ligand {
+		if len(selection.Names) == 0 {
+			indices := make([]int, len(ligand.Atoms))
+			for i := range indices {
+				indices[i] = i
+			}
+			return indices, nil
+		}
+		var indices []int
+		for _, name := range selection.Names {
+			index := slices.IndexFunc(ligand.Atoms, func(atom molecule.Atom) bool { return atom.Name == name })
+			if number, err := strconv.Atoi(name); index < 0 && err == nil && number >= 1 && number <= len(ligand.Atoms) {
+				index = number - 1
+			}
+			if index < 0 {
+				return nil, fmt.Errorf("the ligand has no atom %s", name)
+			}
+			indices = append(indices, index)
+		}
+		return indices, nil
+	}
+	residue, err := molecule.FindResidue(molecule.SplitResidues(receptor), selection.Residue)
+	if err != nil {
+		return nil, err
+	}
+	if len(selection.Names) == 0 {
+		return residue.Atoms, nil
+	}
+	var indices []int
+	for _, name := range selection.Names {
+		index := residue.AtomIndex(receptor, name)
+		if index < 0 {
+			return nil, fmt.Errorf("%s has no atom %s", residue.ID(), name)
+		}
+		indices = append(indices, index)
+	}
+	return indices, nil
+}
+
+// atomGroup is a resolved selection: atoms of the ligand or of the receptor
+type atomGroup struct {
+	ligand bool
+	atoms  []int
+}
+
+// centroid returns the centroid of the group in the current receptor and ligand.
+// Input: an atomGroup, a Molecule receptor, a Molecule ligand
+// Output: a Position3d
+func (group atomGroup) centroid(receptor, ligand molecule.Molecule) molecule.Position3d {
+	source := receptor
+	if group.ligand {
+		source = ligand
+	}
+	var sum molecule.Position3d
+	for _, i := range group.atoms {
+		sum = sum.Add(source.Atoms[i].Position)
+	}
+	return sum.Scale(1 / float64(len(group.atoms)))
+}
+
+// boundRestraint is a restraint with its selections resolved for one receptor and ligand
+type boundRestraint struct {
+	Restraint
+	groups    []atomGroup
+	reference []molecule.Position3d // reference coordinates of the atoms of groups[0], for a position restraint with a reference
+}
+
+// RestraintTerm is the state of one restraint for a pose
+type RestraintTerm struct {
+	Restraint Restraint
+	Value     float64 // distance or RMSD in Å, or torsion in degrees
+	Energy    float64
+}
+
+// RestraintSet holds the restraints of a run bound to the atoms of one receptor and ligand
+type RestraintSet []boundRestraint
+
+// NewRestraintSet resolves the selections of the restraints for a receptor and a ligand. The reference
+// coordinates of a position restraint are those of the atoms of ReferenceMolecule in the same order when it has as
+// many atoms as the restrained molecule, and otherwise those of the atoms with the same names.
+// Input: a slice of Restraints, a Molecule receptor, a Molecule ligand
+// Output: a RestraintSet and an error naming the restraint whose atoms are missing
+func NewRestraintSet(restraints []Restraint, receptor, ligand molecule.Molecule) (RestraintSet, error) {
+	set := make(RestraintSet, 0, len(restraints))
+	for k, restraint := range restraints {
+		bound := boundRestraint{Restraint: restraint}
+		for _, text := range restraint.Atoms {
+			selection, err := ParseSelection(text)
+			var atoms []int
+			if err == nil {
+				atoms, err = selection.Resolve(receptor, ligand)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("restraint %d (%s): %w", k+1, restraint, err)
+			}
+			bound.groups = append(bound.groups, atomGroup{ligand: selection.Ligand, atoms: atoms})
+		}
+		if restraint.Kind == "position" && restraint.Reference != "" {
+			group := bound.groups[0]
+			source := receptor
+			if group.ligand {
+				source = ligand
+			}
+			reference := restraint.ReferenceMolecule.Atoms
+			for _, i := range group.atoms {
+				j := i
+				if len(reference) != len(source.Atoms) {
+					name := source.Atoms[i].Name
+					j = slices.IndexFunc(reference, func(atom molecule.Atom) bool { return atom.Name == name })
+					if j < 0 {
+						return nil, fmt.Errorf("restraint %d (%s): %s has no atom %s", k+1, restraint, restraint.Reference, name)
+					}
+					if slices.IndexFunc(reference[j+1:], func(atom molecule.Atom) bool { return atom.Name == name }) >= 0 {
+						return nil, fmt.Errorf("restraint %d (%s): %s has several atoms named %s", k+1, restraint, restraint.Reference, name)
+					}
+				}
+				bound.reference = append(bound.reference, reference[j].Position)
+			}
+		}
+		set = append(set, bound)
+	}
+	return set, nil
+}
+
+// value measures the restrained quantity and its deviation from the target.
+// Input: a boundRestraint, a Molecule receptor, a Molecule ligand
+// Output: a float64 value (Å or degrees) and a float64 deviation in Å or radians
+func (bound boundRestraint) value(receptor, ligand molecule.Molecule) (float64, float64) {
+	switch bound.Kind {
+	case "distance":
+		value := molecule.Distance(bound.groups[0].centroid(receptor, ligand), bound.groups[1].centroid(receptor, ligand))
+		return value, value - bound.Target
+	case "torsion":
+		var points [4]molecule.Position3d
+		for k, group := range bound.groups {
+			points[k] = group.centroid(receptor, ligand)
+		}
+		value := molecule.Dihedral(points[0], points[1], points[2], points[3]) * 180 / math.Pi
+		return value, math.Remainder(value-bound.Target, 360) * math.Pi / 180
+	}
+	if bound.reference == nil {
+		point := molecule.Position3d{X: bound.Point[0], Y: bound.Point[1], Z: bound.Point[2]}
+		value := molecule.Distance(bound.groups[0].centroid(receptor, ligand), point)
+		return value, value - bound.Target
+	}
+	source := receptor
+	if bound.groups[0].ligand {
+		source = ligand
+	}
+	sum := 0.0
+	for k, i := range bound.groups[0].atoms {
+		d := molecule.Distance(source.Atoms[i].Position, bound.reference[k])
+		sum += d * d
+	}
+	value := math.Sqrt(sum / float64(len(bound.reference)))
+	return value, value - bound.Target
+}
+
+// penalty is the energy of a deviation: Weight times its square, less the flat bottom for flat-bottom restraints.
+// Input: a boundRestraint, a float64 deviation in Å or radians
+// Output: a float64 energy
+func (bound boundRestraint) penalty(deviation float64) float64 {
+	if bound.Form == "flat-bottom" {
+		width := bound.Width
+		if bound.Kind == "torsion" {
+			width *= math.Pi / 180
+		}
+		deviation = math.Max(0, math.Abs(deviation)-width)
+	}
+	return bound.Weight * deviation * deviation
+}
+
+// Energy computes the total restraint energy of a pose.
+// Input: a RestraintSet, a Molecule receptor, a Molecule ligand
+// Output: a float64 energy, 0 for an empty set
+func (set RestraintSet) Energy(receptor, ligand molecule.Molecule) float64 {
+	total := 0.0
+	for _, bound := range set {
+		_, deviation := bound.value(receptor, ligand)
+		total += bound.penalty(deviation)
+	}
+	return total
+}
+
+// Terms measures every restraint of a pose, to report them apart from the physical energy.
+// Input: a RestraintSet, a Molecule receptor, a Molecule ligand
+// Output: a slice of RestraintTerms in restraint order
+func (set RestraintSet) Terms(receptor, ligand molecule.Molecule) []RestraintTerm {
+	terms := make([]RestraintTerm, len(set))
+	for k, bound := range set {
+		value, deviation := bound.value(receptor, ligand)
+		terms[k] = RestraintTerm{Restraint: bound.Restraint, Value: value, Energy: bound.penalty(deviation)}
+	}
+	return terms
+}
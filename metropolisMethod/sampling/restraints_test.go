@@ -0,0 +1,116 @@
+package sampling
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+func TestRestraintValidate(t *testing.T) {
+	valid := Restraint{Kind: "distance", Form: "harmonic", Atoms: []string{"ligand@O1", "receptor:A:LYS10@NZ"}, Target: 3, Weight: 1}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid restraint, got %v", err)
+	}
+	invalid := map[string]Restraint{
+		"kind":      {Kind: "angle", Form: "harmonic", Atoms: []string{"ligand"}, Weight: 1},
+		"form":      {Kind: "distance", Form: "square", Atoms: []string{"ligand", "ligand"}, Weight: 1},
+		"atoms":     {Kind: "torsion", Form: "harmonic", Atoms: []string{"ligand", "ligand"}, Weight: 1},
+		"selection": {Kind: "distance", Form: "harmonic", Atoms: []string{"ligand@", "protein"}, Weight: 1},
+		"weight":    {Kind: "distance", Form: "harmonic", Atoms: []string{"ligand", "receptor:10"}},
+		"width":     {Kind: "distance", Form: "flat-bottom", Atoms: []string{"ligand", "receptor:10"}, Weight: 1},
+		"position":  {Kind: "position", Form: "harmonic", Atoms: []string{"ligand"}, Weight: 1},
+		"point":     {Kind: "position", Form: "harmonic", Atoms: []string{"ligand"}, Weight: 1, Point: []float64{1, 2}},
+	}
+	for name, restraint := range invalid {
+		if err := restraint.Validate(); err == nil {
+			t.Errorf("Expected an invalid %s to be rejected: %+v", name, restraint)
+		}
+	}
+
+	selection, err := ParseSelection(" receptor:A:HIS57@NE2, CE1 ")
+	if err != nil || selection.Ligand || selection.Residue != "A:HIS57" || strings.Join(selection.Names, " ") != "NE2 CE1" {
+		t.Errorf("Expected HIS57 atoms NE2 and CE1, got %+v and %v", selection, err)
+	}
+}
+
+func TestRestraintEnergies(t *testing.T) {
+	receptor, ligand := moltest.Pocket(), moltest.Benzoate()
+	reference := moltest.Benzoate()
+	for i := range reference.Atoms {
+		reference.Atoms[i].Position.X++
+	}
+	torsion := molecule.Dihedral(ligand.Atoms[7].Position, ligand.Atoms[6].Position, ligand.Atoms[0].Position, ligand.Atoms[1].Position) * 180 / math.Pi
+	distance := molecule.Distance(ligand.Atoms[7].Position, receptor.Atoms[1].Position)
+	tests := []struct {
+		restraint     Restraint
+		value, energy float64
+	}{
+		{Restraint{Kind: "distance", Form: "harmonic", Atoms: []string{"ligand@O1", "receptor:A:LYS10@NZ"}, Target: 3, Weight: 2}, distance, 2 * (3 - distance) * (3 - distance)},
+		{Restraint{Kind: "distance", Form: "flat-bottom", Atoms: []string{"ligand@8", "receptor:A:10@NZ"}, Target: 3, Width: 2, Weight: 2}, distance, 0},
+		{Restraint{Kind: "position", Form: "harmonic", Atoms: []string{"ligand@C7"}, Point: []float64{0, 0, 0}, Weight: 1}, 2.89, 2.89 * 2.89},
+		{Restraint{Kind: "position", Form: "flat-bottom", Atoms: []string{"ligand"}, Reference: "reference.mol2", ReferenceMolecule: reference, Width: 0.5, Weight: 4}, 1, 4 * 0.5 * 0.5},
+		{Restraint{Kind: "torsion", Form: "harmonic", Atoms: []string{"ligand@O1", "ligand@C7", "ligand@C1", "ligand@C2"}, Target: torsion + 90, Weight: 1}, torsion, math.Pi * math.Pi / 4},
+		{Restraint{Kind: "torsion", Form: "flat-bottom", Atoms: []string{"ligand@O1", "ligand@C7", "ligand@C1", "ligand@C2"}, Target: torsion + 350, Width: 20, Weight: 1}, torsion, 0},
+	}
+	var restraints []Restraint
+	total := 0.0
+	for _, test := range tests {
+		if err := test.restraint.Validate(); err != nil {
+			t.Fatalf("%s: %v", test.restraint, err)
+		}
+		restraints = append(restraints, test.restraint)
+		total += test.energy
+	}
+	set, err := NewRestraintSet(restraints, receptor, ligand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, term := range set.Terms(receptor, ligand) {
+		if !moltest.AlmostEqual(term.Value, tests[k].value, 1e-9) || !moltest.AlmostEqual(term.Energy, tests[k].energy, 1e-9) {
+			t.Errorf("%s: expected value %g and energy %g, got %g and %g", term.Restraint, tests[k].value, tests[k].energy, term.Value, term.Energy)
+		}
+	}
+	if energy := set.Energy(receptor, ligand); !moltest.AlmostEqual(energy, total, 1e-9) {
+		t.Errorf("Expected a restraint energy of %g, got %g", total, energy)
+	}
+
+	for _, atoms := range [][]string{{"ligand@X9", "receptor:A:LYS10"}, {"ligand", "receptor:A:LYS99"}, {"ligand", "receptor:A:LYS10@CA"}} {
+		missing := Restraint{Kind: "distance", Form: "harmonic", Atoms: atoms, Weight: 1}
+		if _, err := NewRestraintSet([]Restraint{missing}, receptor, ligand); err == nil {
+			t.Errorf("Expected the atoms %v to be missing", atoms)
+		}
+	}
+}
+
+func TestRestraintGuidesSimulation(t *testing.T) {
+	protein, ligand := moltest.Protein(0, 0), moltest.LigandWithCharges(0, 0)
+	target := molecule.Position3d{X: 8, Y: 8, Z: 8}
+	sim := NewSimulation(2000, false, 1, 2)
+	sim.Seed = 5
+	sim.Restraints = []Restraint{{Kind: "position", Form: "harmonic", Atoms: []string{"ligand"}, Point: []float64{target.X, target.Y, target.Z}, Weight: 10}}
+	set, err := NewRestraintSet(sim.Restraints, protein, ligand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pose, _, _, err := SimulateFlexibleLigand(context.Background(), protein, ligand, 0, sim, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, end := set.Terms(protein, ligand)[0].Value, set.Terms(protein, pose)[0].Value
+	if end > 2 || end >= start {
+		t.Errorf("Expected the restraint to pull the ligand from %.2f Å to within 2 Å of its point, got %.2f Å", start, end)
+	}
+	if energy := sim.Energy.Energy(protein, pose); energy != 0 {
+		t.Errorf("Expected the physical energy of an uncharged pair to leave out the restraint, got %g", energy)
+	}
+
+	sim.Restraints[0].Atoms = []string{"ligand@X9"}
+	_, _, _, _, err = RunFlexibleSimulation(context.Background(), protein, []molecule.Molecule{ligand, ligand}, sim, false)
+	if err == nil || !strings.Contains(err.Error(), "ligand 1") {
+		t.Errorf("Expected the error of a restraint whose atom is missing, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package sampling
+
+import "strings"
+
+// Rotamer is a side-chain conformation of the rotamer library
+type Rotamer struct {
+	Name      string    // e.g. "mt" for χ1 minus, χ2 trans
+	Chi       []float64 // χ angles in degrees, starting at χ1
+	Frequency float64   // share of the residues observed in this rotamer
+}
+
+// chiAtoms lists the four atoms defining each χ angle of the residues with rotatable side chains
+var chiAtoms = map[string][][4]string{
+	"ARG": {{"N", "CA", "CB", "CG"}, {"CA", "CB", "CG", "CD"}, {"CB", "CG", "CD", "NE"}, {"CG", "CD", "NE", "CZ"}},
+	"ASN": {{"N", "CA", "CB", "CG"}, {"CA", "CB", "CG", "OD1"}},
+	"ASP": {{"N", "CA", "CB", "CG"}, {"CA", "CB", "CG", "OD1"}},
+	"CYS": {{"N", "CA", "CB", "SG"}},
+	"GLN": {{"N", "CA", "CB", "CG"}, {"CA", "CB", "CG", "CD"}, {"CB", "CG", "CD", "OE1"}},
+	"GLU": {{"N", "CA", "CB", "CG"}, {"CA", "CB", "CG", "CD"}, {"CB", "CG", "CD", "OE1"}},
+	"HIS": {{"N", "CA", "CB", "CG"}, {"CA", "CB", "CG", "ND1"}},
+	"ILE": {{"N", "CA", "CB", "CG1"}, {"CA", "CB", "CG1", "CD1"}},
+	"LEU": {{"N", "CA", "CB", "CG"}, {"CA", "CB", "CG", "CD1"}},
+	"LYS": {{"N", "CA", "CB", "CG"}, {"CA", "CB", "CG", "CD"}, {"CB", "CG", "CD", "CE"}, {"CG", "CD", "CE", "NZ"}},
+	"MET": {{"N", "CA", "CB", "CG"}, {"CA", "CB", "CG", "SD"}, {"CB", "CG", "SD", "CE"}},
+	"PHE": {{"N", "CA", "CB", "CG"}, {"CA", "CB", "CG", "CD1"}},
+	"SER": {{"N", "CA", "CB", "OG"}},
+	"THR": {{"N", "CA", "CB", "OG1"}},
+	"TRP": {{"N", "CA", "CB", "CG"}, {"CA", "CB", "CG", "CD1"}},
+	"TYR": {{"N", "CA", "CB", "CG"}, {"CA", "CB", "CG", "CD1"}},
+	"VAL": {{"N", "CA", "CB", "CG1"}},
+}
+
+// symmetricChi gives the terminal χ angle of the residues whose last group looks the same after a 180° turn, the
+// ring of PHE and TYR and the carboxylate of ASP and GLU, so that χ and χ+180° are one conformation
+var symmetricChi = map[string]int{"ASP": 1, "GLU": 2, "PHE": 1, "TYR": 1}
+
+// rotamerLibrary is a backbone-independent rotamer library: the common rotamers of each residue with their mean χ
+// angles and frequencies, rounded from the penultimate rotamer library of Lovell et al. (2000). The frequencies
+// of a residue need not add up to one; rotamers are drawn in proportion to them.
+var rotamerLibrary = map[string][]Rotamer{
+	"ARG": {
+		{"ptp85", []float64{62, 180, 65, 85}, 0.02},
+		{"ptt180", []float64{62, 180, 180, 180}, 0.03},
+		{"ttp85", []float64{-177, 180, 65, 85}, 0.04},
+		{"ttt180", []float64{-177, 180, 180, 180}, 0.06},
+		{"ttm-85", []float64{-177, 180, -65, -85}, 0.04},
+		{"mtp85", []float64{-67, 180, 65, 85}, 0.06},
+		{"mtt180", []float64{-67, 180, 180, 180}, 0.09},
+		{"mtt85", []float64{-67, 180, 180, 85}, 0.05},
+		{"mtm-85", []float64{-67, 180, -65, -85}, 0.08},
+		{"mmt180", []float64{-62, -68, 180, 180}, 0.04},
+	},
+	"ASN": {
+		{"p-10", []float64{62, -10}, 0.07},
+		{"p30", []float64{62, 30}, 0.09},
+		{"t-20", []float64{-174, -20}, 0.12},
+		{"t30", []float64{-177, 30}, 0.15},
+		{"m-20", []float64{-65, -20}, 0.34},
+		{"m-80", []float64{-65, -75}, 0.08},
+		{"m120", []float64{-65, 120}, 0.04},
+	},
+	"ASP": {
+		{"p-10", []float64{62, -10}, 0.10},
+		{"p30", []float64{62, 30}, 0.09},
+		{"t0", []float64{-177, 0}, 0.21},
+		{"t70", []float64{-177, 65}, 0.06},
+		{"m-20", []float64{-65, -20}, 0.51},
+	},
+	"CYS": {
+		{"p", []float64{62}, 0.14},
+		{"t", []float64{-177}, 0.26},
+		{"m", []float64{-65}, 0.56},
+	},
+	"GLN": {
+		{"pt20", []float64{62, 180, 20}, 0.04},
+		{"tp-100", []float64{-177, 65, -100}, 0.02},
+		{"tp60", []float64{-177, 65, 60}, 0.09},
+		{"tt0", []float64{-177, 180, 0}, 0.16},
+		{"mt-30", []float64{-65, 180, -25}, 0.38},
+		{"mm-40", []float64{-65, -65, -40}, 0.16},
+		{"mm100", []float64{-65, -65, 100}, 0.04},
+	},
+	"GLU": {
+		{"pt-20", []float64{62, 180, -20}, 0.05},
+		{"tp10", []float64{-177, 65, 10}, 0.07},
+		{"tt0", []float64{-177, 180, 0}, 0.24},
+		{"mp0", []float64{-65, 85, 0}, 0.06},
+		{"mt-10", []float64{-67, 180, -10}, 0.33},
+		{"mm-40", []float64{-65, -65, -40}, 0.13},
+	},
+	"HIS": {
+		{"p-80", []float64{62, -75}, 0.09},
+		{"p80", []float64{62, 80}, 0.04},
+		{"t-160", []float64{-177, -165}, 0.05},
+		{"t-80", []float64{-177, -80}, 0.11},
+		{"t60", []float64{-177, 60}, 0.16},
+		{"m-70", []float64{-65, -70}, 0.29},
+		{"m170", []float64{-65, 165}, 0.07},
+		{"m80", []float64{-65, 80}, 0.13},
+	},
+	"ILE": {
+		{"pp", []float64{62, 100}, 0.01},
+		{"pt", []float64{62, 170}, 0.13},
+		{"tp", []float64{-177, 66}, 0.02},
+		{"tt", []float64{-177, 165}, 0.08},
+		{"mp", []float64{-65, 100}, 0.02},
+		{"mt", []float64{-65, 170}, 0.60},
+		{"mm", []float64{-57, -60}, 0.15},
+	},
+	"LEU": {
+		{"pp", []float64{62, 80}, 0.01},
+		{"tp", []float64{-177, 65}, 0.29},
+		{"tt", []float64{-172, 145}, 0.02},
+		{"mp", []float64{-85, 65}, 0.02},
+		{"mt", []float64{-65, 175}, 0.59},
+	},
+	"LYS": {
+		{"pttp", []float64{62, 180, 180, 65}, 0.01},
+		{"pttt", []float64{62, 180, 180, 180}, 0.02},
+		{"tptt", []float64{-177, 68, 180, 180}, 0.05},
+		{"tttp", []float64{-177, 180, 180, 65}, 0.04},
+		{"tttt", []float64{-177, 180, 180, 180}, 0.13},
+		{"tttm", []float64{-177, 180, 180, -65}, 0.03},
+		{"mttp", []float64{-65, 180, 180, 65}, 0.03},
+		{"mttt", []float64{-65, 180, 180, 180}, 0.24},
+		{"mttm", []float64{-65, 180, 180, -65}, 0.04},
+		{"mtmt", []float64{-65, 180, -68, 180}, 0.03},
+		{"mmtt", []float64{-65, -65, 180, 180}, 0.06},
+	},
+	"MET": {
+		{"ptp", []float64{62, 180, 75}, 0.03},
+		{"ptm", []float64{62, 180, -75}, 0.05},
+		{"tpp", []float64{-177, 65, 75}, 0.05},
+		{"tpt", []float64{-177, 65, 180}, 0.02},
+		{"ttp", []float64{-177, 180, 75}, 0.07},
+		{"ttm", []float64{-177, 180, -75}, 0.07},
+		{"mtp", []float64{-67, 180, 75}, 0.17},
+		{"mtt", []float64{-67, 180, 180}, 0.08},
+		{"mtm", []float64{-67, 180, -75}, 0.11},
+		{"mmm", []float64{-65, -65, -70}, 0.19},
+	},
+	"PHE": {
+		{"p90", []float64{62, 90}, 0.13},
+		{"t80", []float64{-177, 80}, 0.33},
+		{"m-85", []float64{-65, -85}, 0.44},
+		{"m-30", []float64{-65, -30}, 0.09},
+	},
+	"SER": {
+		{"p", []float64{64}, 0.48},
+		{"t", []float64{178}, 0.22},
+		{"m", []float64{-65}, 0.29},
+	},
+	"THR": {
+		{"p", []float64{59}, 0.49},
+		{"t", []float64{-171}, 0.07},
+		{"m", []float64{-60}, 0.43},
+	},
+	"TRP": {
+		{"p-90", []float64{62, -90}, 0.09},
+		{"p90", []float64{62, 90}, 0.06},
+		{"t-105", []float64{-177, -105}, 0.16},
+		{"t90", []float64{-177, 90}, 0.18},
+		{"m-90", []float64{-65, -90}, 0.11},
+		{"m0", []float64{-65, -5}, 0.03},
+		{"m95", []float64{-65, 95}, 0.34},
+	},
+	"TYR": {
+		{"p90", []float64{62, 90}, 0.13},
+		{"t80", []float64{-177, 80}, 0.34},
+		{"m-85", []float64{-65, -85}, 0.43},
+		{"m-30", []float64{-65, -30}, 0.09},
+	},
+	"VAL": {
+		{"p", []float64{63}, 0.06},
+		{"t", []float64{175}, 0.73},
+		{"m", []float64{-60}, 0.20},
+	},
+}
+
+// residueAliases maps the names of protonation states to the residue of the rotamer library. Bridged cysteines
+// (CYX) are left out: their side chains are held by the disulfide bond.
+var residueAliases = map[string]string{
+	"HID": "HIS", "HIE": "HIS", "HIP": "HIS", "HSD": "HIS", "HSE": "HIS", "HSP": "HIS",
+	"ASH": "ASP", "GLH": "GLU", "LYN": "LYS", "CYM": "CYS",
+}
+
+// libraryName returns the rotamer library entry of a residue name, or "" when the residue has no rotatable side
+// chain in the library, as for glycine, alanine and proline.
+// Input: a string residue name
+// Output: a string library residue name
+func libraryName(resName string) string {
+	name := strings.ToUpper(resName)
+	if alias, ok := residueAliases[name]; ok {
+		name = alias
+	}
+	if _, ok := rotamerLibrary[name]; !ok {
+		return ""
+	}
+	return name
+}
+
+// Rotamers returns the rotamers of a residue from the built-in backbone-independent library, nil when its side
+// chain has no rotatable bonds in the library.
+// Input: a string residue name, e.g. "TYR" or "HIE"
+// Output: a slice of Rotamers
+func Rotamers(resName string) []Rotamer {
+	return rotamerLibrary[libraryName(resName)]
+}
@@ -0,0 +1,367 @@
+package sampling
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/energy"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// adjustable parameters of the flexible side chains
+const SIDECHAINFRACTION = 0.2 // default share of the moves that turn a side chain instead of moving the ligand
+const ROTAMERJITTER = 10.0    // largest deviation in degrees of a turned χ angle from its rotamer
+const CLASHFACTOR = 0.75      // atoms closer than this share of the sum of their van der Waals radii clash
+const DISULFIDE = 2.5         // largest S–S distance in Å of a disulfide bond
+
+// ChiAngle is a rotatable bond of a side chain
+type ChiAngle struct {
+	Atoms     [4]int // receptor atoms defining the dihedral; the bond runs from Atoms[1] to Atoms[2]
+	Moving    []int  // receptor atoms beyond Atoms[2], which turn with the bond
+	Symmetric bool   // the atoms beyond the bond look the same after a 180° turn, so the angle is only defined modulo 180°
+}
+
+// Set turns the atoms beyond the bond so the χ angle becomes the given angle. The receptor is changed in place.
+// Input: a ChiAngle, a Molecule receptor, a float64 angle in radians
+// Output: none
+func (chi ChiAngle) Set(receptor molecule.Molecule, angle float64) {
+	a := receptor.Atoms[chi.Atoms[0]].Position
+	b := receptor.Atoms[chi.Atoms[1]].Position
+	c := receptor.Atoms[chi.Atoms[2]].Position
+	d := receptor.Atoms[chi.Atoms[3]].Position
+	theta := angle - molecule.Dihedral(a, b, c, d)
+	axis := c.Add(b.Scale(-1))
+	axis.Normalize()
+	for _, i := range chi.Moving {
+		relative := receptor.Atoms[i].Position.Add(b.Scale(-1))
+		receptor.Atoms[i].Position = molecule.RotateAtom(relative, axis, theta).Add(b)
+	}
+}
+
+// Angle returns the current χ angle.
+// Input: a ChiAngle, a Molecule receptor
+// Output: a float64 angle in radians
+func (chi ChiAngle) Angle(receptor molecule.Molecule) float64 {
+	return molecule.Dihedral(receptor.Atoms[chi.Atoms[0]].Position, receptor.Atoms[chi.Atoms[1]].Position,
+		receptor.Atoms[chi.Atoms[2]].Position, receptor.Atoms[chi.Atoms[3]].Position)
+}
+
+// FlexibleResidue is a receptor residue whose side chain turns about its χ angles during a simulation
+type FlexibleResidue struct {
+	Residue  molecule.Residue
+	Chis     []ChiAngle // χ1 first; the atoms of χ1 include those of every later χ
+	Rotamers []Rotamer
+}
+
+// NewFlexibleResidue finds the χ angles of a receptor residue from its atom names, and the atoms each one turns
+// from the bonds between the atoms of the residue.
+// Input: a Molecule receptor, a Residue of the receptor
+// Output: a FlexibleResidue and an error when the residue is not in the rotamer library, misses a χ atom, or its
+// side chain is held by a ring or a disulfide bond
+func NewFlexibleResidue(receptor molecule.Molecule, residue molecule.Residue) (FlexibleResidue, error) {
+	name := libraryName(residue.Name)
+	if name == "" {
+		return FlexibleResidue{}, fmt.Errorf("%s has no rotatable side chain", residue.ID())
+	}
+	local := molecule.Molecule{Atoms: make([]molecule.Atom, len(residue.Atoms))}
+	localIndex := make(map[int]int, len(residue.Atoms))
+	for k, i := range residue.Atoms {
+		local.Atoms[k] = receptor.Atoms[i]
+		localIndex[i] = k
+	}
+	local.Bonds = molecule.InferBonds(local)
+	neighbors := molecule.Neighbors(local)
+	flexible := FlexibleResidue{Residue: residue, Rotamers: rotamerLibrary[name]}
+	symmetric, hasSymmetric := symmetricChi[name]
+	for i, names := range chiAtoms[name] {
+		chi := ChiAngle{Symmetric: hasSymmetric && i == symmetric}
+		for k, atomName := range names {
+			if chi.Atoms[k] = residue.AtomIndex(receptor, atomName); chi.Atoms[k] < 0 {
+				return FlexibleResidue{}, fmt.Errorf("%s has no %s atom", residue.ID(), atomName)
+			}
+		}
+		// the atoms reached from the third atom without crossing back over the bond
+		from, to := localIndex[chi.Atoms[1]], localIndex[chi.Atoms[2]]
+		seen := map[int]bool{from: true, to: true}
+		queue := []int{to}
+		for len(queue) > 0 {
+			k := queue[0]
+			queue = queue[1:]
+			for _, next := range neighbors[k] {
+				if !seen[next] {
+					seen[next] = true
+					queue = append(queue, next)
+					chi.Moving = append(chi.Moving, residue.Atoms[next])
+				}
+			}
+		}
+		if seen[localIndex[chi.Atoms[0]]] {
+			return FlexibleResidue{}, fmt.Errorf("%s has its side chain in a ring with the backbone", residue.ID())
+		}
+		flexible.Chis = append(flexible.Chis, chi)
+	}
+	if name == "CYS" {
+		sulfur := receptor.Atoms[flexible.Chis[0].Atoms[3]]
+		for i, atom := range receptor.Atoms {
+			if _, own := localIndex[i]; !own && atom.Name == "SG" && molecule.Distance(atom.Position, sulfur.Position) < DISULFIDE {
+				return FlexibleResidue{}, fmt.Errorf("%s is held by a disulfide bond", residue.ID())
+			}
+		}
+	}
+	return flexible, nil
+}
+
+// Moving returns the side-chain atoms that turn with any χ angle of the residue, which are those of χ1.
+// Input: a FlexibleResidue
+// Output: a slice of receptor atom indices
+func (residue FlexibleResidue) Moving() []int {
+	return residue.Chis[0].Moving
+}
+
+// NearestRotamer returns the library rotamer closest to the current side chain: the one whose largest difference
+// from the current χ angles is smallest. Symmetric χ angles are compared modulo 180°.
+// Input: a FlexibleResidue, a Molecule receptor
+// Output: a Rotamer
+func (residue FlexibleResidue) NearestRotamer(receptor molecule.Molecule) Rotamer {
+	nearest, smallest := residue.Rotamers[0], math.Inf(1)
+	for _, rotamer := range residue.Rotamers {
+		largest := 0.0
+		for k, chi := range residue.Chis {
+			period := 360.0
+			if chi.Symmetric {
+				period = 180
+			}
+			difference := math.Abs(math.Remainder(chi.Angle(receptor)*180/math.Pi-rotamer.Chi[k], period))
+			largest = math.Max(largest, difference)
+		}
+		if largest < smallest {
+			nearest, smallest = rotamer, largest
+		}
+	}
+	return nearest
+}
+
+// drawRotamer draws a rotamer of the residue in proportion to the rotamer frequencies.
+// Input: a FlexibleResidue, a *rand.Rand source (nil for the global source)
+// Output: a Rotamer
+func (residue FlexibleResidue) drawRotamer(source *rand.Rand) Rotamer {
+	total := 0.0
+	for _, rotamer := range residue.Rotamers {
+		total += rotamer.Frequency
+	}
+	draw := uniform(source) * total
+	for _, rotamer := range residue.Rotamers {
+		if draw -= rotamer.Frequency; draw < 0 {
+			return rotamer
+		}
+	}
+	return residue.Rotamers[len(residue.Rotamers)-1]
+}
+
+// SideChains are the flexible residues of a receptor. The move set then also turns their side chains, and their
+// strain energy—clashes and electrostatics with the rest of the receptor, and clashes with the ligand—is added
+// to the energy a walker minimises, so the side chains don't collapse onto the rest of the complex.
+type SideChains struct {
+	Residues []FlexibleResidue
+	Fraction float64   // share of the moves that turn a side chain instead of moving the ligand
+	radii    []float64 // van der Waals radius of every receptor atom
+	owner    []int     // flexible residue of every receptor atom, -1 for the others
+	mover    []int     // flexible residue whose side chain turns each receptor atom, -1 for the others
+}
+
+// NewSideChains makes the given receptor residues flexible. A residue given twice is used once.
+// Input: a Molecule receptor, a slice of its Residues, a float64 fraction of the moves that turn a side chain
+// Output: a *SideChains and an error when no residue is given or one of them cannot turn
+func NewSideChains(receptor molecule.Molecule, residues []molecule.Residue, fraction float64) (*SideChains, error) {
+	if len(residues) == 0 {
+		return nil, fmt.Errorf("no flexible residues selected")
+	}
+	sideChains := &SideChains{
+		Fraction: fraction,
+		radii:    make([]float64, len(receptor.Atoms)),
+		owner:    make([]int, len(receptor.Atoms)),
+		mover:    make([]int, len(receptor.Atoms)),
+	}
+	for i, atom := range receptor.Atoms {
+		sideChains.radii[i] = vdwRadius(atom)
+		sideChains.owner[i], sideChains.mover[i] = -1, -1
+	}
+	for _, residue := range residues {
+		if len(residue.Atoms) == 0 || sideChains.owner[residue.Atoms[0]] >= 0 {
+			continue
+		}
+		flexible, err := NewFlexibleResidue(receptor, residue)
+		if err != nil {
+			return nil, err
+		}
+		r := len(sideChains.Residues)
+		for _, i := range residue.Atoms {
+			sideChains.owner[i] = r
+		}
+		for _, i := range flexible.Moving() {
+			sideChains.mover[i] = r
+		}
+		sideChains.Residues = append(sideChains.Residues, flexible)
+	}
+	return sideChains, nil
+}
+
+// IDs returns the identifiers of the flexible residues, such as "A:TYR45".
+// Input: a *SideChains
+// Output: a slice of strings
+func (sideChains *SideChains) IDs() []string {
+	ids := make([]string, len(sideChains.Residues))
+	for r, residue := range sideChains.Residues {
+		ids[r] = residue.Residue.ID()
+	}
+	return ids
+}
+
+// BindingSiteResidues returns the receptor residues with a side-chain atom within distance of an atom of any of
+// the ligands, skipping those that cannot turn, such as glycine, alanine, proline and bridged cysteines.
+// Input: a Molecule receptor, a slice of Molecule ligands, a float64 distance in Å
+// Output: a slice of Residues in receptor order
+func BindingSiteResidues(receptor molecule.Molecule, ligands []molecule.Molecule, distance float64) []molecule.Residue {
+	var residues []molecule.Residue
+	for _, residue := range molecule.SplitResidues(receptor) {
+		flexible, err := NewFlexibleResidue(receptor, residue)
+		if err != nil {
+			continue
+		}
+		if nearLigands(receptor, flexible.Moving(), ligands, distance) {
+			residues = append(residues, residue)
+		}
+	}
+	return residues
+}
+
+// nearLigands reports whether any of the receptor atoms lies within distance of an atom of the ligands.
+// Input: a Molecule receptor, a slice of receptor atom indices, a slice of Molecule ligands, a float64 distance in Å
+// Output: a bool
+func nearLigands(receptor molecule.Molecule, atoms []int, ligands []molecule.Molecule, distance float64) bool {
+	for _, i := range atoms {
+		for _, ligand := range ligands {
+			for _, atom := range ligand.Atoms {
+				if molecule.Distance(receptor.Atoms[i].Position, atom.Position) <= distance {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// vdwRadius returns the van der Waals radius of an atom, defaulting to carbon for unknown elements.
+// Input: an Atom
+// Output: a float64 radius in Å
+func vdwRadius(atom molecule.Atom) float64 {
+	if data, ok := molecule.LookupElement(atom.Element); ok {
+		return data.VdWRadius
+	}
+	return 1.70
+}
+
+// clashEnergy is the steric penalty of two atoms: the square of their overlap within CLASHFACTOR of the sum of
+// their van der Waals radii, times the constant of the energy model so it weighs like the electrostatics.
+// Input: two float64 radii in Å, a float64 distance in Å, an EnergyModel
+// Output: a float64 energy, 0 when the atoms don't clash
+func clashEnergy(radiusA, radiusB, distance float64, model energy.EnergyModel) float64 {
+	overlap := CLASHFACTOR*(radiusA+radiusB) - distance
+	if overlap <= 0 {
+		return 0
+	}
+	return model.Constant * overlap * overlap
+}
+
+// Turn sets the χ angles of flexible residue r to a rotamer drawn from the library in proportion to its
+// frequency, each angle off by up to ROTAMERJITTER degrees. The receptor is changed in place.
+// Input: a *SideChains, a Molecule receptor, an int flexible residue index r, a *rand.Rand source (nil for the global source)
+// Output: none
+func (sideChains *SideChains) Turn(receptor molecule.Molecule, r int, source *rand.Rand) {
+	residue := sideChains.Residues[r]
+	rotamer := residue.drawRotamer(source)
+	for k, chi := range residue.Chis {
+		angle := rotamer.Chi[k] + (uniform(source)*2-1)*ROTAMERJITTER
+		chi.Set(receptor, angle*math.Pi/180)
+	}
+}
+
+// ReceptorEnergy computes the strain of the flexible side chains within the receptor: the electrostatics of the
+// model and the clashes between every turning atom and the atoms outside its own residue, each pair counted once.
+// Input: a *SideChains, a Molecule receptor, an EnergyModel
+// Output: a float64 energy
+func (sideChains *SideChains) ReceptorEnergy(receptor molecule.Molecule, model energy.EnergyModel) float64 {
+	total := 0.0
+	for r := range sideChains.Residues {
+		total += sideChains.residueEnergy(receptor, r, model, func(j int) bool {
+			return sideChains.mover[j] >= 0 && sideChains.mover[j] < r
+		})
+	}
+	return total
+}
+
+// residueEnergy computes the strain of the side chain of flexible residue r within the receptor, leaving out the
+// atoms for which skip returns true (nil skips none).
+// Input: a *SideChains, a Molecule receptor, an int flexible residue index r, an EnergyModel, a func skip
+// Output: a float64 energy
+func (sideChains *SideChains) residueEnergy(receptor molecule.Molecule, r int, model energy.EnergyModel, skip func(int) bool) float64 {
+	total := 0.0
+	for _, i := range sideChains.Residues[r].Moving() {
+		for j, atom := range receptor.Atoms {
+			if sideChains.owner[j] == r || (skip != nil && skip(j)) {
+				continue
+			}
+			distance := molecule.Distance(receptor.Atoms[i].Position, atom.Position)
+			total += model.PairEnergy(receptor.Atoms[i], atom, distance) + clashEnergy(sideChains.radii[i], sideChains.radii[j], distance, model)
+		}
+	}
+	return total
+}
+
+// LigandClash computes the clashes between the turning side-chain atoms and the ligand.
+// Input: a *SideChains, a Molecule receptor, a Molecule ligand, an EnergyModel
+// Output: a float64 energy
+func (sideChains *SideChains) LigandClash(receptor molecule.Molecule, ligand molecule.Molecule, model energy.EnergyModel) float64 {
+	radii := make([]float64, len(ligand.Atoms))
+	for k, atom := range ligand.Atoms {
+		radii[k] = vdwRadius(atom)
+	}
+	total := 0.0
+	for _, residue := range sideChains.Residues {
+		for _, i := range residue.Moving() {
+			for k, atom := range ligand.Atoms {
+				total += clashEnergy(sideChains.radii[i], radii[k], molecule.Distance(receptor.Atoms[i].Position, atom.Position), model)
+			}
+		}
+	}
+	return total
+}
+
+// Energy computes the strain energy of the flexible side chains: ReceptorEnergy plus LigandClash.
+// Input: a *SideChains, a Molecule receptor, a Molecule ligand, an EnergyModel
+// Output: a float64 energy
+func (sideChains *SideChains) Energy(receptor, ligand molecule.Molecule, model energy.EnergyModel) float64 {
+	return sideChains.ReceptorEnergy(receptor, model) + sideChains.LigandClash(receptor, ligand, model)
+}
+
+// positions returns the current positions of the turning atoms of flexible residue r.
+// Input: a *SideChains, a Molecule receptor, an int flexible residue index r
+// Output: a slice of Position3d in the order of Moving
+func (sideChains *SideChains) positions(receptor molecule.Molecule, r int) []molecule.Position3d {
+	moving := sideChains.Residues[r].Moving()
+	positions := make([]molecule.Position3d, len(moving))
+	for k, i := range moving {
+		positions[k] = receptor.Atoms[i].Position
+	}
+	return positions
+}
+
+// restore puts the turning atoms of flexible residue r back at positions saved by positions.
+// Input: a *SideChains, a Molecule receptor, an int flexible residue index r, a slice of Position3d
+// Output: none (the receptor is changed in place)
+func (sideChains *SideChains) restore(receptor molecule.Molecule, r int, positions []molecule.Position3d) {
+	for k, i := range sideChains.Residues[r].Moving() {
+		receptor.Atoms[i].Position = positions[k]
+	}
+}
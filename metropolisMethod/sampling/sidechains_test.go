@@ -0,0 +1,130 @@
+package sampling
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+func TestRotamerLibrary(t *testing.T) {
+	for name, rotamers := range rotamerLibrary {
+		for _, rotamer := range rotamers {
+			if len(rotamer.Chi) != len(chiAtoms[name]) || rotamer.Frequency <= 0 {
+				t.Errorf("%s rotamer %s has %d χ angles for %d and frequency %g", name, rotamer.Name, len(rotamer.Chi), len(chiAtoms[name]), rotamer.Frequency)
+			}
+		}
+	}
+	if Rotamers("HIE") == nil || Rotamers("GLY") != nil || Rotamers("CYX") != nil {
+		t.Errorf("Expected rotamers for HIE but none for GLY or CYX")
+	}
+}
+
+func TestFlexibleSerine(t *testing.T) {
+	serine := moltest.Serine()
+	residue := molecule.SplitResidues(serine)[0]
+	flexible, err := NewFlexibleResidue(serine, residue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flexible.Chis) != 1 || len(flexible.Moving()) != 1 || flexible.Moving()[0] != 5 {
+		t.Fatalf("Expected χ1 to turn OG alone, got %+v", flexible.Chis)
+	}
+	bond := molecule.Distance(serine.Atoms[4].Position, serine.Atoms[5].Position)
+	flexible.Chis[0].Set(serine, math.Pi/3)
+	if angle := flexible.Chis[0].Angle(serine); !moltest.AlmostEqual(angle, math.Pi/3, 1e-9) {
+		t.Errorf("Expected χ1 of 60°, got %g°", angle*180/math.Pi)
+	}
+	if moved := molecule.Distance(serine.Atoms[4].Position, serine.Atoms[5].Position); !moltest.AlmostEqual(moved, bond, 1e-9) {
+		t.Errorf("Expected the CB-OG bond to keep its length %g, got %g", bond, moved)
+	}
+	if rotamer := flexible.NearestRotamer(serine); rotamer.Name != "p" {
+		t.Errorf("Expected rotamer p at 60°, got %s", rotamer.Name)
+	}
+
+	sideChains, err := NewSideChains(serine, []molecule.Residue{residue, residue}, 1)
+	if err != nil || len(sideChains.Residues) != 1 {
+		t.Fatalf("Expected one flexible residue, got %v and %v", sideChains, err)
+	}
+	source := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		sideChains.Turn(serine, 0, source)
+		nearest := flexible.NearestRotamer(serine)
+		if difference := math.Abs(math.Remainder(flexible.Chis[0].Angle(serine)*180/math.Pi-nearest.Chi[0], 360)); difference > ROTAMERJITTER+1e-9 {
+			t.Errorf("Expected χ1 within %g° of rotamer %s, got %g° off", ROTAMERJITTER, nearest.Name, difference)
+		}
+	}
+
+	alanine := serine
+	alanine.Atoms = append([]molecule.Atom(nil), serine.Atoms[:5]...)
+	for i := range alanine.Atoms {
+		alanine.Atoms[i].ResName = "ALA"
+	}
+	if _, err := NewFlexibleResidue(alanine, molecule.SplitResidues(alanine)[0]); err == nil {
+		t.Errorf("Expected alanine to have no rotatable side chain")
+	}
+}
+
+func TestNearestRotamerSymmetricRing(t *testing.T) {
+	// serine with OG turned into the CG of a phenylalanine whose ring is cut down to CD1 and CD2
+	receptor := moltest.Serine()
+	receptor.Atoms[5].Name, receptor.Atoms[5].Element = "CG", "C"
+	receptor.Atoms = append(receptor.Atoms,
+		molecule.Atom{Name: "CD1", Element: "C", Position: molecule.Position3d{X: -0.24, Y: 3.47, Z: 1.19}},
+		molecule.Atom{Name: "CD2", Element: "C", Position: molecule.Position3d{X: -0.24, Y: 1.36, Z: 2.35}})
+	for i := range receptor.Atoms {
+		receptor.Atoms[i].ResName, receptor.Atoms[i].ResSeq, receptor.Atoms[i].Chain = "PHE", 45, "A"
+	}
+	flexible, err := NewFlexibleResidue(receptor, molecule.SplitResidues(receptor)[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flexible.Chis) != 2 || flexible.Chis[0].Symmetric || !flexible.Chis[1].Symmetric {
+		t.Fatalf("Expected χ2 alone to be symmetric, got %+v", flexible.Chis)
+	}
+	// χ2 of -90° is the t80 ring flipped by 180°, not m-85
+	flexible.Chis[0].Set(receptor, -174*math.Pi/180)
+	flexible.Chis[1].Set(receptor, -90*math.Pi/180)
+	if rotamer := flexible.NearestRotamer(receptor); rotamer.Name != "t80" {
+		t.Errorf("Expected rotamer t80 at χ1 -174°, χ2 -90°, got %s", rotamer.Name)
+	}
+}
+
+func TestFlexibleSideChainAvoidsClash(t *testing.T) {
+	receptor := moltest.Serine()
+	flexible, _ := NewFlexibleResidue(receptor, molecule.SplitResidues(receptor)[0])
+	// an atom of the next residue right where OG sits in the m rotamer
+	flexible.Chis[0].Set(receptor, -65*math.Pi/180)
+	wall := molecule.Atom{Name: "CB", Element: "C", ResName: "ALA", ResSeq: 46, Chain: "A", Position: receptor.Atoms[5].Position.Add(molecule.Position3d{X: 0.3})}
+	receptor.Atoms = append(receptor.Atoms, wall)
+
+	sim := NewSimulation(400, false, 300, 2)
+	sim.Seed = 3
+	sideChains, err := NewSideChains(receptor, molecule.SplitResidues(receptor)[:1], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim.Flexible = sideChains
+	if strain := sideChains.ReceptorEnergy(receptor, sim.Energy); strain <= 0 {
+		t.Fatalf("Expected the starting side chain to clash, got strain %g", strain)
+	}
+	ligand := moltest.LigandWithCharges(0, 0)
+	pose, final, _, err := SimulateFlexibleLigand(context.Background(), receptor, ligand, 0, sim, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strain := sideChains.Energy(final, pose, sim.Energy); strain != 0 {
+		t.Errorf("Expected the side chain to turn away from the clash, got strain %g", strain)
+	}
+	for i := 0; i < 5; i++ {
+		if final.Atoms[i].Position != receptor.Atoms[i].Position {
+			t.Errorf("Expected atom %s to stay put, moved to %+v", final.Atoms[i].Name, final.Atoms[i].Position)
+		}
+	}
+	if receptor.Atoms[5].Position == final.Atoms[5].Position {
+		t.Errorf("Expected the walkers to turn their own copies of the receptor")
+	}
+}
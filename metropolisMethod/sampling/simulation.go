@@ -0,0 +1,214 @@
+package sampling
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strconv"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/energy"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// these constants are not universal but adjustable parameters for the simulation
+const THRESHOLD = 5        // max distance between protein and ligand in Å to start the simulation with
+const MINDISTANCE = 0.5    // min Distance to maintain between atoms of the ligand for it to be considered a valid perturbation
+const MAXANGLE = 0.79      //max angle in radians (= ~45 degrees) for which atom rotation is allowed when perturbing ligands
+const TEMPERATURE = 310.15 // body temperature
+const STEPSIZE = 0.1       // width in Å of the uniform random displacement of each ligand atom per move
+
+// MoveSet controls the random moves proposed to a ligand
+type MoveSet struct {
+	Rotate      bool    `json:"rotate" toml:"rotate"`
+	StepSize    float64 `json:"step_size" toml:"step_size"`       // width in Å of the uniform displacement of each atom
+	MaxAngle    float64 `json:"max_angle" toml:"max_angle"`       // largest rotation in radians
+	MinDistance float64 `json:"min_distance" toml:"min_distance"` // smallest allowed distance in Å between ligand atoms
+}
+
+// DefaultMoveSet returns the moves of the original engine: 0.1 Å steps, rotations of up to MAXANGLE and atoms kept
+// MINDISTANCE apart.
+// Input: a bool rotate
+// Output: a MoveSet
+func DefaultMoveSet(rotate bool) MoveSet {
+	return MoveSet{Rotate: rotate, StepSize: STEPSIZE, MaxAngle: MAXANGLE, MinDistance: MINDISTANCE}
+}
+
+// Propose returns a perturbed copy of the ligand: a random rotation when enabled, then a uniform displacement of
+// every atom, drawn again until no two atoms are closer than MinDistance.
+// Input: a MoveSet, a Molecule ligand, a *rand.Rand source (nil uses the global source)
+// Output: the perturbed Molecule
+func (moves MoveSet) Propose(ligand molecule.Molecule, source *rand.Rand) molecule.Molecule {
+	for {
+		newLigand := molecule.CopyLigand(ligand)
+		if moves.Rotate {
+			newLigand = RotateLigandFrom(newLigand, moves.MaxAngle, source)
+		}
+		for i := range newLigand.Atoms {
+			newLigand.Atoms[i].Position.X += (uniform(source) - 0.5) * moves.StepSize
+			newLigand.Atoms[i].Position.Y += (uniform(source) - 0.5) * moves.StepSize
+			newLigand.Atoms[i].Position.Z += (uniform(source) - 0.5) * moves.StepSize
+		}
+		if IsCollisionFree(newLigand, moves.MinDistance) {
+			return newLigand
+		}
+	}
+}
+
+// uniform draws a number in [0, 1) from source, or from the global source when source is nil.
+// Input: a *rand.Rand source
+// Output: a float64
+func uniform(source *rand.Rand) float64 {
+	if source == nil {
+		return rand.Float64()
+	}
+	return source.Float64()
+}
+
+// TemperatureSchedules are the supported temperature schedules
+var TemperatureSchedules = []string{"constant", "linear", "exponential"}
+
+// TemperatureSchedule sets the Metropolis temperature of each iteration of a walker
+type TemperatureSchedule struct {
+	Kind  string  `json:"schedule" toml:"schedule"` // "constant", "linear" or "exponential"
+	Start float64 `json:"start" toml:"start"`
+	End   float64 `json:"end" toml:"end"` // final temperature of the linear and exponential schedules
+}
+
+// ConstantTemperature returns a schedule that keeps the temperature fixed.
+// Input: a float64 temperature
+// Output: a TemperatureSchedule
+func ConstantTemperature(temperature float64) TemperatureSchedule {
+	return TemperatureSchedule{Kind: "constant", Start: temperature, End: temperature}
+}
+
+// At returns the temperature of an iteration: Start throughout for a constant schedule, or moving from Start at the
+// first iteration to End at the last one, in equal steps (linear) or by a constant factor (exponential).
+// Input: a TemperatureSchedule, an int iteration (0-based), an int iterations
+// Output: a float64 temperature
+func (schedule TemperatureSchedule) At(iteration, iterations int) float64 {
+	if schedule.Kind == "constant" || iterations < 2 {
+		return schedule.Start
+	}
+	progress := float64(iteration) / float64(iterations-1)
+	if schedule.Kind == "exponential" {
+		return schedule.Start * math.Pow(schedule.End/schedule.Start, progress)
+	}
+	return schedule.Start + (schedule.End-schedule.Start)*progress
+}
+
+// String describes the schedule, e.g. "310.15" or "linear 600 → 310.15".
+// Input: a TemperatureSchedule
+// Output: a string
+func (schedule TemperatureSchedule) String() string {
+	start := strconv.FormatFloat(schedule.Start, 'g', -1, 64)
+	if schedule.Kind == "constant" {
+		return start
+	}
+	return schedule.Kind + " " + start + " → " + strconv.FormatFloat(schedule.End, 'g', -1, 64)
+}
+
+// Validate checks the schedule kind and that the temperatures are positive.
+// Input: a TemperatureSchedule
+// Output: an error or nil
+func (schedule TemperatureSchedule) Validate() error {
+	if !slices.Contains(TemperatureSchedules, schedule.Kind) {
+		return fmt.Errorf("unknown temperature schedule %q, expected one of %v", schedule.Kind, TemperatureSchedules)
+	}
+	if schedule.Start <= 0 {
+		return fmt.Errorf("start temperature must be positive, got %v", schedule.Start)
+	}
+	if schedule.Kind != "constant" && schedule.End <= 0 {
+		return fmt.Errorf("end temperature must be positive, got %v", schedule.End)
+	}
+	return nil
+}
+
+// Simulation holds every parameter of a Metropolis run
+type Simulation struct {
+	Iterations int // iterations per ligand, split evenly over the walkers
+	Walkers    int // parallel walkers per ligand; ligands are also spread over this many goroutines
+	Moves      MoveSet
+	Schedule   TemperatureSchedule
+	Energy     energy.EnergyModel
+	Seed       int64       // seeds the random source of every walker; 0 uses the shared global source
+	Box        *Box        // search box for the ligand centroid; nil searches everywhere
+	Flexible   *SideChains // flexible receptor side chains; nil keeps the receptor rigid
+	Restraints []Restraint // restraints added to the energy of every pose, bound to each ligand by NewRestraintSet
+}
+
+// energy is the energy a walker minimises: the protein–ligand energy of the model, plus the strain of the
+// flexible side chains when there are any and the energy of the restraints.
+// Input: a Simulation, a Molecule receptor, a Molecule ligand, a RestraintSet of the ligand
+// Output: a float64 energy
+func (sim Simulation) energy(receptor, ligand molecule.Molecule, restraints RestraintSet) float64 {
+	total := sim.Energy.Energy(receptor, ligand) + restraints.Energy(receptor, ligand)
+	if sim.Flexible != nil {
+		total += sim.Flexible.Energy(receptor, ligand, sim.Energy)
+	}
+	return total
+}
+
+// Box is a rectangular search region: moves that take the ligand centroid outside it are rejected
+type Box struct {
+	Center molecule.Position3d
+	Size   molecule.Position3d // edge lengths in Å
+}
+
+// Contains reports whether a point lies inside the box, edges included.
+// Input: a Box, a Position3d point
+// Output: a bool
+func (box Box) Contains(point molecule.Position3d) bool {
+	return math.Abs(point.X-box.Center.X) <= box.Size.X/2 &&
+		math.Abs(point.Y-box.Center.Y) <= box.Size.Y/2 &&
+		math.Abs(point.Z-box.Center.Z) <= box.Size.Z/2
+}
+
+// PlaceInBox translates a ligand whose centroid lies outside the box so that the centroid is at the box centre.
+// Input: a Box, a Molecule ligand
+// Output: the ligand, moved when needed, and a bool reporting whether it was moved
+func (box Box) PlaceInBox(ligand molecule.Molecule) (molecule.Molecule, bool) {
+	centroid := molecule.Centroid(molecule.Positions(ligand))
+	if box.Contains(centroid) {
+		return ligand, false
+	}
+	moved := molecule.CopyLigand(ligand)
+	shift := box.Center.Add(centroid.Scale(-1))
+	for i := range moved.Atoms {
+		moved.Atoms[i].Position = moved.Atoms[i].Position.Add(shift)
+	}
+	return moved, true
+}
+
+// NewSimulation returns the simulation of the original engine with the given iterations, rotation, constant
+// temperature and walkers, the default moves and energy model, and the global random source.
+// Input: an int iterations, a bool rotate, a float64 temperature, an int numProcs
+// Output: a Simulation
+func NewSimulation(iterations int, rotate bool, temperature float64, numProcs int) Simulation {
+	return Simulation{
+		Iterations: iterations,
+		Walkers:    numProcs,
+		Moves:      DefaultMoveSet(rotate),
+		Schedule:   ConstantTemperature(temperature),
+		Energy:     energy.DefaultEnergyModel(),
+	}
+}
+
+// source returns the random source of one walker of one ligand, so a seeded run gives the same result however
+// the ligands are spread over goroutines. Walker index Walkers is the source that merges the walkers' poses.
+// Input: a Simulation, an int ligand index, an int walker index
+// Output: a *rand.Rand, or nil when the simulation is not seeded
+func (sim Simulation) source(ligand, walker int) *rand.Rand {
+	if sim.Seed == 0 {
+		return nil
+	}
+	return rand.New(rand.NewSource(sim.Seed + int64(ligand)<<20 + int64(walker)))
+}
+
+// StartSource returns the random source that places one ligand at its starting pose before a run, kept apart
+// from the sources of its walkers.
+// Input: a Simulation, an int ligand index
+// Output: a *rand.Rand, or nil when the simulation is not seeded
+func (sim Simulation) StartSource(ligand int) *rand.Rand {
+	return sim.source(ligand, -1)
+}
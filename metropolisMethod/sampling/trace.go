@@ -0,0 +1,189 @@
+package sampling
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+)
+
+// TRACEPOINTS is the maximum number of points recorded per walker; longer runs are sampled evenly
+const TRACEPOINTS = 1000
+
+// POCKETCUTOFF is the distance in Å from the starting pose within which receptor atoms define the pocket
+const POCKETCUTOFF = 8.0
+
+// WalkerTrace records the state of one Metropolis walker at evenly spaced iterations
+type WalkerTrace struct {
+	Iteration      []int
+	Energy         []float64 // energy of the current pose
+	AcceptanceRate []float64 // fraction of moves accepted so far
+	Temperature    []float64
+	Displacement   []float64 // RMSD from the starting pose in Å
+	PocketDistance []float64 // distance of the ligand centroid from the pocket centre in Å
+	Orientation    []float64 // rotation angle from the starting orientation in degrees
+	stride         int
+	accepted       int
+	start          molecule.Molecule
+	pocket         molecule.Position3d
+}
+
+// NewWalkerTrace creates an empty trace for a walker of the given number of iterations.
+// Input: an int iterations
+// Output: a *WalkerTrace
+func NewWalkerTrace(iterations int) *WalkerTrace {
+	stride := 1
+	if iterations > TRACEPOINTS {
+		stride = (iterations + TRACEPOINTS - 1) / TRACEPOINTS
+	}
+	return &WalkerTrace{stride: stride}
+}
+
+// Begin stores the starting pose of the walker and the pocket centre the pose is measured against.
+// Input: a Molecule protein, a Molecule start
+// Output: none (updates the trace)
+func (trace *WalkerTrace) Begin(protein, start molecule.Molecule) {
+	trace.start = molecule.CopyLigand(start)
+	trace.pocket = PocketCentre(protein, start, POCKETCUTOFF)
+}
+
+// Record counts the move of an iteration and stores the walker state when the iteration is sampled.
+// Iteration 0 is the starting pose.
+// Input: an int iteration, a float64 energy, a bool accepted, a float64 temperature, the current Molecule
+// Output: none (updates the trace)
+func (trace *WalkerTrace) Record(iteration int, energy float64, accepted bool, temperature float64, current molecule.Molecule) {
+	if accepted {
+		trace.accepted++
+	}
+	if iteration%trace.stride != 0 {
+		return
+	}
+	rate := 0.0
+	if iteration > 0 {
+		rate = float64(trace.accepted) / float64(iteration)
+	}
+	positions := molecule.Positions(current)
+	trace.Iteration = append(trace.Iteration, iteration)
+	trace.Energy = append(trace.Energy, energy)
+	trace.AcceptanceRate = append(trace.AcceptanceRate, rate)
+	trace.Temperature = append(trace.Temperature, temperature)
+	trace.Displacement = append(trace.Displacement, analysis.CalculateRMSD(current, trace.start))
+	trace.PocketDistance = append(trace.PocketDistance, molecule.Distance(molecule.Centroid(positions), trace.pocket))
+	trace.Orientation = append(trace.Orientation, OrientationAngle(molecule.Positions(trace.start), positions))
+}
+
+// PocketCentre returns the centroid of the receptor atoms within cutoff of any ligand atom, or the ligand
+// centroid when no receptor atom is that close.
+// Input: a Molecule protein, a Molecule ligand, a float64 cutoff in Å
+// Output: a Position3d
+func PocketCentre(protein, ligand molecule.Molecule, cutoff float64) molecule.Position3d {
+	var pocket []molecule.Position3d
+	for _, atomP := range protein.Atoms {
+		for _, atomL := range ligand.Atoms {
+			if molecule.Distance(atomP.Position, atomL.Position) <= cutoff {
+				pocket = append(pocket, atomP.Position)
+				break
+			}
+		}
+	}
+	if len(pocket) == 0 {
+		return molecule.Centroid(molecule.Positions(ligand))
+	}
+	return molecule.Centroid(pocket)
+}
+
+// OrientationAngle returns the angle of the rotation that best superposes the start onto the current pose.
+// Input: slices of Position3d start and current of equal length
+// Output: a float64 angle in degrees between 0 and 180
+func OrientationAngle(start, current []molecule.Position3d) float64 {
+	if len(start) < 3 {
+		return 0
+	}
+	r, _, _ := analysis.KabschRotation(start, current)
+	cos := (r[0][0] + r[1][1] + r[2][2] - 1) / 2
+	return math.Acos(math.Max(-1, math.Min(1, cos))) * 180 / math.Pi
+}
+
+// traceColumns is the header of the trace CSV files
+var traceColumns = []string{"walker", "iteration", "energy", "acceptance_rate", "temperature", "displacement", "pocket_distance", "orientation"}
+
+// WriteTraceCSV writes the traces of all walkers of a simulation to one CSV file.
+// Input: a string fileName, a slice of WalkerTrace
+// Output: an error or nil
+func WriteTraceCSV(fileName string, walkers []WalkerTrace) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	if err := writer.Write(traceColumns); err != nil {
+		return err
+	}
+	for w, walker := range walkers {
+		for i, iteration := range walker.Iteration {
+			row := []string{
+				strconv.Itoa(w),
+				strconv.Itoa(iteration),
+				strconv.FormatFloat(walker.Energy[i], 'g', -1, 64),
+				strconv.FormatFloat(walker.AcceptanceRate[i], 'f', 4, 64),
+				strconv.FormatFloat(walker.Temperature[i], 'g', -1, 64),
+				strconv.FormatFloat(walker.Displacement[i], 'f', 4, 64),
+				strconv.FormatFloat(walker.PocketDistance[i], 'f', 4, 64),
+				strconv.FormatFloat(walker.Orientation[i], 'f', 2, 64),
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadTraceCSV reads the walker traces written by WriteTraceCSV.
+// Input: a string fileName
+// Output: a slice of WalkerTrace in walker order and an error or nil
+func ReadTraceCSV(fileName string) ([]WalkerTrace, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", fileName, err)
+	}
+	if len(records) == 0 || len(records[0]) != len(traceColumns) {
+		return nil, fmt.Errorf("%s is not a trace file with columns %v", fileName, traceColumns)
+	}
+	var walkers []WalkerTrace
+	for line, record := range records[1:] {
+		values := make([]float64, len(record))
+		for i, field := range record {
+			if values[i], err = strconv.ParseFloat(field, 64); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", fileName, line+2, err)
+			}
+		}
+		w := int(values[0])
+		if w < 0 || w > len(walkers) {
+			return nil, fmt.Errorf("%s line %d: walker %d out of order", fileName, line+2, w)
+		}
+		if w == len(walkers) {
+			walkers = append(walkers, WalkerTrace{})
+		}
+		walker := &walkers[w]
+		walker.Iteration = append(walker.Iteration, int(values[1]))
+		walker.Energy = append(walker.Energy, values[2])
+		walker.AcceptanceRate = append(walker.AcceptanceRate, values[3])
+		walker.Temperature = append(walker.Temperature, values[4])
+		walker.Displacement = append(walker.Displacement, values[5])
+		walker.PocketDistance = append(walker.PocketDistance, values[6])
+		walker.Orientation = append(walker.Orientation, values[7])
+	}
+	return walkers, nil
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+)
+
+// MAXUPLOADBYTES is the largest structure file or request body the server accepts
+const MAXUPLOADBYTES = 64 << 20
+
+// UPLOADFILE is the name of the file that stores an upload's metadata next to it
+const UPLOADFILE = "upload.json"
+
+// UploadedFile is a structure file stored by the server, referred to by its id in job requests
+type UploadedFile struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Atoms int    `json:"atoms"`
+	Path  string `json:"path"`
+}
+
+// JobRequest is the body of POST /api/jobs
+type JobRequest struct {
+	Protein  string          `json:"protein"`  // id of an uploaded file; empty uses inputs.protein of the config
+	Ligands  []string        `json:"ligands"`  // ids of uploaded mol2 files; empty uses the inputs of the config
+	Config   json.RawMessage `json:"config"`   // run configuration decoded over the server defaults; the version may be left out
+	Priority int             `json:"priority"` // higher runs first
+}
+
+// Server is the HTTP/JSON service of the serve command. Uploads and jobs are kept under its directory, so both
+// survive a restart.
+type Server struct {
+	dir      string
+	defaults RunConfig
+	forest   *RandomForest // nil when no model is loaded
+	jobs     *JobManager
+	mutex    sync.Mutex
+	files    map[string]UploadedFile
+}
+
+// NewServer creates a server keeping its files in dir, running up to maxJobs screens at a time, and loads the
+// uploads and jobs stored there by an earlier run.
+// Input: a string dir, a RunConfig defaults for the jobs, a *RandomForest for predictions (nil for none), an int maxJobs
+// Output: a *Server and an error or nil
+func NewServer(dir string, defaults RunConfig, forest *RandomForest, maxJobs int) (*Server, error) {
+	jobs, err := NewJobManager(filepath.Join(dir, "jobs"), maxJobs, func(ctx context.Context, config RunConfig) (ScreenResult, error) {
+		return RunMultipleLigands(ctx, config, "run-config.json")
+	})
+	if err != nil {
+		return nil, err
+	}
+	server := &Server{dir: dir, defaults: defaults, forest: forest, jobs: jobs, files: make(map[string]UploadedFile)}
+	uploads := filepath.Join(dir, "uploads")
+	if err := os.MkdirAll(uploads, 0755); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(uploads)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(uploads, entry.Name(), UPLOADFILE))
+		if err != nil {
+			continue
+		}
+		var file UploadedFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("loading upload %s: %w", entry.Name(), err)
+		}
+		server.files[file.ID] = file
+	}
+	return server, nil
+}
+
+// Handler returns the routes of the service.
+// Input: a *Server
+// Output: an http.Handler
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/health", server.handleHealth)
+	mux.HandleFunc("GET /api/files", server.handleListFiles)
+	mux.HandleFunc("POST /api/files", server.handleUpload)
+	mux.HandleFunc("GET /api/jobs", server.handleListJobs)
+	mux.HandleFunc("POST /api/jobs", server.handleSubmit)
+	mux.HandleFunc("GET /api/jobs/{id}", server.handleJob)
+	mux.HandleFunc("POST /api/jobs/{id}/cancel", server.handleCancel)
+	mux.HandleFunc("GET /api/jobs/{id}/energies", server.handleEnergies)
+	mux.HandleFunc("GET /api/jobs/{id}/poses/{ligand}", server.handlePose)
+	mux.HandleFunc("GET /api/jobs/{id}/files/{path...}", server.handleJobFile)
+	mux.HandleFunc("POST /api/predict", server.handlePredict)
+	return mux
+}
+
+// Run executes the queued jobs until the context is cancelled; see JobManager.Run.
+// Input: a *Server, a context.Context
+// Output: none
+func (server *Server) Run(ctx context.Context) {
+	server.jobs.Run(ctx)
+}
+
+// listFiles returns the files below a directory as slash-separated relative paths.
+// Input: a string dir
+// Output: a slice of strings and an error or nil
+func listFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		relative, err := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(relative))
+		return err
+	})
+	return files, err
+}
+
+// newID returns a random hexadecimal identifier.
+// Input: none
+// Output: a string
+func newID() string {
+	var id [8]byte
+	_, err := rand.Read(id[:])
+	Check(err)
+	return hex.EncodeToString(id[:])
+}
+
+// writeJSON writes a value as a JSON response.
+// Input: an http.ResponseWriter, an int status code, a value
+// Output: none
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		log.Printf("Writing response: %v", err)
+	}
+}
+
+// writeError writes {"error": message} and, for an invalid run configuration, the list of its problems.
+// Input: an http.ResponseWriter, an int status code, an error
+// Output: none
+func writeError(w http.ResponseWriter, status int, err error) {
+	response := map[string]interface{}{"error": err.Error()}
+	var problems ConfigErrors
+	if errors.As(err, &problems) {
+		response["error"], response["problems"] = "invalid run configuration", []string(problems)
+	}
+	writeJSON(w, status, response)
+}
+
+// finishedJob looks up the job of a request and writes an error unless it is done.
+// Input: a *Server, an http.ResponseWriter, an *http.Request with an {id} path value
+// Output: the Job and a bool reporting whether it is done
+func (server *Server) finishedJob(w http.ResponseWriter, r *http.Request) (Job, bool) {
+	job, ok := server.jobs.Get(r.PathValue("id"))
+	switch {
+	case !ok:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %q", r.PathValue("id")))
+	case job.Status != JobDone:
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s is %s", job.ID, job.Status))
+	default:
+		return job, true
+	}
+	return job, false
+}
+
+func (server *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{"status": "ok"}
+	if server.forest != nil {
+		response["model"] = map[string]interface{}{"features": server.forest.Features, "target": server.forest.Target}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (server *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
+	server.mutex.Lock()
+	files := make([]UploadedFile, 0, len(server.files))
+	for _, file := range server.files {
+		files = append(files, file)
+	}
+	server.mutex.Unlock()
+	slices.SortFunc(files, func(a, b UploadedFile) int { return strings.Compare(a.Name, b.Name) })
+	writeJSON(w, http.StatusOK, files)
+}
+
+// handleUpload stores the body of POST /api/files?name=<file name> as a structure file. The extension selects the
+// format (.mol2, .pdb or .pqr) and the file must parse.
+func (server *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	extension := strings.ToLower(filepath.Ext(name))
+	if name == "" || filepath.Base(name) != name || !slices.Contains([]string{".mol2", ".pdb", ".pqr"}, extension) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("the name query parameter must be a .mol2, .pdb or .pqr file name, got %q", name))
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MAXUPLOADBYTES))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	file := UploadedFile{ID: newID(), Name: name}
+	folder := filepath.Join(server.dir, "uploads", file.ID)
+	file.Path = filepath.Join(folder, name)
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := os.WriteFile(file.Path, data, 0644); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	mol, err := molio.LoadMolecule(file.Path)
+	if molio.IsWarning(err) {
+		err = nil
+	}
+	if err == nil && len(mol.Atoms) == 0 {
+		err = fmt.Errorf("%s has no atoms", name)
+	}
+	if err != nil {
+		os.RemoveAll(folder)
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	file.Atoms = len(mol.Atoms)
+	metadata, err := json.Marshal(file)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(folder, UPLOADFILE), metadata, 0644)
+	}
+	if err != nil {
+		os.RemoveAll(folder)
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	server.mutex.Lock()
+	server.files[file.ID] = file
+	server.mutex.Unlock()
+	writeJSON(w, http.StatusCreated, file)
+}
+
+// handleListJobs lists the jobs in submission order, only those with a given status with ?status=<status>.
+func (server *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := server.jobs.List()
+	if status := r.URL.Query().Get("status"); status != "" {
+		jobs = slices.DeleteFunc(jobs, func(job Job) bool { return string(job.Status) != status })
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// handleSubmit validates a JobRequest and queues its screen with its priority. The job writes to its own folder.
+func (server *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var request JobRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAXUPLOADBYTES))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	config, err := server.jobConfig(request)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	job, err := server.jobs.Submit(config, request.Priority)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// jobConfig resolves the run configuration of a job request: the server defaults, then the config of the request,
+// then the uploaded files it names.
+// Input: a *Server, a JobRequest
+// Output: the validated RunConfig and an error or nil
+func (server *Server) jobConfig(request JobRequest) (RunConfig, error) {
+	config := server.defaults
+	if len(request.Config) > 0 {
+		var err error
+		if config, err = ReadRunConfig(bytes.NewReader(request.Config), "json", server.defaults); err != nil {
+			return config, fmt.Errorf("reading config: %w", err)
+		}
+		if config.Version == 0 {
+			config.Version = CONFIGVERSION
+		}
+	}
+	server.mutex.Lock()
+	var errs ConfigErrors
+	if request.Protein != "" {
+		if file, ok := server.files[request.Protein]; ok {
+			config.Inputs.Protein = file.Path
+		} else {
+			errs = append(errs, fmt.Sprintf("protein: no uploaded file %q", request.Protein))
+		}
+	}
+	if len(request.Ligands) > 0 {
+		config.Inputs.Ligands = nil
+		for i, id := range request.Ligands {
+			file, ok := server.files[id]
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Sprintf("ligands[%d]: no uploaded file %q", i, id))
+			case !strings.EqualFold(filepath.Ext(file.Name), ".mol2"):
+				errs = append(errs, fmt.Sprintf("ligands[%d]: %s is not a mol2 file", i, file.Name))
+			default:
+				config.Inputs.Ligands = append(config.Inputs.Ligands, file.Path)
+			}
+		}
+	}
+	server.mutex.Unlock()
+	if len(errs) > 0 {
+		return config, errs
+	}
+	config = completeRunConfig(config)
+	if err := config.Validate(); err != nil {
+		return config, err
+	}
+	return config, config.ValidateInputs()
+}
+
+func (server *Server) handleJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := server.jobs.Get(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %q", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// handleCancel cancels a queued job, or stops the simulation of a running one; poll the job to see it cancelled.
+func (server *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	if _, ok := server.jobs.Get(r.PathValue("id")); !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %q", r.PathValue("id")))
+		return
+	}
+	job, err := server.jobs.Cancel(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// handleEnergies returns the binding energy of every ligand of a finished job, as JSON or, with ?format=csv, as
+// the table of simulations.csv.
+func (server *Server) handleEnergies(w http.ResponseWriter, r *http.Request) {
+	job, ok := server.finishedJob(w, r)
+	if !ok {
+		return
+	}
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"ligand", "BindingEnergy"})
+		for _, ligand := range job.Result.Ligands {
+			writer.Write([]string{ligand.Label, strconv.FormatFloat(ligand.Energy, 'f', 6, 64)})
+		}
+		writer.Flush()
+		return
+	}
+	energies := make([]map[string]interface{}, len(job.Result.Ligands))
+	for i, ligand := range job.Result.Ligands {
+		energies[i] = map[string]interface{}{"ligand": ligand.Label, "energy": analysis.FiniteOrNil(ligand.Energy)}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"protein": job.Result.Protein, "energies": energies})
+}
+
+// handlePose returns the final MOL2 pose of one ligand of a finished job.
+func (server *Server) handlePose(w http.ResponseWriter, r *http.Request) {
+	job, ok := server.finishedJob(w, r)
+	if !ok {
+		return
+	}
+	label := strings.TrimSuffix(r.PathValue("ligand"), ".mol2")
+	for _, ligand := range job.Result.Ligands {
+		if ligand.Label == label {
+			w.Header().Set("Content-Type", "chemical/x-mol2")
+			http.ServeFile(w, r, ligand.Pose)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("job %s has no ligand %q", job.ID, label))
+}
+
+// handleJobFile returns one output file of a job (plots, tables, traces, the report), by its path in Job.Files.
+func (server *Server) handleJobFile(w http.ResponseWriter, r *http.Request) {
+	job, ok := server.jobs.Get(r.PathValue("id"))
+	path := r.PathValue("path")
+	if !ok || !slices.Contains(job.Files, path) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no file %q in job %q", path, r.PathValue("id")))
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(job.Config.Outputs.Dir, filepath.FromSlash(path)))
+}
+
+// handlePredict predicts the target of each row of {"rows": [{"<feature>": value, ...}]} with the loaded random
+// forest. Feature names are matched case-insensitively, as in the predict command.
+func (server *Server) handlePredict(w http.ResponseWriter, r *http.Request) {
+	if server.forest == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("no model loaded; start the server with -model"))
+		return
+	}
+	var request struct {
+		Rows []map[string]float64 `json:"rows"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAXUPLOADBYTES)).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	predictions := make([]float64, len(request.Rows))
+	for i, row := range request.Rows {
+		values := make(map[string]float64, len(row))
+		for name, value := range row {
+			values[strings.ToLower(strings.TrimSpace(name))] = value
+		}
+		x := make([]float64, len(server.forest.Features))
+		for j, feature := range server.forest.Features {
+			value, ok := values[strings.ToLower(strings.TrimSpace(feature))]
+			if !ok {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("rows[%d]: missing feature %q", i, feature))
+				return
+			}
+			x[j] = value
+		}
+		predictions[i] = server.forest.Predict(x)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"target": server.forest.Target, "predictions": predictions})
+}
+
+// ServeMain is the entry point of the "serve" command, which runs the local HTTP/JSON service until interrupted.
+// Usage: serve [flags]
+// Input: a slice of strings args (without the command name)
+// Output: none
+func ServeMain(args []string) {
+	defaults := DefaultRunConfig()
+	defaults.Inputs = InputConfig{}
+	flags := commandFlags("serve", "",
+		"Runs a local HTTP service with JSON endpoints, so the Shiny app, notebooks and scripts can share one process:\n"+
+			"  GET  /api/health                        status and the loaded model\n"+
+			"  POST /api/files?name=<file>             upload a receptor or ligand (.mol2, .pdb or .pqr) as the body\n"+
+			"  GET  /api/files                         list the uploads\n"+
+			"  POST /api/jobs                          submit {\"protein\": id, \"ligands\": [id...], \"config\": {...}, \"priority\": 0}\n"+
+			"  GET  /api/jobs, /api/jobs/{id}          list jobs (?status=queued for one state), poll one\n"+
+			"  POST /api/jobs/{id}/cancel              cancel a queued job or stop a running one\n"+
+			"  GET  /api/jobs/{id}/energies            binding energies (?format=csv for a table)\n"+
+			"  GET  /api/jobs/{id}/poses/{ligand}      final MOL2 pose of a ligand\n"+
+			"  GET  /api/jobs/{id}/files/{path}        any output file listed by the job (plots, report, traces)\n"+
+			"  POST /api/predict                       random forest predictions for {\"rows\": [{feature: value}]}\n"+
+			"Up to -jobs jobs run at a time, highest priority first, each on -procs walkers (by default the CPUs divided by -jobs).\n"+
+			"Uploads and jobs are kept in -workdir; jobs that were queued or running when the server stopped run again after a\n"+
+			"restart. The config of a job is decoded over the defaults set by -config and the flags.")
+	address := flags.String("addr", "127.0.0.1:8080", "address to listen on")
+	workDir := flags.String("workdir", "Output/server", "directory for uploads and job outputs")
+	maxJobs := flags.Int("jobs", 1, "number of jobs run at the same time")
+	modelFile := flags.String("model", "", "random forest model.json for /api/predict (optional)")
+	configFile := flags.String("config", "", "run configuration (.json or .toml) with the defaults of the jobs")
+	defaults.AddSimulationFlags(flags)
+	defaults.AddOutputFlags(flags)
+	flags.Parse(args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return
+	}
+	if *maxJobs < 1 {
+		fmt.Println("-jobs must be at least 1")
+		os.Exit(1)
+	}
+	procsSet := false
+	flags.Visit(func(f *flag.Flag) { procsSet = procsSet || f.Name == "procs" })
+	if !procsSet {
+		defaults.Parallel.Walkers = max(1, runtime.NumCPU() / *maxJobs)
+	}
+	config, err := ResolveRunConfig(flags, *configFile, defaults)
+	exitOnConfigError(err)
+	seeded := false
+	flags.Visit(func(f *flag.Flag) { seeded = seeded || f.Name == "seed" })
+	if !seeded && *configFile != "" {
+		fileConfig, err := LoadRunConfig(*configFile, defaults)
+		seeded = err == nil && fileConfig.Seed != 0
+	}
+	if !seeded {
+		config.Seed = 0 // each job draws its own seed unless its config gives one
+	}
+	var forest *RandomForest
+	if *modelFile != "" {
+		model, err := LoadRandomForest(*modelFile)
+		Check(err)
+		forest = &model
+	}
+	server, err := NewServer(*workDir, config, forest, *maxJobs)
+	Check(err)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	stopped := make(chan struct{})
+	go func() {
+		server.Run(ctx)
+		close(stopped)
+	}()
+	httpServer := &http.Server{Addr: *address, Handler: server.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdown)
+	}()
+	fmt.Printf("Serving on http://%s (files in %s)\n", *address, *workDir)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		Check(err)
+	}
+	<-stopped // running jobs are stopped and saved to be queued again
+}
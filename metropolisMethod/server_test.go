@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/internal/moltest"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+)
+
+// request sends a request to the test server and decodes the JSON response into out (when not nil).
+func request(t *testing.T, method, url string, body []byte, out interface{}) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+			t.Fatalf("Decoding the response of %s %s: %v", method, url, err)
+		}
+	}
+	return response.StatusCode
+}
+
+// upload posts a molecule as a mol2 file and returns its id.
+func upload(t *testing.T, url, name string, m molecule.Molecule) string {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), name)
+	if err := molio.SaveMol2(fileName, m); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file UploadedFile
+	if status := request(t, "POST", url+"/api/files?name="+name, data, &file); status != http.StatusCreated {
+		t.Fatalf("Expected %s to be uploaded, got status %d", name, status)
+	}
+	return file.ID
+}
+
+func TestServerJob(t *testing.T) {
+	defaults := DefaultRunConfig()
+	defaults.Inputs = InputConfig{}
+	defaults.Outputs.Traces, defaults.Outputs.Distributions, defaults.Outputs.Report = false, false, false
+	X, y := make([][]float64, 40), make([]float64, 40)
+	for i := range X {
+		X[i], y[i] = []float64{float64(i)}, float64(10*i)
+	}
+	forest := TrainRandomForest(X, y, []string{"vdw"}, "affinity", DefaultForestSettings())
+	server, err := NewServer(t.TempDir(), defaults, &forest, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Run(ctx)
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	if status := request(t, "POST", ts.URL+"/api/files?name=../x.mol2", []byte("x"), nil); status != http.StatusBadRequest {
+		t.Errorf("Expected a file name with a directory to be refused, got status %d", status)
+	}
+	protein := upload(t, ts.URL, "test_protein.mol2", moltest.Protein(0.5, -0.5))
+	ligand := upload(t, ts.URL, "lig1_ligand.mol2", moltest.Ligand())
+
+	var invalid map[string]interface{}
+	body := []byte(`{"protein": "` + protein + `", "ligands": ["` + ligand + `"], "config": {"iterations": 0}}`)
+	if status := request(t, "POST", ts.URL+"/api/jobs", body, &invalid); status != http.StatusBadRequest || invalid["problems"] == nil {
+		t.Errorf("Expected the problems of an invalid config, got status %d and %v", status, invalid)
+	}
+
+	var job Job
+	body = []byte(`{"protein": "` + protein + `", "ligands": ["` + ligand + `"], "config": {"iterations": 20, "seed": 3, "parallel": {"walkers": 1}}}`)
+	if status := request(t, "POST", ts.URL+"/api/jobs", body, &job); status != http.StatusAccepted || job.Config.Seed != 3 {
+		t.Fatalf("Expected the job to be queued, got status %d and %+v", status, job)
+	}
+	for deadline := time.Now().Add(30 * time.Second); job.Status != JobDone && job.Status != JobFailed && time.Now().Before(deadline); {
+		time.Sleep(20 * time.Millisecond)
+		request(t, "GET", ts.URL+"/api/jobs/"+job.ID, nil, &job)
+	}
+	if job.Status != JobDone {
+		t.Fatalf("Expected the job to finish, got %+v", job)
+	}
+
+	var energies struct {
+		Protein  string
+		Energies []struct {
+			Ligand string
+			Energy float64
+		}
+	}
+	request(t, "GET", ts.URL+"/api/jobs/"+job.ID+"/energies", nil, &energies)
+	if len(energies.Energies) != 1 || energies.Energies[0].Ligand != "lig1" || energies.Protein != "test" {
+		t.Errorf("Expected the energy of lig1 against test, got %+v", energies)
+	}
+	response, err := http.Get(ts.URL + "/api/jobs/" + job.ID + "/poses/lig1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pose bytes.Buffer
+	pose.ReadFrom(response.Body)
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK || !strings.Contains(pose.String(), "@<TRIPOS>ATOM") {
+		t.Errorf("Expected the MOL2 pose of lig1, got status %d", response.StatusCode)
+	}
+	if status := request(t, "GET", ts.URL+"/api/jobs/"+job.ID+"/files/../../uploads", nil, nil); status == http.StatusOK {
+		t.Errorf("Expected files outside the job to be refused")
+	}
+
+	var predictions struct{ Predictions []float64 }
+	if status := request(t, "POST", ts.URL+"/api/predict", []byte(`{"rows": [{"VDW": 30}, {"vdw": 0}]}`), &predictions); status != http.StatusOK ||
+		len(predictions.Predictions) != 2 || predictions.Predictions[0] <= predictions.Predictions[1] {
+		t.Errorf("Expected two ordered predictions, got status %d and %v", status, predictions)
+	}
+	if status := request(t, "POST", ts.URL+"/api/predict", []byte(`{"rows": [{"elec": 3}]}`), nil); status != http.StatusBadRequest {
+		t.Errorf("Expected a row without the model's features to be refused, got status %d", status)
+	}
+}
+
+func TestRunMultipleLigandsReturnsErrors(t *testing.T) {
+	dir := t.TempDir()
+	config := DefaultRunConfig()
+	config.Inputs = InputConfig{Protein: filepath.Join(dir, "test_protein.mol2"), Ligands: []string{filepath.Join(dir, "missing_ligand.mol2")}}
+	config.Outputs.Dir = filepath.Join(dir, "out")
+	if err := molio.SaveMol2(config.Inputs.Protein, moltest.Protein(0.5, -0.5)); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("Expected a missing ligand to be returned as an error, got a panic: %v", r)
+		}
+	}()
+	if _, err := RunMultipleLigands(context.Background(), config, "run-config.json"); err == nil {
+		t.Errorf("Expected an error for a missing ligand file")
+	}
+}
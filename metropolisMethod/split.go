@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+)
+
+// complexFiles expands the inputs of the split command: files are kept and directories are replaced by their
+// .pdb, .ent, .pdb.gz and .ent.gz files.
+// Input: a slice of strings inputs
+// Output: a slice of file names and an error or nil
+func complexFiles(inputs []string) ([]string, error) {
+	var files []string
+	for _, input := range inputs {
+		info, err := os.Stat(input)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, input)
+			continue
+		}
+		entries, err := os.ReadDir(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), ".gz")
+			if !entry.IsDir() && (strings.HasSuffix(name, ".pdb") || strings.HasSuffix(name, ".ent")) {
+				files = append(files, filepath.Join(input, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// SplitMain is the entry point of the "split" command, which splits downloaded PDB complexes into protein and
+// ligand files for the prepare command.
+// Usage: split [flags] complex|directory [...] outputDir
+// Input: a slice of strings args (without the command name)
+// Output: none (writes the split files)
+func SplitMain(args []string) {
+	flags := commandFlags("split", "complex|directory [...] outputDir",
+		"Splits PDB complexes (.pdb, .ent, optionally gzipped, or directories of them) into <pdb>_protein.pdb with the\n"+
+			"ATOM records and <pdb>_ligand.pdb with the HETATM records of the first model. Waters are dropped.")
+	keepWater := flags.Bool("keep-water", false, "keep water molecules in the ligand file")
+	allModels := flags.Bool("models", false, "keep every model in the protein file, as snapshots for ensemble docking")
+	flags.Parse(args)
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return
+	}
+	inputs, outputDir := flags.Args()[:flags.NArg()-1], flags.Arg(flags.NArg()-1)
+	files, err := complexFiles(inputs)
+	Check(err)
+	Check(os.MkdirAll(outputDir, 0755))
+	split := 0
+	for _, file := range files {
+		proteinFile, ligandFile, err := molio.SplitComplexFile(file, outputDir, *keepWater, *allModels)
+		if err != nil {
+			fmt.Println("Skipping:", err)
+			continue
+		}
+		fmt.Println("Split", file, "into", proteinFile, "and", ligandFile)
+		split++
+	}
+	fmt.Printf("Split %d of %d complexes into %s\n", split, len(files), outputDir)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+// plotWalkerSeries plots one value of every walker against the iteration, one line per walker.
+// Input: a slice of WalkerTrace, a function selecting the values of a walker, a string fileName (without extension),
+// a string title, a string yLabel, a PlotOptions
+// Output: none (saves a plot)
+func plotWalkerSeries(walkers []sampling.WalkerTrace, values func(sampling.WalkerTrace) []float64, fileName, title, yLabel string, options PlotOptions) {
+	p := plot.New()
+	p.Title.Text = title
+	p.X.Label.Text = "Iteration"
+	p.Y.Label.Text = yLabel
+	for w, walker := range walkers {
+		series := values(walker)
+		points := make(plotter.XYs, len(series))
+		for i := range series {
+			points[i].X, points[i].Y = float64(walker.Iteration[i]), series[i]
+		}
+		line, err := plotter.NewLine(points)
+		Check(err)
+		line.Color = plotutil.Color(w)
+		p.Add(line)
+		if len(walkers) <= 8 {
+			p.Legend.Add(fmt.Sprintf("walker %d", w), line)
+		}
+	}
+	p.Legend.Top = true
+	savePlot(p, 6*vg.Inch, 4*vg.Inch, fileName, options)
+}
+
+// SaveSimulationTrace writes the walker traces of one simulation as a CSV file and diagnostic plots of the
+// energy, running acceptance rate, temperature and distance from the starting pose against the iteration.
+// Input: a slice of WalkerTrace, a string baseName (the files are baseName.csv and baseName-<plot>), a PlotOptions
+// Output: an error or nil
+func SaveSimulationTrace(walkers []sampling.WalkerTrace, baseName string, options PlotOptions) error {
+	if len(walkers) == 0 {
+		return fmt.Errorf("no walker traces for %s", baseName)
+	}
+	if err := sampling.WriteTraceCSV(baseName+".csv", walkers); err != nil {
+		return err
+	}
+	plotWalkerSeries(walkers, func(w sampling.WalkerTrace) []float64 { return w.Energy }, baseName+"-energy", "Energy trace", "Protein Ligand Binding Energy", options)
+	plotWalkerSeries(walkers, func(w sampling.WalkerTrace) []float64 { return w.AcceptanceRate }, baseName+"-acceptance", "Running acceptance rate", "Accepted moves (fraction)", options)
+	plotWalkerSeries(walkers, func(w sampling.WalkerTrace) []float64 { return w.Temperature }, baseName+"-temperature", "Temperature schedule", "Temperature", options)
+	plotWalkerSeries(walkers, func(w sampling.WalkerTrace) []float64 { return w.Displacement }, baseName+"-displacement", "Distance from the starting pose", "RMSD (Å)", options)
+	return nil
+}
@@ -5,89 +5,78 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"strconv"
+
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/analysis"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molecule"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/molio"
+	"github.com/Simran-Sodhi/drug-design-dynamics/metropolisMethod/sampling"
 )
 
 // MultipleProteinRMSD computes the RMSD for multiple proteins
-// Input: a string dir, an int iterations, a bool rotate, an int numProteins, an int numProcs
+// Input: a string dir, an int numProteins (0 for all), a RunConfig config whose DockingSimulation redocks each
+// complex, an RMSDMode mode, a string outputDir, a PlotOptions, a *Provenance that records the structures used
+// (may be nil)
 // Output: none (prints the average RMSD and generates an RMSD curve plot)
-func MultipleProteinRMSD(dir string, iterations int, rotate bool, numProteins int, numProcs int) {
+func MultipleProteinRMSD(dir string, numProteins int, config RunConfig, mode analysis.RMSDMode, outputDir string, options PlotOptions, provenance *Provenance) {
 	proteinFiles, err := findFilesWithSubstring(dir, "protein")
-	proteinFiles = proteinFiles[0:numProteins]
-	proteinLabels := make([]string, len(proteinFiles))
 	Check(err)
+	if numProteins > 0 && numProteins < len(proteinFiles) {
+		proteinFiles = proteinFiles[0:numProteins]
+	}
+	proteinLabels := make([]string, len(proteinFiles))
 	rmsd := make([]float64, len(proteinFiles))
 	for i := range proteinFiles {
-		protein, err := ParseMol2(proteinFiles[i])
-		Check(err)
-		label := ExtractFileLabel(proteinFiles[i])
+		protein, err := molio.LoadReceptor(proteinFiles[i])
+		warnOrCheck(err)
+		label := molio.ExtractFileLabel(proteinFiles[i])
 		proteinLabels[i] = label
-		ligand, err2 := ParseMol2(dir + "/" + label + "_ligand.mol2")
+		ligand, err2 := molio.ParseMol2(dir + "/" + label + "_ligand.mol2")
 		//ligand = RandomizeLigandPose(ligand)
-		Check(err2)
-		rmsd[i] = CompareRMSD(protein, ligand, iterations, rotate, TEMPERATURE, numProcs)
+		warnOrCheck(err2)
+		if provenance != nil {
+			Check(provenance.Input("protein", proteinFiles[i]))
+			Check(provenance.Input("ligand", dir+"/"+label+"_ligand.mol2"))
+		}
+		sim, err := config.DockingSimulation(protein, []molecule.Molecule{ligand})
+		Check(err)
+		rmsd[i] = CompareRMSD(protein, ligand, i, sim, mode)
+		fmt.Printf("%s: %.3f\n", label, rmsd[i])
 	}
-	fmt.Println("The average RMSD value was:", average(rmsd))
-	outputDir := "Output/rmsd_curve/"
+	fmt.Printf("The average %s RMSD value was: %v\n", mode, analysis.Mean(rmsd))
 	err2 := os.MkdirAll(outputDir, 0755)
 	Check(err2)
-	plotRMSD(proteinLabels, rmsd, outputDir+"rmsd_curve_"+strconv.Itoa(len(proteinFiles)))
+	plotRMSD(proteinLabels, rmsd, filepath.Join(outputDir, "rmsd_curve_"+mode.String()+"_"+strconv.Itoa(len(proteinFiles))), options)
 }
 
 // CompareRMSD simulates energy minimization of the ligand, then calculates the RMSD between the minimized and reference ligand positions.
-// Input: a Molecule protein, a Molecule ligand, an int iterations, a bool rotate, a float64 temperature, an int numProcs
+// Input: a Molecule protein, a Molecule ligand, an int index (selects the random sources of a seeded simulation), a Simulation sim, an RMSDMode mode
 // Output: a float64 RMSD value
-func CompareRMSD(protein Molecule, ligand Molecule, iterations int, rotate bool, temperature float64, numProcs int) float64 {
-	reference := CopyLigand(ligand)
-	ligand = RandomizeLigandPose(ligand)
-	simulated := SimulateEnergyMinimizationParallel(protein, ligand, iterations, rotate, temperature, numProcs)
-	return CalculateRMSD(simulated, reference)
-}
-
-// CalculateRMSD  calculates the root-mean-square deviation (RMSD) between corresponding atoms in the two given molecules
-// Input: Molecules simulated and reference
-// Output: a float64 RMSD value
-func CalculateRMSD(simulated, reference Molecule) float64 {
-	var sumSquaredDist float64
-	numAtoms := float64(len(simulated.atoms))
-
-	for i := 0; i < len(simulated.atoms); i++ {
-		simPos := simulated.atoms[i].Position
-		refPos := reference.atoms[i].Position
-
-		dx := simPos.X - refPos.X
-		dy := simPos.Y - refPos.Y
-		dz := simPos.Z - refPos.Z
-
-		sumSquaredDist += dx*dx + dy*dy + dz*dz
-	}
-	return math.Sqrt(sumSquaredDist / numAtoms)
+func CompareRMSD(protein molecule.Molecule, ligand molecule.Molecule, index int, sim sampling.Simulation, mode analysis.RMSDMode) float64 {
+	reference := molecule.CopyLigand(ligand)
+	ligand = RandomizeLigandPose(ligand, sim.StartSource(index))
+	simulated, _ := sampling.SimulateLigand(protein, ligand, index, sim, false)
+	return analysis.CalculateRMSDMode(simulated, reference, mode)
 }
 
 // RandomizeLigandPose applies random translations and rotations to a ligand molecule to generate a randomized starting pose.
-// Input: a Molecule ligand
+// Input: a Molecule ligand, a *rand.Rand source (nil uses the global source)
 // Output: a Molecule with randomized pose
-func RandomizeLigandPose(ligand Molecule) Molecule {
-	for i := range ligand.atoms {
-		// Apply random translation (±5 Å)
-		ligand.atoms[i].Position.X += (rand.Float64() - 0.5) * 10.0
-		ligand.atoms[i].Position.Y += (rand.Float64() - 0.5) * 10.0
-		ligand.atoms[i].Position.Z += (rand.Float64() - 0.5) * 10.0
+func RandomizeLigandPose(ligand molecule.Molecule, source *rand.Rand) molecule.Molecule {
+	float := rand.Float64
+	if source != nil {
+		float = source.Float64
 	}
-	// Apply random rotation
-	return RotateLigand(ligand, math.Pi)
-}
-
-// average calculates and returns the average of a slice of float64 values
-// Input: a slice of float64 values
-// Output: a float64 average value
-func average(arr []float64) float64 {
-	if len(arr) == 0 {
-		return 0
+	// Apply the same random translation (±5 Å) to every atom so the ligand stays rigid
+	shift := molecule.Position3d{
+		X: (float() - 0.5) * 10.0,
+		Y: (float() - 0.5) * 10.0,
+		Z: (float() - 0.5) * 10.0,
 	}
-	var sum float64
-	for _, value := range arr {
-		sum += value
+	for i := range ligand.Atoms {
+		ligand.Atoms[i].Position = ligand.Atoms[i].Position.Add(shift)
 	}
-	return sum / float64(len(arr))
+	// Apply random rotation
+	return sampling.RotateLigandFrom(ligand, math.Pi, source)
 }